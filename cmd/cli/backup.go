@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/tmbritton/ecs-db/internal/storage"
+)
+
+const backupUsage = `Usage:
+  ecs-db backup list    [--db path]
+  ecs-db backup verify  [--db path] [version]
+  ecs-db backup restore [--db path] <version>
+`
+
+// runBackup implements `ecs-db backup list|verify|restore`.
+func runBackup(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, backupUsage)
+		return 2
+	}
+	fs := flag.NewFlagSet("backup "+args[0], flag.ContinueOnError)
+	dbPath := fs.String("db", defaultDBPath, "database file")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	switch args[0] {
+	case "list":
+		return backupList(*dbPath)
+	case "verify":
+		return backupVerify(*dbPath, fs.Args())
+	case "restore":
+		if fs.NArg() != 1 {
+			fmt.Fprint(os.Stderr, backupUsage)
+			return 2
+		}
+		version, err := strconv.Atoi(fs.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid version %q\n", fs.Arg(0))
+			return 2
+		}
+		if err := storage.RestoreBackup(*dbPath, version); err != nil {
+			fmt.Fprintf(os.Stderr, "Error restoring backup: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Restored %s from schema version %d backup\n", *dbPath, version)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "unknown backup command %q\n\n%s", args[0], backupUsage)
+		return 2
+	}
+}
+
+func backupList(dbPath string) int {
+	backups, err := storage.ListBackups(dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error %v\n", err)
+		return 1
+	}
+	if len(backups) == 0 {
+		fmt.Printf("No backups of %s\n", dbPath)
+		return 0
+	}
+	for _, b := range backups {
+		fmt.Printf("v%-4d %10d bytes  %s  %s\n", b.Version, b.Size, b.ModTime.Format("2006-01-02 15:04:05"), b.Path)
+	}
+	return 0
+}
+
+// backupVerify verifies the backup for the given version, or every backup
+// when no version is given.
+func backupVerify(dbPath string, args []string) int {
+	backups, err := storage.ListBackups(dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error %v\n", err)
+		return 1
+	}
+	if len(args) == 1 {
+		version, err := strconv.Atoi(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid version %q\n", args[0])
+			return 2
+		}
+		var selected []storage.BackupInfo
+		for _, b := range backups {
+			if b.Version == version {
+				selected = append(selected, b)
+			}
+		}
+		if len(selected) == 0 {
+			fmt.Fprintf(os.Stderr, "Error: no schema version %d backup of %s\n", version, dbPath)
+			return 1
+		}
+		backups = selected
+	}
+
+	status := 0
+	for _, b := range backups {
+		version, err := storage.VerifyBackup(b.Path)
+		if err != nil {
+			fmt.Printf("FAIL %v\n", err)
+			status = 1
+			continue
+		}
+		fmt.Printf("ok   %s (schema version %d)\n", b.Path, version)
+	}
+	return status
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/tmbritton/ecs-db/internal/codegen"
+)
+
+const genUsage = `Usage:
+  ecs-db gen go [--schema path] [--mods a,b] [--pkg name] [--out file]
+`
+
+// runGen implements `ecs-db gen go`, which writes typed component structs
+// and accessors for the current schema.
+func runGen(args []string) int {
+	if len(args) == 0 || args[0] != "go" {
+		fmt.Fprint(os.Stderr, genUsage)
+		return 2
+	}
+	fs := flag.NewFlagSet("gen go", flag.ContinueOnError)
+	schemaPath := fs.String("schema", defaultSchemaPath, "schema.json file")
+	modsDir := fs.String("mods-dir", defaultModsPath, "directory of mods whose schema/*.json fragments are merged")
+	mods := fs.String("mods", "", "comma-separated mod load order (default: every mod in -mods-dir, by name)")
+	pkg := fs.String("pkg", "components", "package name of the generated file")
+	out := fs.String("out", "", "output file (default: <pkg>/<pkg>_gen.go)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	dbSchema, _, err := loadSchema(*schemaPath, *modsDir, *mods)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error %v\n", err)
+		return 1
+	}
+	src, err := codegen.GenerateGo(dbSchema, *pkg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error %v\n", err)
+		return 1
+	}
+
+	path := *out
+	if path == "" {
+		path = filepath.Join(*pkg, *pkg+"_gen.go")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating %s: %v\n", filepath.Dir(path), err)
+		return 1
+	}
+	if err := os.WriteFile(path, src, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", path, err)
+		return 1
+	}
+	fmt.Printf("Wrote %s (schema version %d)\n", path, dbSchema.SchemaVersion)
+	return 0
+}
@@ -1,41 +1,50 @@
 package main
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/tmbritton/ecs-db/internal/schema"
 	"github.com/tmbritton/ecs-db/internal/storage"
 )
 
+const (
+	defaultSchemaPath     = "./schema.json"
+	defaultModsPath       = "./mods"
+	defaultDBPath         = "./ecs.db"
+	defaultMigrationsPath = "./migrations"
+)
+
+const usage = `Usage:
+  ecs-db                            open the database, migrating if needed
+  ecs-db migrate [flags]            apply or plan a schema migration
+  ecs-db backup list|verify|restore manage pre-migration backups
+  ecs-db gen go [flags]             generate typed Go component accessors
+`
+
 func main() {
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1], os.Args[2:]))
+	}
+
 	fmt.Println("ECS Database CLI - Starting up")
 
 	// Load schema
-	schemaPath := "./schema.json"
-	schemaBytes, err := os.ReadFile(schemaPath)
+	dbSchema, schemaHash, err := loadSchema(defaultSchemaPath, defaultModsPath, "")
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error loading schema from schema.json: %v\n", err)
-		os.Exit(1)
-	}
-	dbSchema, err := schema.LoadSchema(schemaBytes)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error parsing schema from schema.json: %v\n", err)
-		os.Exit(1)
-	}
-	if err := schema.ValidateSchema(dbSchema); err != nil {
-		fmt.Fprintf(os.Stderr, "Error validating schema from schema.json: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error %v\n", err)
 		os.Exit(1)
 	}
 
-	// Compute hash for build metadata.
-	hash := sha256.Sum256(schemaBytes)
-	schemaHash := hex.EncodeToString(hash[:])
-
 	// Initialize database
-	db, err := storage.NewSQLiteStore("./ecs.db", dbSchema, schemaHash)
+	db, err := storage.NewSQLiteStoreWithConfig(defaultDBPath, storage.StoreConfig{
+		Schema:        dbSchema,
+		SchemaHash:    schemaHash,
+		MigrationsDir: defaultMigrationsPath,
+	})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error initializing database: %v\n", err)
 		os.Exit(1)
@@ -47,3 +56,70 @@ func main() {
 	// TODO: Add command processing here
 	fmt.Println("Ready for commands (not implemented yet)")
 }
+
+// runCommand dispatches a subcommand and returns the process exit code.
+func runCommand(name string, args []string) int {
+	switch name {
+	case "migrate":
+		return runMigrate(args)
+	case "backup":
+		return runBackup(args)
+	case "gen":
+		return runGen(args)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", name, usage)
+		return 2
+	}
+}
+
+// loadSchema loads schema.json at schemaPath merged with the schema
+// fragments of the mods under modsDir, validates the result and returns it
+// with the SHA-256 hex digest of the fragments. mods is a comma-separated
+// load order; when empty, every mod with a schema directory is loaded in
+// name order.
+func loadSchema(schemaPath, modsDir, mods string) (schema.DatabaseSchema, string, error) {
+	paths, err := schemaPaths(schemaPath, modsDir, mods)
+	if err != nil {
+		return schema.DatabaseSchema{}, "", err
+	}
+	set, err := schema.LoadSchemaSet(paths...)
+	if err != nil {
+		return schema.DatabaseSchema{}, "", fmt.Errorf("loading schema: %w", err)
+	}
+	if err := set.Validate(); err != nil {
+		return schema.DatabaseSchema{}, "", fmt.Errorf("validating schema: %w", err)
+	}
+	return set.Schema, set.Hash, nil
+}
+
+// schemaPaths returns schemaPath followed by the mods/<mod>/schema
+// directories of mods, in load order.
+func schemaPaths(schemaPath, modsDir, mods string) ([]string, error) {
+	var names []string
+	if mods != "" {
+		for _, name := range strings.Split(mods, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	} else {
+		entries, err := os.ReadDir(modsDir)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("listing mods in %s: %w", modsDir, err)
+		}
+		for _, e := range entries {
+			if !e.IsDir() {
+				continue
+			}
+			if info, err := os.Stat(filepath.Join(modsDir, e.Name(), "schema")); err == nil && info.IsDir() {
+				names = append(names, e.Name())
+			}
+		}
+	}
+
+	paths := []string{schemaPath}
+	for _, name := range names {
+		paths = append(paths, filepath.Join(modsDir, name, "schema"))
+	}
+	return paths, nil
+}
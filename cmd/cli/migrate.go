@@ -0,0 +1,99 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/tmbritton/ecs-db/internal/schema"
+	"github.com/tmbritton/ecs-db/internal/storage"
+)
+
+// runMigrate implements `ecs-db migrate`. With --plan it prints what the
+// migration would do and changes nothing; otherwise it applies it.
+func runMigrate(args []string) int {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dbPath := fs.String("db", defaultDBPath, "database file")
+	schemaPath := fs.String("schema", defaultSchemaPath, "schema.json file")
+	modsDir := fs.String("mods-dir", defaultModsPath, "directory of mods whose schema/*.json fragments are merged")
+	mods := fs.String("mods", "", "comma-separated mod load order (default: every mod in -mods-dir, by name)")
+	migrationsDir := fs.String("migrations", defaultMigrationsPath, "data migrations directory (vN/*.sql)")
+	plan := fs.Bool("plan", false, "print the migration plan without applying it")
+	asJSON := fs.Bool("json", false, "with --plan, print the plan as JSON")
+	yes := fs.Bool("yes", false, "apply destructive changes without confirmation")
+	backups := fs.Int("backups", 3, "number of pre-migration backups to keep (0 disables backups)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	dbSchema, schemaHash, err := loadSchema(*schemaPath, *modsDir, *mods)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error %v\n", err)
+		return 1
+	}
+
+	if _, err := os.Stat(*dbPath); errors.Is(err, os.ErrNotExist) {
+		fmt.Printf("No database at %s; it will be created at schema version %d.\n",
+			*dbPath, dbSchema.SchemaVersion)
+		if *plan {
+			return 0
+		}
+	} else if *plan {
+		return printPlan(*dbPath, *migrationsDir, dbSchema, *asJSON)
+	}
+
+	policy := storage.MigrationConfirm
+	if *yes {
+		policy = storage.MigrationAuto
+	}
+	store, err := storage.NewSQLiteStoreWithConfig(*dbPath, storage.StoreConfig{
+		Schema:          dbSchema,
+		SchemaHash:      schemaHash,
+		MigrationPolicy: policy,
+		BackupRetention: *backups,
+		MigrationsDir:   *migrationsDir,
+	})
+	var confirm *storage.MigrationRequiresConfirmation
+	if errors.As(err, &confirm) {
+		fmt.Fprintf(os.Stderr, "%v\nRe-run with --yes to apply, or --plan to review.\n", err)
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error migrating database: %v\n", err)
+		return 1
+	}
+	_ = store.Close()
+	fmt.Printf("Database at schema version %d\n", dbSchema.SchemaVersion)
+	return 0
+}
+
+// printPlan opens the database without migrating it and prints the plan.
+func printPlan(dbPath, migrationsDir string, dbSchema schema.DatabaseSchema, asJSON bool) int {
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		return 1
+	}
+	defer func() { _ = db.Close() }()
+
+	p, err := storage.NewMigrationRunner(db, dbSchema, storage.MigrationConfirm, nil).
+		WithMigrationsDir(migrationsDir).
+		Plan()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error planning migration: %v\n", err)
+		return 1
+	}
+	if !asJSON {
+		fmt.Print(p.Text())
+		return 0
+	}
+	out, err := p.JSON()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding plan: %v\n", err)
+		return 1
+	}
+	fmt.Println(string(out))
+	return 0
+}
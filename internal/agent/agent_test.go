@@ -59,7 +59,16 @@ type attachedComp struct {
 	values   map[string]any
 }
 
-func (w *captureWorldWriter) SpawnEntity(entityType string) (int64, error) { return 1, nil }
+func (w *captureWorldWriter) SpawnEntity(entityType string, components map[string]map[string]any) (int64, error) {
+	return 1, nil
+}
+
+func (w *captureWorldWriter) SpawnPrefab(prefab string, overrides map[string]map[string]any) (int64, error) {
+	return 1, nil
+}
+
+func (w *captureWorldWriter) DestroyEntity(entityID int64) error { return nil }
+
 func (w *captureWorldWriter) DetachComponent(entityID int64, compName string) error {
 	w.detached = append(w.detached, compName)
 	return nil
@@ -74,6 +83,12 @@ func (w *captureWorldWriter) SetComponentValue(entityID int64, compName, field s
 	return nil
 }
 
+func (w *captureWorldWriter) SetResource(name, field string, value any) error { return nil }
+
+func (w *captureWorldWriter) AddRelation(int64, string, int64, map[string]any) error { return nil }
+
+func (w *captureWorldWriter) RemoveRelation(int64, string, int64) error { return nil }
+
 // alwaysHasComponent is a WorldReader where HasComponent always returns true.
 type alwaysHasComponent struct{}
 
@@ -85,6 +100,16 @@ func (r *alwaysHasComponent) FindEntityByType(entityType string) (int64, error)
 	return 0, fmt.Errorf("FindEntityByType: not implemented in test stub")
 }
 
+func (r *alwaysHasComponent) IsA(int64, string) (bool, error) { return false, nil }
+
+func (r *alwaysHasComponent) GetResource(string, string) (any, error) { return nil, nil }
+
+func (r *alwaysHasComponent) RelatedEntities(int64, string) ([]int64, error) { return nil, nil }
+
+func (r *alwaysHasComponent) EntitiesRelatedTo(string, int64) ([]int64, error) { return nil, nil }
+
+func (r *alwaysHasComponent) Query(Query) (QueryRows, error) { return nil, nil }
+
 // actionFunc adapts a plain function to ActionHandler.
 type actionFunc func(ActionContext) error
 
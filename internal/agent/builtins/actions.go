@@ -194,17 +194,105 @@ func (a *dealDamageAction) Run(ctx agent.ActionContext) error {
 
 type spawnEntityAction struct{}
 
+// Run spawns an entity of params.entity_type with the component values in
+// params.components (component name → field values). When params.store_as
+// names a context key, the new entity's ID is written to it, so a later
+// action can target the spawned entity.
 func (a *spawnEntityAction) Run(ctx agent.ActionContext) error {
 	entityType, _ := ctx.Params["entity_type"].(string)
 	if entityType == "" {
 		return nil
 	}
-	id, err := ctx.World.SpawnEntity(entityType)
+	components, err := componentsParam(ctx, "spawnEntity")
+	if err != nil {
+		return err
+	}
+	id, err := ctx.World.SpawnEntity(entityType, components)
 	if err != nil {
 		return err
 	}
 	fmt.Printf("[agent] spawnEntity: created entity %d of type %q\n", id, entityType)
-	return nil
+	return storeSpawnedID(ctx, "spawnEntity", id)
+}
+
+// componentsParam reads params.components, component name → field values.
+// A component given as null has no values; any other non-object is an
+// error naming the action.
+func componentsParam(ctx agent.ActionContext, action string) (map[string]map[string]any, error) {
+	components := map[string]map[string]any{}
+	c, _ := ctx.Params["components"].(map[string]any)
+	for name, data := range c {
+		switch values := data.(type) {
+		case map[string]any:
+			components[name] = values
+		case nil:
+			components[name] = map[string]any{}
+		default:
+			return nil, fmt.Errorf("%s: component %q: data must be an object, got %T", action, name, data)
+		}
+	}
+	return components, nil
+}
+
+// storeSpawnedID writes id to the context key in params.store_as, if any.
+// A key missing from the ContextManifest is logged and skipped, as in
+// setTimer.
+func storeSpawnedID(ctx agent.ActionContext, action string, id int64) error {
+	key, _ := ctx.Params["store_as"].(string)
+	if key == "" {
+		return nil
+	}
+	comp := manifestComp(ctx, key)
+	if comp == "" {
+		fmt.Printf("[agent] %s: key %q not in ContextManifest\n", action, key)
+		return nil
+	}
+	return ctx.World.SetComponentValue(ctx.EntityID, comp, key, id)
+}
+
+// ── spawnPrefab ───────────────────────────────────────────────────────────────
+
+type spawnPrefabAction struct{}
+
+// Run spawns an entity from params.prefab ("Goblin" or "Goblin/elite"),
+// with params.components merged over the prefab's values, and stores its
+// ID like spawnEntity.
+func (a *spawnPrefabAction) Run(ctx agent.ActionContext) error {
+	prefab, _ := ctx.Params["prefab"].(string)
+	if prefab == "" {
+		return nil
+	}
+	overrides, err := componentsParam(ctx, "spawnPrefab")
+	if err != nil {
+		return err
+	}
+	id, err := ctx.World.SpawnPrefab(prefab, overrides)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("[agent] spawnPrefab: created entity %d from prefab %q\n", id, prefab)
+	return storeSpawnedID(ctx, "spawnPrefab", id)
+}
+
+// ── destroyEntity ─────────────────────────────────────────────────────────────
+
+type destroyEntityAction struct{}
+
+// Run destroys the current entity, or params.target when it is set to an
+// entity ID or "$player". A target that cannot be resolved is skipped, as
+// in dealDamage. Actions after destroyEntity still run; later writes to a
+// destroyed entity fail or affect nothing, so it belongs last in its list.
+func (a *destroyEntityAction) Run(ctx agent.ActionContext) error {
+	targetID := ctx.EntityID
+	if target, ok := ctx.Params["target"]; ok && target != "$self" {
+		if ctx.Reader == nil {
+			return nil
+		}
+		if targetID, ok = resolveTargetID(ctx.Reader, target); !ok {
+			return nil
+		}
+	}
+	return ctx.World.DestroyEntity(targetID)
 }
 
 // ── attachComponent ───────────────────────────────────────────────────────────
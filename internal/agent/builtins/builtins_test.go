@@ -4,10 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"math"
+	"strings"
 	"testing"
 
 	"github.com/tmbritton/ecs-db/internal/agent"
 	"github.com/tmbritton/ecs-db/internal/agent/builtins"
+	"github.com/tmbritton/ecs-db/internal/schema"
 	"github.com/tmbritton/ecs-db/internal/storage"
 	_ "modernc.org/sqlite"
 )
@@ -262,21 +264,162 @@ func TestAction_dealDamage_PlayerSentinel(t *testing.T) {
 
 func TestAction_spawnEntity(t *testing.T) {
 	db := setupBuiltinsDB(t)
+	for _, stmt := range []string{
+		`CREATE TABLE world (key TEXT PRIMARY KEY, value TEXT NOT NULL)`,
+		`INSERT INTO world (key, value) VALUES ('current_tick', '4')`,
+		`CREATE TABLE comp_summoner (entity_id INTEGER PRIMARY KEY, minion INTEGER)`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
 	entityID := insertEntity(t, db, "Goblin")
+	if _, err := db.Exec("INSERT INTO comp_summoner (entity_id) VALUES (?)", entityID); err != nil {
+		t.Fatal(err)
+	}
 
 	r := builtins.NewRegistry()
 	runAction(t, db, func(w agent.WorldWriter, rd agent.WorldReader) {
-		ctx := actx(entityID, w, rd, map[string]any{"entity_type": "Player"})
+		ctx := actx(entityID, w, rd, map[string]any{
+			"entity_type": "Player",
+			"components":  map[string]any{"Health": map[string]any{"hp": 30.0}},
+			"store_as":    "minion",
+		})
+		ctx.ContextManifest = map[string]string{"minion": "Summoner"}
 		handler, _ := r.GetAction("spawnEntity")
 		if err := handler.Run(ctx); err != nil {
 			t.Fatalf("spawnEntity: %v", err)
 		}
 	})
 
+	var id, createdTick int64
+	db.QueryRow("SELECT id, created_tick FROM entities WHERE entity_type = 'Player'").Scan(&id, &createdTick)
+	if id == 0 || createdTick != 4 {
+		t.Fatalf("Player entity %d created at tick %d, want one created at tick 4", id, createdTick)
+	}
+	var hp float64
+	db.QueryRow("SELECT hp FROM comp_health WHERE entity_id = ?", id).Scan(&hp)
+	if hp != 30 {
+		t.Errorf("Player hp = %v, want 30", hp)
+	}
+	var minion int64
+	db.QueryRow("SELECT minion FROM comp_summoner WHERE entity_id = ?", entityID).Scan(&minion)
+	if minion != id {
+		t.Errorf("Summoner.minion = %d, want the new entity %d", minion, id)
+	}
+}
+
+func TestAction_spawnPrefab(t *testing.T) {
+	db := setupBuiltinsDB(t)
+	for _, stmt := range []string{
+		`CREATE TABLE world (key TEXT PRIMARY KEY, value TEXT NOT NULL)`,
+		`INSERT INTO world (key, value) VALUES ('current_tick', '2')`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := schema.DatabaseSchema{
+		SchemaVersion: 1,
+		Components: map[string]schema.Component{
+			"Health": {Type: schema.ComponentTypeObject, Properties: map[string]schema.Property{
+				"hp":    {Type: schema.PropertyTypeNumber},
+				"maxHp": {Type: schema.PropertyTypeNumber, Default: float64(100)},
+			}},
+		},
+		EntityTypes: map[string]schema.EntityType{"Imp": {RequiredComponents: []string{"Health"}}},
+		Prefabs: map[string]schema.Prefab{"Imp": {
+			EntityType: "Imp",
+			Components: map[string]map[string]any{"Health": {"hp": 12.0}},
+			Variants:   map[string]schema.PrefabVariant{"big": {Components: map[string]map[string]any{"Health": {"hp": 40.0}}}},
+		}},
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	w, rd := storage.NewTxWorldWriterWithSchema(tx, s), storage.NewTxWorldReaderWithSchema(tx, s)
+	handler, _ := builtins.NewRegistry().GetAction("spawnPrefab")
+	if err := handler.Run(actx(0, w, rd, map[string]any{
+		"prefab":     "Imp/big",
+		"components": map[string]any{"Health": map[string]any{"maxHp": 60.0}},
+	})); err != nil {
+		t.Fatalf("spawnPrefab: %v", err)
+	}
+	if err := handler.Run(actx(0, w, rd, map[string]any{"prefab": "Imp/tiny"})); err == nil {
+		t.Error("spawnPrefab of an undeclared variant succeeded")
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	var hp, maxHp float64
+	var createdTick int64
+	err = db.QueryRow(`SELECT h.hp, h.maxhp, e.created_tick FROM entities e
+		JOIN comp_health h ON h.entity_id = e.id WHERE e.entity_type = 'Imp'`).Scan(&hp, &maxHp, &createdTick)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hp != 40 || maxHp != 60 || createdTick != 2 {
+		t.Errorf("Imp hp/maxHp/tick = %v/%v/%d, want 40/60/2", hp, maxHp, createdTick)
+	}
+}
+
+func TestAction_destroyEntity(t *testing.T) {
+	db := setupBuiltinsDB(t)
+	if _, err := db.Exec(`CREATE TABLE world (key TEXT PRIMARY KEY, value TEXT NOT NULL)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO world (key, value) VALUES ('current_tick', '9')`); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.EnsureInterpreterTables(db); err != nil {
+		t.Fatal(err)
+	}
+	goblinID := insertEntity(t, db, "Goblin")
+	playerID := insertEntity(t, db, "Player")
+	otherID := insertEntity(t, db, "Goblin")
+
+	r := builtins.NewRegistry()
+	handler, _ := r.GetAction("destroyEntity")
+	runAction(t, db, func(w agent.WorldWriter, rd agent.WorldReader) {
+		for _, params := range []map[string]any{
+			{"target": "$player"},
+			{"target": float64(otherID)},
+			{"target": float64(otherID)}, // already destroyed: not an error
+			{},                           // self
+		} {
+			if err := handler.Run(actx(goblinID, w, rd, params)); err != nil {
+				t.Fatalf("destroyEntity %v: %v", params, err)
+			}
+		}
+	})
+
 	var count int
-	db.QueryRow("SELECT COUNT(*) FROM entities WHERE entity_type = 'Player'").Scan(&count)
-	if count != 1 {
-		t.Errorf("Player entities = %d, want 1", count)
+	db.QueryRow("SELECT COUNT(*) FROM entities").Scan(&count)
+	if count != 0 {
+		t.Errorf("entities = %d, want 0", count)
+	}
+	rows, err := db.Query("SELECT entity_id, tick FROM transitions WHERE event = 'despawn' ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var despawned []int64
+	for rows.Next() {
+		var id, tick int64
+		if err := rows.Scan(&id, &tick); err != nil {
+			t.Fatal(err)
+		}
+		if tick != 9 {
+			t.Errorf("despawn of %d at tick %d, want 9", id, tick)
+		}
+		despawned = append(despawned, id)
+	}
+	if len(despawned) != 3 || despawned[0] != playerID || despawned[1] != otherID || despawned[2] != goblinID {
+		t.Errorf("despawned = %v, want [%d %d %d]", despawned, playerID, otherID, goblinID)
 	}
 }
 
@@ -324,6 +467,50 @@ func TestAction_detachComponent(t *testing.T) {
 	}
 }
 
+func TestAction_tagComponent(t *testing.T) {
+	db := setupBuiltinsDB(t)
+	if _, err := db.Exec(`CREATE TABLE comp_stunned (entity_id INTEGER PRIMARY KEY)`); err != nil {
+		t.Fatal(err)
+	}
+	entityID := insertEntity(t, db, "Goblin")
+	s := schema.DatabaseSchema{
+		SchemaVersion: 1,
+		Components:    map[string]schema.Component{"Stunned": {Type: schema.ComponentTypeTag}},
+		EntityTypes:   map[string]schema.EntityType{"Goblin": {OptionalComponents: []string{"Stunned"}}},
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	w, rd := storage.NewTxWorldWriterWithSchema(tx, s), storage.NewTxWorldReaderWithSchema(tx, s)
+	r := builtins.NewRegistry()
+	attach, _ := r.GetAction("attachComponent")
+	detach, _ := r.GetAction("detachComponent")
+	stunned, _ := r.GetGuard("hasComponent")
+	params := map[string]any{"component": "Stunned"}
+
+	if err := attach.Run(actx(entityID, w, rd, params)); err != nil {
+		t.Fatalf("attachComponent: %v", err)
+	}
+	if !stunned.Evaluate(gctx(entityID, rd, params)) {
+		t.Error("hasComponent(Stunned) after attach = false, want true")
+	}
+	if err := detach.Run(actx(entityID, w, rd, params)); err != nil {
+		t.Fatalf("detachComponent: %v", err)
+	}
+	if stunned.Evaluate(gctx(entityID, rd, params)) {
+		t.Error("hasComponent(Stunned) after detach = true, want false")
+	}
+	err = attach.Run(actx(entityID, w, rd, map[string]any{
+		"component": "Stunned", "data": map[string]any{"value": true},
+	}))
+	if err == nil || !strings.Contains(err.Error(), "tag components have no fields") {
+		t.Errorf("attachComponent with data on a tag err = %v, want a tag field error", err)
+	}
+}
+
 func TestAction_log(t *testing.T) {
 	r := builtins.NewRegistry()
 	handler, ok := r.GetAction("log")
@@ -530,15 +717,41 @@ func TestGuard_healthAbove_False(t *testing.T) {
 	}
 }
 
+func TestGuard_isA(t *testing.T) {
+	db := setupBuiltinsDB(t)
+	entityID := insertEntity(t, db, "Goblin")
+	insertEntity(t, db, "Player")
+
+	r := builtins.NewRegistry()
+	tests := []struct {
+		params map[string]any
+		want   bool
+	}{
+		{map[string]any{"type": "Goblin"}, true},
+		{map[string]any{"type": "Player"}, false},
+		{map[string]any{"type": "Player", "target": "$player"}, true},
+		{map[string]any{}, false},
+	}
+	for _, tt := range tests {
+		got := readGuard(t, db, func(rd agent.WorldReader) bool {
+			handler, _ := r.GetGuard("isA")
+			return handler.Evaluate(gctx(entityID, rd, tt.params))
+		})
+		if got != tt.want {
+			t.Errorf("isA(%v) = %v, want %v", tt.params, got, tt.want)
+		}
+	}
+}
+
 // ── Registration ──────────────────────────────────────────────────────────────
 
 func TestRegisterBuiltins_AllPresent(t *testing.T) {
 	r := builtins.NewRegistry()
 
 	wantActions := []string{
-		"attachComponent", "dealDamage", "detachComponent",
+		"attachComponent", "dealDamage", "destroyEntity", "detachComponent",
 		"log", "moveTowardTarget", "pickRandomTarget",
-		"setPursueTarget", "setTimer", "spawnEntity",
+		"setPursueTarget", "setTimer", "spawnEntity", "spawnPrefab",
 	}
 	for _, name := range wantActions {
 		if _, ok := r.GetAction(name); !ok {
@@ -546,7 +759,7 @@ func TestRegisterBuiltins_AllPresent(t *testing.T) {
 		}
 	}
 
-	wantGuards := []string{"atTarget", "hasComponent", "healthAbove", "inRange", "timerExpired"}
+	wantGuards := []string{"atTarget", "hasComponent", "healthAbove", "inRange", "isA", "timerExpired"}
 	for _, name := range wantGuards {
 		if _, ok := r.GetGuard(name); !ok {
 			t.Errorf("guard %q not registered", name)
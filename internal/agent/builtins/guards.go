@@ -101,3 +101,22 @@ func (g *healthAboveGuard) Evaluate(ctx agent.GuardContext) bool {
 	hp, _ := ctx.World.GetComponentValue(ctx.EntityID, "Health", "hp")
 	return toFloat(hp) > threshold
 }
+
+// ── isA ───────────────────────────────────────────────────────────────────────
+
+type isAGuard struct{}
+
+func (g *isAGuard) Evaluate(ctx agent.GuardContext) bool {
+	entityType, _ := ctx.Params["type"].(string)
+	if entityType == "" {
+		return false
+	}
+	targetID := ctx.EntityID
+	if target, ok := ctx.Params["target"]; ok {
+		if targetID, ok = resolveTargetID(ctx.World, target); !ok {
+			return false
+		}
+	}
+	is, _ := ctx.World.IsA(targetID, entityType)
+	return is
+}
@@ -57,12 +57,32 @@ func registerActions(r *agent.Registry) {
 
 	r.RegisterAction(agent.ActionMeta{
 		Name:        "spawnEntity",
-		Description: "Create a new entity of the given type; logs the new entity ID.",
+		Description: "Create a new entity of the given type with optional component values, starting its behavior machine; logs the new entity ID and stores it in the store_as context key.",
 		Params: []agent.ParamSchema{
 			{Name: "entity_type", Type: "string", Required: true},
+			{Name: "components", Type: "object", Required: false},
+			{Name: "store_as", Type: "string", Required: false},
 		},
 	}, &spawnEntityAction{})
 
+	r.RegisterAction(agent.ActionMeta{
+		Name:        "spawnPrefab",
+		Description: "Create a new entity from a schema prefab (\"Goblin\" or \"Goblin/elite\"), with optional component values overriding the prefab's; stores the new entity ID in the store_as context key.",
+		Params: []agent.ParamSchema{
+			{Name: "prefab", Type: "string", Required: true},
+			{Name: "components", Type: "object", Required: false},
+			{Name: "store_as", Type: "string", Required: false},
+		},
+	}, &spawnPrefabAction{})
+
+	r.RegisterAction(agent.ActionMeta{
+		Name:        "destroyEntity",
+		Description: "Destroy the target entity with everything attached to it. Target is \"$self\" (the default), an entity ID or \"$player\".",
+		Params: []agent.ParamSchema{
+			{Name: "target", Type: "string", Required: false, Default: "$self"},
+		},
+	}, &destroyEntityAction{})
+
 	r.RegisterAction(agent.ActionMeta{
 		Name:        "attachComponent",
 		Description: "Attach a component to the current entity with optional initial field values.",
@@ -127,4 +147,13 @@ func registerGuards(r *agent.Registry) {
 			{Name: "threshold", Type: "number", Required: true},
 		},
 	}, &healthAboveGuard{})
+
+	r.RegisterGuard(agent.GuardMeta{
+		Name:        "isA",
+		Description: "True when the entity (or target) is of the given entity type or extends it.",
+		Params: []agent.ParamSchema{
+			{Name: "type", Type: "string", Required: true},
+			{Name: "target", Type: "string", Required: false},
+		},
+	}, &isAGuard{})
 }
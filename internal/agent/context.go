@@ -11,10 +11,30 @@ type Event struct {
 // The concrete implementation (backed by *sql.Tx) lives in internal/storage.
 // Agent code never imports storage directly.
 type WorldWriter interface {
-	SpawnEntity(entityType string) (int64, error)
+	// SpawnEntity creates an entity of entityType at the current tick with
+	// the given components (component name → field values) and returns its
+	// ID. Implementations with a schema validate it as entity creation
+	// does and start the type's behavior machine.
+	SpawnEntity(entityType string, components map[string]map[string]any) (int64, error)
+	// SpawnPrefab spawns an entity from a prefab declared in the schema
+	// ("Goblin", or "Goblin/elite" for a variant) with overrides merged over
+	// its component values, as SpawnEntity would spawn it.
+	SpawnPrefab(prefab string, overrides map[string]map[string]any) (int64, error)
 	AttachComponent(entityID int64, compName string, values map[string]any) error
 	DetachComponent(entityID int64, compName string) error
 	SetComponentValue(entityID int64, compName, field string, value any) error
+	// DestroyEntity deletes the entity with everything attached to it and
+	// records a despawn in transitions. Destroying an entity that no
+	// longer exists is not an error.
+	DestroyEntity(entityID int64) error
+	// SetResource sets one property of a world resource (see schema
+	// "resources").
+	SetResource(name, field string, value any) error
+	// AddRelation links sourceID to targetID through a relation component
+	// (see schema type "relation"), with optional property values.
+	AddRelation(sourceID int64, relation string, targetID int64, values map[string]any) error
+	// RemoveRelation unlinks sourceID from targetID.
+	RemoveRelation(sourceID int64, relation string, targetID int64) error
 }
 
 // WorldReader is the read-side interface that guards (and read-capable actions) use
@@ -24,7 +44,24 @@ type WorldReader interface {
 	HasComponent(entityID int64, compName string) (bool, error)
 	// FindEntityByType returns the ID of the first entity of the given type.
 	// Used to resolve the "$player" sentinel in dealDamage, inRange, setPursueTarget.
+	// Entities of any subtype of entityType (see schema "extends") match too.
 	FindEntityByType(entityType string) (int64, error)
+	// IsA reports whether the entity's type is ancestor or extends it.
+	IsA(entityID int64, ancestor string) (bool, error)
+	// GetResource returns one property of a world resource, such as
+	// the time of day, that belongs to no entity.
+	GetResource(name, field string) (any, error)
+	// RelatedEntities returns the targets entityID relates to through the
+	// relation, in ascending id order.
+	RelatedEntities(entityID int64, relation string) ([]int64, error)
+	// EntitiesRelatedTo returns the entities that relate to targetID
+	// through the relation, in ascending id order — e.g. every entity
+	// that is ChildOf targetID.
+	EntitiesRelatedTo(relation string, targetID int64) ([]int64, error)
+	// Query returns the entities matching q with the values of its With
+	// components — e.g. every entity with Position and Sprite but not
+	// Dead. The caller must Close the rows.
+	Query(q Query) (QueryRows, error)
 }
 
 // ActionHandler is implemented by Go code that executes a named XState action.
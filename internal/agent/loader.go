@@ -8,6 +8,12 @@ import (
 	"github.com/tmbritton/ecs-db/internal/schema"
 )
 
+// MachineSource returns loaded machine definitions by machine ID. *Loader
+// is one.
+type MachineSource interface {
+	Get(machineID string) (*MachineDefinition, bool)
+}
+
 // Loader reads, parses, and validates machine definition files. It retains
 // the last successfully validated definition for each machine ID so that a
 // failed hot-reload leaves the previous version in service.
@@ -59,3 +65,6 @@ func (l *Loader) Get(machineID string) (*MachineDefinition, bool) {
 	def, ok := l.machines[machineID]
 	return def, ok
 }
+
+// Compile-time interface check.
+var _ MachineSource = (*Loader)(nil)
@@ -0,0 +1,173 @@
+package agent
+
+import "strings"
+
+// Query selects the entities that have every With component and none of
+// the Without components, optionally filtered by Where conditions on
+// component values, sorted by OrderBy fields and capped by Limit. A Query
+// is a value: each method returns a modified copy, so a base query can be
+// shared and extended.
+//
+//	q := agent.Query{}.With("Position", "Sprite").Without("Dead").
+//		Where("Health.hp", "<", 10).OrderBy("Health.hp").Limit(20)
+//
+// Fields are named "Component.property" for object components and by the
+// component name alone for components holding a single value (string,
+// integer, number, boolean, array and entity-ref). A field must belong to
+// a With component. The storage package compiles a Query to one SELECT
+// joining the comp_* tables.
+type Query struct {
+	with    []string
+	without []string
+	where   []QueryCondition
+	orderBy []QueryOrder
+	limit   int
+}
+
+// QueryCondition is one Where filter: Field Op Value.
+type QueryCondition struct {
+	Field string
+	Op    string
+	Value any
+}
+
+// QueryOrder is one OrderBy key.
+type QueryOrder struct {
+	Field      string
+	Descending bool
+}
+
+// QueryOps are the comparison operators accepted by Query.Where.
+var QueryOps = map[string]bool{"=": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true}
+
+// With requires the matched entities to have every named component.
+func (q Query) With(components ...string) Query {
+	q.with = append(q.with[:len(q.with):len(q.with)], components...)
+	return q
+}
+
+// Without excludes entities that have any of the named components.
+func (q Query) Without(components ...string) Query {
+	q.without = append(q.without[:len(q.without):len(q.without)], components...)
+	return q
+}
+
+// Where keeps the entities whose field compares to value with op, one of
+// QueryOps. A NULL field matches no condition.
+func (q Query) Where(field, op string, value any) Query {
+	q.where = append(q.where[:len(q.where):len(q.where)], QueryCondition{Field: field, Op: op, Value: value})
+	return q
+}
+
+// OrderBy sorts the results by field, ascending. Later calls add
+// tie-breakers; entity id is always the last one.
+func (q Query) OrderBy(field string) Query {
+	q.orderBy = append(q.orderBy[:len(q.orderBy):len(q.orderBy)], QueryOrder{Field: field})
+	return q
+}
+
+// OrderByDesc sorts the results by field, descending.
+func (q Query) OrderByDesc(field string) Query {
+	q.orderBy = append(q.orderBy[:len(q.orderBy):len(q.orderBy)], QueryOrder{Field: field, Descending: true})
+	return q
+}
+
+// Limit caps the number of results. Zero or less means no limit.
+func (q Query) Limit(n int) Query {
+	q.limit = n
+	return q
+}
+
+// Components returns the With components in the order given.
+func (q Query) Components() []string { return q.with }
+
+// Excluded returns the Without components in the order given.
+func (q Query) Excluded() []string { return q.without }
+
+// Conditions returns the Where conditions in the order given.
+func (q Query) Conditions() []QueryCondition { return q.where }
+
+// Order returns the OrderBy keys in the order given.
+func (q Query) Order() []QueryOrder { return q.orderBy }
+
+// MaxRows returns the Limit, or 0 for none.
+func (q Query) MaxRows() int { return q.limit }
+
+// QueryRow is one entity matched by a Query, with the values of its With
+// components keyed by field name as described on Query. Values are the
+// SQLite values GetComponentValue returns: int64 for integer and boolean
+// properties, float64 for numbers and string for strings and JSON.
+type QueryRow struct {
+	EntityID   int64
+	EntityType string
+	Values     map[string]any
+}
+
+// Value returns the named field, matching names case-insensitively, or nil
+// when the row has no such field.
+func (r QueryRow) Value(field string) any {
+	if v, ok := r.Values[field]; ok {
+		return v
+	}
+	for name, v := range r.Values {
+		if strings.EqualFold(name, field) {
+			return v
+		}
+	}
+	return nil
+}
+
+// Int returns an integer, boolean or entity-ref field. ok is false when
+// the field is missing, NULL or not an integer.
+func (r QueryRow) Int(field string) (v int64, ok bool) {
+	v, ok = r.Value(field).(int64)
+	return v, ok
+}
+
+// Float returns a number field, converting integers. ok is false when the
+// field is missing, NULL or not numeric.
+func (r QueryRow) Float(field string) (float64, bool) {
+	switch v := r.Value(field).(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// String returns a string field. ok is false when the field is missing,
+// NULL or not a string.
+func (r QueryRow) String(field string) (v string, ok bool) {
+	v, ok = r.Value(field).(string)
+	return v, ok
+}
+
+// Bool returns a boolean field, stored as 0 or 1. ok is false when the
+// field is missing, NULL or not an integer.
+func (r QueryRow) Bool(field string) (v bool, ok bool) {
+	n, ok := r.Int(field)
+	return n != 0, ok
+}
+
+// QueryRows iterates the results of a Query, in the manner of sql.Rows:
+//
+//	rows, err := reader.Query(q)
+//	if err != nil { ... }
+//	defer rows.Close()
+//	for rows.Next() {
+//		row := rows.Row()
+//		...
+//	}
+//	if err := rows.Err(); err != nil { ... }
+type QueryRows interface {
+	// Next advances to the next row, returning false when there are no
+	// more rows or an error occurred.
+	Next() bool
+	// Row returns the current row.
+	Row() QueryRow
+	// Err returns the error, if any, that ended the iteration.
+	Err() error
+	// Close releases the rows. It is safe to call more than once.
+	Close() error
+}
@@ -0,0 +1,62 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQuery_IsAValue(t *testing.T) {
+	base := Query{}.With("Position").Where("Position.x", ">", 0)
+	a := base.With("Sprite").Where("Position.y", "<", 5)
+	b := base.With("Health").Without("Dead").OrderByDesc("Health.hp").Limit(3)
+
+	if got := base.Components(); !reflect.DeepEqual(got, []string{"Position"}) {
+		t.Errorf("base With = %v, want [Position]", got)
+	}
+	if got := a.Components(); !reflect.DeepEqual(got, []string{"Position", "Sprite"}) {
+		t.Errorf("a With = %v, want [Position Sprite]", got)
+	}
+	if got := b.Components(); !reflect.DeepEqual(got, []string{"Position", "Health"}) {
+		t.Errorf("b With = %v, want [Position Health]", got)
+	}
+	if len(base.Conditions()) != 1 || len(a.Conditions()) != 2 || len(b.Conditions()) != 1 {
+		t.Errorf("conditions = %d/%d/%d, want 1/2/1", len(base.Conditions()), len(a.Conditions()), len(b.Conditions()))
+	}
+	if got := b.Order(); !reflect.DeepEqual(got, []QueryOrder{{Field: "Health.hp", Descending: true}}) {
+		t.Errorf("b Order = %v", got)
+	}
+	if base.MaxRows() != 0 || b.MaxRows() != 3 || len(b.Excluded()) != 1 || len(a.Excluded()) != 0 {
+		t.Errorf("limit/without leaked between copies: base %d, b %d, a without %v", base.MaxRows(), b.MaxRows(), a.Excluded())
+	}
+}
+
+func TestQueryRow_Accessors(t *testing.T) {
+	row := QueryRow{Values: map[string]any{
+		"Health.hp":    int64(7),
+		"Health.armor": (*int64)(nil),
+		"Position.x":   1.5,
+		"Name":         "Grak",
+		"Alive":        int64(1),
+	}}
+	if v, ok := row.Int("health.HP"); !ok || v != 7 {
+		t.Errorf("Int = %v, %v; want 7", v, ok)
+	}
+	if _, ok := row.Int("Health.armor"); ok {
+		t.Error("Int(NULL) ok = true, want false")
+	}
+	if v, ok := row.Float("Health.hp"); !ok || v != 7 {
+		t.Errorf("Float(integer) = %v, %v; want 7", v, ok)
+	}
+	if v, ok := row.Float("Position.x"); !ok || v != 1.5 {
+		t.Errorf("Float = %v, %v; want 1.5", v, ok)
+	}
+	if v, ok := row.String("Name"); !ok || v != "Grak" {
+		t.Errorf("String = %q, %v; want Grak", v, ok)
+	}
+	if v, ok := row.Bool("Alive"); !ok || !v {
+		t.Errorf("Bool = %v, %v; want true", v, ok)
+	}
+	if row.Value("Mana") != nil {
+		t.Error("Value(missing) != nil")
+	}
+}
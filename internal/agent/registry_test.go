@@ -24,7 +24,15 @@ func (h *testGuardHandler) Evaluate(GuardContext) bool { return h.result }
 
 type testWorldWriter struct{}
 
-func (w *testWorldWriter) SpawnEntity(entityType string) (int64, error) { return 1, nil }
+func (w *testWorldWriter) SpawnEntity(entityType string, components map[string]map[string]any) (int64, error) {
+	return 1, nil
+}
+
+func (w *testWorldWriter) SpawnPrefab(prefab string, overrides map[string]map[string]any) (int64, error) {
+	return 1, nil
+}
+
+func (w *testWorldWriter) DestroyEntity(entityID int64) error { return nil }
 func (w *testWorldWriter) AttachComponent(entityID int64, compName string, values map[string]any) error {
 	return nil
 }
@@ -32,6 +40,11 @@ func (w *testWorldWriter) DetachComponent(entityID int64, compName string) error
 func (w *testWorldWriter) SetComponentValue(entityID int64, compName, field string, value any) error {
 	return nil
 }
+func (w *testWorldWriter) SetResource(name, field string, value any) error { return nil }
+func (w *testWorldWriter) AddRelation(int64, string, int64, map[string]any) error {
+	return nil
+}
+func (w *testWorldWriter) RemoveRelation(int64, string, int64) error { return nil }
 
 type testWorldReader struct{}
 
@@ -47,6 +60,16 @@ func (r *testWorldReader) FindEntityByType(entityType string) (int64, error) {
 	return 0, fmt.Errorf("FindEntityByType: not implemented in test stub")
 }
 
+func (r *testWorldReader) IsA(int64, string) (bool, error) { return false, nil }
+
+func (r *testWorldReader) GetResource(string, string) (any, error) { return nil, nil }
+
+func (r *testWorldReader) RelatedEntities(int64, string) ([]int64, error) { return nil, nil }
+
+func (r *testWorldReader) EntitiesRelatedTo(string, int64) ([]int64, error) { return nil, nil }
+
+func (r *testWorldReader) Query(Query) (QueryRows, error) { return nil, nil }
+
 func TestContextTypes_Compile(t *testing.T) {
 	ac := ActionContext{
 		EntityID:        1,
@@ -0,0 +1,484 @@
+// Package codegen generates typed Go accessors from a schema, so gameplay
+// code can read and write components through structs instead of string
+// component and field names.
+package codegen
+
+import (
+	"fmt"
+	"go/format"
+	"go/token"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/tmbritton/ecs-db/internal/schema"
+)
+
+// Import paths of the interfaces the generated accessors are built on.
+const (
+	agentImport = "github.com/tmbritton/ecs-db/internal/agent"
+	worldImport = "github.com/tmbritton/ecs-db/internal/world"
+)
+
+// fieldKind selects how a field is converted to and from the values the
+// world interfaces exchange.
+type fieldKind int
+
+const (
+	kindInt fieldKind = iota
+	kindFloat
+	kindString
+	kindBool
+	kindArray
+)
+
+var kindGoType = map[fieldKind]string{
+	kindInt:    "int64",
+	kindFloat:  "float64",
+	kindString: "string",
+	kindBool:   "bool",
+	kindArray:  "[]any",
+}
+
+var kindConverter = map[fieldKind]string{
+	kindInt:    "asInt64",
+	kindFloat:  "asFloat64",
+	kindString: "asString",
+	kindBool:   "asBool",
+	kindArray:  "asArray",
+}
+
+// goField is one struct field of a generated component.
+type goField struct {
+	Name     string // Go field name
+	Key      string // field name passed to the world interfaces
+	Kind     fieldKind
+	Nullable bool
+	Computed bool // read by Get, skipped by values and Set
+}
+
+func (f goField) goType() string {
+	if f.Nullable {
+		return "*" + kindGoType[f.Kind]
+	}
+	return kindGoType[f.Kind]
+}
+
+// goComponent is one generated component struct.
+type goComponent struct {
+	Name     string // component name in the schema
+	TypeName string // Go type name
+	Doc      string
+	Fields   []goField
+	Tag      bool
+	Relation bool // only the name constant is generated
+}
+
+// GenerateGo renders a gofmt'ed Go source file for package pkg declaring
+// one struct per component of s, constants for every component and entity
+// type name, and Get/Set/Attach accessors per component:
+//
+//	GetHealth(ctx, reader, id) reads through agent.WorldReader
+//	SetHealth(ctx, writer, id, v) writes every field through agent.WorldWriter
+//	AttachHealth(ctx, tx, id, v) attaches the component through world.Tx
+//
+// Property names become exported Go names ("maxHp" → MaxHp), so removing a
+// property from the schema and regenerating breaks every use of its field
+// at compile time. Nullable properties are pointers, as are computed ones,
+// which Get reads and Set and Attach skip. Nested object and array
+// properties are JSON strings, as they are stored. Tags have no fields and
+// no Set accessor. Relations link two entities rather than
+// attach to one, so they get only their name constant.
+func GenerateGo(s schema.DatabaseSchema, pkg string) ([]byte, error) {
+	if !token.IsIdentifier(pkg) {
+		return nil, fmt.Errorf("GenerateGo: invalid package name %q", pkg)
+	}
+	comps, err := goComponents(s)
+	if err != nil {
+		return nil, fmt.Errorf("GenerateGo: %w", err)
+	}
+	entityTypes, err := goNames(sortedNames(s.EntityTypes), "entity type")
+	if err != nil {
+		return nil, fmt.Errorf("GenerateGo: %w", err)
+	}
+
+	if err := checkTopLevelNames(comps, entityTypes); err != nil {
+		return nil, fmt.Errorf("GenerateGo: %w", err)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "// Code generated by ecs-db gen go; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "// Package %s provides typed access to the components of schema version %d.\n", pkg, s.SchemaVersion)
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+	fmt.Fprintf(&b, "import (\n\t\"context\"\n\t\"encoding/json\"\n\t\"fmt\"\n\t\"reflect\"\n\n\t%q\n\t%q\n)\n\n", agentImport, worldImport)
+
+	fmt.Fprintf(&b, "// SchemaVersion is the schemaVersion this file was generated from.\n")
+	fmt.Fprintf(&b, "const SchemaVersion = %d\n\n", s.SchemaVersion)
+
+	b.WriteString("// Entity type names.\nconst (\n")
+	for _, et := range entityTypes {
+		fmt.Fprintf(&b, "\tEntityType%s = %q\n", et.goName, et.name)
+	}
+	b.WriteString(")\n\n// Component names.\nconst (\n")
+	for _, c := range comps {
+		fmt.Fprintf(&b, "\tComponent%s = %q\n", c.TypeName, c.Name)
+	}
+	b.WriteString(")\n")
+
+	for _, c := range comps {
+		if !c.Relation {
+			writeComponent(&b, c)
+		}
+	}
+	b.WriteString(helpers)
+
+	src, err := format.Source([]byte(b.String()))
+	if err != nil {
+		return nil, fmt.Errorf("GenerateGo: formatting output: %w", err)
+	}
+	return src, nil
+}
+
+// writeComponent renders the struct, values method and accessors of c.
+func writeComponent(b *strings.Builder, c goComponent) {
+	fmt.Fprintf(b, "\n// ── %s %s\n\n", c.Name, strings.Repeat("─", max(3, 70-len(c.Name))))
+	fmt.Fprintf(b, "// %s is the %s component (%s).\n", c.TypeName, c.Name, c.Doc)
+	fmt.Fprintf(b, "type %s struct {\n", c.TypeName)
+	for _, f := range c.Fields {
+		fmt.Fprintf(b, "\t%s %s\n", f.Name, f.goType())
+	}
+	b.WriteString("}\n\n")
+
+	// values
+	fmt.Fprintf(b, "func (c %s) values() map[string]any {\n", c.TypeName)
+	if c.Tag {
+		b.WriteString("\treturn nil\n}\n\n")
+	} else {
+		b.WriteString("\treturn map[string]any{\n")
+		for _, f := range c.Fields {
+			if f.Computed {
+				continue
+			}
+			if f.Nullable {
+				fmt.Fprintf(b, "\t\t%q: deref(c.%s),\n", f.Key, f.Name)
+			} else {
+				fmt.Fprintf(b, "\t\t%q: c.%s,\n", f.Key, f.Name)
+			}
+		}
+		b.WriteString("\t}\n}\n\n")
+	}
+
+	// Get
+	fmt.Fprintf(b, "// Get%s reads the %s component of entity id. ok is false when the\n", c.TypeName, c.Name)
+	b.WriteString("// component is not attached.\n")
+	fmt.Fprintf(b, "func Get%s(ctx context.Context, r agent.WorldReader, id int64) (c %s, ok bool, err error) {\n", c.TypeName, c.TypeName)
+	b.WriteString("\tif err := ctx.Err(); err != nil {\n\t\treturn c, false, err\n\t}\n")
+	fmt.Fprintf(b, "\tif ok, err = r.HasComponent(id, Component%s); err != nil || !ok {\n\t\treturn c, false, err\n\t}\n", c.TypeName)
+	if len(c.Fields) > 0 {
+		b.WriteString("\tvar v any\n")
+	}
+	for _, f := range c.Fields {
+		fmt.Fprintf(b, "\tif v, err = r.GetComponentValue(id, Component%s, %q); err != nil {\n\t\treturn c, false, err\n\t}\n", c.TypeName, f.Key)
+		if f.Nullable {
+			fmt.Fprintf(b, "\tif c.%s, err = nullable(v, %s); err != nil {\n", f.Name, kindConverter[f.Kind])
+		} else {
+			fmt.Fprintf(b, "\tif c.%s, err = %s(v); err != nil {\n", f.Name, kindConverter[f.Kind])
+		}
+		fmt.Fprintf(b, "\t\treturn c, false, fmt.Errorf(\"%s.%s: %%w\", err)\n\t}\n", c.Name, f.Key)
+	}
+	b.WriteString("\treturn c, true, nil\n}\n\n")
+
+	// Set
+	if !c.Tag {
+		fmt.Fprintf(b, "// Set%s writes every field of the %s component of entity id, which\n", c.TypeName, c.Name)
+		b.WriteString("// must already be attached.\n")
+		fmt.Fprintf(b, "func Set%s(ctx context.Context, w agent.WorldWriter, id int64, c %s) error {\n", c.TypeName, c.TypeName)
+		b.WriteString("\tif err := ctx.Err(); err != nil {\n\t\treturn err\n\t}\n")
+		for _, f := range c.Fields {
+			if f.Computed {
+				continue
+			}
+			val := "c." + f.Name
+			switch {
+			case f.Kind == kindArray:
+				b.WriteString("\tencoded, err := encodeArray(c." + f.Name + ")\n\tif err != nil {\n")
+				fmt.Fprintf(b, "\t\treturn fmt.Errorf(\"%s.%s: %%w\", err)\n\t}\n", c.Name, f.Key)
+				val = "encoded"
+			case f.Nullable:
+				val = "deref(c." + f.Name + ")"
+			}
+			fmt.Fprintf(b, "\tif err := w.SetComponentValue(id, Component%s, %q, %s); err != nil {\n\t\treturn err\n\t}\n",
+				c.TypeName, f.Key, val)
+		}
+		b.WriteString("\treturn nil\n}\n\n")
+	}
+
+	// Attach
+	fmt.Fprintf(b, "// Attach%s attaches the %s component to entity id.\n", c.TypeName, c.Name)
+	fmt.Fprintf(b, "func Attach%s(ctx context.Context, tx world.Tx, id int64, c %s) error {\n", c.TypeName, c.TypeName)
+	fmt.Fprintf(b, "\treturn tx.AttachComponent(ctx, id, Component%s, c.values())\n}\n", c.TypeName)
+}
+
+// checkTopLevelNames rejects schemas whose generated declarations would
+// collide, such as a component named EntityTypeGoblin next to an entity
+// type named Goblin.
+func checkTopLevelNames(comps []goComponent, entityTypes []goName) error {
+	declared := map[string]string{"SchemaVersion": "the SchemaVersion constant"}
+	declare := func(id, owner string) error {
+		if prev, ok := declared[id]; ok {
+			return fmt.Errorf("%s and %s both generate %s", prev, owner, id)
+		}
+		declared[id] = owner
+		return nil
+	}
+	for _, et := range entityTypes {
+		if err := declare("EntityType"+et.goName, fmt.Sprintf("entity type %q", et.name)); err != nil {
+			return err
+		}
+	}
+	for _, c := range comps {
+		owner := fmt.Sprintf("component %q", c.Name)
+		ids := []string{c.TypeName, "Component" + c.TypeName, "Get" + c.TypeName, "Attach" + c.TypeName}
+		if c.Relation {
+			ids = []string{"Component" + c.TypeName}
+		} else if !c.Tag {
+			ids = append(ids, "Set"+c.TypeName)
+		}
+		for _, id := range ids {
+			if err := declare(id, owner); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// goComponents describes every component of s, sorted by name.
+func goComponents(s schema.DatabaseSchema) ([]goComponent, error) {
+	names, err := goNames(sortedNames(s.Components), "component")
+	if err != nil {
+		return nil, err
+	}
+	out := make([]goComponent, 0, len(names))
+	for _, n := range names {
+		comp := s.Components[n.name]
+		c := goComponent{Name: n.name, TypeName: n.goName, Doc: comp.Type}
+		switch comp.Type {
+		case schema.ComponentTypeObject:
+			fields, err := objectFields(n.name, comp.Properties)
+			if err != nil {
+				return nil, err
+			}
+			c.Fields = fields
+		case schema.ComponentTypeTag:
+			c.Tag = true
+		case schema.ComponentTypeRelation:
+			c.Relation = true
+		case schema.ComponentTypeEntityRef:
+			c.Fields = []goField{{Name: "Target", Key: "target_entity_id", Kind: kindInt}}
+		case schema.ComponentTypeArray:
+			c.Fields = []goField{{Name: "Value", Key: "value", Kind: kindArray}}
+		default:
+			c.Fields = []goField{{Name: "Value", Key: "value", Kind: propertyKind(comp.Type)}}
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// objectFields maps the properties of an object component to fields,
+// sorted by property name.
+func objectFields(compName string, props map[string]schema.Property) ([]goField, error) {
+	names, err := goNames(sortedNames(props), fmt.Sprintf("component %q property", compName))
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]goField, 0, len(names))
+	for _, n := range names {
+		prop := props[n.name]
+		fields = append(fields, goField{
+			Name:     n.goName,
+			Key:      n.name,
+			Kind:     propertyKind(prop.Type),
+			Nullable: prop.Nullable || prop.IsComputed(),
+			Computed: prop.IsComputed(),
+		})
+	}
+	return fields, nil
+}
+
+// propertyKind maps a property or scalar component type to its field kind.
+// Nested objects and arrays inside an object component are stored as JSON
+// text and surface as strings.
+func propertyKind(typ string) fieldKind {
+	switch typ {
+	case schema.PropertyTypeInteger, schema.PropertyTypeEntityRef:
+		return kindInt
+	case schema.PropertyTypeNumber:
+		return kindFloat
+	case schema.PropertyTypeBoolean:
+		return kindBool
+	}
+	return kindString
+}
+
+type goName struct {
+	name   string
+	goName string
+}
+
+// goNames pairs each name with its exported Go identifier and rejects
+// names that do not map to one, or that map to the same one.
+func goNames(names []string, what string) ([]goName, error) {
+	out := make([]goName, 0, len(names))
+	seen := make(map[string]string)
+	for _, name := range names {
+		id := exportedName(name)
+		if !token.IsIdentifier(id) || !token.IsExported(id) {
+			return nil, fmt.Errorf("%s %q has no Go identifier", what, name)
+		}
+		if prev, ok := seen[id]; ok {
+			return nil, fmt.Errorf("%s names %q and %q both map to Go name %s", what, prev, name, id)
+		}
+		seen[id] = name
+		out = append(out, goName{name: name, goName: id})
+	}
+	return out, nil
+}
+
+// exportedName turns a schema name into an exported Go identifier:
+// "maxHp" → "MaxHp", "image_id" → "ImageId", "goblin-stats" → "GoblinStats".
+func exportedName(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if r == '_' || r == '-' || r == ' ' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// helpers is appended to every generated file. The world interfaces hand
+// back what the SQLite driver scanned: int64 for INTEGER columns (booleans
+// included), float64 for REAL, string or []byte for TEXT, and nil or a
+// typed nil pointer for NULL.
+const helpers = `
+// ── Conversions ─────────────────────────────────────────────────────────────
+
+func asInt64(v any) (int64, error) {
+	switch x := v.(type) {
+	case int64:
+		return x, nil
+	case float64:
+		if x == float64(int64(x)) {
+			return int64(x), nil
+		}
+	case bool:
+		if x {
+			return 1, nil
+		}
+		return 0, nil
+	case nil:
+		return 0, nil
+	}
+	return 0, fmt.Errorf("cannot convert %T to int64", v)
+}
+
+func asFloat64(v any) (float64, error) {
+	switch x := v.(type) {
+	case float64:
+		return x, nil
+	case int64:
+		return float64(x), nil
+	case nil:
+		return 0, nil
+	}
+	return 0, fmt.Errorf("cannot convert %T to float64", v)
+}
+
+func asString(v any) (string, error) {
+	switch x := v.(type) {
+	case string:
+		return x, nil
+	case []byte:
+		return string(x), nil
+	case nil:
+		return "", nil
+	}
+	return "", fmt.Errorf("cannot convert %T to string", v)
+}
+
+func asBool(v any) (bool, error) {
+	switch x := v.(type) {
+	case bool:
+		return x, nil
+	case int64:
+		return x != 0, nil
+	case nil:
+		return false, nil
+	}
+	return false, fmt.Errorf("cannot convert %T to bool", v)
+}
+
+func asArray(v any) ([]any, error) {
+	s, err := asString(v)
+	if err != nil || s == "" {
+		return nil, err
+	}
+	var out []any
+	if err := json.Unmarshal([]byte(s), &out); err != nil {
+		return nil, fmt.Errorf("decoding array: %w", err)
+	}
+	return out, nil
+}
+
+func encodeArray(v []any) (string, error) {
+	if v == nil {
+		v = []any{}
+	}
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+// nullable converts v with conv, mapping nil and typed nil pointers to nil.
+func nullable[T any](v any, conv func(any) (T, error)) (*T, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		v = rv.Elem().Interface()
+	}
+	x, err := conv(v)
+	if err != nil {
+		return nil, err
+	}
+	return &x, nil
+}
+
+// deref returns *p, or nil for a nil pointer.
+func deref[T any](p *T) any {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
+`
@@ -0,0 +1,273 @@
+package codegen
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tmbritton/ecs-db/internal/schema"
+)
+
+const genSchemaJSON = `{
+  "schemaVersion": 4,
+  "components": {
+    "Health": {
+      "type": "object",
+      "properties": {
+        "hp": {"type": "integer"},
+        "maxHp": {"type": "integer"},
+        "ratio": {"type": "number", "computed": "hp * 1.0 / maxHp"},
+        "regen": {"type": "number", "nullable": true}
+      }
+    },
+    "Name": {"type": "string"},
+    "Leader": {"type": "entity-ref"},
+    "Inventory": {"type": "array", "items": {"type": "string"}},
+    "Frozen": {"type": "tag"},
+    "ChildOf": {"type": "relation"}
+  },
+  "entityTypes": {
+    "Goblin": {"requiredComponents": ["Health"], "allowExtraComponents": true},
+    "goblin-archer": {"extends": "Goblin"}
+  }
+}`
+
+func loadGenSchema(t *testing.T, data string) schema.DatabaseSchema {
+	t.Helper()
+	s, err := schema.LoadSchema([]byte(data))
+	if err != nil {
+		t.Fatalf("LoadSchema: %v", err)
+	}
+	if err := schema.ValidateSchema(s); err != nil {
+		t.Fatalf("ValidateSchema: %v", err)
+	}
+	return s
+}
+
+func TestGenerateGo_Declarations(t *testing.T) {
+	src, err := GenerateGo(loadGenSchema(t, genSchemaJSON), "gamedata")
+	if err != nil {
+		t.Fatalf("GenerateGo: %v", err)
+	}
+	out := string(src)
+	for _, want := range []string{
+		"// Code generated by ecs-db gen go; DO NOT EDIT.",
+		"package gamedata",
+		"const SchemaVersion = 4",
+		`EntityTypeGoblin       = "Goblin"`,
+		`EntityTypeGoblinArcher = "goblin-archer"`,
+		`ComponentHealth    = "Health"`,
+		"type Health struct {\n\tHp    int64\n\tMaxHp int64\n\tRatio *float64\n\tRegen *float64\n}",
+		"type Name struct {\n\tValue string\n}",
+		"type Leader struct {\n\tTarget int64\n}",
+		"type Inventory struct {\n\tValue []any\n}",
+		"type Frozen struct {\n}",
+		"func GetHealth(ctx context.Context, r agent.WorldReader, id int64) (c Health, ok bool, err error)",
+		"func SetHealth(ctx context.Context, w agent.WorldWriter, id int64, c Health) error",
+		"func AttachHealth(ctx context.Context, tx world.Tx, id int64, c Health) error",
+		`c.Regen, err = nullable(v, asFloat64)`,
+		`c.Ratio, err = nullable(v, asFloat64)`,
+		`r.GetComponentValue(id, ComponentLeader, "target_entity_id")`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("generated source missing %q", want)
+		}
+	}
+	if strings.Contains(out, `"ratio": deref(c.Ratio)`) || strings.Contains(out, `ComponentHealth, "ratio", `) {
+		t.Error("computed ratio must be skipped by values and SetHealth")
+	}
+	if strings.Contains(out, "func SetFrozen") {
+		t.Error("tags have no fields and should get no Set accessor")
+	}
+	if !strings.Contains(out, `ComponentChildOf   = "ChildOf"`) || strings.Contains(out, "type ChildOf struct") {
+		t.Error("relations should get their name constant and no struct or accessors")
+	}
+}
+
+func TestGenerateGo_Errors(t *testing.T) {
+	base := func() schema.DatabaseSchema { return loadGenSchema(t, genSchemaJSON) }
+	tests := []struct {
+		name    string
+		edit    func(*schema.DatabaseSchema)
+		pkg     string
+		wantErr string
+	}{
+		{"bad package", func(*schema.DatabaseSchema) {}, "game-data", "invalid package name"},
+		{"colliding properties", func(s *schema.DatabaseSchema) {
+			s.Components["Health"].Properties["max_hp"] = schema.Property{Type: schema.PropertyTypeInteger}
+		}, "gamedata", `both map to Go name MaxHp`},
+		{"unexported component", func(s *schema.DatabaseSchema) {
+			s.Components["9lives"] = schema.Component{Type: schema.ComponentTypeInteger}
+		}, "gamedata", `component "9lives" has no Go identifier`},
+		{"colliding declarations", func(s *schema.DatabaseSchema) {
+			s.Components["EntityTypeGoblin"] = schema.Component{Type: schema.ComponentTypeTag}
+		}, "gamedata", "both generate EntityTypeGoblin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := base()
+			tt.edit(&s)
+			_, err := GenerateGo(s, tt.pkg)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExportedName(t *testing.T) {
+	for in, want := range map[string]string{
+		"hp":           "Hp",
+		"maxHp":        "MaxHp",
+		"image_id":     "ImageId",
+		"goblin-stats": "GoblinStats",
+		"GoblinStats":  "GoblinStats",
+	} {
+		if got := exportedName(in); got != want {
+			t.Errorf("exportedName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// roundTripTest exercises the generated accessors against a real store.
+const roundTripTest = `package gamedata
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/tmbritton/ecs-db/internal/schema"
+	"github.com/tmbritton/ecs-db/internal/storage"
+)
+
+func TestAccessors(t *testing.T) {
+	ctx := context.Background()
+	s, err := schema.LoadSchema([]byte(schemaJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := storage.NewSQLiteStore(filepath.Join(t.TempDir(), "world.db"), s, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	tx, err := store.BeginTx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leader, _ := tx.InsertEntity(ctx, EntityTypeGoblin, 0)
+	id, _ := tx.InsertEntity(ctx, EntityTypeGoblinArcher, 0)
+	regen := 0.5
+	for _, err := range []error{
+		AttachHealth(ctx, tx, id, Health{Hp: 5, MaxHp: 10, Regen: &regen}),
+		AttachName(ctx, tx, id, Name{Value: "Snag"}),
+		AttachLeader(ctx, tx, id, Leader{Target: leader}),
+		AttachInventory(ctx, tx, id, Inventory{Value: []any{"rope"}}),
+		AttachFrozen(ctx, tx, id, Frozen{}),
+		tx.Commit(),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sqlTx, err := store.DB().Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlTx.Rollback()
+	r := storage.NewTxWorldReaderWithSchema(sqlTx, s)
+	w := storage.NewTxWorldWriterWithSchema(sqlTx, s)
+
+	h, ok, err := GetHealth(ctx, r, id)
+	if err != nil || !ok || h.Hp != 5 || h.MaxHp != 10 || h.Regen == nil || *h.Regen != 0.5 ||
+		h.Ratio == nil || *h.Ratio != 0.5 {
+		t.Fatalf("GetHealth = %+v, %v, %v", h, ok, err)
+	}
+	if err := SetHealth(ctx, w, id, Health{Hp: 1, MaxHp: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if h, _, _ := GetHealth(ctx, r, id); h.Hp != 1 || h.Regen != nil {
+		t.Errorf("after SetHealth = %+v", h)
+	}
+	if n, _, _ := GetName(ctx, r, id); n.Value != "Snag" {
+		t.Errorf("GetName = %+v", n)
+	}
+	if l, _, _ := GetLeader(ctx, r, id); l.Target != leader {
+		t.Errorf("GetLeader = %+v, want %d", l, leader)
+	}
+	if err := SetInventory(ctx, w, id, Inventory{Value: []any{"rope", "torch"}}); err != nil {
+		t.Fatal(err)
+	}
+	if inv, _, _ := GetInventory(ctx, r, id); len(inv.Value) != 2 || inv.Value[1] != "torch" {
+		t.Errorf("GetInventory = %+v", inv)
+	}
+	if _, ok, _ := GetFrozen(ctx, r, id); !ok {
+		t.Error("GetFrozen ok = false, want true")
+	}
+	if _, ok, _ := GetFrozen(ctx, r, leader); ok {
+		t.Error("GetFrozen on untagged entity ok = true, want false")
+	}
+}
+`
+
+// genPackage writes src and extra files into a fresh package directory
+// inside this module, so the generated code can import internal packages.
+func genPackage(t *testing.T, src []byte, files map[string]string) string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("builds generated code with the go tool")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go tool not available")
+	}
+	dir, err := os.MkdirTemp(".", "gen_test_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	files["gamedata_gen.go"] = string(src)
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return "./" + filepath.Base(dir)
+}
+
+func TestGenerateGo_CompilesAndRoundTrips(t *testing.T) {
+	src, err := GenerateGo(loadGenSchema(t, genSchemaJSON), "gamedata")
+	if err != nil {
+		t.Fatalf("GenerateGo: %v", err)
+	}
+	pkg := genPackage(t, src, map[string]string{
+		"schema_test.go":    "package gamedata\n\nconst schemaJSON = `" + genSchemaJSON + "`\n",
+		"accessors_test.go": roundTripTest,
+	})
+	if out, err := exec.Command("go", "test", pkg).CombinedOutput(); err != nil {
+		t.Fatalf("go test %s: %v\n%s", pkg, err, out)
+	}
+}
+
+func TestGenerateGo_RemovedPropertyBreaksBuild(t *testing.T) {
+	s := loadGenSchema(t, genSchemaJSON)
+	delete(s.Components["Health"].Properties, "maxHp")
+	src, err := GenerateGo(s, "gamedata")
+	if err != nil {
+		t.Fatalf("GenerateGo: %v", err)
+	}
+	pkg := genPackage(t, src, map[string]string{
+		"use.go": "package gamedata\n\nfunc full(h Health) bool { return h.Hp == h.MaxHp }\n",
+	})
+	out, err := exec.Command("go", "build", pkg).CombinedOutput()
+	if err == nil {
+		t.Fatal("go build succeeded, want a compile error for the removed MaxHp field")
+	}
+	if !strings.Contains(string(out), "MaxHp") {
+		t.Errorf("compile error does not mention MaxHp:\n%s", out)
+	}
+}
@@ -14,6 +14,13 @@ const (
 	ComponentTypeInteger   = "integer"
 	ComponentTypeNumber    = "number"
 	ComponentTypeBoolean   = "boolean"
+	// ComponentTypeTag is a marker component with no data: attaching it
+	// only records that the entity has it.
+	ComponentTypeTag = "tag"
+	// ComponentTypeRelation links a source entity to a target entity. It
+	// is stored in rel_<name>, one row per (source, target) pair, with
+	// its optional properties as columns.
+	ComponentTypeRelation = "relation"
 )
 
 var supportedComponentTypes = map[string]bool{
@@ -24,20 +31,34 @@ var supportedComponentTypes = map[string]bool{
 	ComponentTypeInteger:   true,
 	ComponentTypeNumber:    true,
 	ComponentTypeBoolean:   true,
+	ComponentTypeTag:       true,
+	ComponentTypeRelation:  true,
 }
 
 // Component represents one entry in the top-level "components" map of
 // schema.json. It is unmarshalled polymorphically based on the "type" field.
+//
+// RenamedFrom names the component's previous name so that migrations rename
+// the existing comp_* table instead of dropping it and creating a new one.
+// Indexes declares SQLite indexes on the component table.
+//
+// OnDelete and RefType apply to entity-ref components, as they do to
+// entity-ref properties.
 type Component struct {
-	Type       string              `json:"type"`
-	Behavior   string              `json:"behavior,omitempty"`
-	Properties map[string]Property `json:"properties,omitempty"`
-	Items      *Property           `json:"items,omitempty"`
+	Type        string              `json:"type"`
+	Behavior    string              `json:"behavior,omitempty"`
+	Properties  map[string]Property `json:"properties,omitempty"`
+	Items       *Property           `json:"items,omitempty"`
+	RenamedFrom string              `json:"renamedFrom,omitempty"`
+	Indexes     []Index             `json:"indexes,omitempty"`
+	OnDelete    string              `json:"onDelete,omitempty"`
+	RefType     string              `json:"refType,omitempty"`
 }
 
 // UnmarshalJSON implements polymorphic decoding based on the "type" field.
 // It validates that the type is recognised and that the structural fields
-// (Properties for object, Items for array) are consistent.
+// (Properties for object, Items for array, neither for tag, no Items for
+// relation) are consistent.
 func (c *Component) UnmarshalJSON(data []byte) error {
 	// First pass: extract the raw type key.
 	var raw struct {
@@ -65,6 +86,9 @@ func (c *Component) UnmarshalJSON(data []byte) error {
 	}
 
 	// Structural validation.
+	if err := c.validateReference(); err != nil {
+		return err
+	}
 	switch c.Type {
 	case ComponentTypeObject:
 		if len(c.Properties) == 0 {
@@ -84,6 +108,20 @@ func (c *Component) UnmarshalJSON(data []byte) error {
 		if err := c.Items.Validate(); err != nil {
 			return fmt.Errorf("component %q items: %w", ComponentTypeArray, err)
 		}
+	case ComponentTypeTag:
+		if len(c.Properties) > 0 || c.Items != nil {
+			return fmt.Errorf("component type %q must not define %q or %q",
+				ComponentTypeTag, "properties", "items")
+		}
+	case ComponentTypeRelation:
+		if c.Items != nil {
+			return fmt.Errorf("component type %q must not define %q", ComponentTypeRelation, "items")
+		}
+		for name, prop := range c.Properties {
+			if err := prop.Validate(); err != nil {
+				return fmt.Errorf("relation property %q: %w", name, err)
+			}
+		}
 	}
 	return nil
 }
@@ -97,6 +135,8 @@ func supportedComponentTypeList() string {
 		ComponentTypeInteger,
 		ComponentTypeNumber,
 		ComponentTypeBoolean,
+		ComponentTypeTag,
+		ComponentTypeRelation,
 	}
 	s := ""
 	for i, t := range types {
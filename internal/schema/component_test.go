@@ -133,6 +133,21 @@ func TestComponentUnmarshalJSON(t *testing.T) {
 			json:    `{"type": "array", "items": {"type": "email"}}`,
 			wantErr: true,
 		},
+		{
+			name:    "valid tag component",
+			json:    `{"type": "tag"}`,
+			wantErr: false,
+			check: func(t *testing.T, c *Component) {
+				if c.Type != ComponentTypeTag {
+					t.Errorf("Type = %q, want %q", c.Type, ComponentTypeTag)
+				}
+			},
+		},
+		{
+			name:    "tag with properties",
+			json:    `{"type": "tag", "properties": {"x": {"type": "number"}}}`,
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
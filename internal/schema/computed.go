@@ -0,0 +1,236 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A computed property is derived from its sibling properties by a SQL
+// expression such as "hp * 1.0 / maxHp". It is stored as a generated
+// column, VIRTUAL (evaluated on read) by default or STORED (written with
+// the row) when "stored" is set. Its value is maintained by SQLite, so it
+// is read like any other property and every write to it is refused.
+//
+// Expressions are restricted to what a generated column can evaluate for
+// every row: sibling property names, literals, operators, CASE and CAST,
+// and the deterministic functions in computedFunctions. A computed
+// property cannot reference another computed property.
+
+// computedKeywords are the SQL keywords accepted in a computed expression.
+var computedKeywords = map[string]bool{
+	"and": true, "or": true, "not": true, "null": true, "is": true, "in": true,
+	"between": true, "like": true, "glob": true, "case": true, "when": true,
+	"then": true, "else": true, "end": true, "cast": true, "as": true,
+	"integer": true, "real": true, "text": true, "numeric": true,
+	"true": true, "false": true,
+}
+
+// computedFunctions are the SQL functions accepted in a computed
+// expression. All are deterministic, as SQLite requires for generated
+// columns.
+var computedFunctions = map[string]bool{
+	"abs": true, "ceil": true, "ceiling": true, "coalesce": true, "exp": true,
+	"floor": true, "ifnull": true, "iif": true, "instr": true, "length": true,
+	"ln": true, "log": true, "lower": true, "ltrim": true, "max": true,
+	"min": true, "mod": true, "nullif": true, "pow": true, "power": true,
+	"printf": true, "replace": true, "round": true, "rtrim": true,
+	"sign": true, "sqrt": true, "substr": true, "trim": true, "trunc": true,
+	"upper": true, "json_extract": true, "json_array_length": true,
+}
+
+// IsComputed reports whether the property declares a "computed"
+// expression.
+func (p Property) IsComputed() bool {
+	return strings.TrimSpace(p.Computed) != ""
+}
+
+// GeneratedClause renders the GENERATED ALWAYS AS clause of a computed
+// property's column, or "" for a plain property. It is shared by CREATE
+// TABLE, ALTER TABLE ADD COLUMN and the rebuild path.
+func (p Property) GeneratedClause() string {
+	if !p.IsComputed() {
+		return ""
+	}
+	storage := "VIRTUAL"
+	if p.Stored {
+		storage = "STORED"
+	}
+	return fmt.Sprintf("GENERATED ALWAYS AS (%s) %s", strings.TrimSpace(p.Computed), storage)
+}
+
+// validateComputed checks the computed and stored keywords of p on their
+// own: a computed property is a scalar with no default, nullable flag or
+// value constraints, since its value never comes from a write. The
+// expression is checked against the sibling properties by
+// validateComputedProperties.
+func (p Property) validateComputed() error {
+	if !p.IsComputed() {
+		if p.Stored {
+			return fmt.Errorf("stored is only valid on computed properties")
+		}
+		return nil
+	}
+	switch p.Type {
+	case PropertyTypeString, PropertyTypeInteger, PropertyTypeNumber, PropertyTypeBoolean:
+	default:
+		return fmt.Errorf("computed is only valid on %q, %q, %q and %q properties, not %q",
+			PropertyTypeString, PropertyTypeInteger, PropertyTypeNumber, PropertyTypeBoolean, p.Type)
+	}
+	switch {
+	case p.Default != nil:
+		return fmt.Errorf("computed properties cannot declare a default")
+	case p.Nullable:
+		return fmt.Errorf("computed properties cannot declare nullable")
+	case p.HasConstraints():
+		return fmt.Errorf("computed properties cannot declare value constraints")
+	}
+	return nil
+}
+
+// validateComputedProperties checks that computed properties appear only
+// as top-level properties of object components, and that each expression
+// references only plain sibling properties and the keywords and functions
+// allowed in generated columns.
+func validateComputedProperties(s DatabaseSchema) error {
+	for _, name := range sortedComponentNames(s) {
+		comp := s.Components[name]
+		for _, propName := range sortedPropertyNames(comp.Properties) {
+			prop := comp.Properties[propName]
+			owner := fmt.Sprintf("component %q property %q", name, propName)
+			if nestedComputed(prop.Properties) || (prop.Items != nil && nestedComputed(map[string]Property{"items": *prop.Items})) {
+				return fmt.Errorf("%s: computed is only supported on top-level properties", owner)
+			}
+			if !prop.IsComputed() {
+				continue
+			}
+			if comp.Type != ComponentTypeObject {
+				return fmt.Errorf("%s: computed is only supported on %q components, not %q",
+					owner, ComponentTypeObject, comp.Type)
+			}
+			if err := prop.validateComputed(); err != nil {
+				return fmt.Errorf("%s: %w", owner, err)
+			}
+			if err := checkComputedExpr(prop.Computed, propName, comp.Properties); err != nil {
+				return fmt.Errorf("%s: %w", owner, err)
+			}
+		}
+		if comp.Items != nil && nestedComputed(map[string]Property{"items": *comp.Items}) {
+			return fmt.Errorf("component %q items: computed is not supported inside arrays", name)
+		}
+	}
+	for name, res := range s.Resources {
+		if nestedComputed(res.Properties) {
+			return fmt.Errorf("resource %q: computed is not supported on resources", name)
+		}
+	}
+	return nil
+}
+
+// nestedComputed reports whether any property in props, or beneath them,
+// declares computed.
+func nestedComputed(props map[string]Property) bool {
+	for _, p := range props {
+		if p.IsComputed() || nestedComputed(p.Properties) {
+			return true
+		}
+		if p.Items != nil && nestedComputed(map[string]Property{"items": *p.Items}) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkComputedExpr scans expr, the expression of property self, and
+// checks that every identifier is an allowed keyword, an allowed function
+// applied with parentheses, or a plain property among siblings. Names
+// compare case-insensitively, like the columns they map to. String
+// literals are skipped; quoted identifiers, parameters and statement
+// separators are refused.
+func checkComputedExpr(expr, self string, siblings map[string]Property) error {
+	if strings.TrimSpace(expr) == "" {
+		return fmt.Errorf("computed expression is empty")
+	}
+	depth := 0
+	for i := 0; i < len(expr); {
+		ch := expr[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			i++
+		case isIdentStart(ch):
+			start := i
+			for i < len(expr) && isIdentPart(expr[i]) {
+				i++
+			}
+			word := expr[start:i]
+			lower := strings.ToLower(word)
+			next := i
+			for next < len(expr) && (expr[next] == ' ' || expr[next] == '\t') {
+				next++
+			}
+			call := next < len(expr) && expr[next] == '('
+			switch {
+			case computedKeywords[lower]:
+			case call:
+				if !computedFunctions[lower] {
+					return fmt.Errorf("computed expression calls unsupported function %q", word)
+				}
+			default:
+				if strings.EqualFold(word, self) {
+					return fmt.Errorf("computed expression references the property itself")
+				}
+				prop, ok := PropertyByName(siblings, word)
+				if !ok {
+					return fmt.Errorf("computed expression references unknown property %q", word)
+				}
+				if prop.IsComputed() {
+					return fmt.Errorf("computed expression references computed property %q", word)
+				}
+			}
+		case ch >= '0' && ch <= '9' || ch == '.':
+			for i < len(expr) && (isIdentPart(expr[i]) || expr[i] == '.') {
+				i++
+			}
+		case ch == '\'':
+			i++
+			for {
+				if i >= len(expr) {
+					return fmt.Errorf("computed expression has an unterminated string literal")
+				}
+				if expr[i] == '\'' {
+					if i+1 < len(expr) && expr[i+1] == '\'' {
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				i++
+			}
+		case ch == '(':
+			depth++
+			i++
+		case ch == ')':
+			depth--
+			if depth < 0 {
+				return fmt.Errorf("computed expression has unbalanced parentheses")
+			}
+			i++
+		case strings.IndexByte("+-*/%<>=!|&~,", ch) >= 0:
+			i++
+		default:
+			return fmt.Errorf("computed expression contains unexpected character %q", ch)
+		}
+	}
+	if depth != 0 {
+		return fmt.Errorf("computed expression has unbalanced parentheses")
+	}
+	return nil
+}
+
+func isIdentStart(ch byte) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func isIdentPart(ch byte) bool {
+	return isIdentStart(ch) || (ch >= '0' && ch <= '9')
+}
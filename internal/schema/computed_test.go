@@ -0,0 +1,173 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadSchema_Computed(t *testing.T) {
+	s, err := LoadSchema([]byte(`{
+		"schemaVersion": 1,
+		"components": {
+			"Health": {"type": "object", "properties": {
+				"hp": {"type": "integer"},
+				"maxHp": {"type": "integer"},
+				"ratio": {"type": "number", "computed": "hp * 1.0 / maxHp", "stored": true}
+			}}
+		},
+		"entityTypes": {"Goblin": {"requiredComponents": ["Health"]}}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateSchema(s); err != nil {
+		t.Fatal(err)
+	}
+	ratio := s.Components["Health"].Properties["ratio"]
+	if got, want := ratio.GeneratedClause(), "GENERATED ALWAYS AS (hp * 1.0 / maxHp) STORED"; got != want {
+		t.Errorf("GeneratedClause = %q, want %q", got, want)
+	}
+	if PropertyNotNull(ratio) {
+		t.Error("PropertyNotNull(computed) = true, want false: the expression may be NULL")
+	}
+
+	_, err = LoadSchema([]byte(`{"schemaVersion": 1, "components": {"Health": {"type": "object", "properties": {
+		"hp": {"type": "integer", "stored": true}}}}}`))
+	if err == nil || !strings.Contains(err.Error(), "stored is only valid on computed properties") {
+		t.Errorf("err = %v, want stored refused without computed", err)
+	}
+}
+
+func TestValidateSchema_Computed(t *testing.T) {
+	minimum := 0.0
+	tests := []struct {
+		name    string
+		prop    Property
+		wantErr string
+	}{
+		{"valid", Property{Type: PropertyTypeNumber, Computed: "hp * 1.0 / maxHp"}, ""},
+		{"functions and case", Property{Type: PropertyTypeInteger,
+			Computed: "CASE WHEN hp > 0 THEN floor(hp / 32) ELSE NULL END"}, ""},
+		{"string literal", Property{Type: PropertyTypeString, Computed: "iif(hp > 0, 'alive', 'it''s dead')"}, ""},
+		{"case-insensitive sibling", Property{Type: PropertyTypeInteger, Computed: "MAXHP - HP"}, ""},
+		{"unknown property", Property{Type: PropertyTypeNumber, Computed: "hp / mana"},
+			`references unknown property "mana"`},
+		{"itself", Property{Type: PropertyTypeNumber, Computed: "ratio + 1"}, "references the property itself"},
+		{"computed sibling", Property{Type: PropertyTypeNumber, Computed: "lost + 1"},
+			`references computed property "lost"`},
+		{"unsupported function", Property{Type: PropertyTypeNumber, Computed: "random()"},
+			`unsupported function "random"`},
+		{"statement separator", Property{Type: PropertyTypeNumber, Computed: "hp; DROP TABLE entities"},
+			"unexpected character ';'"},
+		{"unbalanced", Property{Type: PropertyTypeNumber, Computed: "(hp + 1"}, "unbalanced parentheses"},
+		{"unterminated string", Property{Type: PropertyTypeString, Computed: "'abc"}, "unterminated string literal"},
+		{"object type", Property{Type: PropertyTypeObject, Computed: "hp",
+			Properties: map[string]Property{"a": {Type: PropertyTypeInteger}}}, "computed is only valid on"},
+		{"default", Property{Type: PropertyTypeNumber, Computed: "hp", Default: float64(1)}, "cannot declare a default"},
+		{"nullable", Property{Type: PropertyTypeNumber, Computed: "hp", Nullable: true}, "cannot declare nullable"},
+		{"constraints", Property{Type: PropertyTypeNumber, Computed: "hp", Minimum: &minimum},
+			"cannot declare value constraints"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := DatabaseSchema{
+				SchemaVersion: 1,
+				Components: map[string]Component{"Health": {Type: ComponentTypeObject, Properties: map[string]Property{
+					"hp":    {Type: PropertyTypeInteger},
+					"maxHp": {Type: PropertyTypeInteger},
+					"lost":  {Type: PropertyTypeInteger, Computed: "maxHp - hp"},
+					"ratio": tt.prop,
+				}}},
+				EntityTypes: map[string]EntityType{"Goblin": {RequiredComponents: []string{"Health"}, ValidationLevel: ValidationStrict}},
+			}
+			err := ValidateSchema(s)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateSchema_ComputedPlacement(t *testing.T) {
+	computed := Property{Type: PropertyTypeInteger, Computed: "1 + 1"}
+	tests := []struct {
+		name    string
+		comps   map[string]Component
+		res     map[string]Resource
+		wantErr string
+	}{
+		{"relation property", map[string]Component{"Likes": {Type: ComponentTypeRelation,
+			Properties: map[string]Property{"w": computed}}}, nil, `computed is only supported on "object" components`},
+		{"nested property", map[string]Component{"Stats": {Type: ComponentTypeObject,
+			Properties: map[string]Property{"inner": {Type: PropertyTypeObject, Properties: map[string]Property{"w": computed}}}}},
+			nil, "computed is only supported on top-level properties"},
+		{"resource", nil, map[string]Resource{"Clock": {Properties: map[string]Property{"w": computed}}},
+			`resource "Clock": computed is not supported on resources`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comps := map[string]Component{"Health": {Type: ComponentTypeInteger}}
+			for name, c := range tt.comps {
+				comps[name] = c
+			}
+			s := DatabaseSchema{
+				SchemaVersion: 1,
+				Components:    comps,
+				EntityTypes:   map[string]EntityType{"Goblin": {RequiredComponents: []string{"Health"}, ValidationLevel: ValidationStrict}},
+				Resources:     tt.res,
+			}
+			if err := ValidateSchema(s); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDiff_Computed(t *testing.T) {
+	domain := &DomainSchema{
+		Components: map[string]DomainComponent{
+			"health": {Type: "object", Columns: []DomainColumn{
+				{Name: "entity_id", SQLType: "INTEGER", IsPK: true},
+				{Name: "hp", SQLType: "INTEGER"},
+				{Name: "maxhp", SQLType: "INTEGER"},
+				{Name: "ratio", SQLType: "REAL", Nullable: true, Computed: "hp * 1.0 / maxHp"},
+				{Name: "lost", SQLType: "INTEGER", Nullable: true, Computed: "maxHp - hp", Stored: true},
+				{Name: "pct", SQLType: "INTEGER", Nullable: true, Computed: "hp * 100 / maxHp"},
+				{Name: "bonus", SQLType: "INTEGER"},
+			}},
+		},
+		EntityTypeNames: map[string]bool{},
+	}
+	file := &DatabaseSchema{
+		Components: map[string]Component{"Health": {Type: ComponentTypeObject, Properties: map[string]Property{
+			"hp":    {Type: PropertyTypeInteger},
+			"maxHp": {Type: PropertyTypeInteger},
+			// unchanged
+			"ratio": {Type: PropertyTypeNumber, Computed: " hp * 1.0 / maxHp "},
+			// STORED → VIRTUAL
+			"lost": {Type: PropertyTypeInteger, Computed: "maxHp - hp"},
+			// computed → plain
+			"pct": {Type: PropertyTypeInteger, Default: float64(0)},
+			// plain → computed
+			"bonus": {Type: PropertyTypeInteger, Computed: "hp / 10"},
+		}}},
+		EntityTypes: map[string]EntityType{},
+	}
+
+	got := Diff(domain, file, nil)
+	want := []string{"bonus", "lost", "pct"}
+	if len(got) != len(want) {
+		t.Fatalf("Diff = %+v, want changed_property_computed for %v", got, want)
+	}
+	for i, prop := range want {
+		if got[i].Kind != ChangedPropertyComputed || got[i].Component != "health" || got[i].Property != prop {
+			t.Errorf("change %d = %+v, want %s on health.%s", i, got[i], ChangedPropertyComputed, prop)
+		}
+	}
+}
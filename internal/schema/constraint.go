@@ -0,0 +1,270 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// HasConstraints reports whether the property declares any value constraint
+// keyword (minimum, maximum, enum, pattern, minLength, maxLength).
+func (p Property) HasConstraints() bool {
+	return p.Minimum != nil || p.Maximum != nil || len(p.Enum) > 0 ||
+		p.Pattern != "" || p.MinLength != nil || p.MaxLength != nil
+}
+
+// CheckExpr renders the constraints of p that SQLite can express as the
+// condition of a CHECK on column col, or "" if there are none. Like
+// PropertySQLType it is shared by DDL generation and the diff, which
+// compares it with the CHECK of the existing column. Pattern is enforced
+// on write only: SQLite ships without a REGEXP implementation.
+func (p Property) CheckExpr(col string) string {
+	var conds []string
+	if p.Minimum != nil {
+		conds = append(conds, fmt.Sprintf("%s >= %s", col, formatConstraintNumber(*p.Minimum)))
+	}
+	if p.Maximum != nil {
+		conds = append(conds, fmt.Sprintf("%s <= %s", col, formatConstraintNumber(*p.Maximum)))
+	}
+	if p.MinLength != nil {
+		conds = append(conds, fmt.Sprintf("length(%s) >= %d", col, *p.MinLength))
+	}
+	if p.MaxLength != nil {
+		conds = append(conds, fmt.Sprintf("length(%s) <= %d", col, *p.MaxLength))
+	}
+	if len(p.Enum) > 0 {
+		members := make([]string, 0, len(p.Enum))
+		for _, m := range p.Enum {
+			if s, ok := m.(string); ok {
+				members = append(members, "'"+strings.ReplaceAll(s, "'", "''")+"'")
+			} else if n, ok := constraintNumber(m); ok {
+				members = append(members, formatConstraintNumber(n))
+			}
+		}
+		conds = append(conds, fmt.Sprintf("%s IN (%s)", col, strings.Join(members, ", ")))
+	}
+	return strings.Join(conds, " AND ")
+}
+
+// validateConstraints checks that the constraint keywords declared on p are
+// applicable to its type and internally consistent (minimum <= maximum,
+// enum members of the right type, pattern compiles, etc.).
+func (p Property) validateConstraints() error {
+	numeric := p.Type == PropertyTypeInteger || p.Type == PropertyTypeNumber
+
+	if (p.Minimum != nil || p.Maximum != nil) && !numeric {
+		return fmt.Errorf("minimum/maximum are only valid on %q and %q properties, not %q",
+			PropertyTypeInteger, PropertyTypeNumber, p.Type)
+	}
+	if p.Minimum != nil && p.Maximum != nil && *p.Minimum > *p.Maximum {
+		return fmt.Errorf("minimum %s is greater than maximum %s",
+			formatConstraintNumber(*p.Minimum), formatConstraintNumber(*p.Maximum))
+	}
+
+	if (p.Pattern != "" || p.MinLength != nil || p.MaxLength != nil) && p.Type != PropertyTypeString {
+		return fmt.Errorf("pattern/minLength/maxLength are only valid on %q properties, not %q",
+			PropertyTypeString, p.Type)
+	}
+	if p.Pattern != "" {
+		if _, err := regexp.Compile(p.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", p.Pattern, err)
+		}
+	}
+	if p.MinLength != nil && *p.MinLength < 0 {
+		return fmt.Errorf("minLength must be >= 0, got %d", *p.MinLength)
+	}
+	if p.MaxLength != nil && *p.MaxLength < 0 {
+		return fmt.Errorf("maxLength must be >= 0, got %d", *p.MaxLength)
+	}
+	if p.MinLength != nil && p.MaxLength != nil && *p.MinLength > *p.MaxLength {
+		return fmt.Errorf("minLength %d is greater than maxLength %d", *p.MinLength, *p.MaxLength)
+	}
+
+	if len(p.Enum) > 0 {
+		if !numeric && p.Type != PropertyTypeString {
+			return fmt.Errorf("enum is only valid on %q, %q and %q properties, not %q",
+				PropertyTypeString, PropertyTypeInteger, PropertyTypeNumber, p.Type)
+		}
+		for i, member := range p.Enum {
+			if err := p.checkType(member); err != nil {
+				return fmt.Errorf("enum[%d]: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// CheckValue returns a descriptive error if v violates one of the
+// constraint keywords declared on p. A nil value is always accepted —
+// nullability is enforced separately. Properties without constraints
+// accept any value.
+//
+// Object and array values are checked against the constraints of their
+// nested properties and items; see checkNested.
+//
+// Pattern matching uses Go regexp syntax and, like JSON Schema, is
+// unanchored: use ^ and $ to match the whole string.
+func (p Property) CheckValue(v any) error {
+	if v == nil {
+		return nil
+	}
+	if p.Type == PropertyTypeObject || p.Type == PropertyTypeArray {
+		return p.checkNested(v)
+	}
+	if !p.HasConstraints() {
+		return nil
+	}
+	if err := p.checkType(v); err != nil {
+		return err
+	}
+
+	switch p.Type {
+	case PropertyTypeInteger, PropertyTypeNumber:
+		n, _ := constraintNumber(v)
+		if p.Minimum != nil && n < *p.Minimum {
+			return fmt.Errorf("value %s is less than minimum %s",
+				formatConstraintNumber(n), formatConstraintNumber(*p.Minimum))
+		}
+		if p.Maximum != nil && n > *p.Maximum {
+			return fmt.Errorf("value %s is greater than maximum %s",
+				formatConstraintNumber(n), formatConstraintNumber(*p.Maximum))
+		}
+	case PropertyTypeString:
+		s := v.(string)
+		length := utf8.RuneCountInString(s)
+		if p.MinLength != nil && length < *p.MinLength {
+			return fmt.Errorf("length %d is less than minLength %d", length, *p.MinLength)
+		}
+		if p.MaxLength != nil && length > *p.MaxLength {
+			return fmt.Errorf("length %d is greater than maxLength %d", length, *p.MaxLength)
+		}
+		if p.Pattern != "" {
+			re, err := regexp.Compile(p.Pattern)
+			if err != nil {
+				return fmt.Errorf("invalid pattern %q: %w", p.Pattern, err)
+			}
+			if !re.MatchString(s) {
+				return fmt.Errorf("value %q does not match pattern %q", s, p.Pattern)
+			}
+		}
+	}
+
+	if len(p.Enum) > 0 && !p.enumContains(v) {
+		return fmt.Errorf("value %v is not one of the allowed enum values %v", v, p.Enum)
+	}
+	return nil
+}
+
+// checkNested checks the fields of an object value against p.Properties
+// and the elements of an array value against p.Items. The value may be
+// decoded (map[string]any or []any) or the JSON text stored in the column;
+// text that does not decode, or decodes to the wrong shape, is not checked
+// here. Nested fields are matched case-insensitively, in name order, and
+// fields with no matching property are skipped.
+func (p Property) checkNested(v any) error {
+	if text, ok := v.(string); ok {
+		if err := json.Unmarshal([]byte(text), &v); err != nil {
+			return nil
+		}
+	}
+	switch p.Type {
+	case PropertyTypeObject:
+		fields, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		for _, name := range sortedKeys(fields) {
+			child, ok := PropertyByName(p.Properties, name)
+			if !ok {
+				continue
+			}
+			if err := child.CheckValue(fields[name]); err != nil {
+				return fmt.Errorf("property %q: %w", name, err)
+			}
+		}
+	case PropertyTypeArray:
+		items, ok := v.([]any)
+		if !ok || p.Items == nil {
+			return nil
+		}
+		for i, item := range items {
+			if err := p.Items.CheckValue(item); err != nil {
+				return fmt.Errorf("items[%d]: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkType reports whether v has a Go type compatible with p.Type for the
+// purposes of constraint checking. Only string and numeric types are
+// constrained, so other property types accept any value.
+func (p Property) checkType(v any) error {
+	switch p.Type {
+	case PropertyTypeString:
+		if _, ok := v.(string); !ok {
+			return fmt.Errorf("expected string, got %T", v)
+		}
+	case PropertyTypeInteger:
+		n, ok := constraintNumber(v)
+		if !ok {
+			return fmt.Errorf("expected integer, got %T", v)
+		}
+		if n != float64(int64(n)) {
+			return fmt.Errorf("expected integer, got %s", formatConstraintNumber(n))
+		}
+	case PropertyTypeNumber:
+		if _, ok := constraintNumber(v); !ok {
+			return fmt.Errorf("expected number, got %T", v)
+		}
+	}
+	return nil
+}
+
+// enumContains reports whether v equals one of p.Enum. Numbers compare by
+// value so that int64(3) matches a JSON-decoded float64(3).
+func (p Property) enumContains(v any) bool {
+	for _, member := range p.Enum {
+		if s, ok := v.(string); ok {
+			if ms, ok := member.(string); ok && ms == s {
+				return true
+			}
+			continue
+		}
+		n, ok := constraintNumber(v)
+		if !ok {
+			continue
+		}
+		if mn, ok := constraintNumber(member); ok && mn == n {
+			return true
+		}
+	}
+	return false
+}
+
+// constraintNumber coerces the numeric types produced by JSON decoding,
+// SQLite scans and Go callers to float64.
+func constraintNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// formatConstraintNumber renders a constraint bound without trailing zeros
+// (0 rather than 0.000000), suitable for both error messages and SQL.
+func formatConstraintNumber(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,284 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+func ptrFloat(f float64) *float64 { return &f }
+func ptrInt(i int) *int           { return &i }
+
+func TestPropertyValidate_Constraints(t *testing.T) {
+	tests := []struct {
+		name    string
+		prop    Property
+		wantErr string
+	}{
+		{
+			name: "integer with minimum and maximum",
+			prop: Property{Type: PropertyTypeInteger, Minimum: ptrFloat(0), Maximum: ptrFloat(100)},
+		},
+		{
+			name: "string with pattern and lengths",
+			prop: Property{Type: PropertyTypeString, Pattern: "^[a-z]+$", MinLength: ptrInt(1), MaxLength: ptrInt(32)},
+		},
+		{
+			name: "string enum",
+			prop: Property{Type: PropertyTypeString, Enum: []any{"idle", "walk"}},
+		},
+		{
+			name: "integer enum",
+			prop: Property{Type: PropertyTypeInteger, Enum: []any{1.0, 2.0}},
+		},
+		{
+			name:    "minimum on string",
+			prop:    Property{Type: PropertyTypeString, Minimum: ptrFloat(0)},
+			wantErr: "minimum/maximum are only valid",
+		},
+		{
+			name:    "minimum greater than maximum",
+			prop:    Property{Type: PropertyTypeNumber, Minimum: ptrFloat(5), Maximum: ptrFloat(1)},
+			wantErr: "minimum 5 is greater than maximum 1",
+		},
+		{
+			name:    "pattern on integer",
+			prop:    Property{Type: PropertyTypeInteger, Pattern: "x"},
+			wantErr: "pattern/minLength/maxLength are only valid",
+		},
+		{
+			name:    "invalid pattern",
+			prop:    Property{Type: PropertyTypeString, Pattern: "("},
+			wantErr: "invalid pattern",
+		},
+		{
+			name:    "negative minLength",
+			prop:    Property{Type: PropertyTypeString, MinLength: ptrInt(-1)},
+			wantErr: "minLength must be >= 0",
+		},
+		{
+			name:    "minLength greater than maxLength",
+			prop:    Property{Type: PropertyTypeString, MinLength: ptrInt(4), MaxLength: ptrInt(2)},
+			wantErr: "minLength 4 is greater than maxLength 2",
+		},
+		{
+			name:    "enum on boolean",
+			prop:    Property{Type: PropertyTypeBoolean, Enum: []any{true}},
+			wantErr: "enum is only valid",
+		},
+		{
+			name:    "enum member of wrong type",
+			prop:    Property{Type: PropertyTypeString, Enum: []any{"a", 1.0}},
+			wantErr: "enum[1]: expected string",
+		},
+		{
+			name:    "non-integral integer enum member",
+			prop:    Property{Type: PropertyTypeInteger, Enum: []any{1.5}},
+			wantErr: "expected integer",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.prop.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate() = nil, want error containing %q", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() = %q, want substring %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProperty_CheckValue(t *testing.T) {
+	hp := Property{Type: PropertyTypeInteger, Minimum: ptrFloat(0), Maximum: ptrFloat(100)}
+	imageID := Property{Type: PropertyTypeString, MinLength: ptrInt(1), Pattern: "^[a-z_]+$"}
+	state := Property{Type: PropertyTypeString, Enum: []any{"idle", "walk"}}
+	facing := Property{Type: PropertyTypeInteger, Enum: []any{0.0, 90.0, 180.0, 270.0}}
+	stats := Property{Type: PropertyTypeObject, Properties: map[string]Property{
+		"hp":   hp,
+		"tags": {Type: PropertyTypeArray, Items: &Property{Type: PropertyTypeString, MinLength: ptrInt(1)}},
+	}}
+
+	tests := []struct {
+		name    string
+		prop    Property
+		value   any
+		wantErr string
+	}{
+		{"in range int", hp, 40, ""},
+		{"in range int64", hp, int64(100), ""},
+		{"in range float64", hp, 0.0, ""},
+		{"below minimum", hp, -40, "value -40 is less than minimum 0"},
+		{"above maximum", hp, int64(101), "value 101 is greater than maximum 100"},
+		{"non-integral integer", hp, 1.5, "expected integer"},
+		{"wrong type", hp, "ten", "expected integer"},
+		{"nil always accepted", hp, nil, ""},
+		{"matching string", imageID, "goblin", ""},
+		{"empty string", imageID, "", "length 0 is less than minLength 1"},
+		{"pattern mismatch", imageID, "Goblin", `does not match pattern`},
+		{"enum member", state, "walk", ""},
+		{"enum non-member", state, "run", "not one of the allowed enum values"},
+		{"numeric enum as int64", facing, int64(90), ""},
+		{"numeric enum non-member", facing, 45, "not one of the allowed enum values"},
+		{"unconstrained property", Property{Type: PropertyTypeString}, 12, ""},
+		{"nested field in range", stats, map[string]any{"HP": 40.0, "tags": []any{"a"}}, ""},
+		{"nested field out of range", stats, map[string]any{"hp": 140.0}, `property "hp": value 140 is greater than maximum 100`},
+		{"nested item", stats, map[string]any{"tags": []any{"ok", ""}}, `property "tags": items[1]: length 0`},
+		{"nested JSON text", stats, `{"hp": -1}`, `property "hp": value -1 is less than minimum 0`},
+		{"array items", Property{Type: PropertyTypeArray, Items: &hp}, []any{1.0, 200.0}, "items[1]: value 200"},
+		{"nested wrong shape", stats, []any{1.0}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.prop.CheckValue(tt.value)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("CheckValue(%v) unexpected error: %v", tt.value, err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("CheckValue(%v) = nil, want error containing %q", tt.value, tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("CheckValue(%v) = %q, want substring %q", tt.value, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadSchema_PropertyConstraints(t *testing.T) {
+	s, err := LoadSchema([]byte(`{
+		"schemaVersion": 1,
+		"components": {
+			"Health": {
+				"type": "object",
+				"properties": {
+					"hp": {"type": "integer", "minimum": 0, "maximum": 500}
+				}
+			},
+			"Sprite": {
+				"type": "object",
+				"properties": {
+					"imageId": {"type": "string", "minLength": 1, "pattern": "^[a-z_]+$"},
+					"layer": {"type": "string", "enum": ["ground", "air"]}
+				}
+			}
+		},
+		"entityTypes": {"Goblin": {"requiredComponents": ["Health", "Sprite"]}}
+	}`))
+	if err != nil {
+		t.Fatalf("LoadSchema() unexpected error: %v", err)
+	}
+	if err := ValidateSchema(s); err != nil {
+		t.Fatalf("ValidateSchema() unexpected error: %v", err)
+	}
+
+	hp := s.Components["Health"].Properties["hp"]
+	if hp.Minimum == nil || *hp.Minimum != 0 || hp.Maximum == nil || *hp.Maximum != 500 {
+		t.Errorf("hp bounds = %v..%v, want 0..500", hp.Minimum, hp.Maximum)
+	}
+	layer := s.Components["Sprite"].Properties["layer"]
+	if len(layer.Enum) != 2 {
+		t.Errorf("layer enum = %v, want 2 members", layer.Enum)
+	}
+}
+
+func TestLoadSchema_InvalidPropertyConstraint(t *testing.T) {
+	_, err := LoadSchema([]byte(`{
+		"schemaVersion": 1,
+		"components": {
+			"Health": {
+				"type": "object",
+				"properties": {"hp": {"type": "integer", "minimum": 10, "maximum": 1}}
+			}
+		},
+		"entityTypes": {"Goblin": {"requiredComponents": ["Health"]}}
+	}`))
+	if err == nil {
+		t.Fatal("LoadSchema() = nil, want error for minimum > maximum")
+	}
+	if !strings.Contains(err.Error(), `"hp"`) {
+		t.Errorf("error should name the property: %v", err)
+	}
+}
+
+func TestValidateSchema_PropertyConstraintPhase(t *testing.T) {
+	s := DatabaseSchema{
+		SchemaVersion: 1,
+		Components: map[string]Component{
+			"Sprite": {
+				Type: ComponentTypeObject,
+				Properties: map[string]Property{
+					"imageId": {Type: PropertyTypeString, Minimum: ptrFloat(1)},
+				},
+			},
+		},
+		EntityTypes: map[string]EntityType{
+			"Goblin": {RequiredComponents: []string{"Sprite"}, ValidationLevel: ValidationStrict},
+		},
+	}
+	err := ValidateSchema(s)
+	if err == nil {
+		t.Fatal("ValidateSchema() = nil, want property constraint error")
+	}
+	if !strings.Contains(err.Error(), "property constraints:") {
+		t.Errorf("expected property constraints phase error, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), `component "Sprite" property "imageId"`) {
+		t.Errorf("error should name component and property: %v", err)
+	}
+}
+
+func TestDiff_Constraint(t *testing.T) {
+	domain := &DomainSchema{
+		Components: map[string]DomainComponent{
+			"stats": {Type: "object", Columns: []DomainColumn{
+				{Name: "entity_id", SQLType: "INTEGER", IsPK: true},
+				{Name: "armor", SQLType: "INTEGER"},
+				{Name: "hp", SQLType: "INTEGER", Check: "hp >= 0"},
+				{Name: "mp", SQLType: "INTEGER", Check: "mp >= 0"},
+				{Name: "name", SQLType: "TEXT", Check: "length(name) <= 8"},
+				{Name: "oldrank", SQLType: "TEXT", Check: "oldrank IN ('a', 'b')"},
+			}},
+		},
+		EntityTypeNames: map[string]bool{},
+	}
+	file := &DatabaseSchema{
+		Components: map[string]Component{"Stats": {Type: ComponentTypeObject, Properties: map[string]Property{
+			// added
+			"armor": {Type: PropertyTypeInteger, Maximum: ptrFloat(10)},
+			// unchanged
+			"hp": {Type: PropertyTypeInteger, Minimum: ptrFloat(0)},
+			// dropped
+			"mp": {Type: PropertyTypeInteger},
+			// tightened
+			"name": {Type: PropertyTypeString, MaxLength: ptrInt(4)},
+			// renamed, same enum
+			"rank": {Type: PropertyTypeString, Enum: []any{"a", "b"}, RenamedFrom: "oldRank"},
+		}}},
+		EntityTypes: map[string]EntityType{},
+	}
+
+	var got []Change
+	for _, c := range Diff(domain, file, nil) {
+		if c.Kind != ChangeRenamedProperty {
+			got = append(got, c)
+		}
+	}
+	want := []string{"armor", "mp", "name"}
+	if len(got) != len(want) {
+		t.Fatalf("Diff = %+v, want changed_property_constraint for %v", got, want)
+	}
+	for i, prop := range want {
+		if got[i].Kind != ChangedPropertyConstraint || got[i].Component != "stats" || got[i].Property != prop {
+			t.Errorf("change %d = %+v, want %s on stats.%s", i, got[i], ChangedPropertyConstraint, prop)
+		}
+	}
+}
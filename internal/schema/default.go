@@ -0,0 +1,95 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// HasDefault reports whether the property declares a "default" value.
+func (p Property) HasDefault() bool {
+	return p.Default != nil
+}
+
+// validateDefault checks that a declared default matches the property type
+// and satisfies the property's own constraints. entity-ref properties
+// cannot declare a default: there is no entity id that is valid in every
+// world.
+func (p Property) validateDefault() error {
+	if p.Default == nil {
+		return nil
+	}
+	var ok bool
+	switch p.Type {
+	case PropertyTypeString:
+		_, ok = p.Default.(string)
+	case PropertyTypeInteger:
+		n, isNum := constraintNumber(p.Default)
+		ok = isNum && n == float64(int64(n))
+	case PropertyTypeNumber:
+		_, ok = constraintNumber(p.Default)
+	case PropertyTypeBoolean:
+		_, ok = p.Default.(bool)
+	case PropertyTypeObject:
+		_, ok = p.Default.(map[string]any)
+	case PropertyTypeArray:
+		_, ok = p.Default.([]any)
+	case PropertyTypeEntityRef:
+		return fmt.Errorf("default is not supported on %q properties", PropertyTypeEntityRef)
+	}
+	if !ok {
+		return fmt.Errorf("default %v does not match property type %q", p.Default, p.Type)
+	}
+	if err := p.CheckValue(p.Default); err != nil {
+		return fmt.Errorf("default: %w", err)
+	}
+	return nil
+}
+
+// DefaultValue returns the declared default in the form written to a
+// comp_* column: object and array defaults are JSON-encoded, all other
+// types are returned as declared. The second result is false when the
+// property has no default.
+func (p Property) DefaultValue() (any, bool) {
+	if p.Default == nil {
+		return nil, false
+	}
+	switch p.Type {
+	case PropertyTypeObject, PropertyTypeArray:
+		data, err := json.Marshal(p.Default)
+		if err != nil {
+			return nil, false
+		}
+		return string(data), true
+	}
+	return p.Default, true
+}
+
+// WithDefaults returns a copy of values in which every property of an
+// object component that declares a default and is absent from values is
+// filled in. Keys are matched case-insensitively, mirroring the lowercase
+// column names in comp_* tables. Non-object components return a copy of
+// values unchanged.
+func (c Component) WithDefaults(values map[string]any) map[string]any {
+	out := make(map[string]any, len(values))
+	for k, v := range values {
+		out[k] = v
+	}
+	if c.Type != ComponentTypeObject {
+		return out
+	}
+
+	present := make(map[string]bool, len(values))
+	for k := range values {
+		present[strings.ToLower(k)] = true
+	}
+	for name, prop := range c.Properties {
+		if present[strings.ToLower(name)] {
+			continue
+		}
+		if v, ok := prop.DefaultValue(); ok {
+			out[name] = v
+		}
+	}
+	return out
+}
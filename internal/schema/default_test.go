@@ -0,0 +1,112 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPropertyValidate_Default(t *testing.T) {
+	tests := []struct {
+		name    string
+		prop    Property
+		wantErr string
+	}{
+		{"string", Property{Type: PropertyTypeString, Default: "goblin"}, ""},
+		{"integer", Property{Type: PropertyTypeInteger, Default: 100.0}, ""},
+		{"number", Property{Type: PropertyTypeNumber, Default: 1.5}, ""},
+		{"boolean", Property{Type: PropertyTypeBoolean, Default: false}, ""},
+		{"array", Property{Type: PropertyTypeArray, Items: &Property{Type: PropertyTypeString}, Default: []any{}}, ""},
+		{"non-integral integer", Property{Type: PropertyTypeInteger, Default: 1.5}, "does not match property type"},
+		{"string for number", Property{Type: PropertyTypeNumber, Default: "1"}, "does not match property type"},
+		{"entity-ref", Property{Type: PropertyTypeEntityRef, Default: 1.0}, "default is not supported"},
+		{
+			"violates own constraint",
+			Property{Type: PropertyTypeInteger, Minimum: ptrFloat(1), Default: 0.0},
+			"default: value 0 is less than minimum 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.prop.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProperty_DefaultValue(t *testing.T) {
+	if _, ok := (Property{Type: PropertyTypeInteger}).DefaultValue(); ok {
+		t.Error("DefaultValue() ok = true for property without default")
+	}
+	v, ok := Property{Type: PropertyTypeInteger, Default: 100.0}.DefaultValue()
+	if !ok || v != 100.0 {
+		t.Errorf("DefaultValue() = %v, %v; want 100, true", v, ok)
+	}
+	v, ok = Property{Type: PropertyTypeArray, Default: []any{"a"}}.DefaultValue()
+	if !ok || v != `["a"]` {
+		t.Errorf("array DefaultValue() = %v, %v; want JSON string", v, ok)
+	}
+}
+
+func TestComponent_WithDefaults(t *testing.T) {
+	comp := Component{
+		Type: ComponentTypeObject,
+		Properties: map[string]Property{
+			"hp":    {Type: PropertyTypeInteger, Default: 10.0},
+			"maxHp": {Type: PropertyTypeInteger, Default: 100.0},
+			"armor": {Type: PropertyTypeInteger},
+		},
+	}
+	in := map[string]any{"hp": 5, "maxhp": 50}
+	got := comp.WithDefaults(in)
+
+	if got["hp"] != 5 {
+		t.Errorf("hp = %v, want caller value 5", got["hp"])
+	}
+	if _, ok := got["maxHp"]; ok {
+		t.Error("maxHp should not be filled when maxhp was supplied (case-insensitive)")
+	}
+	if _, ok := got["armor"]; ok {
+		t.Error("armor has no default and should stay absent")
+	}
+	if len(in) != 2 {
+		t.Error("WithDefaults must not mutate its input")
+	}
+
+	got = comp.WithDefaults(nil)
+	if got["hp"] != 10.0 || got["maxHp"] != 100.0 {
+		t.Errorf("WithDefaults(nil) = %v, want hp=10 maxHp=100", got)
+	}
+}
+
+func TestLoadSchema_PropertyDefault(t *testing.T) {
+	s, err := LoadSchema([]byte(`{
+		"schemaVersion": 1,
+		"components": {
+			"Health": {
+				"type": "object",
+				"properties": {
+					"hp": {"type": "integer"},
+					"maxHp": {"type": "integer", "default": 100}
+				}
+			}
+		},
+		"entityTypes": {"Goblin": {"requiredComponents": ["Health"]}}
+	}`))
+	if err != nil {
+		t.Fatalf("LoadSchema() unexpected error: %v", err)
+	}
+	if err := ValidateSchema(s); err != nil {
+		t.Fatalf("ValidateSchema() unexpected error: %v", err)
+	}
+	if d := s.Components["Health"].Properties["maxHp"].Default; d != 100.0 {
+		t.Errorf("maxHp default = %v, want 100", d)
+	}
+}
@@ -10,6 +10,8 @@ import (
 type DomainSchema struct {
 	SchemaVersion   int
 	Components      map[string]DomainComponent // key = lowercase name
+	Resources       map[string]DomainResource  // key = lowercase name
+	Relations       map[string]DomainRelation  // key = lowercase name
 	EntityTypeNames map[string]bool
 }
 
@@ -17,52 +19,130 @@ type DomainSchema struct {
 type DomainComponent struct {
 	Type    string         // "object", "string", "integer", etc.
 	Columns []DomainColumn // ordered by PRAGMA cid
+	Indexes []DomainIndex  // declared indexes, sorted by name
 }
 
-// DomainColumn represents a single column in a component table.
+// DomainResource represents a resource table's structure as found in the
+// DB. Its first column is the id primary key of the single row.
+type DomainResource struct {
+	Columns []DomainColumn // ordered by PRAGMA cid
+}
+
+// DomainRelation represents a relation table's structure as found in the
+// DB. Its source_id and target_id columns form the primary key.
+type DomainRelation struct {
+	Columns []DomainColumn // ordered by PRAGMA cid
+}
+
+// DomainColumn represents a single column in a component, resource or
+// relation table.
 type DomainColumn struct {
-	Name    string
-	SQLType string
-	IsPK    bool
+	Name     string
+	SQLType  string
+	IsPK     bool
+	Nullable bool // true when the column has no NOT NULL constraint
+	// References is true when the column is a foreign key to entities(id);
+	// OnDelete is its delete policy as an onDelete keyword ("" for none).
+	References bool
+	OnDelete   string
+	// Computed is the expression of a generated column ("" for a plain
+	// column); Stored is true when the generated column is STORED.
+	Computed string
+	Stored   bool
+	// Check is the condition of the column's CHECK constraint ("" for
+	// none).
+	Check string
 }
 
 // ChangeKind identifies the category of a schema change.
 type ChangeKind string
 
 const (
-	ChangeAddedComponent    ChangeKind = "added_component"
-	ChangeRemovedComponent  ChangeKind = "removed_component"
-	ChangeAddedProperty     ChangeKind = "added_property"
-	ChangeRemovedProperty   ChangeKind = "removed_property"
-	ChangedPropertyType     ChangeKind = "changed_property_type"
+	// ChangeRenamedComponent and ChangeRenamedProperty are emitted for
+	// components and properties whose renamedFrom hint names a table or
+	// column that still exists in the database under its old name.
+	ChangeRenamedComponent ChangeKind = "renamed_component"
+	ChangeRenamedProperty  ChangeKind = "renamed_property"
+	ChangeAddedComponent   ChangeKind = "added_component"
+	ChangeRemovedComponent ChangeKind = "removed_component"
+	ChangeAddedProperty    ChangeKind = "added_property"
+	ChangeRemovedProperty  ChangeKind = "removed_property"
+	ChangedPropertyType    ChangeKind = "changed_property_type"
+	// ChangedPropertyNullability is emitted when a property keeps its SQL
+	// type but gains or loses its NOT NULL constraint.
+	ChangedPropertyNullability ChangeKind = "changed_property_nullability"
+	// ChangedPropertyReference is emitted when an entity-ref column lacks
+	// its foreign key or has a different onDelete policy than declared.
+	ChangedPropertyReference ChangeKind = "changed_property_reference"
+	// ChangedPropertyComputed is emitted when a column's generated
+	// expression or storage differs from the declared computed property,
+	// including a plain property becoming computed or the reverse.
+	ChangedPropertyComputed ChangeKind = "changed_property_computed"
+	// ChangedPropertyConstraint is emitted when a column's CHECK differs
+	// from the constraints declared on the property (minimum, maximum,
+	// enum, minLength, maxLength), whether they were added, tightened,
+	// relaxed or dropped.
+	ChangedPropertyConstraint ChangeKind = "changed_property_constraint"
+	// ChangeAddedIndex and ChangeRemovedIndex are emitted for declared
+	// indexes; an index whose definition changes is removed and re-added.
+	ChangeAddedIndex        ChangeKind = "added_index"
+	ChangeRemovedIndex      ChangeKind = "removed_index"
 	ChangeAddedEntityType   ChangeKind = "added_entity_type"
 	ChangeRemovedEntityType ChangeKind = "removed_entity_type"
 	ChangeChangedEntityType ChangeKind = "changed_entity_type"
+	// ChangeAddedResource and ChangeRemovedResource are emitted for res_*
+	// tables. Property changes on a resource reuse the property kinds
+	// above with Resource set instead of Component.
+	ChangeAddedResource   ChangeKind = "added_resource"
+	ChangeRemovedResource ChangeKind = "removed_resource"
+	// ChangeAddedRelation and ChangeRemovedRelation are emitted for rel_*
+	// tables. Property changes on a relation carry Relation instead of
+	// Component.
+	ChangeAddedRelation   ChangeKind = "added_relation"
+	ChangeRemovedRelation ChangeKind = "removed_relation"
 )
 
 // Change represents a single structural difference between the database
 // schema and the file schema.
 type Change struct {
-	Kind      ChangeKind
-	Component string      // lowercase component name
-	Property  string      // lowercase property name (for property-level changes)
-	OldType   string      // old SQL type (for type changes)
-	NewType   string      // new SQL type (for type changes)
-	ETName    string      // entity type name (for entity-type changes)
-	OldET     *EntityType // previous entity type spec (for changed_entity_type)
-	NewET     *EntityType // new entity type spec (for changed_entity_type)
+	Kind      ChangeKind `json:"kind"`
+	Component string     `json:"component,omitempty"` // lowercase component name
+	Resource  string     `json:"resource,omitempty"`  // lowercase resource name (for resource changes)
+	Relation  string     `json:"relation,omitempty"`  // lowercase relation name (for relation changes)
+	Property  string     `json:"property,omitempty"`  // lowercase property name (for property-level changes)
+	OldName   string     `json:"oldName,omitempty"`   // previous lowercase component or property name (for renames)
+	OldType   string     `json:"oldType,omitempty"`   // old SQL type (for type changes)
+	NewType   string     `json:"newType,omitempty"`   // new SQL type (for type changes)
+	// OldNullable/NewNullable carry the column nullability for
+	// changed_property_nullability.
+	OldNullable bool        `json:"oldNullable,omitempty"`
+	NewNullable bool        `json:"newNullable,omitempty"`
+	Index       string      `json:"index,omitempty"`         // index name (for index changes)
+	ETName      string      `json:"entityType,omitempty"`    // entity type name (for entity-type changes)
+	OldET       *EntityType `json:"oldEntityType,omitempty"` // previous entity type spec (for changed_entity_type)
+	NewET       *EntityType `json:"newEntityType,omitempty"` // new entity type spec (for changed_entity_type)
 }
 
 // phase returns a numeric priority used for deterministic ordering.
-// Additions come first (1), modifications second (2), removals last (3).
+// Renames come first (0) so that every later change can address tables and
+// columns by their new names; then additions (1), modifications (2) and
+// removals (3). Index additions come last (4), once every column they
+// cover exists.
 func (c Change) phase() int {
 	switch c.Kind {
-	case ChangeAddedComponent, ChangeAddedProperty, ChangeAddedEntityType:
+	case ChangeRenamedComponent, ChangeRenamedProperty:
+		return 0
+	case ChangeAddedComponent, ChangeAddedProperty, ChangeAddedEntityType, ChangeAddedResource,
+		ChangeAddedRelation:
 		return 1
-	case ChangedPropertyType, ChangeChangedEntityType:
+	case ChangedPropertyType, ChangedPropertyNullability, ChangedPropertyReference, ChangedPropertyComputed,
+		ChangedPropertyConstraint, ChangeChangedEntityType:
 		return 2
-	case ChangeRemovedComponent, ChangeRemovedProperty, ChangeRemovedEntityType:
+	case ChangeRemovedComponent, ChangeRemovedProperty, ChangeRemovedEntityType, ChangeRemovedIndex,
+		ChangeRemovedResource, ChangeRemovedRelation:
 		return 3
+	case ChangeAddedIndex:
+		return 4
 	}
 	return 99 // safety net
 }
@@ -70,16 +150,33 @@ func (c Change) phase() int {
 // sortKey returns a comparable tuple for ordering changes within a phase.
 func (c Change) sortKey() string {
 	primary := c.Component
+	if primary == "" && c.Resource != "" {
+		primary = c.Resource
+	}
+	if primary == "" && c.Relation != "" {
+		primary = c.Relation
+	}
 	if primary == "" && c.ETName != "" {
 		primary = c.ETName
 	}
-	return primary + "\x00" + c.Property + "\x00" + string(c.Kind)
+	return primary + "\x00" + c.Property + c.Index + "\x00" + string(c.Kind)
 }
 
 // Diff computes the structural differences between the as-built database
 // schema and the current file schema. Returns an empty (non-nil) slice for
-// identical schemas. Changes are ordered: additions → modifications →
-// removals, with alphabetical sorting within each phase.
+// identical schemas. Changes are ordered: renames → additions →
+// modifications → removals → index additions, with alphabetical sorting
+// within each phase.
+//
+// A component or property whose renamedFrom hint names something present in
+// the database but absent from the file is reported as a rename rather than
+// as a removal plus an addition. Any structural difference between the old
+// table or column and the renamed declaration is reported against the new
+// name.
+//
+// Resources and relations are compared like object components, their
+// properties by column; see diffResources and diffRelations. Relation
+// components are compared against the rel_* tables only.
 //
 // Entity type changes require both the current file schema and the previous
 // file schema, since entity type spec details are not stored in the database.
@@ -89,6 +186,8 @@ func Diff(domain *DomainSchema, file, oldFile *DatabaseSchema) []Change {
 	if domain == nil {
 		domain = &DomainSchema{
 			Components:      make(map[string]DomainComponent),
+			Resources:       make(map[string]DomainResource),
+			Relations:       make(map[string]DomainRelation),
 			EntityTypeNames: make(map[string]bool),
 		}
 	}
@@ -103,27 +202,36 @@ func Diff(domain *DomainSchema, file, oldFile *DatabaseSchema) []Change {
 
 	// ── Component diff ───────────────────────────────────────────────
 	// Build lowercase key sets.
-	dbCompNames := make([]string, 0, len(domain.Components))
+	dbCompSet := make(map[string]bool, len(domain.Components))
 	for k := range domain.Components {
-		dbCompNames = append(dbCompNames, strings.ToLower(k))
+		dbCompSet[strings.ToLower(k)] = true
 	}
-	fileCompNames := make([]string, 0, len(file.Components))
-	for k := range file.Components {
-		fileCompNames = append(fileCompNames, strings.ToLower(k))
+	fileComps := make(map[string]Component, len(file.Components))
+	for k, c := range file.Components {
+		if c.Type == ComponentTypeRelation {
+			continue // stored in rel_*, see diffRelations
+		}
+		fileComps[strings.ToLower(k)] = c
 	}
-
-	dbCompSet := make(map[string]bool, len(dbCompNames))
-	for _, n := range dbCompNames {
-		dbCompSet[n] = true
+	fileHints := make(map[string]string, len(fileComps))
+	for name, c := range fileComps {
+		fileHints[name] = c.RenamedFrom
 	}
-	fileCompSet := make(map[string]bool, len(fileCompNames))
-	for _, n := range fileCompNames {
-		fileCompSet[n] = true
+	// renames maps old (DB) name → new (file) name.
+	renames := matchRenames(dbCompSet, fileHints)
+	renamedTo := make(map[string]bool, len(renames))
+	for oldName, newName := range renames {
+		renamedTo[newName] = true
+		changes = append(changes, Change{
+			Kind:      ChangeRenamedComponent,
+			Component: newName,
+			OldName:   oldName,
+		})
 	}
 
 	// Components in file but not in DB → added.
-	for _, name := range fileCompNames {
-		if !dbCompSet[name] {
+	for name := range fileComps {
+		if !dbCompSet[name] && !renamedTo[name] {
 			changes = append(changes, Change{
 				Kind:      ChangeAddedComponent,
 				Component: name,
@@ -132,8 +240,8 @@ func Diff(domain *DomainSchema, file, oldFile *DatabaseSchema) []Change {
 	}
 
 	// Components in DB but not in file → removed.
-	for _, name := range dbCompNames {
-		if !fileCompSet[name] {
+	for name := range dbCompSet {
+		if _, ok := fileComps[name]; !ok && renames[name] == "" {
 			changes = append(changes, Change{
 				Kind:      ChangeRemovedComponent,
 				Component: name,
@@ -141,45 +249,26 @@ func Diff(domain *DomainSchema, file, oldFile *DatabaseSchema) []Change {
 		}
 	}
 
-	// Components present in both → structural comparison.
-	for _, name := range dbCompNames {
-		if !fileCompSet[name] {
-			continue
+	// Components present in both (directly or through a rename) →
+	// structural comparison under the file name.
+	for dbName, dbComp := range domain.Components {
+		name := strings.ToLower(dbName)
+		if newName, ok := renames[name]; ok {
+			name = newName
 		}
-		dbComp := domain.Components[name]
-
-		// Find the corresponding file component by lowercase name.
-		var fileComp Component
-		for fk, fc := range file.Components {
-			if strings.ToLower(fk) == name {
-				fileComp = fc
-				break
-			}
-		}
-
-		dbIsObject := dbComp.Type == "object"
-		fileIsObject := fileComp.Type == ComponentTypeObject
-
-		if dbIsObject != fileIsObject {
-			// Structural incompatibility → treat as remove + add.
-			changes = append(changes, Change{
-				Kind:      ChangeRemovedComponent,
-				Component: name,
-			})
-			changes = append(changes, Change{
-				Kind:      ChangeAddedComponent,
-				Component: name,
-			})
+		fileComp, ok := fileComps[name]
+		if !ok {
 			continue
 		}
-
-		if dbIsObject && fileIsObject {
-			diffObjectProperties(name, dbComp.Columns, fileComp.Properties, &changes)
-		} else {
-			diffScalarComponent(name, dbComp.Columns, fileComp, &changes)
-		}
+		diffComponent(name, dbComp, fileComp, &changes)
 	}
 
+	// ── Resource diff ────────────────────────────────────────────────
+	diffResources(domain.Resources, file.Resources, &changes)
+
+	// ── Relation diff ────────────────────────────────────────────────
+	diffRelations(domain.Relations, Relations(file), &changes)
+
 	// ── Entity type diff (names against DB) ──────────────────────────
 	fileETNames := make([]string, 0, len(file.EntityTypes))
 	for k := range file.EntityTypes {
@@ -188,7 +277,7 @@ func Diff(domain *DomainSchema, file, oldFile *DatabaseSchema) []Change {
 
 	for _, fk := range fileETNames {
 		if !domain.EntityTypeNames[fk] {
-			et := file.EntityTypes[fk]
+			et, _ := ResolveEntityType(file, fk)
 			changes = append(changes, Change{
 				Kind:   ChangeAddedEntityType,
 				ETName: fk,
@@ -207,12 +296,15 @@ func Diff(domain *DomainSchema, file, oldFile *DatabaseSchema) []Change {
 	}
 
 	// ── Entity type diff (specs, requires oldFile) ───────────────────
+	// Types are compared with their inherited fields merged, so editing a
+	// parent also reports every subtype it changes.
 	if oldFile != nil {
-		for name, oldET := range oldFile.EntityTypes {
-			newET, ok := file.EntityTypes[name]
+		for name := range oldFile.EntityTypes {
+			newET, ok := ResolveEntityType(file, name)
 			if !ok {
 				continue // already handled as removed above
 			}
+			oldET, _ := ResolveEntityType(oldFile, name)
 			if !entityTypeDeepEqual(oldET, newET) {
 				oldCopy := oldET
 				newCopy := newET
@@ -239,26 +331,204 @@ func Diff(domain *DomainSchema, file, oldFile *DatabaseSchema) []Change {
 	return changes
 }
 
+// diffComponent compares one component table against its file declaration.
+// compName is the file's lowercase name, which differs from the table's
+// current name when the component is being renamed.
+func diffComponent(compName string, dbComp DomainComponent, fileComp Component, changes *[]Change) {
+	// A tag table is an object table without property columns, so tags and
+	// objects convert into each other by adding or removing columns.
+	dbIsObject := dbComp.Type == "object" || dbComp.Type == ComponentTypeTag
+	fileIsObject := fileComp.Type == ComponentTypeObject || fileComp.Type == ComponentTypeTag
+
+	if dbIsObject != fileIsObject {
+		// Structural incompatibility → treat as remove + add.
+		*changes = append(*changes, Change{
+			Kind:      ChangeRemovedComponent,
+			Component: compName,
+		})
+		*changes = append(*changes, Change{
+			Kind:      ChangeAddedComponent,
+			Component: compName,
+		})
+		return
+	}
+
+	if dbIsObject && fileIsObject {
+		diffObjectProperties(compName, dbComp.Columns, fileComp.Properties, changes)
+	} else {
+		diffScalarComponent(compName, dbComp.Columns, fileComp, changes)
+	}
+	diffIndexes(compName, dbComp.Indexes, ComponentIndexes(compName, fileComp), changes)
+}
+
+// diffResources compares the res_* tables against the file's resources.
+// A resource present on both sides has its properties compared like an
+// object component's; those changes carry Resource instead of Component.
+func diffResources(dbRes map[string]DomainResource, fileRes map[string]Resource, changes *[]Change) {
+	db := make(map[string]DomainResource, len(dbRes))
+	for k, r := range dbRes {
+		db[strings.ToLower(k)] = r
+	}
+	file := make(map[string]Resource, len(fileRes))
+	for k, r := range fileRes {
+		file[strings.ToLower(k)] = r
+	}
+
+	for name := range file {
+		if _, ok := db[name]; !ok {
+			*changes = append(*changes, Change{Kind: ChangeAddedResource, Resource: name})
+		}
+	}
+	for name, dbR := range db {
+		fileR, ok := file[name]
+		if !ok {
+			*changes = append(*changes, Change{Kind: ChangeRemovedResource, Resource: name})
+			continue
+		}
+		var propChanges []Change
+		diffObjectProperties(name, dbR.Columns, fileR.Properties, &propChanges)
+		for _, c := range propChanges {
+			c.Resource, c.Component = c.Component, ""
+			*changes = append(*changes, c)
+		}
+	}
+}
+
+// diffRelations compares the rel_* tables against the file's relation
+// components. A relation present on both sides has its properties compared
+// like an object component's; the source_id and target_id key columns are
+// skipped as primary-key columns. Those changes carry Relation instead of
+// Component.
+func diffRelations(dbRel map[string]DomainRelation, fileRel map[string]Component, changes *[]Change) {
+	db := make(map[string]DomainRelation, len(dbRel))
+	for k, r := range dbRel {
+		db[strings.ToLower(k)] = r
+	}
+	file := make(map[string]Component, len(fileRel))
+	for k, r := range fileRel {
+		file[strings.ToLower(k)] = r
+	}
+
+	for name := range file {
+		if _, ok := db[name]; !ok {
+			*changes = append(*changes, Change{Kind: ChangeAddedRelation, Relation: name})
+		}
+	}
+	for name, dbR := range db {
+		fileR, ok := file[name]
+		if !ok {
+			*changes = append(*changes, Change{Kind: ChangeRemovedRelation, Relation: name})
+			continue
+		}
+		var propChanges []Change
+		diffObjectProperties(name, dbR.Columns, fileR.Properties, &propChanges)
+		for _, c := range propChanges {
+			c.Relation, c.Component = c.Component, ""
+			*changes = append(*changes, c)
+		}
+	}
+}
+
+// diffIndexes compares the indexes on a component table against the
+// file's declarations by name. An index whose columns or uniqueness
+// changed is reported as removed and added again.
+func diffIndexes(compName string, dbIdx, fileIdx []DomainIndex, changes *[]Change) {
+	db := make(map[string]DomainIndex, len(dbIdx))
+	for _, idx := range dbIdx {
+		db[strings.ToLower(idx.Name)] = idx
+	}
+	file := make(map[string]bool, len(fileIdx))
+	for _, idx := range fileIdx {
+		file[idx.Name] = true
+		old, ok := db[idx.Name]
+		if ok && sameIndex(old, idx) {
+			continue
+		}
+		if ok {
+			*changes = append(*changes, Change{Kind: ChangeRemovedIndex, Component: compName, Index: idx.Name})
+		}
+		*changes = append(*changes, Change{Kind: ChangeAddedIndex, Component: compName, Index: idx.Name})
+	}
+	for name := range db {
+		if !file[name] {
+			*changes = append(*changes, Change{Kind: ChangeRemovedIndex, Component: compName, Index: name})
+		}
+	}
+}
+
+// matchRenames pairs renamedFrom hints with names that exist on the DB side.
+// hints maps each lowercase file name to its renamedFrom hint. A hint is
+// honoured only when the file name is not in the DB yet, the old name is in
+// the DB, and the old name is not itself still declared in the file. When
+// several declarations claim the same old name, the alphabetically first
+// wins. Returns old name → new name, both lowercase.
+func matchRenames(dbNames map[string]bool, hints map[string]string) map[string]string {
+	names := make([]string, 0, len(hints))
+	for name := range hints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	renames := make(map[string]string)
+	for _, name := range names {
+		oldName := strings.ToLower(hints[name])
+		if oldName == "" || dbNames[name] || !dbNames[oldName] {
+			continue
+		}
+		if _, declared := hints[oldName]; declared {
+			continue
+		}
+		if _, claimed := renames[oldName]; claimed {
+			continue
+		}
+		renames[oldName] = name
+	}
+	return renames
+}
+
 // diffObjectProperties compares columns of an object component table against
 // the file's property declarations.
 func diffObjectProperties(compName string, dbCols []DomainColumn, fileProps map[string]Property, changes *[]Change) {
 	// Build sets of column names (lowercase, excluding entity_id).
 	dbColNames := make(map[string]string) // name → SQLType
+	dbNullable := make(map[string]bool)
+	dbColSet := make(map[string]bool)
+	dbCol := make(map[string]DomainColumn)
 	for _, c := range dbCols {
 		if c.IsPK {
 			continue // skip entity_id
 		}
 		dbColNames[strings.ToLower(c.Name)] = strings.ToUpper(c.SQLType)
+		dbNullable[strings.ToLower(c.Name)] = c.Nullable
+		dbColSet[strings.ToLower(c.Name)] = true
+		dbCol[strings.ToLower(c.Name)] = c
 	}
 
 	filePropNames := make(map[string]string) // name → SQLType
+	fileProp := make(map[string]Property)
+	fileHints := make(map[string]string)
 	for name, prop := range fileProps {
 		filePropNames[strings.ToLower(name)] = PropertySQLType(prop)
+		fileProp[strings.ToLower(name)] = prop
+		fileHints[strings.ToLower(name)] = prop.RenamedFrom
+	}
+
+	// Renamed columns: old (DB) name → new (file) name.
+	renames := matchRenames(dbColSet, fileHints)
+	renamedTo := make(map[string]bool, len(renames))
+	for oldName, newName := range renames {
+		renamedTo[newName] = true
+		*changes = append(*changes, Change{
+			Kind:      ChangeRenamedProperty,
+			Component: compName,
+			Property:  newName,
+			OldName:   oldName,
+		})
 	}
 
 	// Properties in file but not in DB → added.
 	for fp := range filePropNames {
-		if _, ok := dbColNames[fp]; !ok {
+		if _, ok := dbColNames[fp]; !ok && !renamedTo[fp] {
 			*changes = append(*changes, Change{
 				Kind:      ChangeAddedProperty,
 				Component: compName,
@@ -270,7 +540,7 @@ func diffObjectProperties(compName string, dbCols []DomainColumn, fileProps map[
 
 	// Properties in DB but not in file → removed.
 	for dp := range dbColNames {
-		if _, ok := filePropNames[dp]; !ok {
+		if _, ok := filePropNames[dp]; !ok && renames[dp] == "" {
 			*changes = append(*changes, Change{
 				Kind:      ChangeRemovedProperty,
 				Component: compName,
@@ -280,9 +550,20 @@ func diffObjectProperties(compName string, dbCols []DomainColumn, fileProps map[
 		}
 	}
 
-	// Properties in both: compare SQL types.
-	for dp, dbType := range dbColNames {
-		if ft, ok := filePropNames[dp]; ok && ft != dbType {
+	// Properties in both (directly or through a rename): compare SQL types,
+	// then computed expressions, then CHECK constraints, then nullability,
+	// then the foreign key of entity-ref columns. Each of these rebuilds the table, so the first
+	// difference subsumes the rest. Changes are reported under the new name.
+	for col, dbType := range dbColNames {
+		dp := col
+		if newName, ok := renames[col]; ok {
+			dp = newName
+		}
+		ft, ok := filePropNames[dp]
+		if !ok {
+			continue
+		}
+		if ft != dbType {
 			*changes = append(*changes, Change{
 				Kind:      ChangedPropertyType,
 				Component: compName,
@@ -290,7 +571,54 @@ func diffObjectProperties(compName string, dbCols []DomainColumn, fileProps map[
 				OldType:   dbType,
 				NewType:   ft,
 			})
+			continue
+		}
+		prop := fileProp[dp]
+		if computedDiffers(dbCol[col], prop) {
+			*changes = append(*changes, Change{
+				Kind:      ChangedPropertyComputed,
+				Component: compName,
+				Property:  dp,
+				OldType:   dbType,
+				NewType:   ft,
+			})
+			continue
 		}
+		if checkDiffers(dbCol[col], col, prop) {
+			*changes = append(*changes, Change{
+				Kind:      ChangedPropertyConstraint,
+				Component: compName,
+				Property:  dp,
+				OldType:   dbType,
+				NewType:   ft,
+			})
+			continue
+		}
+		fileNullable := !PropertyNotNull(prop)
+		// entity-ref columns added by ALTER TABLE ADD COLUMN are necessarily
+		// nullable (SQLite cannot backfill a NOT NULL reference), so a
+		// nullable DB column is up to date for a NOT NULL entity-ref property.
+		if fileNullable == dbNullable[col] || (!fileNullable && prop.Type == PropertyTypeEntityRef) {
+			if prop.Type == PropertyTypeEntityRef && referenceDiffers(dbCol[col], prop.OnDelete) {
+				*changes = append(*changes, Change{
+					Kind:      ChangedPropertyReference,
+					Component: compName,
+					Property:  dp,
+					OldType:   dbType,
+					NewType:   ft,
+				})
+			}
+			continue
+		}
+		*changes = append(*changes, Change{
+			Kind:        ChangedPropertyNullability,
+			Component:   compName,
+			Property:    dp,
+			OldType:     dbType,
+			NewType:     ft,
+			OldNullable: dbNullable[col],
+			NewNullable: fileNullable,
+		})
 	}
 }
 
@@ -311,11 +639,45 @@ func diffScalarComponent(compName string, dbCols []DomainColumn, fileComp Compon
 				OldType:   strings.ToUpper(c.SQLType),
 				NewType:   fileSQLType,
 			})
+		} else if fileComp.Type == ComponentTypeEntityRef && referenceDiffers(c, fileComp.OnDelete) {
+			*changes = append(*changes, Change{
+				Kind:      ChangedPropertyReference,
+				Component: compName,
+				Property:  "value",
+				OldType:   fileSQLType,
+				NewType:   fileSQLType,
+			})
 		}
 		return
 	}
 }
 
+// referenceDiffers reports whether an entity-ref column's foreign key is
+// missing or has a different delete policy than onDelete.
+func referenceDiffers(col DomainColumn, onDelete string) bool {
+	return !col.References || col.OnDelete != onDelete
+}
+
+// computedDiffers reports whether a column's generated expression or
+// storage differs from the computed declaration of p. Plain columns and
+// plain properties both have an empty expression.
+func computedDiffers(col DomainColumn, p Property) bool {
+	expr := strings.TrimSpace(p.Computed)
+	return col.Computed != expr || (expr != "" && col.Stored != p.Stored)
+}
+
+// checkDiffers reports whether a column's CHECK differs from the one
+// declared for p. The expected condition is rendered with the column's
+// current name, so a rename in the same batch does not count as a change.
+// Computed columns carry no CHECK.
+func checkDiffers(col DomainColumn, colName string, p Property) bool {
+	expr := ""
+	if !p.IsComputed() {
+		expr = p.CheckExpr(colName)
+	}
+	return col.Check != expr
+}
+
 // propertySQLTypeForComponent returns the SQL type for a scalar component type.
 // Only valid for non-object component types.
 func propertySQLTypeForComponent(compType string) string {
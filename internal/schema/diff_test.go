@@ -365,6 +365,171 @@ func TestDiff_SameSQLType_NoChange(t *testing.T) {
 	}
 }
 
+func TestDiff_ChangedPropertyNullability(t *testing.T) {
+	domain := &DomainSchema{
+		Components: map[string]DomainComponent{
+			"target": {
+				Type: "object",
+				Columns: []DomainColumn{
+					{Name: "entity_id", SQLType: "INTEGER", IsPK: true},
+					{Name: "target_x", SQLType: "REAL"},
+					{Name: "target_y", SQLType: "REAL", Nullable: true},
+				},
+			},
+		},
+		EntityTypeNames: make(map[string]bool),
+	}
+	file := &DatabaseSchema{
+		Components: map[string]Component{
+			"Target": {Type: ComponentTypeObject, Properties: map[string]Property{
+				"target_x": {Type: PropertyTypeNumber, Nullable: true},
+				"target_y": {Type: PropertyTypeNumber},
+			}},
+		},
+		EntityTypes: map[string]EntityType{},
+	}
+
+	changes := Diff(domain, file, nil)
+	assertChanges(t, changes, []Change{
+		{Kind: ChangedPropertyNullability, Component: "target", Property: "target_x"},
+		{Kind: ChangedPropertyNullability, Component: "target", Property: "target_y"},
+	})
+	if changes[0].OldNullable || !changes[0].NewNullable {
+		t.Errorf("target_x nullability = %v → %v, want false → true", changes[0].OldNullable, changes[0].NewNullable)
+	}
+	if !changes[1].OldNullable || changes[1].NewNullable {
+		t.Errorf("target_y nullability = %v → %v, want true → false", changes[1].OldNullable, changes[1].NewNullable)
+	}
+}
+
+func TestDiff_EntityRefAddedByAlter_NoNullabilityChange(t *testing.T) {
+	// entity-ref columns added by ALTER TABLE ADD COLUMN are nullable even
+	// when the property is not; that must not trigger a rebuild.
+	domain := &DomainSchema{
+		Components: map[string]DomainComponent{
+			"follow": {
+				Type: "object",
+				Columns: []DomainColumn{
+					{Name: "entity_id", SQLType: "INTEGER", IsPK: true},
+					{Name: "leader", SQLType: "INTEGER", Nullable: true, References: true},
+				},
+			},
+		},
+		EntityTypeNames: make(map[string]bool),
+	}
+	file := &DatabaseSchema{
+		Components: map[string]Component{
+			"Follow": {Type: ComponentTypeObject, Properties: map[string]Property{
+				"leader": {Type: PropertyTypeEntityRef},
+			}},
+		},
+		EntityTypes: map[string]EntityType{},
+	}
+
+	if changes := Diff(domain, file, nil); len(changes) != 0 {
+		t.Fatalf("expected no changes, got %+v", changes)
+	}
+}
+
+// ── Renames ─────────────────────────────────────────────────────────
+
+func TestDiff_RenamedComponent(t *testing.T) {
+	domain := &DomainSchema{
+		Components: map[string]DomainComponent{
+			"health": {
+				Type: "object",
+				Columns: []DomainColumn{
+					{Name: "entity_id", SQLType: "INTEGER", IsPK: true},
+					{Name: "hp", SQLType: "INTEGER"},
+				},
+			},
+		},
+		EntityTypeNames: make(map[string]bool),
+	}
+	file := &DatabaseSchema{
+		Components: map[string]Component{
+			"Vitality": {Type: ComponentTypeObject, RenamedFrom: "Health", Properties: map[string]Property{
+				"hp":  {Type: PropertyTypeInteger},
+				"max": {Type: PropertyTypeInteger},
+			}},
+		},
+		EntityTypes: map[string]EntityType{},
+	}
+
+	changes := Diff(domain, file, nil)
+	assertChanges(t, changes, []Change{
+		{Kind: ChangeRenamedComponent, Component: "vitality"},
+		{Kind: ChangeAddedProperty, Component: "vitality", Property: "max"},
+	})
+	if changes[0].OldName != "health" {
+		t.Errorf("OldName = %q, want health", changes[0].OldName)
+	}
+}
+
+func TestDiff_RenamedProperty(t *testing.T) {
+	domain := &DomainSchema{
+		Components: map[string]DomainComponent{
+			"health": {
+				Type: "object",
+				Columns: []DomainColumn{
+					{Name: "entity_id", SQLType: "INTEGER", IsPK: true},
+					{Name: "hp", SQLType: "INTEGER"},
+				},
+			},
+		},
+		EntityTypeNames: make(map[string]bool),
+	}
+	file := &DatabaseSchema{
+		Components: map[string]Component{
+			"Health": {Type: ComponentTypeObject, Properties: map[string]Property{
+				"current": {Type: PropertyTypeNumber, RenamedFrom: "hp"},
+			}},
+		},
+		EntityTypes: map[string]EntityType{},
+	}
+
+	changes := Diff(domain, file, nil)
+	assertChanges(t, changes, []Change{
+		{Kind: ChangeRenamedProperty, Component: "health", Property: "current"},
+		{Kind: ChangedPropertyType, Component: "health", Property: "current"},
+	})
+	if changes[0].OldName != "hp" {
+		t.Errorf("OldName = %q, want hp", changes[0].OldName)
+	}
+	if changes[1].OldType != "INTEGER" || changes[1].NewType != "REAL" {
+		t.Errorf("type change = %s → %s, want INTEGER → REAL", changes[1].OldType, changes[1].NewType)
+	}
+}
+
+func TestDiff_RenameHintAlreadyApplied_NoChange(t *testing.T) {
+	// Once the rename has run, the hint stays in schema.json but names
+	// nothing in the DB and must be ignored.
+	domain := &DomainSchema{
+		Components: map[string]DomainComponent{
+			"vitality": {
+				Type: "object",
+				Columns: []DomainColumn{
+					{Name: "entity_id", SQLType: "INTEGER", IsPK: true},
+					{Name: "current", SQLType: "INTEGER"},
+				},
+			},
+		},
+		EntityTypeNames: make(map[string]bool),
+	}
+	file := &DatabaseSchema{
+		Components: map[string]Component{
+			"Vitality": {Type: ComponentTypeObject, RenamedFrom: "Health", Properties: map[string]Property{
+				"current": {Type: PropertyTypeInteger, RenamedFrom: "hp"},
+			}},
+		},
+		EntityTypes: map[string]EntityType{},
+	}
+
+	if changes := Diff(domain, file, nil); len(changes) != 0 {
+		t.Fatalf("expected no changes, got %+v", changes)
+	}
+}
+
 // ── Structural incompatibility ──────────────────────────────────────
 
 func TestDiff_ObjectToScalar_RemoveAdd(t *testing.T) {
@@ -420,6 +585,68 @@ func TestDiff_ScalarToObject_RemoveAdd(t *testing.T) {
 	})
 }
 
+func TestDiff_Tag_NoChange(t *testing.T) {
+	domain := &DomainSchema{
+		Components: map[string]DomainComponent{
+			"frozen": {
+				Type:    "tag",
+				Columns: []DomainColumn{{Name: "entity_id", SQLType: "INTEGER", IsPK: true}},
+			},
+		},
+		EntityTypeNames: make(map[string]bool),
+	}
+	file := &DatabaseSchema{
+		Components:  map[string]Component{"Frozen": {Type: ComponentTypeTag}},
+		EntityTypes: map[string]EntityType{},
+	}
+
+	assertChanges(t, Diff(domain, file, nil), nil)
+}
+
+func TestDiff_TagToObject_AddsProperties(t *testing.T) {
+	domain := &DomainSchema{
+		Components: map[string]DomainComponent{
+			"frozen": {
+				Type:    "tag",
+				Columns: []DomainColumn{{Name: "entity_id", SQLType: "INTEGER", IsPK: true}},
+			},
+		},
+		EntityTypeNames: make(map[string]bool),
+	}
+	file := &DatabaseSchema{
+		Components: map[string]Component{
+			"Frozen": {Type: ComponentTypeObject, Properties: map[string]Property{"turns": {Type: PropertyTypeInteger}}},
+		},
+		EntityTypes: map[string]EntityType{},
+	}
+
+	// Tags and objects share a table layout, so the rows are kept.
+	assertChanges(t, Diff(domain, file, nil), []Change{
+		{Kind: ChangeAddedProperty, Component: "frozen", Property: "turns"},
+	})
+}
+
+func TestDiff_TagToScalar_RemoveAdd(t *testing.T) {
+	domain := &DomainSchema{
+		Components: map[string]DomainComponent{
+			"frozen": {
+				Type:    "tag",
+				Columns: []DomainColumn{{Name: "entity_id", SQLType: "INTEGER", IsPK: true}},
+			},
+		},
+		EntityTypeNames: make(map[string]bool),
+	}
+	file := &DatabaseSchema{
+		Components:  map[string]Component{"Frozen": {Type: ComponentTypeBoolean}},
+		EntityTypes: map[string]EntityType{},
+	}
+
+	assertChanges(t, Diff(domain, file, nil), []Change{
+		{Kind: ChangeAddedComponent, Component: "frozen"},
+		{Kind: ChangeRemovedComponent, Component: "frozen"},
+	})
+}
+
 // ── Ordering ────────────────────────────────────────────────────────
 
 func TestDiff_Ordering_MixedChanges(t *testing.T) {
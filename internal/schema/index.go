@@ -0,0 +1,139 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Index declares a SQLite index on a component table. Columns name the
+// component's properties in index order; a non-object component has a
+// single indexable column, named "value". Name is optional and defaults to
+// idx_comp_<component>_<columns joined by "_">.
+type Index struct {
+	Name    string   `json:"name,omitempty"`
+	Columns []string `json:"columns"`
+	Unique  bool     `json:"unique,omitempty"`
+}
+
+// DomainIndex is an index as it exists (or will exist) in the database:
+// its resolved name and the lowercase SQL columns it covers, in order.
+type DomainIndex struct {
+	Name    string
+	Columns []string
+	Unique  bool
+}
+
+// ComponentIndexes resolves the indexes declared on comp to their table
+// columns and final names, sorted by name. compName is the component's
+// name in the schema; invalid declarations are expected to have been
+// rejected by ValidateSchema.
+func ComponentIndexes(compName string, comp Component) []DomainIndex {
+	out := make([]DomainIndex, 0, len(comp.Indexes))
+	for _, idx := range comp.Indexes {
+		cols := make([]string, len(idx.Columns))
+		for i, c := range idx.Columns {
+			cols[i] = indexColumnName(comp, c)
+		}
+		name := strings.ToLower(idx.Name)
+		if name == "" {
+			name = "idx_comp_" + strings.ToLower(compName) + "_" + strings.Join(cols, "_")
+		}
+		out = append(out, DomainIndex{Name: name, Columns: cols, Unique: idx.Unique})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// indexColumnName maps a declared index column to the component table
+// column: the lowercase property name for objects, the value column
+// otherwise.
+func indexColumnName(comp Component, col string) string {
+	if comp.Type == ComponentTypeEntityRef && strings.EqualFold(col, "value") {
+		return "target_entity_id"
+	}
+	return strings.ToLower(col)
+}
+
+// sameIndex reports whether two resolved indexes have the same definition.
+func sameIndex(a, b DomainIndex) bool {
+	if a.Unique != b.Unique || len(a.Columns) != len(b.Columns) {
+		return false
+	}
+	for i := range a.Columns {
+		if !strings.EqualFold(a.Columns[i], b.Columns[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// validateIndexes checks every component's index declarations: each index
+// names at least one existing column, no column twice, and index names are
+// unique across the schema (SQLite index names share one namespace).
+func validateIndexes(s DatabaseSchema) error {
+	seen := make(map[string]string) // index name → component
+	for _, name := range sortedComponentNames(s) {
+		comp := s.Components[name]
+		for i, idx := range comp.Indexes {
+			if len(idx.Columns) == 0 {
+				return fmt.Errorf("component %q index %d: columns must name at least one property", name, i)
+			}
+			if idx.Name != "" && !isSQLIdentifier(idx.Name) {
+				return fmt.Errorf("component %q index %d: name %q must contain only letters, digits and underscores",
+					name, i, idx.Name)
+			}
+			used := make(map[string]bool, len(idx.Columns))
+			for _, col := range idx.Columns {
+				if !componentHasColumn(comp, col) {
+					switch comp.Type {
+					case ComponentTypeObject:
+						return fmt.Errorf("component %q index %d: unknown property %q", name, i, col)
+					case ComponentTypeTag:
+						return fmt.Errorf("component %q index %d: %q components have no columns to index",
+							name, i, ComponentTypeTag)
+					}
+					return fmt.Errorf("component %q index %d: %q components can only index %q, got %q",
+						name, i, comp.Type, "value", col)
+				}
+				if used[strings.ToLower(col)] {
+					return fmt.Errorf("component %q index %d: column %q listed twice", name, i, col)
+				}
+				used[strings.ToLower(col)] = true
+			}
+		}
+		for _, idx := range ComponentIndexes(name, comp) {
+			if prev, ok := seen[idx.Name]; ok {
+				return fmt.Errorf("index %q is declared by both %q and %q", idx.Name, prev, name)
+			}
+			seen[idx.Name] = name
+		}
+	}
+	return nil
+}
+
+// componentHasColumn reports whether col names an indexable column of comp.
+// Tags have no column besides entity_id, which is already the primary key.
+func componentHasColumn(comp Component, col string) bool {
+	if comp.Type == ComponentTypeTag {
+		return false
+	}
+	if comp.Type != ComponentTypeObject {
+		return strings.EqualFold(col, "value")
+	}
+	_, ok := PropertyByName(comp.Properties, col)
+	return ok
+}
+
+// isSQLIdentifier reports whether s is a bare SQL identifier.
+func isSQLIdentifier(s string) bool {
+	for i, r := range s {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,146 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+func indexedSprite(indexes ...Index) DatabaseSchema {
+	return DatabaseSchema{
+		SchemaVersion: 1,
+		Components: map[string]Component{
+			"Sprite": {
+				Type: ComponentTypeObject,
+				Properties: map[string]Property{
+					"imageId": {Type: PropertyTypeString},
+					"layer":   {Type: PropertyTypeInteger},
+				},
+				Indexes: indexes,
+			},
+			"Owner": {Type: ComponentTypeEntityRef},
+		},
+		EntityTypes: map[string]EntityType{
+			"Goblin": {ValidationLevel: ValidationStrict},
+		},
+	}
+}
+
+func TestComponentIndexes_ResolvesNamesAndColumns(t *testing.T) {
+	s := indexedSprite(
+		Index{Columns: []string{"imageId"}},
+		Index{Name: "sprite_layer_image", Columns: []string{"layer", "imageId"}, Unique: true},
+	)
+	got := ComponentIndexes("Sprite", s.Components["Sprite"])
+	if len(got) != 2 {
+		t.Fatalf("got %d indexes, want 2", len(got))
+	}
+	if got[0].Name != "idx_comp_sprite_imageid" || got[0].Columns[0] != "imageid" || got[0].Unique {
+		t.Errorf("index 0 = %+v", got[0])
+	}
+	if got[1].Name != "sprite_layer_image" || strings.Join(got[1].Columns, ",") != "layer,imageid" || !got[1].Unique {
+		t.Errorf("index 1 = %+v", got[1])
+	}
+
+	owner := ComponentIndexes("Owner", Component{Type: ComponentTypeEntityRef, Indexes: []Index{{Columns: []string{"value"}}}})
+	if owner[0].Name != "idx_comp_owner_target_entity_id" || owner[0].Columns[0] != "target_entity_id" {
+		t.Errorf("entity-ref index = %+v", owner[0])
+	}
+}
+
+func TestValidateSchema_Indexes(t *testing.T) {
+	tests := []struct {
+		name    string
+		indexes []Index
+		wantErr string
+	}{
+		{"valid", []Index{{Columns: []string{"imageId", "layer"}, Unique: true}}, ""},
+		{"no columns", []Index{{}}, "at least one property"},
+		{"unknown property", []Index{{Columns: []string{"frame"}}}, `unknown property "frame"`},
+		{"duplicate column", []Index{{Columns: []string{"layer", "LAYER"}}}, "listed twice"},
+		{"bad name", []Index{{Name: "drop table", Columns: []string{"layer"}}}, "letters, digits and underscores"},
+		{"duplicate name", []Index{{Columns: []string{"layer"}}, {Columns: []string{"layer"}, Unique: true}}, "declared by both"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSchema(indexedSprite(tt.indexes...))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	s := indexedSprite()
+	s.Components["Owner"] = Component{Type: ComponentTypeEntityRef, Indexes: []Index{{Columns: []string{"target"}}}}
+	if err := ValidateSchema(s); err == nil || !strings.Contains(err.Error(), `can only index "value"`) {
+		t.Errorf("scalar index err = %v", err)
+	}
+}
+
+func TestLoadSchema_Indexes(t *testing.T) {
+	s, err := LoadSchema([]byte(`{
+		"schemaVersion": 1,
+		"components": {
+			"Health": {
+				"type": "object",
+				"properties": {"hp": {"type": "integer"}},
+				"indexes": [{"columns": ["hp"]}, {"name": "health_hp_unique", "columns": ["hp"], "unique": true}]
+			}
+		},
+		"entityTypes": {"Goblin": {"requiredComponents": ["Health"]}}
+	}`))
+	if err != nil {
+		t.Fatalf("LoadSchema: %v", err)
+	}
+	idx := s.Components["Health"].Indexes
+	if len(idx) != 2 || idx[1].Name != "health_hp_unique" || !idx[1].Unique {
+		t.Errorf("Indexes = %+v", idx)
+	}
+}
+
+func TestDiff_Indexes(t *testing.T) {
+	domain := &DomainSchema{
+		Components: map[string]DomainComponent{
+			"sprite": {
+				Type: "object",
+				Columns: []DomainColumn{
+					{Name: "entity_id", SQLType: "INTEGER", IsPK: true},
+					{Name: "imageid", SQLType: "TEXT"},
+					{Name: "layer", SQLType: "INTEGER"},
+				},
+				Indexes: []DomainIndex{
+					{Name: "idx_comp_sprite_imageid", Columns: []string{"imageid"}},
+					{Name: "idx_comp_sprite_layer", Columns: []string{"layer"}},
+					{Name: "stale", Columns: []string{"layer"}},
+				},
+			},
+		},
+		EntityTypeNames: map[string]bool{"Goblin": true},
+	}
+	file := indexedSprite(
+		Index{Columns: []string{"imageId"}},             // unchanged
+		Index{Columns: []string{"layer"}, Unique: true}, // now unique
+		Index{Columns: []string{"imageId", "layer"}},    // new
+	)
+	delete(file.Components, "Owner")
+
+	changes := Diff(domain, &file, nil)
+	var got []string
+	for _, c := range changes {
+		got = append(got, string(c.Kind)+":"+c.Index)
+	}
+	want := []string{
+		"removed_index:idx_comp_sprite_layer",
+		"removed_index:stale",
+		"added_index:idx_comp_sprite_imageid_layer",
+		"added_index:idx_comp_sprite_layer",
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("changes = %v\nwant      %v", got, want)
+	}
+}
@@ -0,0 +1,163 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ResolveEntityType returns the named entity type with everything it
+// inherits through extends merged in:
+//
+//   - requiredComponents and optionalComponents are the union of the
+//     type's own lists and its ancestors', parents first. A component a
+//     parent makes optional and the subtype requires is required.
+//   - behavior and validationLevel are inherited unless the subtype sets
+//     them.
+//   - allowExtraComponents is true if the type or any ancestor allows
+//     extra components; a subtype cannot narrow what its parent permits.
+//
+// The returned type's Extends is unchanged. ok is false when name is not
+// declared. On a schema that failed validateInheritance the chain is
+// followed until it leaves the schema or loops back on itself.
+func ResolveEntityType(s *DatabaseSchema, name string) (EntityType, bool) {
+	et, ok := s.EntityTypes[name]
+	if !ok {
+		return EntityType{}, false
+	}
+	return resolveEntityType(s, et, map[string]bool{name: true}), true
+}
+
+func resolveEntityType(s *DatabaseSchema, et EntityType, visited map[string]bool) EntityType {
+	if et.Extends == "" || visited[et.Extends] {
+		return et
+	}
+	parentDecl, ok := s.EntityTypes[et.Extends]
+	if !ok {
+		return et
+	}
+	visited[et.Extends] = true
+	parent := resolveEntityType(s, parentDecl, visited)
+
+	out := et
+	out.RequiredComponents = mergeNames(parent.RequiredComponents, et.RequiredComponents)
+	var inheritedOptional []string
+	for _, c := range parent.OptionalComponents {
+		if !containsName(et.RequiredComponents, c) {
+			inheritedOptional = append(inheritedOptional, c)
+		}
+	}
+	out.OptionalComponents = mergeNames(inheritedOptional, et.OptionalComponents)
+	if out.Behavior == "" {
+		out.Behavior = parent.Behavior
+	}
+	if out.ValidationLevel == "" {
+		out.ValidationLevel = parent.ValidationLevel
+	}
+	out.AllowExtraComponents = et.AllowExtraComponents || parent.AllowExtraComponents
+	return out
+}
+
+// IsA reports whether entityType is ancestor or extends it, directly or
+// through a chain of parents. Unknown types are only equal to themselves.
+func IsA(s *DatabaseSchema, entityType, ancestor string) bool {
+	visited := make(map[string]bool)
+	for name := entityType; name != "" && !visited[name]; name = s.EntityTypes[name].Extends {
+		if name == ancestor {
+			return true
+		}
+		visited[name] = true
+	}
+	return false
+}
+
+// Subtypes returns ancestor and every declared entity type that IsA
+// ancestor, sorted by name. It is the set of entity_type values that
+// "any ancestor" queries match.
+func Subtypes(s *DatabaseSchema, ancestor string) []string {
+	out := []string{ancestor}
+	for name := range s.EntityTypes {
+		if name != ancestor && IsA(s, name, ancestor) {
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// validateInheritance checks that every extends names a declared entity
+// type, that no chain of extends loops back on itself, and that no subtype
+// lists one of its parent's required components as optional.
+func validateInheritance(s DatabaseSchema) error {
+	names := make([]string, 0, len(s.EntityTypes))
+	for name := range s.EntityTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		et := s.EntityTypes[name]
+		if et.Extends == "" {
+			continue
+		}
+		if _, ok := s.EntityTypes[et.Extends]; !ok {
+			return fmt.Errorf("entityType %q extends undeclared entity type %q", name, et.Extends)
+		}
+		chain := []string{name}
+		seen := map[string]bool{name: true}
+		for parent := et.Extends; parent != ""; parent = s.EntityTypes[parent].Extends {
+			chain = append(chain, parent)
+			if seen[parent] {
+				return fmt.Errorf("entityType %q: extends cycle %s", name, formatChain(chain))
+			}
+			seen[parent] = true
+		}
+	}
+
+	for _, name := range names {
+		et := s.EntityTypes[name]
+		if et.Extends == "" {
+			continue
+		}
+		parent, _ := ResolveEntityType(&s, et.Extends)
+		for _, opt := range et.OptionalComponents {
+			if containsName(parent.RequiredComponents, opt) {
+				return fmt.Errorf("entityType %q: component %q is required by %q and cannot be made optional",
+					name, opt, et.Extends)
+			}
+		}
+	}
+	return nil
+}
+
+// formatChain renders an extends chain as "A → B → A".
+func formatChain(chain []string) string {
+	out := ""
+	for i, name := range chain {
+		if i > 0 {
+			out += " → "
+		}
+		out += fmt.Sprintf("%q", name)
+	}
+	return out
+}
+
+// mergeNames returns a followed by the names of b not already in a.
+func mergeNames(a, b []string) []string {
+	out := make([]string, 0, len(a)+len(b))
+	out = append(out, a...)
+	for _, name := range b {
+		if !containsName(out, name) {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
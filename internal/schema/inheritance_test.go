@@ -0,0 +1,170 @@
+package schema
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func goblinFamily() DatabaseSchema {
+	return DatabaseSchema{
+		SchemaVersion: 1,
+		Components: map[string]Component{
+			"Position": {Type: ComponentTypeObject, Properties: map[string]Property{"x": {Type: PropertyTypeNumber}}},
+			"Health":   {Type: ComponentTypeInteger},
+			"Bow":      {Type: ComponentTypeTag},
+			"Mana":     {Type: ComponentTypeInteger},
+			"Loot":     {Type: ComponentTypeString},
+		},
+		EntityTypes: map[string]EntityType{
+			"Goblin": {
+				Behavior:           "wandering_goblin",
+				RequiredComponents: []string{"Position", "Health"},
+				OptionalComponents: []string{"Loot", "Mana"},
+				ValidationLevel:    ValidationWarning,
+			},
+			"GoblinArcher": {Extends: "Goblin", RequiredComponents: []string{"Bow"}},
+			"GoblinShaman": {
+				Extends:              "Goblin",
+				Behavior:             "shaman",
+				RequiredComponents:   []string{"Mana"},
+				AllowExtraComponents: true,
+				ValidationLevel:      ValidationStrict,
+			},
+			"GoblinArcherCaptain": {Extends: "GoblinArcher"},
+		},
+	}
+}
+
+func TestResolveEntityType(t *testing.T) {
+	s := goblinFamily()
+
+	archer, ok := ResolveEntityType(&s, "GoblinArcher")
+	if !ok {
+		t.Fatal("GoblinArcher not found")
+	}
+	want := EntityType{
+		Extends:            "Goblin",
+		Behavior:           "wandering_goblin",
+		RequiredComponents: []string{"Position", "Health", "Bow"},
+		OptionalComponents: []string{"Loot", "Mana"},
+		ValidationLevel:    ValidationWarning,
+	}
+	if !reflect.DeepEqual(archer, want) {
+		t.Errorf("GoblinArcher = %+v, want %+v", archer, want)
+	}
+
+	shaman, _ := ResolveEntityType(&s, "GoblinShaman")
+	if got := shaman.RequiredComponents; !reflect.DeepEqual(got, []string{"Position", "Health", "Mana"}) {
+		t.Errorf("shaman required = %v", got)
+	}
+	if got := shaman.OptionalComponents; !reflect.DeepEqual(got, []string{"Loot"}) {
+		t.Errorf("shaman optional = %v, want Mana promoted to required", got)
+	}
+	if shaman.Behavior != "shaman" || shaman.ValidationLevel != ValidationStrict || !shaman.AllowExtraComponents {
+		t.Errorf("shaman overrides not kept: %+v", shaman)
+	}
+
+	captain, _ := ResolveEntityType(&s, "GoblinArcherCaptain")
+	if got := captain.RequiredComponents; !reflect.DeepEqual(got, []string{"Position", "Health", "Bow"}) {
+		t.Errorf("captain required = %v, want inherited through two levels", got)
+	}
+
+	if _, ok := ResolveEntityType(&s, "Dragon"); ok {
+		t.Error("ResolveEntityType(Dragon) ok = true, want false")
+	}
+}
+
+func TestIsA(t *testing.T) {
+	s := goblinFamily()
+	tests := []struct {
+		entityType, ancestor string
+		want                 bool
+	}{
+		{"Goblin", "Goblin", true},
+		{"GoblinArcher", "Goblin", true},
+		{"GoblinArcherCaptain", "Goblin", true},
+		{"GoblinArcherCaptain", "GoblinArcher", true},
+		{"Goblin", "GoblinArcher", false},
+		{"GoblinShaman", "GoblinArcher", false},
+		{"Player", "Player", true},
+		{"Player", "Goblin", false},
+	}
+	for _, tt := range tests {
+		if got := IsA(&s, tt.entityType, tt.ancestor); got != tt.want {
+			t.Errorf("IsA(%q, %q) = %v, want %v", tt.entityType, tt.ancestor, got, tt.want)
+		}
+	}
+
+	want := []string{"Goblin", "GoblinArcher", "GoblinArcherCaptain", "GoblinShaman"}
+	if got := Subtypes(&s, "Goblin"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Subtypes(Goblin) = %v, want %v", got, want)
+	}
+}
+
+func TestValidateSchema_Inheritance(t *testing.T) {
+	tests := []struct {
+		name    string
+		edit    func(map[string]EntityType)
+		wantErr string
+	}{
+		{"valid", func(map[string]EntityType) {}, ""},
+		{"undeclared parent", func(ets map[string]EntityType) {
+			ets["Hobgoblin"] = EntityType{Extends: "Orc"}
+		}, `extends undeclared entity type "Orc"`},
+		{"self cycle", func(ets map[string]EntityType) {
+			ets["Goblin"] = EntityType{Extends: "Goblin", ValidationLevel: ValidationStrict}
+		}, "extends cycle"},
+		{"long cycle", func(ets map[string]EntityType) {
+			g := ets["Goblin"]
+			g.Extends = "GoblinArcherCaptain"
+			ets["Goblin"] = g
+		}, "extends cycle"},
+		{"demoted required", func(ets map[string]EntityType) {
+			ets["GoblinScout"] = EntityType{Extends: "Goblin", OptionalComponents: []string{"Health"}}
+		}, `component "Health" is required by "Goblin" and cannot be made optional`},
+		{"inherited undeclared component", func(ets map[string]EntityType) {
+			ets["GoblinScout"] = EntityType{Extends: "Goblin", RequiredComponents: []string{"Wings"}}
+		}, `undeclared component "Wings"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := goblinFamily()
+			tt.edit(s.EntityTypes)
+			err := ValidateSchema(s)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadSchema_ExtendsInheritsValidationLevel(t *testing.T) {
+	s, err := LoadSchema([]byte(`{
+		"schemaVersion": 1,
+		"components": {"Health": {"type": "integer"}},
+		"entityTypes": {
+			"Goblin": {"requiredComponents": ["Health"], "validationLevel": "warning"},
+			"GoblinArcher": {"extends": "Goblin"}
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateSchema(s); err != nil {
+		t.Fatal(err)
+	}
+	archer, _ := ResolveEntityType(&s, "GoblinArcher")
+	if archer.ValidationLevel != ValidationWarning {
+		t.Errorf("ValidationLevel = %q, want inherited %q", archer.ValidationLevel, ValidationWarning)
+	}
+	if !archer.IsComponentRequired("Health") {
+		t.Error("GoblinArcher does not inherit required Health")
+	}
+}
@@ -181,6 +181,74 @@ func TestValidateSchema_ComponentInBothRequiredAndOptional(t *testing.T) {
 	}
 }
 
+func TestValidateSchema_RenameHints(t *testing.T) {
+	tests := []struct {
+		name       string
+		components map[string]Component
+		wantErr    string
+	}{
+		{
+			name: "valid component and property renames",
+			components: map[string]Component{
+				"Vitality": {Type: ComponentTypeObject, RenamedFrom: "Health", Properties: map[string]Property{
+					"current": {Type: PropertyTypeInteger, RenamedFrom: "hp"},
+				}},
+			},
+		},
+		{
+			name: "component renamed from a declared component",
+			components: map[string]Component{
+				"Health":   {Type: ComponentTypeInteger},
+				"Vitality": {Type: ComponentTypeInteger, RenamedFrom: "health"},
+			},
+			wantErr: `component "Vitality": renamedFrom "health" names a component that is still declared`,
+		},
+		{
+			name: "two components renamed from the same name",
+			components: map[string]Component{
+				"A": {Type: ComponentTypeInteger, RenamedFrom: "Old"},
+				"B": {Type: ComponentTypeInteger, RenamedFrom: "Old"},
+			},
+			wantErr: `components "A" and "B" are both renamedFrom "Old"`,
+		},
+		{
+			name: "property renamed from a sibling",
+			components: map[string]Component{
+				"Health": {Type: ComponentTypeObject, Properties: map[string]Property{
+					"hp":      {Type: PropertyTypeInteger},
+					"current": {Type: PropertyTypeInteger, RenamedFrom: "hp"},
+				}},
+			},
+			wantErr: `property "current": renamedFrom "hp" names a property that is still declared`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names := make([]string, 0, len(tt.components))
+			for name := range tt.components {
+				names = append(names, name)
+			}
+			s := DatabaseSchema{
+				SchemaVersion: 1,
+				Components:    tt.components,
+				EntityTypes: map[string]EntityType{
+					"Goblin": {RequiredComponents: names, ValidationLevel: ValidationStrict},
+				},
+			}
+			err := ValidateSchema(s)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ValidateSchema() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ValidateSchema() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestValidateSchema_InvalidValidationLevel(t *testing.T) {
 	s := DatabaseSchema{
 		SchemaVersion: 1,
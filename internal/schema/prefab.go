@@ -0,0 +1,317 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// Prefab is one entry in the top-level "prefabs" map of schema.json: a
+// named template for spawning an entity of EntityType with default values
+// for its components, so that designers can tune a Goblin's stats without
+// touching Go or machine JSON.
+//
+// Components maps a component name to its field values, in the shape
+// entity creation takes: object components by property name, other
+// components as "value" (or "target_entity_id" for entity-refs) and tags
+// as {}. An integer or number field may instead hold a range such as
+// {"min": 6, "max": 10}, which is drawn afresh for every spawn.
+//
+// Variants layer further values over the base, so "Goblin/elite" is the
+// Goblin prefab with the elite variant's values merged in field by field.
+type Prefab struct {
+	EntityType string                    `json:"entityType"`
+	Components map[string]map[string]any `json:"components,omitempty"`
+	Variants   map[string]PrefabVariant  `json:"variants,omitempty"`
+}
+
+// PrefabVariant is one entry in a prefab's "variants" map. Its component
+// values override or add to the base prefab's.
+type PrefabVariant struct {
+	Components map[string]map[string]any `json:"components"`
+}
+
+// PrefabVariantSeparator separates a prefab name from a variant name in a
+// prefab reference such as "Goblin/elite".
+const PrefabVariantSeparator = "/"
+
+// ValueRange is a randomised prefab value: a number drawn uniformly from
+// [Min, Max], or a whole number for integer fields.
+type ValueRange struct {
+	Min, Max float64
+}
+
+// AsValueRange reports whether v is a range, an object with exactly the
+// numeric keys "min" and "max".
+func AsValueRange(v any) (ValueRange, bool) {
+	m, ok := v.(map[string]any)
+	if !ok || len(m) != 2 {
+		return ValueRange{}, false
+	}
+	lo, okLo := constraintNumber(m["min"])
+	hi, okHi := constraintNumber(m["max"])
+	if !okLo || !okHi {
+		return ValueRange{}, false
+	}
+	return ValueRange{Min: lo, Max: hi}, true
+}
+
+// check reports whether r can be drawn for prop, an integer or number
+// property: min must not exceed max, an integer range must hold a whole
+// number and every value it can draw must satisfy prop's constraints.
+func (r ValueRange) check(prop Property) error {
+	if r.Min > r.Max {
+		return fmt.Errorf("range min %s is greater than max %s",
+			formatConstraintNumber(r.Min), formatConstraintNumber(r.Max))
+	}
+	// An integer field draws whole numbers, so its bounds are the whole
+	// numbers inside the range.
+	lo, hi := r.Min, r.Max
+	if prop.Type == PropertyTypeInteger {
+		lo, hi = math.Ceil(lo), math.Floor(hi)
+		if lo > hi {
+			return fmt.Errorf("range [%s, %s] holds no integer",
+				formatConstraintNumber(r.Min), formatConstraintNumber(r.Max))
+		}
+	}
+	for _, bound := range []float64{lo, hi} {
+		if err := prop.CheckValue(bound); err != nil {
+			return fmt.Errorf("range: %w", err)
+		}
+	}
+	return nil
+}
+
+// draw returns a value of r, which must pass check, for a field of
+// propType, using rnd or, when it is nil, the math/rand top-level
+// functions.
+func (r ValueRange) draw(propType string, rnd *rand.Rand) any {
+	float := rand.Float64
+	int63n := rand.Int63n
+	if rnd != nil {
+		float, int63n = rnd.Float64, rnd.Int63n
+	}
+	if propType == PropertyTypeInteger {
+		lo, hi := int64(math.Ceil(r.Min)), int64(math.Floor(r.Max))
+		return lo + int63n(hi-lo+1)
+	}
+	return r.Min + float()*(r.Max-r.Min)
+}
+
+// SplitPrefabRef splits a prefab reference into its prefab and variant
+// names; the variant is "" when ref names the base prefab.
+func SplitPrefabRef(ref string) (prefab, variant string) {
+	prefab, variant, _ = strings.Cut(ref, PrefabVariantSeparator)
+	return prefab, variant
+}
+
+// InstantiatePrefab returns the entity type and component values to spawn
+// for the prefab reference ref ("Goblin" or "Goblin/elite"): the base
+// values, the variant's merged over them and overrides merged over both,
+// field by field, with every range drawn from rnd (nil uses the math/rand
+// top-level functions). The result is a fresh map the caller may keep.
+//
+// Ranges are checked as validatePrefabs checks them at load, so an
+// override range that cannot be drawn is an error. The other values are
+// not validated; entity creation validates them like any other.
+func InstantiatePrefab(
+	s *DatabaseSchema,
+	ref string,
+	overrides map[string]map[string]any,
+	rnd *rand.Rand,
+) (string, map[string]map[string]any, error) {
+	name, variantName := SplitPrefabRef(ref)
+	p, ok := s.Prefabs[name]
+	if !ok {
+		return "", nil, fmt.Errorf("prefab %q is not declared", name)
+	}
+	layers := []map[string]map[string]any{p.Components}
+	if variantName != "" {
+		v, ok := p.Variants[variantName]
+		if !ok {
+			return "", nil, fmt.Errorf("prefab %q has no variant %q", name, variantName)
+		}
+		layers = append(layers, v.Components)
+	}
+	components := mergePrefabComponents(append(layers, overrides)...)
+
+	for compName, values := range components {
+		comp, canonical := ComponentByName(s, compName)
+		for field, v := range values {
+			r, ok := AsValueRange(v)
+			if !ok {
+				continue
+			}
+			prop, ok := prefabField(comp, field)
+			if canonical == "" || !ok || (prop.Type != PropertyTypeInteger && prop.Type != PropertyTypeNumber) {
+				continue
+			}
+			// Ranges in the prefab were checked at load; those in overrides
+			// come from the caller.
+			if err := r.check(prop); err != nil {
+				return "", nil, fmt.Errorf("prefab %q: component %q field %q: %w", ref, compName, field, err)
+			}
+			values[field] = r.draw(prop.Type, rnd)
+		}
+	}
+	return p.EntityType, components, nil
+}
+
+// mergePrefabComponents merges the component values of layers, later
+// layers overriding earlier ones field by field, into a fresh map.
+// Component names are matched case-insensitively and keep the spelling of
+// the first layer that names them.
+func mergePrefabComponents(layers ...map[string]map[string]any) map[string]map[string]any {
+	out := make(map[string]map[string]any)
+	names := make(map[string]string) // lowercase → name in out
+	for _, layer := range layers {
+		for _, compName := range sortedKeys(layer) {
+			key, ok := names[strings.ToLower(compName)]
+			if !ok {
+				key = compName
+				names[strings.ToLower(compName)] = key
+				out[key] = make(map[string]any)
+			}
+			for field, v := range layer[compName] {
+				out[key][field] = v
+			}
+		}
+	}
+	return out
+}
+
+// prefabField returns the property a prefab value for field of comp is
+// checked against: the declared property of an object component, or the
+// single value of any other component type. Tags, relations and computed
+// properties have no settable fields.
+func prefabField(comp Component, field string) (Property, bool) {
+	switch comp.Type {
+	case ComponentTypeObject:
+		prop, ok := PropertyByName(comp.Properties, field)
+		if !ok || prop.IsComputed() {
+			return Property{}, false
+		}
+		return prop, true
+	case ComponentTypeEntityRef:
+		if field == "target_entity_id" || field == "target" {
+			return Property{Type: PropertyTypeEntityRef}, true
+		}
+	case ComponentTypeArray, ComponentTypeString, ComponentTypeInteger, ComponentTypeNumber, ComponentTypeBoolean:
+		if field == "value" {
+			return Property{Type: comp.Type}, true
+		}
+	}
+	return Property{}, false
+}
+
+// validatePrefabs checks that every prefab spawns a declared entity type;
+// that its components and those of each variant are declared
+// components the type allows, unless it only warns; that every value names a settable field and
+// satisfies its property's constraints; that ranges are only given to
+// integer and number fields, with min <= max; and, for entity types with
+// strict validation, that the base prefab and each variant provide every
+// required component.
+func validatePrefabs(s DatabaseSchema) error {
+	for _, name := range sortedKeys(s.Prefabs) {
+		p := s.Prefabs[name]
+		if name == "" || strings.Contains(name, PrefabVariantSeparator) {
+			return fmt.Errorf("prefab %q: name must be non-empty and must not contain %q", name, PrefabVariantSeparator)
+		}
+		et, ok := ResolveEntityType(&s, p.EntityType)
+		if !ok {
+			return fmt.Errorf("prefab %q: entityType %q is not declared", name, p.EntityType)
+		}
+		et.ApplyDefaults()
+
+		if err := validatePrefabComponents(s, et, p.Components); err != nil {
+			return fmt.Errorf("prefab %q: %w", name, err)
+		}
+		if err := checkPrefabRequired(et, p.Components); err != nil {
+			return fmt.Errorf("prefab %q: %w", name, err)
+		}
+		for _, variantName := range sortedKeys(p.Variants) {
+			v := p.Variants[variantName]
+			if variantName == "" || strings.Contains(variantName, PrefabVariantSeparator) {
+				return fmt.Errorf("prefab %q: variant name %q must be non-empty and must not contain %q",
+					name, variantName, PrefabVariantSeparator)
+			}
+			if err := validatePrefabComponents(s, et, v.Components); err != nil {
+				return fmt.Errorf("prefab %q variant %q: %w", name, variantName, err)
+			}
+			if err := checkPrefabRequired(et, mergePrefabComponents(p.Components, v.Components)); err != nil {
+				return fmt.Errorf("prefab %q variant %q: %w", name, variantName, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validatePrefabComponents checks one layer of prefab component values
+// against the resolved entity type et. Component names are matched
+// case-insensitively, as ComponentByName matches them. Like entity
+// creation, an entity type with warning validation accepts components it
+// does not allow.
+func validatePrefabComponents(s DatabaseSchema, et EntityType, components map[string]map[string]any) error {
+	for _, compName := range sortedKeys(components) {
+		comp, canonical := ComponentByName(&s, compName)
+		if canonical == "" {
+			return fmt.Errorf("component %q is not declared", compName)
+		}
+		if comp.Type == ComponentTypeRelation {
+			return fmt.Errorf("component %q is a relation; relations are added between entities", compName)
+		}
+		if et.ValidationLevel != ValidationWarning && !et.IsComponentAllowed(canonical) {
+			return fmt.Errorf("component %q is not allowed on its entity type", compName)
+		}
+		values := components[compName]
+		for _, field := range sortedKeys(values) {
+			prop, ok := prefabField(comp, field)
+			if !ok {
+				return fmt.Errorf("component %q has no settable field %q", compName, field)
+			}
+			v := values[field]
+			r, isRange := AsValueRange(v)
+			switch {
+			case isRange && (prop.Type == PropertyTypeString || prop.Type == PropertyTypeBoolean ||
+				prop.Type == PropertyTypeEntityRef):
+				return fmt.Errorf("component %q field %q: ranges are only supported on integer and number fields",
+					compName, field)
+			case isRange && (prop.Type == PropertyTypeInteger || prop.Type == PropertyTypeNumber):
+				if err := r.check(prop); err != nil {
+					return fmt.Errorf("component %q field %q: %w", compName, field, err)
+				}
+				continue
+			}
+			if v == nil && !prop.Nullable {
+				return fmt.Errorf("component %q field %q: null is not allowed", compName, field)
+			}
+			if err := prop.CheckValue(v); err != nil {
+				return fmt.Errorf("component %q field %q: %w", compName, field, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkPrefabRequired reports the first required component of et missing
+// from components, unless et only warns about missing components.
+// Component names are matched case-insensitively.
+func checkPrefabRequired(et EntityType, components map[string]map[string]any) error {
+	if et.ValidationLevel == ValidationWarning {
+		return nil
+	}
+	provided := make(map[string]bool, len(components))
+	for compName := range components {
+		provided[strings.ToLower(compName)] = true
+	}
+	required := append([]string(nil), et.RequiredComponents...)
+	sort.Strings(required)
+	for _, req := range required {
+		if !provided[strings.ToLower(req)] {
+			return fmt.Errorf("required component %q is missing", req)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package schema
+
+import (
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const prefabSchemaJSON = `{
+	"schemaVersion": 1,
+	"components": {
+		"Stats": {"type": "object", "properties": {
+			"hp":    {"type": "integer", "minimum": 1, "maximum": 50},
+			"speed": {"type": "number", "default": 1},
+			"power": {"type": "integer", "computed": "hp * 2"}
+		}},
+		"Name":    {"type": "string"},
+		"Boss":    {"type": "tag"},
+		"ChildOf": {"type": "relation"},
+		"Loot":    {"type": "array", "items": {"type": "string"}}
+	},
+	"entityTypes": {
+		"Goblin": {"requiredComponents": ["Stats"], "optionalComponents": ["Name", "Boss"]},
+		"Chest":  {"optionalComponents": ["Loot"], "validationLevel": "warning", "requiredComponents": ["Name"]}
+	},
+	"prefabs": {
+		"Grunt": {
+			"entityType": "Goblin",
+			"components": {"Stats": {"hp": {"min": 6, "max": 10}, "speed": 1.5}},
+			"variants": {
+				"elite": {"components": {"Stats": {"hp": 30}, "Boss": {}}}
+			}
+		},
+		"Chest": {"entityType": "Chest", "components": {"Loot": {"value": ["gold"]}}}
+	}
+}`
+
+func TestLoadSchema_Prefabs(t *testing.T) {
+	s, err := LoadSchema([]byte(prefabSchemaJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateSchema(s); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Prefabs["Grunt"].Variants["elite"].Components["Stats"]["hp"]; got != float64(30) {
+		t.Errorf("Grunt/elite hp = %v, want 30", got)
+	}
+
+	_, err = LoadSchema([]byte(`{"schemaVersion": 1, "prefabs": {"Grunt": {}, "Grunt": {}}}`))
+	if err == nil || !strings.Contains(err.Error(), `duplicate prefabs key "Grunt"`) {
+		t.Errorf("err = %v, want duplicate prefabs key", err)
+	}
+}
+
+func TestInstantiatePrefab(t *testing.T) {
+	s, err := LoadSchema([]byte(prefabSchemaJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rnd := rand.New(rand.NewSource(1))
+
+	seen := map[int64]bool{}
+	for i := 0; i < 200; i++ {
+		entityType, comps, err := InstantiatePrefab(&s, "Grunt", nil, rnd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entityType != "Goblin" {
+			t.Fatalf("entity type = %q, want Goblin", entityType)
+		}
+		hp, ok := comps["Stats"]["hp"].(int64)
+		if !ok || hp < 6 || hp > 10 {
+			t.Fatalf("hp = %#v, want an int64 in [6, 10]", comps["Stats"]["hp"])
+		}
+		seen[hp] = true
+	}
+	if len(seen) != 5 {
+		t.Errorf("drew hp values %v, want all of 6 to 10", seen)
+	}
+	// Drawing must not write the range back into the schema.
+	if _, ok := AsValueRange(s.Prefabs["Grunt"].Components["Stats"]["hp"]); !ok {
+		t.Error("InstantiatePrefab modified the prefab")
+	}
+
+	_, comps, err := InstantiatePrefab(&s, "Grunt/elite", map[string]map[string]any{
+		"stats": {"speed": 3.0},
+		"Name":  {"value": "Grak"},
+	}, rnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]map[string]any{
+		"Stats": {"hp": float64(30), "speed": 3.0},
+		"Boss":  {},
+		"Name":  {"value": "Grak"},
+	}
+	if !reflect.DeepEqual(comps, want) {
+		t.Errorf("Grunt/elite = %v, want %v", comps, want)
+	}
+
+	for ref, wantErr := range map[string]string{
+		"Orc":         `prefab "Orc" is not declared`,
+		"Grunt/giant": `prefab "Grunt" has no variant "giant"`,
+	} {
+		if _, _, err := InstantiatePrefab(&s, ref, nil, rnd); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("InstantiatePrefab(%q) err = %v, want %q", ref, err, wantErr)
+		}
+	}
+
+	// Override ranges are checked before they are drawn.
+	for _, tt := range []struct {
+		hp      map[string]any
+		wantErr string
+	}{
+		{map[string]any{"min": 5.0, "max": 3.0}, `field "hp": range min 5 is greater than max 3`},
+		{map[string]any{"min": 5.2, "max": 5.8}, `field "hp": range [5.2, 5.8] holds no integer`},
+		{map[string]any{"min": 40.0, "max": 60.0}, `field "hp": range: value 60 is greater than maximum 50`},
+	} {
+		_, _, err := InstantiatePrefab(&s, "Grunt", map[string]map[string]any{"Stats": {"hp": tt.hp}}, rnd)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("override hp %v: err = %v, want %q", tt.hp, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateSchema_Prefabs(t *testing.T) {
+	tests := []struct {
+		name    string
+		prefab  Prefab
+		wantErr string
+	}{
+		{"unknown entity type", Prefab{EntityType: "Orc"}, `prefab "P": entityType "Orc" is not declared`},
+		{"missing required", Prefab{EntityType: "Goblin"}, `prefab "P": required component "Stats" is missing`},
+		{"warning level may omit required", Prefab{EntityType: "Chest"}, ""},
+		{"warning level may add disallowed", Prefab{EntityType: "Chest", Components: map[string]map[string]any{
+			"Boss": {}}}, ""},
+		{"required matched case-insensitively", Prefab{EntityType: "Goblin", Components: map[string]map[string]any{
+			"stats": {"hp": 5.0}}}, ""},
+		{"undeclared component", Prefab{EntityType: "Goblin", Components: map[string]map[string]any{
+			"Stats": {}, "Mana": {}}}, `component "Mana" is not declared`},
+		{"relation", Prefab{EntityType: "Goblin", Components: map[string]map[string]any{
+			"Stats": {}, "ChildOf": {}}}, `component "ChildOf" is a relation`},
+		{"not allowed", Prefab{EntityType: "Goblin", Components: map[string]map[string]any{
+			"Stats": {}, "Loot": {"value": []any{}}}}, `component "Loot" is not allowed`},
+		{"unknown field", Prefab{EntityType: "Goblin", Components: map[string]map[string]any{
+			"Stats": {"mana": 3.0}}}, `component "Stats" has no settable field "mana"`},
+		{"computed field", Prefab{EntityType: "Goblin", Components: map[string]map[string]any{
+			"Stats": {"power": 3.0}}}, `no settable field "power"`},
+		{"tag field", Prefab{EntityType: "Goblin", Components: map[string]map[string]any{
+			"Stats": {}, "Boss": {"value": true}}}, `component "Boss" has no settable field "value"`},
+		{"constraint", Prefab{EntityType: "Goblin", Components: map[string]map[string]any{
+			"Stats": {"hp": 99.0}}}, `component "Stats" field "hp": value 99 is greater than maximum 50`},
+		{"range outside constraint", Prefab{EntityType: "Goblin", Components: map[string]map[string]any{
+			"Stats": {"hp": map[string]any{"min": 0.0, "max": 5.0}}}}, `field "hp": range: value 0 is less than minimum 1`},
+		{"inverted range", Prefab{EntityType: "Goblin", Components: map[string]map[string]any{
+			"Stats": {"hp": map[string]any{"min": 9.0, "max": 5.0}}}}, `range min 9 is greater than max 5`},
+		{"range without integer", Prefab{EntityType: "Goblin", Components: map[string]map[string]any{
+			"Stats": {"hp": map[string]any{"min": 5.2, "max": 5.8}}}}, `range [5.2, 5.8] holds no integer`},
+		{"range on string", Prefab{EntityType: "Goblin", Components: map[string]map[string]any{
+			"Stats": {}, "Name": {"value": map[string]any{"min": 1.0, "max": 2.0}}}}, `field "value": ranges are only supported on integer and number fields`},
+		{"bad variant", Prefab{EntityType: "Goblin", Components: map[string]map[string]any{"Stats": {}},
+			Variants: map[string]PrefabVariant{"elite": {Components: map[string]map[string]any{
+				"Stats": {"hp": 0.0}}}}}, `prefab "P" variant "elite": component "Stats" field "hp"`},
+		{"valid variant", Prefab{EntityType: "Goblin", Variants: map[string]PrefabVariant{
+			"elite": {Components: map[string]map[string]any{"Stats": {"hp": 20.0}}}}},
+			`prefab "P": required component "Stats" is missing`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := LoadSchema([]byte(prefabSchemaJSON))
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.Prefabs = map[string]Prefab{"P": tt.prefab}
+			err = ValidateSchema(s)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadSchemaSet_Prefabs(t *testing.T) {
+	root := t.TempDir()
+	base := writeFragments(t, filepath.Join(root, "base"), map[string]string{"schema.json": baseFragment})
+	mod := writeFragments(t, filepath.Join(root, "mod"), map[string]string{
+		"a.json": `{
+  "prefabs": {
+    "Grunt": {"entityType": "Goblin", "components": {"Position": {}, "Health": {"value": 7}}}
+  }
+}`,
+		"b.json": `{"prefabs": {"Grunt": {"entityType": "Goblin"}}}`,
+		"c.json": `{"prefabs": {"Scout": {"entityType": "Goblin", "components": {"Position": {}}}}}`,
+	})
+
+	set, err := LoadSchemaSet(filepath.Join(base, "schema.json"), filepath.Join(mod, "a.json"))
+	if err != nil {
+		t.Fatalf("LoadSchemaSet: %v", err)
+	}
+	if _, ok := set.Schema.Prefabs["Grunt"]; !ok {
+		t.Errorf("Prefabs = %v, want Grunt merged", set.Schema.Prefabs)
+	}
+	if got, want := set.Prefabs["Grunt"], (Source{File: filepath.Join(mod, "a.json"), Line: 3}); got != want {
+		t.Errorf("Prefabs[Grunt] = %v, want %v", got, want)
+	}
+	if err := set.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+
+	_, err = LoadSchemaSet(filepath.Join(base, "schema.json"), filepath.Join(mod, "a.json"), filepath.Join(mod, "b.json"))
+	if err == nil || !strings.Contains(err.Error(), `prefab "Grunt" is defined in both`) {
+		t.Errorf("err = %v, want a prefab conflict", err)
+	}
+
+	// An invalid prefab is reported at the line that defined it.
+	set, err = LoadSchemaSet(filepath.Join(base, "schema.json"), filepath.Join(mod, "c.json"))
+	if err != nil {
+		t.Fatalf("LoadSchemaSet: %v", err)
+	}
+	err = set.Validate()
+	if err == nil || !strings.HasPrefix(err.Error(), filepath.Join(mod, "c.json")+":1: ") ||
+		!strings.Contains(err.Error(), `prefab "Scout": required component "Health" is missing`) {
+		t.Errorf("err = %v, want the missing Health reported at c.json:1", err)
+	}
+}
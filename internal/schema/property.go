@@ -29,10 +29,45 @@ var supportedPropertyTypes = map[string]bool{
 // definitions. For array-type components the Items pointer describes
 // the type of each element. Primitive types (string, integer, number,
 // boolean) and entity-ref have no children.
+//
+// The optional constraint keywords (Minimum, Maximum, Enum, Pattern,
+// MinLength, MaxLength) follow JSON Schema semantics and are checked on
+// every write. See constraint.go.
+//
+// Default, when set, is used for CREATE TABLE and ALTER TABLE ADD COLUMN
+// DDL and fills omitted fields on entity creation and component attach.
+// See default.go.
+//
+// Nullable marks a property of an object component whose column may hold
+// NULL ("no target yet"). Properties are NOT NULL by default.
+//
+// RenamedFrom names the property's previous name so that migrations rename
+// the existing column instead of dropping it and adding a new one.
+//
+// OnDelete and RefType apply to entity-ref properties: OnDelete sets the
+// foreign key's delete policy and RefType restricts which entity type may
+// be referenced. See reference.go.
+//
+// Computed declares a SQL expression over sibling properties that derives
+// the property's value; Stored makes its generated column STORED instead of
+// VIRTUAL. Computed properties are read-only. See computed.go.
 type Property struct {
-	Type       string              `json:"type"`
-	Properties map[string]Property `json:"properties,omitempty"`
-	Items      *Property           `json:"items,omitempty"`
+	Type        string              `json:"type"`
+	Properties  map[string]Property `json:"properties,omitempty"`
+	Items       *Property           `json:"items,omitempty"`
+	Minimum     *float64            `json:"minimum,omitempty"`
+	Maximum     *float64            `json:"maximum,omitempty"`
+	Enum        []any               `json:"enum,omitempty"`
+	Pattern     string              `json:"pattern,omitempty"`
+	MinLength   *int                `json:"minLength,omitempty"`
+	MaxLength   *int                `json:"maxLength,omitempty"`
+	Default     any                 `json:"default,omitempty"`
+	Nullable    bool                `json:"nullable,omitempty"`
+	RenamedFrom string              `json:"renamedFrom,omitempty"`
+	OnDelete    string              `json:"onDelete,omitempty"`
+	RefType     string              `json:"refType,omitempty"`
+	Computed    string              `json:"computed,omitempty"`
+	Stored      bool                `json:"stored,omitempty"`
 }
 
 // Validate returns a descriptive error if the property definition is
@@ -46,6 +81,18 @@ func (p Property) Validate() error {
 		return fmt.Errorf("unsupported property type %q: must be %s",
 			p.Type, supportedTypeList())
 	}
+	if err := p.validateConstraints(); err != nil {
+		return err
+	}
+	if err := p.validateDefault(); err != nil {
+		return err
+	}
+	if err := p.validateReference(); err != nil {
+		return err
+	}
+	if err := p.validateComputed(); err != nil {
+		return err
+	}
 	switch p.Type {
 	case PropertyTypeObject:
 		if len(p.Properties) == 0 {
@@ -0,0 +1,165 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Supported onDelete values for entity-ref properties and components. They
+// decide what happens to a reference when the entity it points at is
+// deleted. Without onDelete the delete is refused while references remain
+// (SQLite's default NO ACTION).
+const (
+	// OnDeleteCascade deletes the referencing component row.
+	OnDeleteCascade = "cascade"
+	// OnDeleteSetNull clears the reference; the property must be nullable.
+	OnDeleteSetNull = "setNull"
+	// OnDeleteRestrict refuses the delete immediately, even inside a
+	// transaction that would remove the reference before committing.
+	OnDeleteRestrict = "restrict"
+)
+
+var onDeleteSQL = map[string]string{
+	OnDeleteCascade:  "CASCADE",
+	OnDeleteSetNull:  "SET NULL",
+	OnDeleteRestrict: "RESTRICT",
+}
+
+// ReferenceClause renders the column constraint of an entity-ref column:
+// REFERENCES entities(id) followed by the ON DELETE action for onDelete,
+// if any. It is shared by CREATE TABLE, ALTER TABLE ADD COLUMN and the
+// rebuild path so that all three produce the same foreign key.
+func ReferenceClause(onDelete string) string {
+	if action, ok := onDeleteSQL[onDelete]; ok {
+		return "REFERENCES entities(id) ON DELETE " + action
+	}
+	return "REFERENCES entities(id)"
+}
+
+// OnDeleteFromSQL maps a foreign key's ON DELETE action as reported by
+// PRAGMA foreign_key_list back to its onDelete keyword. NO ACTION, the
+// default, maps to "".
+func OnDeleteFromSQL(action string) string {
+	for keyword, sql := range onDeleteSQL {
+		if strings.EqualFold(action, sql) {
+			return keyword
+		}
+	}
+	return ""
+}
+
+// validateReference checks the onDelete and refType keywords of p: both
+// apply to entity-ref properties only, and setNull needs a nullable column.
+func (p Property) validateReference() error {
+	if p.OnDelete == "" && p.RefType == "" {
+		return nil
+	}
+	if p.Type != PropertyTypeEntityRef {
+		return fmt.Errorf("onDelete/refType are only valid on %q properties, not %q",
+			PropertyTypeEntityRef, p.Type)
+	}
+	if err := validateOnDelete(p.OnDelete); err != nil {
+		return err
+	}
+	if p.OnDelete == OnDeleteSetNull && !p.Nullable {
+		return fmt.Errorf("onDelete %q requires the property to be nullable", OnDeleteSetNull)
+	}
+	return nil
+}
+
+// validateReference checks the onDelete and refType keywords of an
+// entity-ref component. Its target column is NOT NULL, so setNull is
+// refused; cascade already detaches the component. A relation accepts
+// refType but not onDelete: both of its ends always cascade.
+func (c Component) validateReference() error {
+	if c.OnDelete == "" && c.RefType == "" {
+		return nil
+	}
+	if c.Type == ComponentTypeRelation {
+		if c.OnDelete != "" {
+			return fmt.Errorf("onDelete is not valid on a %q component: deleting either end always deletes the relation",
+				ComponentTypeRelation)
+		}
+		return nil
+	}
+	if c.Type != ComponentTypeEntityRef {
+		return fmt.Errorf("onDelete/refType are only valid on %q and %q components, not %q",
+			ComponentTypeEntityRef, ComponentTypeRelation, c.Type)
+	}
+	if err := validateOnDelete(c.OnDelete); err != nil {
+		return err
+	}
+	if c.OnDelete == OnDeleteSetNull {
+		return fmt.Errorf("onDelete %q is not valid on a component; use %q to detach it",
+			OnDeleteSetNull, OnDeleteCascade)
+	}
+	return nil
+}
+
+func validateOnDelete(onDelete string) error {
+	if onDelete == "" {
+		return nil
+	}
+	if _, ok := onDeleteSQL[onDelete]; !ok {
+		return fmt.Errorf("invalid onDelete %q (must be %q, %q or %q)",
+			onDelete, OnDeleteCascade, OnDeleteSetNull, OnDeleteRestrict)
+	}
+	return nil
+}
+
+// validateReferences checks that every refType names a declared entity type
+// and that onDelete and refType appear only where they take effect: on
+// entity-ref components and on the top-level entity-ref properties of
+// object components, which are the only references stored in their own
+// column.
+func validateReferences(s DatabaseSchema) error {
+	checkRefType := func(owner, refType string) error {
+		if refType == "" {
+			return nil
+		}
+		if _, ok := s.EntityTypes[refType]; !ok {
+			return fmt.Errorf("%s: refType %q is not a declared entity type", owner, refType)
+		}
+		return nil
+	}
+	for _, name := range sortedComponentNames(s) {
+		comp := s.Components[name]
+		if err := comp.validateReference(); err != nil {
+			return fmt.Errorf("component %q: %w", name, err)
+		}
+		if err := checkRefType(fmt.Sprintf("component %q", name), comp.RefType); err != nil {
+			return err
+		}
+		for _, propName := range sortedPropertyNames(comp.Properties) {
+			prop := comp.Properties[propName]
+			owner := fmt.Sprintf("component %q property %q", name, propName)
+			if err := prop.validateReference(); err != nil {
+				return fmt.Errorf("%s: %w", owner, err)
+			}
+			if err := checkRefType(owner, prop.RefType); err != nil {
+				return err
+			}
+			if nestedReference(prop.Properties) || (prop.Items != nil && nestedReference(map[string]Property{"items": *prop.Items})) {
+				return fmt.Errorf("%s: onDelete/refType are only supported on top-level properties", owner)
+			}
+		}
+		if comp.Items != nil && nestedReference(map[string]Property{"items": *comp.Items}) {
+			return fmt.Errorf("component %q items: onDelete/refType are not supported inside arrays", name)
+		}
+	}
+	return nil
+}
+
+// nestedReference reports whether any property in props, or beneath them,
+// declares onDelete or refType.
+func nestedReference(props map[string]Property) bool {
+	for _, p := range props {
+		if p.OnDelete != "" || p.RefType != "" || nestedReference(p.Properties) {
+			return true
+		}
+		if p.Items != nil && nestedReference(map[string]Property{"items": *p.Items}) {
+			return true
+		}
+	}
+	return false
+}
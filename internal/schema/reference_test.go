@@ -0,0 +1,111 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+func refSchema(target Property, leader Component) DatabaseSchema {
+	return DatabaseSchema{
+		SchemaVersion: 1,
+		Components: map[string]Component{
+			"Target": {Type: ComponentTypeObject, Properties: map[string]Property{"entity": target}},
+			"Leader": leader,
+		},
+		EntityTypes: map[string]EntityType{
+			"Goblin": {ValidationLevel: ValidationStrict},
+		},
+	}
+}
+
+func TestValidateSchema_References(t *testing.T) {
+	ref := Property{Type: PropertyTypeEntityRef}
+	leader := Component{Type: ComponentTypeEntityRef}
+	tests := []struct {
+		name    string
+		target  Property
+		leader  Component
+		wantErr string
+	}{
+		{"plain", ref, leader, ""},
+		{"cascade and refType", Property{Type: PropertyTypeEntityRef, OnDelete: OnDeleteCascade, RefType: "Goblin"},
+			Component{Type: ComponentTypeEntityRef, OnDelete: OnDeleteRestrict, RefType: "Goblin"}, ""},
+		{"nullable setNull", Property{Type: PropertyTypeEntityRef, OnDelete: OnDeleteSetNull, Nullable: true}, leader, ""},
+		{"setNull needs nullable", Property{Type: PropertyTypeEntityRef, OnDelete: OnDeleteSetNull}, leader, "requires the property to be nullable"},
+		{"unknown onDelete", Property{Type: PropertyTypeEntityRef, OnDelete: "nuke"}, leader, `invalid onDelete "nuke"`},
+		{"onDelete on integer", Property{Type: PropertyTypeInteger, OnDelete: OnDeleteCascade}, leader, "only valid on"},
+		{"unknown refType", Property{Type: PropertyTypeEntityRef, RefType: "Dragon"}, leader, `refType "Dragon" is not a declared entity type`},
+		{"component setNull", ref, Component{Type: ComponentTypeEntityRef, OnDelete: OnDeleteSetNull}, "not valid on a component"},
+		{"nested", Property{Type: PropertyTypeObject, Properties: map[string]Property{
+			"inner": {Type: PropertyTypeEntityRef, OnDelete: OnDeleteCascade},
+		}}, leader, "only supported on top-level properties"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSchema(refSchema(tt.target, tt.leader))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReferenceClause(t *testing.T) {
+	for onDelete, want := range map[string]string{
+		"":               "REFERENCES entities(id)",
+		OnDeleteCascade:  "REFERENCES entities(id) ON DELETE CASCADE",
+		OnDeleteSetNull:  "REFERENCES entities(id) ON DELETE SET NULL",
+		OnDeleteRestrict: "REFERENCES entities(id) ON DELETE RESTRICT",
+	} {
+		if got := ReferenceClause(onDelete); got != want {
+			t.Errorf("ReferenceClause(%q) = %q, want %q", onDelete, got, want)
+		}
+		if got := OnDeleteFromSQL(strings.TrimPrefix(want, "REFERENCES entities(id) ON DELETE ")); onDelete != "" && got != onDelete {
+			t.Errorf("OnDeleteFromSQL round trip = %q, want %q", got, onDelete)
+		}
+	}
+	if got := OnDeleteFromSQL("NO ACTION"); got != "" {
+		t.Errorf("OnDeleteFromSQL(NO ACTION) = %q, want empty", got)
+	}
+}
+
+func TestDiff_ChangedPropertyReference(t *testing.T) {
+	domain := &DomainSchema{
+		Components: map[string]DomainComponent{
+			"target": {
+				Type: "object",
+				Columns: []DomainColumn{
+					{Name: "entity_id", SQLType: "INTEGER", IsPK: true},
+					{Name: "entity", SQLType: "INTEGER"}, // plain INTEGER, no foreign key
+				},
+			},
+			"leader": {
+				Type: "entity-ref",
+				Columns: []DomainColumn{
+					{Name: "entity_id", SQLType: "INTEGER", IsPK: true},
+					{Name: "target_entity_id", SQLType: "INTEGER", References: true},
+				},
+			},
+		},
+		EntityTypeNames: map[string]bool{"Goblin": true},
+	}
+	file := refSchema(Property{Type: PropertyTypeEntityRef},
+		Component{Type: ComponentTypeEntityRef, OnDelete: OnDeleteCascade})
+
+	changes := Diff(domain, &file, nil)
+	if len(changes) != 2 {
+		t.Fatalf("changes = %+v, want leader and target reference changes", changes)
+	}
+	for i, want := range []struct{ comp, prop string }{{"leader", "value"}, {"target", "entity"}} {
+		c := changes[i]
+		if c.Kind != ChangedPropertyReference || c.Component != want.comp || c.Property != want.prop {
+			t.Errorf("change %d = %+v, want %s.%s reference change", i, c, want.comp, want.prop)
+		}
+	}
+}
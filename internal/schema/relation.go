@@ -0,0 +1,55 @@
+package schema
+
+import "fmt"
+
+// Relation components are declared in the "components" map with type
+// "relation". Unlike other components they are not attached to a single
+// entity: each row of rel_<name> links a source entity to a target entity,
+// and an entity may relate to any number of targets. Deleting either end
+// deletes the row, so onDelete is not configurable; refType restricts the
+// entity type of the target. Properties are optional and are stored as
+// columns laid out like an object component's.
+
+// RelationByName looks up a relation component from a DatabaseSchema by
+// name, case-insensitively. Returns the component and its canonical
+// (original-case) name, or "" when no relation of that name is declared.
+func RelationByName(db *DatabaseSchema, name string) (Component, string) {
+	comp, canonical := ComponentByName(db, name)
+	if canonical == "" || comp.Type != ComponentTypeRelation {
+		return Component{}, ""
+	}
+	return comp, canonical
+}
+
+// Relations returns the relation components of the schema, keyed by their
+// declared name.
+func Relations(db *DatabaseSchema) map[string]Component {
+	out := make(map[string]Component)
+	for name, comp := range db.Components {
+		if comp.Type == ComponentTypeRelation {
+			out[name] = comp
+		}
+	}
+	return out
+}
+
+// validateRelations checks that relation components declare no indexes
+// (rel_* tables are keyed and indexed on both ends already), behavior or
+// renamedFrom hint. validateCrossReference refuses them on entity types.
+func validateRelations(s DatabaseSchema) error {
+	for _, name := range sortedComponentNames(s) {
+		comp := s.Components[name]
+		if comp.Type != ComponentTypeRelation {
+			continue
+		}
+		switch {
+		case len(comp.Indexes) > 0:
+			return fmt.Errorf("relation %q: indexes are not supported on relations", name)
+		case comp.Behavior != "":
+			return fmt.Errorf("relation %q: behavior is not supported on relations", name)
+		case comp.RenamedFrom != "":
+			return fmt.Errorf("relation %q: renamedFrom is not supported on relations", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadSchema_Relations(t *testing.T) {
+	s, err := LoadSchema([]byte(`{
+		"schemaVersion": 1,
+		"components": {
+			"Health": {"type": "integer"},
+			"ChildOf": {"type": "relation", "refType": "Goblin"},
+			"Likes": {"type": "relation", "properties": {"weight": {"type": "number", "default": 1}}}
+		},
+		"entityTypes": {"Goblin": {"requiredComponents": ["Health"]}}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateSchema(s); err != nil {
+		t.Fatal(err)
+	}
+	if rel, name := RelationByName(&s, "childof"); name != "ChildOf" || rel.RefType != "Goblin" {
+		t.Errorf("RelationByName(childof) = %+v, %q", rel, name)
+	}
+	if _, name := RelationByName(&s, "Health"); name != "" {
+		t.Errorf("RelationByName(Health) = %q, want no match for a non-relation", name)
+	}
+	if got := Relations(&s); len(got) != 2 {
+		t.Errorf("Relations = %v, want ChildOf and Likes", got)
+	}
+
+	_, err = LoadSchema([]byte(`{"schemaVersion": 1, "components": {
+		"ChildOf": {"type": "relation", "items": {"type": "string"}}}}`))
+	if err == nil || !strings.Contains(err.Error(), `must not define "items"`) {
+		t.Errorf("err = %v, want items rejected on a relation", err)
+	}
+}
+
+func TestValidateSchema_Relations(t *testing.T) {
+	tests := []struct {
+		name     string
+		relation Component
+		listed   bool // list the relation on the Goblin entity type
+		wantErr  string
+	}{
+		{"valid", Component{Type: ComponentTypeRelation, RefType: "Goblin"}, false, ""},
+		{"onDelete", Component{Type: ComponentTypeRelation, OnDelete: OnDeleteCascade}, false,
+			`onDelete is not valid on a "relation" component`},
+		{"unknown refType", Component{Type: ComponentTypeRelation, RefType: "Dragon"}, false,
+			`refType "Dragon" is not a declared entity type`},
+		{"index", Component{Type: ComponentTypeRelation, Properties: map[string]Property{"w": {Type: PropertyTypeNumber}},
+			Indexes: []Index{{Columns: []string{"w"}}}}, false, `relation "ChildOf": indexes are not supported`},
+		{"behavior", Component{Type: ComponentTypeRelation, Behavior: "follow"}, false,
+			`relation "ChildOf": behavior is not supported`},
+		{"renamedFrom", Component{Type: ComponentTypeRelation, RenamedFrom: "ParentOf"}, false,
+			`relation "ChildOf": renamedFrom is not supported`},
+		{"listed on entity type", Component{Type: ComponentTypeRelation}, true,
+			`entityType "Goblin": component "ChildOf" is a relation`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			goblin := EntityType{RequiredComponents: []string{"Health"}, ValidationLevel: ValidationStrict}
+			if tt.listed {
+				goblin.OptionalComponents = []string{"ChildOf"}
+			}
+			s := DatabaseSchema{
+				SchemaVersion: 1,
+				Components:    map[string]Component{"Health": {Type: ComponentTypeInteger}, "ChildOf": tt.relation},
+				EntityTypes:   map[string]EntityType{"Goblin": goblin},
+			}
+			err := ValidateSchema(s)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDiff_Relations(t *testing.T) {
+	key := []DomainColumn{
+		{Name: "source_id", SQLType: "INTEGER", IsPK: true},
+		{Name: "target_id", SQLType: "INTEGER", IsPK: true},
+	}
+	domain := &DomainSchema{
+		Components: map[string]DomainComponent{
+			"owns": {Type: "object", Columns: []DomainColumn{{Name: "entity_id", SQLType: "INTEGER", IsPK: true}}},
+		},
+		Relations: map[string]DomainRelation{
+			"likes":   {Columns: append(append([]DomainColumn{}, key...), DomainColumn{Name: "weight", SQLType: "INTEGER"})},
+			"follows": {Columns: key},
+		},
+		EntityTypeNames: map[string]bool{},
+	}
+	file := &DatabaseSchema{
+		Components: map[string]Component{
+			"Likes": {Type: ComponentTypeRelation, Properties: map[string]Property{
+				"weight": {Type: PropertyTypeNumber},
+				"since":  {Type: PropertyTypeInteger, Default: float64(0)},
+			}},
+			// Owns changes from a tag-like object component to a relation.
+			"Owns": {Type: ComponentTypeRelation},
+		},
+		EntityTypes: map[string]EntityType{},
+	}
+
+	got := Diff(domain, file, nil)
+	want := []Change{
+		{Kind: ChangeAddedProperty, Relation: "likes", Property: "since", NewType: "INTEGER"},
+		{Kind: ChangeAddedRelation, Relation: "owns"},
+		{Kind: ChangedPropertyType, Relation: "likes", Property: "weight", OldType: "INTEGER", NewType: "REAL"},
+		{Kind: ChangeRemovedRelation, Relation: "follows"},
+		{Kind: ChangeRemovedComponent, Component: "owns"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Diff = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i].Kind != want[i].Kind || got[i].Relation != want[i].Relation || got[i].Component != want[i].Component ||
+			got[i].Property != want[i].Property || got[i].OldType != want[i].OldType || got[i].NewType != want[i].NewType {
+			t.Errorf("change %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
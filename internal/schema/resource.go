@@ -0,0 +1,80 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Resource is one entry in the top-level "resources" map of schema.json: a
+// world-wide value such as the time of day or the weather, which belongs to
+// no entity. Each resource is stored as the single row of a res_<name>
+// table whose columns are its properties, laid out like an object
+// component's.
+//
+// The row is created with the table, so every property must be nullable or
+// declare a default.
+type Resource struct {
+	Properties map[string]Property `json:"properties"`
+}
+
+// ResourceByName looks up a Resource from a DatabaseSchema by name,
+// case-insensitively. Returns the resource and its canonical
+// (original-case) name, or "" when it is not declared.
+func ResourceByName(db *DatabaseSchema, name string) (Resource, string) {
+	lower := strings.ToLower(name)
+	for k, r := range db.Resources {
+		if strings.ToLower(k) == lower {
+			return r, k
+		}
+	}
+	return Resource{}, ""
+}
+
+// validateResources checks that every resource name maps to a distinct
+// res_* table, that each resource declares at least one valid property,
+// that every property can be filled when the row is created, and that
+// refType names a declared entity type.
+func validateResources(s DatabaseSchema) error {
+	names := make([]string, 0, len(s.Resources))
+	for name := range s.Resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tables := make(map[string]string, len(names))
+	for _, name := range names {
+		res := s.Resources[name]
+		if !isSQLIdentifier(name) {
+			return fmt.Errorf("resource %q: name must be a SQL identifier (letters, digits and underscores)", name)
+		}
+		if prev, ok := tables[strings.ToLower(name)]; ok {
+			return fmt.Errorf("resources %q and %q both map to table res_%s", prev, name, strings.ToLower(name))
+		}
+		tables[strings.ToLower(name)] = name
+
+		if len(res.Properties) == 0 {
+			return fmt.Errorf("resource %q must define %q with at least one property", name, "properties")
+		}
+		for _, propName := range sortedPropertyNames(res.Properties) {
+			prop := res.Properties[propName]
+			if err := prop.Validate(); err != nil {
+				return fmt.Errorf("resource %q property %q: %w", name, propName, err)
+			}
+			if !prop.Nullable && !prop.HasDefault() {
+				return fmt.Errorf("resource %q property %q: must be nullable or declare a default", name, propName)
+			}
+			if prop.RefType != "" {
+				if _, ok := s.EntityTypes[prop.RefType]; !ok {
+					return fmt.Errorf("resource %q property %q: refType %q is not a declared entity type",
+						name, propName, prop.RefType)
+				}
+			}
+			if nestedReference(prop.Properties) || (prop.Items != nil && nestedReference(map[string]Property{"items": *prop.Items})) {
+				return fmt.Errorf("resource %q property %q: onDelete/refType are only supported on top-level properties",
+					name, propName)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadSchema_Resources(t *testing.T) {
+	s, err := LoadSchema([]byte(`{
+		"schemaVersion": 1,
+		"components": {"Health": {"type": "integer"}},
+		"entityTypes": {"Goblin": {"requiredComponents": ["Health"]}},
+		"resources": {
+			"Clock": {"properties": {
+				"isNight": {"type": "boolean", "default": false},
+				"weather": {"type": "string", "nullable": true}
+			}}
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateSchema(s); err != nil {
+		t.Fatal(err)
+	}
+	res, name := ResourceByName(&s, "clock")
+	if name != "Clock" || len(res.Properties) != 2 {
+		t.Errorf("ResourceByName(clock) = %+v, %q", res, name)
+	}
+
+	_, err = LoadSchema([]byte(`{"schemaVersion": 1, "resources": {"Clock": {}, "Clock": {}}}`))
+	if err == nil || !strings.Contains(err.Error(), `duplicate resources key "Clock"`) {
+		t.Errorf("err = %v, want duplicate resources key", err)
+	}
+}
+
+func TestValidateSchema_Resources(t *testing.T) {
+	tests := []struct {
+		name      string
+		resources map[string]Resource
+		wantErr   string
+	}{
+		{"valid", map[string]Resource{
+			"Clock": {Properties: map[string]Property{"tick": {Type: PropertyTypeInteger, Default: float64(0)}}},
+		}, ""},
+		{"bad name", map[string]Resource{
+			"game-clock": {Properties: map[string]Property{"tick": {Type: PropertyTypeInteger, Default: float64(0)}}},
+		}, `resource "game-clock": name must be a SQL identifier`},
+		{"same table", map[string]Resource{
+			"Clock": {Properties: map[string]Property{"tick": {Type: PropertyTypeInteger, Nullable: true}}},
+			"clock": {Properties: map[string]Property{"tick": {Type: PropertyTypeInteger, Nullable: true}}},
+		}, `resources "Clock" and "clock" both map to table res_clock`},
+		{"no properties", map[string]Resource{"Clock": {}}, `resource "Clock" must define "properties"`},
+		{"no default", map[string]Resource{
+			"Clock": {Properties: map[string]Property{"tick": {Type: PropertyTypeInteger}}},
+		}, `resource "Clock" property "tick": must be nullable or declare a default`},
+		{"invalid property", map[string]Resource{
+			"Clock": {Properties: map[string]Property{"tick": {Type: "date", Nullable: true}}},
+		}, `resource "Clock" property "tick": unsupported property type "date"`},
+		{"unknown refType", map[string]Resource{
+			"Boss": {Properties: map[string]Property{"current": {Type: PropertyTypeEntityRef, Nullable: true, RefType: "Dragon"}}},
+		}, `refType "Dragon" is not a declared entity type`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := DatabaseSchema{
+				SchemaVersion: 1,
+				Components:    map[string]Component{"Health": {Type: ComponentTypeInteger}},
+				EntityTypes:   map[string]EntityType{"Goblin": {RequiredComponents: []string{"Health"}, ValidationLevel: ValidationStrict}},
+				Resources:     tt.resources,
+			}
+			err := ValidateSchema(s)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDiff_Resources(t *testing.T) {
+	domain := &DomainSchema{
+		Components: map[string]DomainComponent{},
+		Resources: map[string]DomainResource{
+			"clock": {Columns: []DomainColumn{
+				{Name: "id", SQLType: "INTEGER", IsPK: true},
+				{Name: "tick", SQLType: "INTEGER"},
+				{Name: "label", SQLType: "TEXT", Nullable: true},
+			}},
+			"weather": {Columns: []DomainColumn{
+				{Name: "id", SQLType: "INTEGER", IsPK: true},
+				{Name: "rain", SQLType: "REAL"},
+			}},
+		},
+		EntityTypeNames: map[string]bool{},
+	}
+	file := &DatabaseSchema{
+		Components:  map[string]Component{},
+		EntityTypes: map[string]EntityType{},
+		Resources: map[string]Resource{
+			"Clock": {Properties: map[string]Property{
+				"tick":    {Type: PropertyTypeNumber, Default: float64(0)},
+				"isNight": {Type: PropertyTypeBoolean, Default: false},
+			}},
+			"Economy": {Properties: map[string]Property{"gold": {Type: PropertyTypeInteger, Default: float64(0)}}},
+		},
+	}
+
+	got := Diff(domain, file, nil)
+	want := []Change{
+		{Kind: ChangeAddedProperty, Resource: "clock", Property: "isnight", NewType: "INTEGER"},
+		{Kind: ChangeAddedResource, Resource: "economy"},
+		{Kind: ChangedPropertyType, Resource: "clock", Property: "tick", OldType: "INTEGER", NewType: "REAL"},
+		{Kind: ChangeRemovedProperty, Resource: "clock", Property: "label", OldType: "TEXT"},
+		{Kind: ChangeRemovedResource, Resource: "weather"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Diff = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i].Kind != want[i].Kind || got[i].Resource != want[i].Resource || got[i].Component != "" ||
+			got[i].Property != want[i].Property || got[i].OldType != want[i].OldType || got[i].NewType != want[i].NewType {
+			t.Errorf("change %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,435 @@
+package schema
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Supported patch operations. A patch edits a definition from another
+// fragment instead of redefining it, which would be a conflict.
+const (
+	// PatchAddOptionalComponent adds Component to the optionalComponents of
+	// EntityType.
+	PatchAddOptionalComponent = "addOptionalComponent"
+)
+
+// Patch is one entry of a fragment's "patches" array.
+type Patch struct {
+	Op         string `json:"op"`
+	EntityType string `json:"entityType"`
+	Component  string `json:"component"`
+}
+
+// Source is the place a definition was declared: a fragment file and the
+// 1-based line of its key (or, for a patch, of its opening brace).
+type Source struct {
+	File string
+	Line int
+}
+
+func (s Source) String() string {
+	return fmt.Sprintf("%s:%d", s.File, s.Line)
+}
+
+// SourceError is a validation error attributed to the definition it is
+// about.
+type SourceError struct {
+	Source Source
+	Err    error
+}
+
+func (e *SourceError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Source, e.Err)
+}
+
+func (e *SourceError) Unwrap() error { return e.Err }
+
+// SchemaSet is a schema merged from fragment files, with the provenance of
+// every definition it contains.
+type SchemaSet struct {
+	Schema DatabaseSchema
+	// Files are the fragment files in load order.
+	Files []string
+	// Components, EntityTypes, Resources and Prefabs map each
+	// definition's name to where it was declared.
+	Components  map[string]Source
+	EntityTypes map[string]Source
+	Resources   map[string]Source
+	Prefabs     map[string]Source
+	// Patches maps each entity type to the patches applied to it, in load
+	// order.
+	Patches map[string][]Source
+	// Hash is the SHA-256 hex digest of the fragment bytes concatenated in
+	// load order; for a single file it is the digest of that file.
+	Hash string
+}
+
+// fragment is one schema file of a set. Unlike schema.json on its own, a
+// fragment may omit schemaVersion, components and entityTypes.
+type fragment struct {
+	SchemaVersion json.RawMessage       `json:"schemaVersion"`
+	Components    map[string]Component  `json:"components"`
+	EntityTypes   map[string]EntityType `json:"entityTypes"`
+	Resources     map[string]Resource   `json:"resources"`
+	Prefabs       map[string]Prefab     `json:"prefabs"`
+	Patches       []Patch               `json:"patches"`
+}
+
+// LoadSchemaSet reads schema fragments from paths and merges them in the
+// order given. A path that is a directory contributes its *.json files in
+// name order, so a mod's mods/<mod>/schema directory can be passed as is.
+//
+// Fragments have the shape of schema.json plus an optional "patches"
+// array. The merged schemaVersion is the highest any fragment declares; at
+// least one must declare it. A component, entity type, resource or prefab
+// defined by two fragments is a conflict, reported with both files, unless the
+// definitions are identical. Patches apply after every definition is
+// merged. Entity type defaults are applied as in LoadSchema; call Validate
+// on the result.
+func LoadSchemaSet(paths ...string) (*SchemaSet, error) {
+	files, err := expandSchemaPaths(paths)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("LoadSchemaSet: no schema files")
+	}
+
+	set := &SchemaSet{
+		Schema: DatabaseSchema{
+			Components:  make(map[string]Component),
+			EntityTypes: make(map[string]EntityType),
+		},
+		Files:       files,
+		Components:  make(map[string]Source),
+		EntityTypes: make(map[string]Source),
+		Resources:   make(map[string]Source),
+		Prefabs:     make(map[string]Source),
+		Patches:     make(map[string][]Source),
+	}
+	componentKeys := make(map[string]string) // lowercase name → declared name
+	resourceKeys := make(map[string]string)
+	type pendingPatch struct {
+		Patch
+		source Source
+	}
+	var patches []pendingPatch
+	hash := sha256.New()
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("reading schema fragment %q: %w", file, err)
+		}
+		hash.Write(data)
+
+		frag, lines, err := loadFragment(data)
+		if err != nil {
+			return nil, fmt.Errorf("loading schema fragment %q: %w", file, err)
+		}
+
+		if len(frag.SchemaVersion) > 0 && string(frag.SchemaVersion) != "null" {
+			version, err := parseSchemaVersion(frag.SchemaVersion)
+			if err != nil {
+				return nil, fmt.Errorf("loading schema fragment %q: %w", file, err)
+			}
+			if version > set.Schema.SchemaVersion {
+				set.Schema.SchemaVersion = version
+			}
+		}
+
+		for _, name := range sortedKeys(frag.Components) {
+			comp := frag.Components[name]
+			src := Source{File: file, Line: lines.components[name]}
+			// Component tables are named case-insensitively, so "Health"
+			// and "health" collide.
+			if prev, ok := componentKeys[strings.ToLower(name)]; ok {
+				if prev == name && reflect.DeepEqual(set.Schema.Components[prev], comp) {
+					continue
+				}
+				return nil, fmt.Errorf("component %q is defined in both %s and %s",
+					name, set.Components[prev], src)
+			}
+			componentKeys[strings.ToLower(name)] = name
+			set.Schema.Components[name] = comp
+			set.Components[name] = src
+		}
+
+		for _, name := range sortedKeys(frag.EntityTypes) {
+			et := frag.EntityTypes[name]
+			src := Source{File: file, Line: lines.entityTypes[name]}
+			if prevSrc, ok := set.EntityTypes[name]; ok {
+				if reflect.DeepEqual(set.Schema.EntityTypes[name], et) {
+					continue
+				}
+				return nil, fmt.Errorf("entityType %q is defined in both %s and %s; use a patch to extend it",
+					name, prevSrc, src)
+			}
+			set.Schema.EntityTypes[name] = et
+			set.EntityTypes[name] = src
+		}
+
+		for _, name := range sortedKeys(frag.Resources) {
+			res := frag.Resources[name]
+			src := Source{File: file, Line: lines.resources[name]}
+			if prev, ok := resourceKeys[strings.ToLower(name)]; ok {
+				if prev == name && reflect.DeepEqual(set.Schema.Resources[prev], res) {
+					continue
+				}
+				return nil, fmt.Errorf("resource %q is defined in both %s and %s",
+					name, set.Resources[prev], src)
+			}
+			if set.Schema.Resources == nil {
+				set.Schema.Resources = make(map[string]Resource)
+			}
+			resourceKeys[strings.ToLower(name)] = name
+			set.Schema.Resources[name] = res
+			set.Resources[name] = src
+		}
+
+		for _, name := range sortedKeys(frag.Prefabs) {
+			p := frag.Prefabs[name]
+			src := Source{File: file, Line: lines.prefabs[name]}
+			if prevSrc, ok := set.Prefabs[name]; ok {
+				if reflect.DeepEqual(set.Schema.Prefabs[name], p) {
+					continue
+				}
+				return nil, fmt.Errorf("prefab %q is defined in both %s and %s", name, prevSrc, src)
+			}
+			if set.Schema.Prefabs == nil {
+				set.Schema.Prefabs = make(map[string]Prefab)
+			}
+			set.Schema.Prefabs[name] = p
+			set.Prefabs[name] = src
+		}
+
+		for i, p := range frag.Patches {
+			patches = append(patches, pendingPatch{Patch: p, source: Source{File: file, Line: lines.patches[i]}})
+		}
+	}
+
+	if set.Schema.SchemaVersion == 0 {
+		return nil, fmt.Errorf("schemaVersion: not declared by any of %s", strings.Join(files, ", "))
+	}
+
+	for _, p := range patches {
+		if err := applyPatch(&set.Schema, p.Patch); err != nil {
+			return nil, &SourceError{Source: p.source, Err: err}
+		}
+		set.Patches[p.EntityType] = append(set.Patches[p.EntityType], p.source)
+	}
+
+	applyEntityTypeDefaults(set.Schema.EntityTypes)
+	set.Hash = hex.EncodeToString(hash.Sum(nil))
+	return set, nil
+}
+
+// applyPatch applies p to s in place.
+func applyPatch(s *DatabaseSchema, p Patch) error {
+	switch p.Op {
+	case PatchAddOptionalComponent:
+		et, ok := s.EntityTypes[p.EntityType]
+		if !ok {
+			return fmt.Errorf("patch %s: entityType %q is not defined", p.Op, p.EntityType)
+		}
+		if _, ok := s.Components[p.Component]; !ok {
+			return fmt.Errorf("patch %s: entityType %q: component %q is not defined", p.Op, p.EntityType, p.Component)
+		}
+		if et.IsComponentRequired(p.Component) {
+			return fmt.Errorf("patch %s: entityType %q already requires component %q", p.Op, p.EntityType, p.Component)
+		}
+		if !et.IsComponentOptional(p.Component) {
+			et.OptionalComponents = append(append([]string(nil), et.OptionalComponents...), p.Component)
+		}
+		s.EntityTypes[p.EntityType] = et
+		return nil
+	case "":
+		return fmt.Errorf("patch: op is required")
+	}
+	return fmt.Errorf("patch: unknown op %q (must be %q)", p.Op, PatchAddOptionalComponent)
+}
+
+// definitionRef finds the first definition a validation error names.
+var definitionRef = regexp.MustCompile(`(component|entityType|entity type|resource|prefab) "([^"]+)"`)
+
+// Validate runs ValidateSchema on the merged schema. An error about a
+// component, entity type, resource or prefab is returned as a *SourceError pointing at the
+// file and line that defined it.
+func (ss *SchemaSet) Validate() error {
+	err := ValidateSchema(ss.Schema)
+	if err == nil {
+		return nil
+	}
+	if src, ok := ss.sourceOf(err.Error()); ok {
+		return &SourceError{Source: src, Err: err}
+	}
+	return err
+}
+
+// sourceOf returns the source of the first definition named in msg.
+func (ss *SchemaSet) sourceOf(msg string) (Source, bool) {
+	for _, m := range definitionRef.FindAllStringSubmatch(msg, -1) {
+		sources := ss.EntityTypes
+		switch m[1] {
+		case "component":
+			sources = ss.Components
+		case "resource":
+			sources = ss.Resources
+		case "prefab":
+			sources = ss.Prefabs
+		}
+		if src, ok := sources[m[2]]; ok {
+			return src, true
+		}
+	}
+	return Source{}, false
+}
+
+// expandSchemaPaths replaces each directory in paths with its *.json files
+// in name order.
+func expandSchemaPaths(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading schema path: %w", err)
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(path, "*.json"))
+		if err != nil {
+			return nil, fmt.Errorf("listing schema fragments in %q: %w", path, err)
+		}
+		sort.Strings(matches)
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+// fragmentLines holds the 1-based line of each definition in a fragment.
+type fragmentLines struct {
+	components  map[string]int
+	entityTypes map[string]int
+	resources   map[string]int
+	prefabs     map[string]int
+	patches     []int
+}
+
+// loadFragment parses one fragment and locates its definitions.
+func loadFragment(data []byte) (fragment, fragmentLines, error) {
+	if err := detectDuplicateKeys(data); err != nil {
+		return fragment{}, fragmentLines{}, err
+	}
+	var frag fragment
+	if err := json.Unmarshal(data, &frag); err != nil {
+		return fragment{}, fragmentLines{}, fmt.Errorf("failed to parse fragment: %w", err)
+	}
+	lines, err := scanFragmentLines(data)
+	if err != nil {
+		return fragment{}, fragmentLines{}, err
+	}
+	return frag, lines, nil
+}
+
+// scanFragmentLines walks the token stream of a fragment and records the
+// line of every components, entityTypes, resources and prefabs key and of
+// every patch.
+func scanFragmentLines(data []byte) (fragmentLines, error) {
+	lines := fragmentLines{
+		components:  make(map[string]int),
+		entityTypes: make(map[string]int),
+		resources:   make(map[string]int),
+		prefabs:     make(map[string]int),
+	}
+	lineAt := func(offset int64) int {
+		return bytes.Count(data[:offset], []byte("\n")) + 1
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil {
+		return lines, err
+	}
+	for dec.More() {
+		keyTok, err := dec.Token()
+		if err != nil {
+			return lines, err
+		}
+		switch keyTok {
+		case "components", "entityTypes", "resources", "prefabs":
+			target := lines.components
+			switch keyTok {
+			case "entityTypes":
+				target = lines.entityTypes
+			case "resources":
+				target = lines.resources
+			case "prefabs":
+				target = lines.prefabs
+			}
+			if delim, err := dec.Token(); err != nil {
+				return lines, err
+			} else if delim != json.Delim('{') {
+				continue // a scalar; json.Unmarshal already rejected it
+			}
+			for dec.More() {
+				nameTok, err := dec.Token()
+				if err != nil {
+					return lines, err
+				}
+				if name, ok := nameTok.(string); ok {
+					target[name] = lineAt(dec.InputOffset())
+				}
+				if err := skipValue(dec); err != nil {
+					return lines, err
+				}
+			}
+			if _, err := dec.Token(); err != nil {
+				return lines, err
+			}
+		case "patches":
+			if delim, err := dec.Token(); err != nil {
+				return lines, err
+			} else if delim != json.Delim('[') {
+				continue
+			}
+			for dec.More() {
+				tok, err := dec.Token()
+				if err != nil {
+					return lines, err
+				}
+				lines.patches = append(lines.patches, lineAt(dec.InputOffset()))
+				if delim, ok := tok.(json.Delim); ok && (delim == '{' || delim == '[') {
+					if err := skipContainer(dec); err != nil {
+						return lines, err
+					}
+				}
+			}
+			if _, err := dec.Token(); err != nil {
+				return lines, err
+			}
+		default:
+			if err := skipValue(dec); err != nil {
+				return lines, err
+			}
+		}
+	}
+	return lines, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
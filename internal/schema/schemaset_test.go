@@ -0,0 +1,273 @@
+package schema
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const baseFragment = `{
+  "schemaVersion": 2,
+  "components": {
+    "Position": {
+      "type": "object",
+      "properties": {"x": {"type": "number"}, "y": {"type": "number"}}
+    },
+    "Health": {"type": "integer"}
+  },
+  "entityTypes": {
+    "Goblin": {"requiredComponents": ["Position", "Health"]}
+  }
+}`
+
+// writeFragments writes name → content under dir and returns dir.
+func writeFragments(t *testing.T, dir string, files map[string]string) string {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadSchemaSet_MergesModFragments(t *testing.T) {
+	root := t.TempDir()
+	writeFragments(t, root, map[string]string{"schema.json": baseFragment})
+	modDir := writeFragments(t, filepath.Join(root, "mods", "lanterns", "schema"), map[string]string{
+		"components.json": `{
+  "components": {
+    "Lantern": {"type": "tag"}
+  }
+}`,
+		"goblins.json": `{
+  "schemaVersion": 3,
+  "entityTypes": {
+    "GoblinScout": {"extends": "Goblin", "requiredComponents": ["Lantern"]}
+  },
+  "patches": [
+    {"op": "addOptionalComponent", "entityType": "Goblin", "component": "Lantern"}
+  ]
+}`,
+	})
+
+	set, err := LoadSchemaSet(filepath.Join(root, "schema.json"), modDir)
+	if err != nil {
+		t.Fatalf("LoadSchemaSet: %v", err)
+	}
+	if err := set.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	if set.Schema.SchemaVersion != 3 {
+		t.Errorf("SchemaVersion = %d, want 3 (highest declared)", set.Schema.SchemaVersion)
+	}
+	wantFiles := []string{
+		filepath.Join(root, "schema.json"),
+		filepath.Join(modDir, "components.json"),
+		filepath.Join(modDir, "goblins.json"),
+	}
+	if strings.Join(set.Files, "|") != strings.Join(wantFiles, "|") {
+		t.Errorf("Files = %v, want %v", set.Files, wantFiles)
+	}
+
+	goblin := set.Schema.EntityTypes["Goblin"]
+	if !goblin.IsComponentOptional("Lantern") {
+		t.Errorf("Goblin optional = %v, want Lantern patched in", goblin.OptionalComponents)
+	}
+	if goblin.ValidationLevel != ValidationStrict {
+		t.Errorf("Goblin ValidationLevel = %q, want default %q", goblin.ValidationLevel, ValidationStrict)
+	}
+
+	for name, want := range map[string]Source{
+		"Position": {File: wantFiles[0], Line: 4},
+		"Health":   {File: wantFiles[0], Line: 8},
+		"Lantern":  {File: wantFiles[1], Line: 3},
+	} {
+		if got := set.Components[name]; got != want {
+			t.Errorf("Components[%q] = %v, want %v", name, got, want)
+		}
+	}
+	if got, want := set.EntityTypes["GoblinScout"], (Source{File: wantFiles[2], Line: 4}); got != want {
+		t.Errorf("EntityTypes[GoblinScout] = %v, want %v", got, want)
+	}
+	if got := set.Patches["Goblin"]; len(got) != 1 || got[0] != (Source{File: wantFiles[2], Line: 7}) {
+		t.Errorf("Patches[Goblin] = %v, want [%s:7]", got, wantFiles[2])
+	}
+}
+
+func TestLoadSchemaSet_SingleFileMatchesLoadSchema(t *testing.T) {
+	path := filepath.Join(writeFragments(t, t.TempDir(), map[string]string{"schema.json": baseFragment}), "schema.json")
+	set, err := LoadSchemaSet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	single, err := LoadSchema([]byte(baseFragment))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set.Schema.SchemaVersion != single.SchemaVersion ||
+		len(set.Schema.Components) != len(single.Components) ||
+		len(set.Schema.EntityTypes) != len(single.EntityTypes) {
+		t.Errorf("set schema = %+v, want %+v", set.Schema, single)
+	}
+	if len(set.Hash) != 64 {
+		t.Errorf("Hash = %q, want a SHA-256 hex digest", set.Hash)
+	}
+}
+
+func TestLoadSchemaSet_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		mod     string
+		wantErr []string
+	}{
+		{
+			name: "conflicting component",
+			mod:  `{"components": {"Health": {"type": "number"}}}`,
+			wantErr: []string{`component "Health" is defined in both`,
+				filepath.Join("base", "schema.json") + ":8", filepath.Join("mod", "a.json") + ":1"},
+		},
+		{
+			name:    "component differing only in case",
+			mod:     `{"components": {"health": {"type": "integer"}}}`,
+			wantErr: []string{`component "health" is defined in both`},
+		},
+		{
+			name:    "conflicting entity type",
+			mod:     `{"entityTypes": {"Goblin": {"requiredComponents": ["Health"]}}}`,
+			wantErr: []string{`entityType "Goblin" is defined in both`, "use a patch"},
+		},
+		{
+			name:    "patch unknown entity type",
+			mod:     "{\n\"patches\": [\n{\"op\": \"addOptionalComponent\", \"entityType\": \"Orc\", \"component\": \"Health\"}]}",
+			wantErr: []string{filepath.Join("mod", "a.json") + ":3", `entityType "Orc" is not defined`},
+		},
+		{
+			name:    "patch unknown component",
+			mod:     `{"patches": [{"op": "addOptionalComponent", "entityType": "Goblin", "component": "Wings"}]}`,
+			wantErr: []string{`component "Wings" is not defined`},
+		},
+		{
+			name:    "patch already required",
+			mod:     `{"patches": [{"op": "addOptionalComponent", "entityType": "Goblin", "component": "Health"}]}`,
+			wantErr: []string{`already requires component "Health"`},
+		},
+		{
+			name:    "unknown patch op",
+			mod:     `{"patches": [{"op": "removeEntityType", "entityType": "Goblin"}]}`,
+			wantErr: []string{`unknown op "removeEntityType"`},
+		},
+		{
+			name:    "bad schemaVersion",
+			mod:     `{"schemaVersion": 1.5}`,
+			wantErr: []string{"a.json", "schemaVersion: expected integer"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			base := writeFragments(t, filepath.Join(root, "base"), map[string]string{"schema.json": baseFragment})
+			mod := writeFragments(t, filepath.Join(root, "mod"), map[string]string{"a.json": tt.mod})
+
+			_, err := LoadSchemaSet(filepath.Join(base, "schema.json"), mod)
+			if err == nil {
+				t.Fatal("want error, got nil")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("err = %v, want containing %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadSchemaSet_IdenticalRedefinitionAllowed(t *testing.T) {
+	root := t.TempDir()
+	base := writeFragments(t, filepath.Join(root, "base"), map[string]string{"schema.json": baseFragment})
+	mod := writeFragments(t, filepath.Join(root, "mod"), map[string]string{
+		"a.json": `{"components": {"Health": {"type": "integer"}}}`,
+	})
+
+	set, err := LoadSchemaSet(filepath.Join(base, "schema.json"), mod)
+	if err != nil {
+		t.Fatalf("LoadSchemaSet: %v", err)
+	}
+	if got := set.Components["Health"].File; got != filepath.Join(base, "schema.json") {
+		t.Errorf("Health source = %q, want the first definition", got)
+	}
+}
+
+func TestLoadSchemaSet_NoSchemaVersion(t *testing.T) {
+	dir := writeFragments(t, t.TempDir(), map[string]string{
+		"a.json": `{"components": {"Health": {"type": "integer"}}}`,
+	})
+	_, err := LoadSchemaSet(dir)
+	if err == nil || !strings.Contains(err.Error(), "schemaVersion: not declared") {
+		t.Fatalf("err = %v, want schemaVersion not declared", err)
+	}
+}
+
+func TestSchemaSet_ValidateReportsSource(t *testing.T) {
+	root := t.TempDir()
+	base := writeFragments(t, filepath.Join(root, "base"), map[string]string{"schema.json": baseFragment})
+	mod := writeFragments(t, filepath.Join(root, "mod"), map[string]string{
+		"a.json": `{
+  "entityTypes": {
+    "Hobgoblin": {"requiredComponents": ["Wings"]}
+  }
+}`,
+	})
+
+	set, err := LoadSchemaSet(filepath.Join(base, "schema.json"), mod)
+	if err != nil {
+		t.Fatalf("LoadSchemaSet: %v", err)
+	}
+	err = set.Validate()
+	var srcErr *SourceError
+	if !errors.As(err, &srcErr) {
+		t.Fatalf("Validate err = %v, want *SourceError", err)
+	}
+	want := Source{File: filepath.Join(mod, "a.json"), Line: 3}
+	if srcErr.Source != want {
+		t.Errorf("Source = %v, want %v", srcErr.Source, want)
+	}
+	if !strings.Contains(err.Error(), `undeclared component "Wings"`) {
+		t.Errorf("err = %v, want the validation message kept", err)
+	}
+}
+
+func TestLoadSchemaSet_Resources(t *testing.T) {
+	root := t.TempDir()
+	base := writeFragments(t, filepath.Join(root, "base"), map[string]string{"schema.json": baseFragment})
+	mod := writeFragments(t, filepath.Join(root, "mod"), map[string]string{
+		"a.json": `{
+  "resources": {
+    "Clock": {"properties": {"tick": {"type": "integer", "default": 0}}}
+  }
+}`,
+		"b.json": `{"resources": {"clock": {"properties": {"hour": {"type": "integer", "default": 0}}}}}`,
+	})
+
+	set, err := LoadSchemaSet(filepath.Join(base, "schema.json"), filepath.Join(mod, "a.json"))
+	if err != nil {
+		t.Fatalf("LoadSchemaSet: %v", err)
+	}
+	if _, ok := set.Schema.Resources["Clock"]; !ok {
+		t.Errorf("Resources = %v, want Clock merged", set.Schema.Resources)
+	}
+	if got, want := set.Resources["Clock"], (Source{File: filepath.Join(mod, "a.json"), Line: 3}); got != want {
+		t.Errorf("Resources[Clock] = %v, want %v", got, want)
+	}
+
+	_, err = LoadSchemaSet(filepath.Join(base, "schema.json"), mod)
+	if err == nil || !strings.Contains(err.Error(), `resource "clock" is defined in both`) {
+		t.Errorf("err = %v, want a resource conflict", err)
+	}
+}
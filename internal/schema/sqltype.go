@@ -22,3 +22,11 @@ func PropertySQLType(p Property) string {
 		return "TEXT"
 	}
 }
+
+// PropertyNotNull reports whether the column for p carries a NOT NULL
+// constraint. Like PropertySQLType it is shared by DDL generation and the
+// diff so that both agree on what an up-to-date column looks like.
+// Computed properties never do: their expression may evaluate to NULL.
+func PropertyNotNull(p Property) bool {
+	return !p.Nullable && !p.IsComputed()
+}
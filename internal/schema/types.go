@@ -14,12 +14,16 @@ type DatabaseSchema struct {
 	SchemaVersion int                   `json:"schemaVersion"`
 	Components    map[string]Component  `json:"components"`
 	EntityTypes   map[string]EntityType `json:"entityTypes"`
+	Resources     map[string]Resource   `json:"resources,omitempty"`
+	Prefabs       map[string]Prefab     `json:"prefabs,omitempty"`
 }
 
 // EntityType is a named template declaring which components an entity of
 // that type must have, may have, and whether additional components are
-// permitted after creation.
+// permitted after creation. An entity type that extends another inherits
+// its parent's components and settings; see ResolveEntityType.
 type EntityType struct {
+	Extends              string          `json:"extends,omitempty"`
 	Behavior             string          `json:"behavior,omitempty"`
 	RequiredComponents   []string        `json:"requiredComponents"`
 	OptionalComponents   []string        `json:"optionalComponents"`
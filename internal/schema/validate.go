@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
@@ -20,6 +21,8 @@ var knownSQLComponentTypes = map[string]bool{
 	ComponentTypeInteger:   true,
 	ComponentTypeNumber:    true,
 	ComponentTypeBoolean:   true,
+	ComponentTypeTag:       true,
+	ComponentTypeRelation:  true,
 }
 
 // LoadSchema parses schema.json bytes into a DatabaseSchema.
@@ -36,40 +39,62 @@ func LoadSchema(jsonData []byte) (DatabaseSchema, error) {
 		SchemaVersion json.RawMessage       `json:"schemaVersion"`
 		Components    map[string]Component  `json:"components"`
 		EntityTypes   map[string]EntityType `json:"entityTypes"`
+		Resources     map[string]Resource   `json:"resources"`
+		Prefabs       map[string]Prefab     `json:"prefabs"`
 	}
 	if err := json.Unmarshal(jsonData, &raw); err != nil {
 		return DatabaseSchema{}, fmt.Errorf("failed to parse schema.json: %w", err)
 	}
 
-	// ── schemaVersion: must be a JSON integer ──
+	version, err := parseSchemaVersion(raw.SchemaVersion)
+	if err != nil {
+		return DatabaseSchema{}, err
+	}
+	applyEntityTypeDefaults(raw.EntityTypes)
+
+	return DatabaseSchema{
+		SchemaVersion: version,
+		Components:    raw.Components,
+		EntityTypes:   raw.EntityTypes,
+		Resources:     raw.Resources,
+		Prefabs:       raw.Prefabs,
+	}, nil
+}
+
+// parseSchemaVersion decodes schemaVersion, which must be a JSON integer
+// >= 1.
+func parseSchemaVersion(raw json.RawMessage) (int, error) {
 	var version float64
-	if err := json.Unmarshal(raw.SchemaVersion, &version); err != nil {
-		return DatabaseSchema{}, fmt.Errorf("schemaVersion: expected integer, got %s", string(raw.SchemaVersion))
+	if err := json.Unmarshal(raw, &version); err != nil {
+		return 0, fmt.Errorf("schemaVersion: expected integer, got %s", string(raw))
 	}
 	if version != float64(int(version)) {
-		return DatabaseSchema{}, fmt.Errorf("schemaVersion: expected integer, got %g", version)
+		return 0, fmt.Errorf("schemaVersion: expected integer, got %g", version)
 	}
 	if int(version) < 1 {
-		return DatabaseSchema{}, fmt.Errorf("schemaVersion: must be >= 1, got %d", int(version))
+		return 0, fmt.Errorf("schemaVersion: must be >= 1, got %d", int(version))
 	}
+	return int(version), nil
+}
 
-	// ── Entity type defaults ──
-	for i, et := range raw.EntityTypes {
+// applyEntityTypeDefaults applies EntityType.ApplyDefaults in place. A
+// subtype that omits validationLevel inherits its parent's, so defaults
+// only apply to the roots of an extends chain.
+func applyEntityTypeDefaults(ets map[string]EntityType) {
+	for name, et := range ets {
+		if et.Extends != "" {
+			continue
+		}
 		et.ApplyDefaults()
-		raw.EntityTypes[i] = et
+		ets[name] = et
 	}
-
-	return DatabaseSchema{
-		SchemaVersion: int(version),
-		Components:    raw.Components,
-		EntityTypes:   raw.EntityTypes,
-	}, nil
 }
 
 // detectDuplicateKeys uses a token stream to detect when the top-level
-// "components" or "entityTypes" maps contain the same key more than once.
-// Go's json.Unmarshal silently takes the last value for duplicate keys, so
-// we must pre-validate with raw tokens before unmarshalling.
+// "components", "entityTypes", "resources" or "prefabs" maps contain the
+// same key more than once. Go's json.Unmarshal silently takes the last value for
+// duplicate keys, so we must pre-validate with raw tokens before
+// unmarshalling.
 func detectDuplicateKeys(jsonData []byte) error {
 	dec := json.NewDecoder(strings.NewReader(string(jsonData)))
 
@@ -88,7 +113,7 @@ func detectDuplicateKeys(jsonData []byte) error {
 		if !ok {
 			continue
 		}
-		if key != "components" && key != "entityTypes" {
+		if key != "components" && key != "entityTypes" && key != "resources" && key != "prefabs" {
 			// Not a map we care about — skip its value entirely.
 			if err := skipValue(dec); err != nil {
 				return fmt.Errorf("detectDuplicateKeys: %w", err)
@@ -185,7 +210,7 @@ func skipContainer(dec *json.Decoder) error {
 }
 
 // ValidateSchema performs semantic validation on a loaded schema.
-// It runs three validation phases in order, short-circuiting on the
+// It runs four validation phases in order, short-circuiting on the
 // first error encountered.
 func ValidateSchema(s DatabaseSchema) error {
 	if err := validateStructure(s); err != nil {
@@ -197,6 +222,9 @@ func ValidateSchema(s DatabaseSchema) error {
 	if err := validateSQLCompatibility(s); err != nil {
 		return fmt.Errorf("SQL compatibility: %w", err)
 	}
+	if err := validatePropertyConstraints(s); err != nil {
+		return fmt.Errorf("property constraints: %w", err)
+	}
 	return nil
 }
 
@@ -220,17 +248,49 @@ func validateStructure(s DatabaseSchema) error {
 	return nil
 }
 
-// validateCrossReference checks that entity type references resolve to
-// declared components, that required ∩ optional is empty, and that
-// validationLevel values are valid.
+// validateCrossReference checks that extends chains resolve without
+// cycles, that entity type references resolve to declared components, that
+// required ∩ optional is empty, that validationLevel values are valid, that
+// renamedFrom hints are unambiguous, that refType names a declared
+// entity type, that computed expressions reference sibling properties,
+// that resources, relations and prefabs are well formed, and that no
+// entity type lists a relation. Entity types are checked with their inherited fields
+// merged.
 func validateCrossReference(s DatabaseSchema) error {
-	for typeName, et := range s.EntityTypes {
+	if err := validateRenameHints(s); err != nil {
+		return err
+	}
+	if err := validateReferences(s); err != nil {
+		return err
+	}
+	if err := validateInheritance(s); err != nil {
+		return err
+	}
+	if err := validateComputedProperties(s); err != nil {
+		return err
+	}
+	if err := validateResources(s); err != nil {
+		return err
+	}
+	if err := validateRelations(s); err != nil {
+		return err
+	}
+	if err := validatePrefabs(s); err != nil {
+		return err
+	}
+	for typeName := range s.EntityTypes {
+		et, _ := ResolveEntityType(&s, typeName)
 		allComponents := append(et.RequiredComponents, et.OptionalComponents...)
 		for _, compName := range allComponents {
-			if _, ok := s.Components[compName]; !ok {
+			comp, ok := s.Components[compName]
+			if !ok {
 				return fmt.Errorf("entityType %q references undeclared component %q",
 					typeName, compName)
 			}
+			if comp.Type == ComponentTypeRelation {
+				return fmt.Errorf("entityType %q: component %q is a relation; relations are added between entities, not listed on entity types",
+					typeName, compName)
+			}
 		}
 
 		for _, req := range et.RequiredComponents {
@@ -253,10 +313,58 @@ func validateCrossReference(s DatabaseSchema) error {
 	return nil
 }
 
+// validateRenameHints checks that no component renamedFrom names a
+// component that is still declared, that no property renamedFrom names a
+// sibling property, and that no old name is claimed twice. Names compare
+// case-insensitively, like the comp_* tables and columns they map to.
+func validateRenameHints(s DatabaseSchema) error {
+	compNames := make(map[string]bool, len(s.Components))
+	for name := range s.Components {
+		compNames[strings.ToLower(name)] = true
+	}
+	claimed := make(map[string]string)
+	for _, name := range sortedComponentNames(s) {
+		comp := s.Components[name]
+		if old := strings.ToLower(comp.RenamedFrom); old != "" {
+			if compNames[old] {
+				return fmt.Errorf("component %q: renamedFrom %q names a component that is still declared",
+					name, comp.RenamedFrom)
+			}
+			if prev, ok := claimed[old]; ok {
+				return fmt.Errorf("components %q and %q are both renamedFrom %q",
+					prev, name, comp.RenamedFrom)
+			}
+			claimed[old] = name
+		}
+
+		propNames := make(map[string]bool, len(comp.Properties))
+		for propName := range comp.Properties {
+			propNames[strings.ToLower(propName)] = true
+		}
+		propClaimed := make(map[string]string)
+		for _, propName := range sortedPropertyNames(comp.Properties) {
+			old := strings.ToLower(comp.Properties[propName].RenamedFrom)
+			if old == "" {
+				continue
+			}
+			if propNames[old] {
+				return fmt.Errorf("component %q property %q: renamedFrom %q names a property that is still declared",
+					name, propName, comp.Properties[propName].RenamedFrom)
+			}
+			if prev, ok := propClaimed[old]; ok {
+				return fmt.Errorf("component %q: properties %q and %q are both renamedFrom %q",
+					name, prev, propName, comp.Properties[propName].RenamedFrom)
+			}
+			propClaimed[old] = propName
+		}
+	}
+	return nil
+}
+
 // validateSQLCompatibility checks that every component's type can be
-// mapped to a SQL column by the storage layer. This catches cases where
-// a component declares a type that has no corresponding CREATE TABLE
-// generator.
+// mapped to a SQL column by the storage layer and that its declared
+// indexes can be created. This catches cases where a component declares a
+// type that has no corresponding CREATE TABLE generator.
 func validateSQLCompatibility(s DatabaseSchema) error {
 	for name, comp := range s.Components {
 		if !knownSQLComponentTypes[comp.Type] {
@@ -264,6 +372,43 @@ func validateSQLCompatibility(s DatabaseSchema) error {
 				name, comp.Type)
 		}
 	}
+	return validateIndexes(s)
+}
+
+// validatePropertyConstraints checks the minimum/maximum/enum/pattern/
+// minLength/maxLength and default keywords on every component property.
+// Schemas built in Go bypass Component.UnmarshalJSON, so this phase cannot
+// rely on the checks already done at load time.
+func validatePropertyConstraints(s DatabaseSchema) error {
+	for compName, comp := range s.Components {
+		for propName, prop := range comp.Properties {
+			if err := validateNestedConstraints(prop); err != nil {
+				return fmt.Errorf("component %q property %q: %w", compName, propName, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateNestedConstraints runs validateConstraints and validateDefault on
+// p and every nested property or array item beneath it.
+func validateNestedConstraints(p Property) error {
+	if err := p.validateConstraints(); err != nil {
+		return err
+	}
+	if err := p.validateDefault(); err != nil {
+		return err
+	}
+	for name, child := range p.Properties {
+		if err := validateNestedConstraints(child); err != nil {
+			return fmt.Errorf("property %q: %w", name, err)
+		}
+	}
+	if p.Items != nil {
+		if err := validateNestedConstraints(*p.Items); err != nil {
+			return fmt.Errorf("items: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -321,3 +466,24 @@ func InitSchema(path string) (DatabaseSchema, error) {
 	}
 	return s, nil
 }
+
+// sortedComponentNames returns the component names of s in sorted order so
+// that validation errors are deterministic.
+func sortedComponentNames(s DatabaseSchema) []string {
+	names := make([]string, 0, len(s.Components))
+	for name := range s.Components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sortedPropertyNames returns the keys of props in sorted order.
+func sortedPropertyNames(props map[string]Property) []string {
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -361,3 +361,41 @@ func createGoblinWithVelocity(ctx context.Context, store *SQLiteStore) (*world.E
 		{Name: "Velocity", Values: map[string]interface{}{"dx": 1.0, "dy": 2.0}},
 	})
 }
+
+func TestEntityService_AttachComponent_FillsDeclaredDefaults(t *testing.T) {
+	s := adSchema()
+	s.Components["Health"] = schema.Component{
+		Type: schema.ComponentTypeObject,
+		Properties: map[string]schema.Property{
+			"hp":    {Type: schema.PropertyTypeInteger},
+			"maxHp": {Type: schema.PropertyTypeInteger, Default: 100.0},
+		},
+	}
+	et := s.EntityTypes["Goblin"]
+	et.RequiredComponents = []string{"Position"}
+	et.OptionalComponents = []string{"Health"}
+	s.EntityTypes["Goblin"] = et
+
+	store := makeStore(t, s)
+	ctx := context.Background()
+	svc := world.NewEntityService(store)
+	svc.SetSchema(s)
+
+	e, err := svc.CreateEntity(ctx, "Goblin", []world.EntityComponent{
+		{Name: "Position", Values: map[string]interface{}{"x": 1.0, "y": 2.0}},
+	})
+	if err != nil {
+		t.Fatalf("CreateEntity: %v", err)
+	}
+	if err := svc.AttachComponent(ctx, e.ID, "Health", map[string]interface{}{"hp": 30}); err != nil {
+		t.Fatalf("AttachComponent with only hp: %v", err)
+	}
+
+	var hp, maxHP int
+	if err := store.db.QueryRow("SELECT hp, maxhp FROM comp_health WHERE entity_id = ?", e.ID).Scan(&hp, &maxHP); err != nil {
+		t.Fatal(err)
+	}
+	if hp != 30 || maxHP != 100 {
+		t.Errorf("health = (%d, %d), want (30, 100)", hp, maxHP)
+	}
+}
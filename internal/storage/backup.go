@@ -3,11 +3,13 @@ package storage
 import (
 	"database/sql"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // backupDatabase creates a copy of the database at {dbPath}.bak.v{version}
@@ -15,7 +17,7 @@ import (
 // file. Any pre-existing backup at that path is removed first.
 // Returns the backup path on success.
 func backupDatabase(db *sql.DB, dbPath string, version int) (string, error) {
-	backupPath := fmt.Sprintf("%s.bak.v%d", dbPath, version)
+	backupPath := backupPathFor(dbPath, version)
 	_ = os.Remove(backupPath)
 	// Escape single quotes in the path to avoid SQL injection.
 	escaped := strings.ReplaceAll(backupPath, "'", "''")
@@ -25,39 +27,159 @@ func backupDatabase(db *sql.DB, dbPath string, version int) (string, error) {
 	return backupPath, nil
 }
 
-// pruneBackups removes old backups for the database at dbPath, keeping the
-// newest retention backup files. Files that do not match the versioned naming
-// pattern are ignored. Deletion failures are logged but do not return an error.
-func pruneBackups(dbPath string, retention int, logger MigrationLogger) {
-	pattern := dbPath + ".bak.v*"
-	matches, err := filepath.Glob(pattern)
+// backupPathFor returns the path of the backup taken before migrating the
+// database at dbPath away from version.
+func backupPathFor(dbPath string, version int) string {
+	return fmt.Sprintf("%s.bak.v%d", dbPath, version)
+}
+
+// BackupInfo describes one versioned backup of a database.
+type BackupInfo struct {
+	Path    string
+	Version int // schema version the database had when the backup was taken
+	Size    int64
+	ModTime time.Time
+}
+
+// ListBackups returns the versioned backups ({dbPath}.bak.vN) of the
+// database at dbPath, oldest version first. Files whose suffix is not a
+// number are ignored.
+func ListBackups(dbPath string) ([]BackupInfo, error) {
+	matches, err := filepath.Glob(dbPath + ".bak.v*")
 	if err != nil {
-		return
+		return nil, fmt.Errorf("listing backups: %w", err)
 	}
+	// Glob cleans the paths it returns ("./x" becomes "x"), so compare
+	// base names.
+	prefix := filepath.Base(dbPath) + ".bak.v"
 
-	type backup struct {
-		path    string
-		version int
-	}
-	prefix := dbPath + ".bak.v"
-	backups := make([]backup, 0, len(matches))
+	backups := make([]BackupInfo, 0, len(matches))
 	for _, m := range matches {
-		vStr := strings.TrimPrefix(m, prefix)
-		v, err := strconv.Atoi(vStr)
+		v, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(m), prefix))
 		if err != nil {
 			continue // skip non-numeric suffixes
 		}
-		backups = append(backups, backup{path: m, version: v})
+		fi, err := os.Stat(m)
+		if err != nil {
+			return nil, fmt.Errorf("stat backup %s: %w", m, err)
+		}
+		backups = append(backups, BackupInfo{Path: m, Version: v, Size: fi.Size(), ModTime: fi.ModTime()})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Version < backups[j].Version })
+	return backups, nil
+}
+
+// BackupVerificationError is returned when a backup (or a freshly migrated
+// database) fails verification.
+type BackupVerificationError struct {
+	Path   string
+	Reason string
+}
+
+func (e *BackupVerificationError) Error() string {
+	return fmt.Sprintf("verifying %s: %s", e.Path, e.Reason)
+}
+
+// VerifyBackup checks that the file at path is a healthy ecs-db database:
+// PRAGMA integrity_check passes and meta holds a readable schema_version,
+// which it returns. Failures are *BackupVerificationError.
+func VerifyBackup(path string) (int, error) {
+	if _, err := os.Stat(path); err != nil {
+		return 0, &BackupVerificationError{Path: path, Reason: err.Error()}
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return 0, &BackupVerificationError{Path: path, Reason: err.Error()}
+	}
+	defer func() { _ = db.Close() }()
+	return verifyDatabase(db, path)
+}
+
+// verifyDatabase runs PRAGMA integrity_check on db and reads its
+// schema_version. path is only used in errors.
+func verifyDatabase(db *sql.DB, path string) (int, error) {
+	var result string
+	if err := db.QueryRow("PRAGMA integrity_check").Scan(&result); err != nil {
+		return 0, &BackupVerificationError{Path: path, Reason: fmt.Sprintf("integrity_check: %v", err)}
+	}
+	if result != "ok" {
+		return 0, &BackupVerificationError{Path: path, Reason: "integrity_check: " + result}
+	}
+	version, err := ReadSchemaVersion(db)
+	if err != nil {
+		return 0, &BackupVerificationError{Path: path, Reason: err.Error()}
+	}
+	return version, nil
+}
+
+// RestoreBackup replaces the database at dbPath with its backup for
+// version. The backup is verified, copied next to dbPath and renamed over
+// it, so dbPath is never left half-written; the backup file itself is kept.
+// Stale -wal and -shm files of the replaced database are removed before the
+// swap so SQLite does not replay them into the restored file.
+//
+// Every connection to dbPath must be closed before calling RestoreBackup.
+func RestoreBackup(dbPath string, version int) error {
+	backupPath := backupPathFor(dbPath, version)
+	if _, err := VerifyBackup(backupPath); err != nil {
+		return err
+	}
+	return restoreFile(dbPath, backupPath)
+}
+
+// restoreFile atomically swaps the file at backupPath into dbPath.
+func restoreFile(dbPath, backupPath string) error {
+	tmpPath := dbPath + ".restore"
+	if err := copyFile(backupPath, tmpPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("copying backup: %w", err)
+	}
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(dbPath + suffix); err != nil && !os.IsNotExist(err) {
+			_ = os.Remove(tmpPath)
+			return fmt.Errorf("removing %s%s: %w", dbPath, suffix, err)
+		}
+	}
+	if err := os.Rename(tmpPath, dbPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("swapping in backup: %w", err)
 	}
+	return nil
+}
 
-	sort.Slice(backups, func(i, j int) bool { return backups[i].version < backups[j].version })
+// copyFile copies src to dst and syncs dst to disk.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
 
-	if len(backups) <= retention {
+// pruneBackups removes old backups for the database at dbPath, keeping the
+// newest retention backup files. Files that do not match the versioned naming
+// pattern are ignored. Deletion failures are logged but do not return an error.
+func pruneBackups(dbPath string, retention int, logger MigrationLogger) {
+	backups, err := ListBackups(dbPath)
+	if err != nil || len(backups) <= retention {
 		return
 	}
 	for _, b := range backups[:len(backups)-retention] {
-		if err := os.Remove(b.path); err != nil {
-			logger.Warnf("backup pruning: failed to remove %s: %v", b.path, err)
+		if err := os.Remove(b.Path); err != nil {
+			logger.Warnf("backup pruning: failed to remove %s: %v", b.Path, err)
 		}
 	}
 }
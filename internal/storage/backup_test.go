@@ -2,6 +2,7 @@ package storage
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
 	"testing"
@@ -244,3 +245,118 @@ func TestPruneBackups_LogsWarningOnRemoveFailure(t *testing.T) {
 		t.Error("expected Warnf call when os.Remove fails on a non-empty directory")
 	}
 }
+
+func TestListBackups_SortedAndRelativePaths(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	dbPath := "./world.sqlite"
+	for _, suffix := range []string{".bak.v10", ".bak.v2", ".bak.vfoo"} {
+		if err := os.WriteFile(dbPath+suffix, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	backups, err := ListBackups(dbPath)
+	if err != nil {
+		t.Fatalf("ListBackups: %v", err)
+	}
+	if len(backups) != 2 || backups[0].Version != 2 || backups[1].Version != 10 {
+		t.Fatalf("backups = %+v, want v2 then v10", backups)
+	}
+	if backups[0].Size != 1 || backups[0].ModTime.IsZero() {
+		t.Errorf("backup info = %+v, want size and mod time", backups[0])
+	}
+}
+
+func TestVerifyBackup_RejectsCorruptFile(t *testing.T) {
+	path := t.TempDir() + "/world.sqlite.bak.v1"
+	if err := os.WriteFile(path, []byte("not a database, just text padding it out"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var verr *BackupVerificationError
+	if _, err := VerifyBackup(path); !errors.As(err, &verr) {
+		t.Fatalf("expected *BackupVerificationError, got %T: %v", err, err)
+	}
+}
+
+func TestRestoreBackup_RoundTrip(t *testing.T) {
+	dbPath := t.TempDir() + "/world.sqlite"
+	s1 := historySchema(1, schema.EntityType{ValidationLevel: schema.ValidationStrict})
+	store, err := NewSQLiteStore(dbPath, s1, "")
+	if err != nil {
+		t.Fatalf("v1 open: %v", err)
+	}
+	if _, err := backupDatabase(store.DB(), dbPath, 1); err != nil {
+		t.Fatalf("backupDatabase: %v", err)
+	}
+	// Diverge from the backup after it was taken.
+	if _, err := store.DB().Exec("INSERT INTO entities (id, entity_type) VALUES (1, 'Goblin')"); err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Close()
+	if err := os.WriteFile(dbPath+"-wal", []byte("stale"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RestoreBackup(dbPath, 1); err != nil {
+		t.Fatalf("RestoreBackup: %v", err)
+	}
+	if _, err := os.Stat(dbPath + "-wal"); !os.IsNotExist(err) {
+		t.Error("stale -wal file should have been removed")
+	}
+	if _, err := os.Stat(dbPath + ".bak.v1"); err != nil {
+		t.Errorf("backup should be kept: %v", err)
+	}
+
+	db := openFileDB(t, dbPath)
+	var n int
+	if err := db.QueryRow("SELECT count(*) FROM entities").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Errorf("entities = %d, want 0 (the backup's state)", n)
+	}
+}
+
+func TestRestoreBackup_MissingVersion(t *testing.T) {
+	dbPath := t.TempDir() + "/world.sqlite"
+	var verr *BackupVerificationError
+	if err := RestoreBackup(dbPath, 3); !errors.As(err, &verr) {
+		t.Fatalf("expected *BackupVerificationError, got %T: %v", err, err)
+	}
+}
+
+func TestAutoRestore_FailedVerificationRestoresBackup(t *testing.T) {
+	withDataMigrations(t)
+	dbPath := t.TempDir() + "/world.sqlite"
+	s1 := historySchema(1, schema.EntityType{ValidationLevel: schema.ValidationStrict})
+	store, err := NewSQLiteStore(dbPath, s1, "")
+	if err != nil {
+		t.Fatalf("v1 open: %v", err)
+	}
+	_ = store.Close()
+
+	// The hp type change forces a rebuild, which runs with foreign keys off,
+	// so the hook's orphan row commits and only verification catches it.
+	RegisterDataMigration(1, 2, func(tx *sql.Tx) error {
+		_, err := tx.Exec("INSERT INTO comp_health (entity_id, hp) VALUES (999, 1)")
+		return err
+	})
+	s2 := historySchema(2, schema.EntityType{ValidationLevel: schema.ValidationStrict})
+	s2.Components["Health"] = schema.Component{
+		Type:       schema.ComponentTypeObject,
+		Properties: map[string]schema.Property{"hp": {Type: schema.PropertyTypeNumber}},
+	}
+
+	_, err = NewSQLiteStoreWithConfig(dbPath, StoreConfig{Schema: s2, BackupRetention: 1, AutoRestore: true})
+	var mverr *MigrationVerificationError
+	if !errors.As(err, &mverr) {
+		t.Fatalf("expected *MigrationVerificationError, got %T: %v", err, err)
+	}
+	if !mverr.Restored {
+		t.Errorf("Restored = false: %v", err)
+	}
+	if v, err := VerifyBackup(dbPath); err != nil || v != 1 {
+		t.Errorf("restored database version = %d, %v; want 1", v, err)
+	}
+}
@@ -2,14 +2,17 @@ package storage
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/tmbritton/ecs-db/internal/schema"
 )
 
 // componentTableSQL generates the CREATE TABLE statement for a single component.
-// Object components produce one typed column per property. Non-object components
-// produce a single "value" column with an appropriate SQL type.
+// Object components produce one typed column per property. Tag components have
+// only entity_id. Other components produce a single "value" column with an
+// appropriate SQL type.
 func componentTableSQL(name string, comp schema.Component) (string, error) {
 	sql := fmt.Sprintf("CREATE TABLE IF NOT EXISTS comp_%s (\n", strings.ToLower(name))
 	cols := []string{
@@ -19,13 +22,11 @@ func componentTableSQL(name string, comp schema.Component) (string, error) {
 	switch comp.Type {
 	case schema.ComponentTypeObject:
 		for propName, prop := range comp.Properties {
-			sqlType := propertySQLType(prop)
-			col := fmt.Sprintf("\t%s %s NOT NULL", strings.ToLower(propName), sqlType)
-			cols = append(cols, col)
+			cols = append(cols, "\t"+objectColumnDef(propName, prop))
 		}
 
 	case schema.ComponentTypeEntityRef:
-		cols = append(cols, "\ttarget_entity_id INTEGER NOT NULL REFERENCES entities(id)")
+		cols = append(cols, "\ttarget_entity_id INTEGER NOT NULL "+schema.ReferenceClause(comp.OnDelete))
 
 	case schema.ComponentTypeArray:
 		// Arrays are stored as JSON in a single column regardless of item type.
@@ -43,6 +44,9 @@ func componentTableSQL(name string, comp schema.Component) (string, error) {
 	case schema.ComponentTypeBoolean:
 		cols = append(cols, "\tvalue INTEGER NOT NULL DEFAULT 0")
 
+	case schema.ComponentTypeTag:
+		// A tag's presence is its only data.
+
 	default:
 		return "", fmt.Errorf("unsupported component type %q", comp.Type)
 	}
@@ -52,7 +56,174 @@ func componentTableSQL(name string, comp schema.Component) (string, error) {
 	return sql, nil
 }
 
+// resourceTableSQL generates the CREATE TABLE statement for a resource.
+// The table holds a single row, pinned to id 1 by a CHECK constraint, with
+// one column per property laid out as for an object component.
+func resourceTableSQL(name string, res schema.Resource) string {
+	return buildCreateTable("res_"+strings.ToLower(name), "", resourceColumns(res))
+}
+
+// resourceColumns returns the column definitions of a resource table in
+// property-name order.
+func resourceColumns(res schema.Resource) []string {
+	names := make([]string, 0, len(res.Properties))
+	for name := range res.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	cols := []string{"id INTEGER PRIMARY KEY CHECK (id = 1)"}
+	for _, propName := range names {
+		cols = append(cols, objectColumnDef(propName, res.Properties[propName]))
+	}
+	return cols
+}
+
+// resourceSeedSQL inserts a resource's single row. The schema requires
+// every resource property to be nullable or to declare a default, so the
+// column defaults fill it.
+func resourceSeedSQL(name string) string {
+	return fmt.Sprintf("INSERT OR IGNORE INTO res_%s (id) VALUES (1)", strings.ToLower(name))
+}
+
+// relationTableSQL generates the CREATE TABLE statement for a relation
+// component: one row per (source, target) pair, both foreign keys to
+// entities(id) that delete the row with either end, followed by one column
+// per property laid out as for an object component.
+func relationTableSQL(name string, comp schema.Component) string {
+	return buildCreateTable("rel_"+strings.ToLower(name), "", append(relationColumns(comp), relationKeyConstraint))
+}
+
+// relationKeyConstraint is the composite primary key of every rel_* table.
+const relationKeyConstraint = "PRIMARY KEY (source_id, target_id)"
+
+// relationColumns returns the column definitions of a relation table: the
+// two key columns, then the properties in property-name order.
+func relationColumns(comp schema.Component) []string {
+	names := make([]string, 0, len(comp.Properties))
+	for name := range comp.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	cols := []string{
+		"source_id INTEGER NOT NULL REFERENCES entities(id) ON DELETE CASCADE",
+		"target_id INTEGER NOT NULL REFERENCES entities(id) ON DELETE CASCADE",
+	}
+	for _, propName := range names {
+		cols = append(cols, objectColumnDef(propName, comp.Properties[propName]))
+	}
+	return cols
+}
+
+// relationIndexSQL creates the index on target_id that serves reverse
+// lookups ("every entity that relates to X"); the primary key already
+// serves lookups by source.
+func relationIndexSQL(name string) string {
+	lower := strings.ToLower(name)
+	return fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_rel_%s_target ON rel_%s(target_id)", lower, lower)
+}
+
+// componentIndexSQL generates one CREATE INDEX statement per index declared
+// on the component, in index-name order.
+func componentIndexSQL(name string, comp schema.Component) []string {
+	indexes := schema.ComponentIndexes(name, comp)
+	stmts := make([]string, 0, len(indexes))
+	for _, idx := range indexes {
+		stmts = append(stmts, createIndexSQL(strings.ToLower(name), idx))
+	}
+	return stmts
+}
+
+// createIndexSQL renders the CREATE INDEX statement for idx on
+// comp_<compName>.
+func createIndexSQL(compName string, idx schema.DomainIndex) string {
+	unique := ""
+	if idx.Unique {
+		unique = "UNIQUE "
+	}
+	return fmt.Sprintf("CREATE %sINDEX IF NOT EXISTS %s ON comp_%s(%s)",
+		unique, idx.Name, compName, strings.Join(idx.Columns, ", "))
+}
+
 // propertySQLType maps a Property to its SQLite column type.
 func propertySQLType(p schema.Property) string {
 	return schema.PropertySQLType(p)
 }
+
+// objectColumnDef renders the column definition for one property of an
+// object component: name, SQL type, NOT NULL (unless the property is
+// nullable), the declared default (if any), the CHECK clause for its
+// constraints and, for entity-ref properties, the foreign key to
+// entities(id) with its onDelete policy. A computed property is its
+// generated column instead: name, SQL type and GENERATED ALWAYS AS clause.
+// It is shared by CREATE TABLE and the table-rebuild path so both produce
+// identical columns.
+func objectColumnDef(propName string, prop schema.Property) string {
+	colName := strings.ToLower(propName)
+	if prop.IsComputed() {
+		return fmt.Sprintf("%s %s %s", colName, propertySQLType(prop), prop.GeneratedClause())
+	}
+	notNull := ""
+	if schema.PropertyNotNull(prop) {
+		notNull = " NOT NULL"
+	}
+	ref := ""
+	if prop.Type == schema.PropertyTypeEntityRef {
+		ref = " " + schema.ReferenceClause(prop.OnDelete)
+	}
+	return fmt.Sprintf("%s %s%s%s%s%s",
+		colName, propertySQLType(prop), notNull, propertyDefaultClause(prop), propertyCheckClause(colName, prop), ref)
+}
+
+// propertyDefaultClause renders the property's declared default as a
+// DEFAULT clause (with a leading space), or "" if none is declared.
+func propertyDefaultClause(p schema.Property) string {
+	v, ok := p.DefaultValue()
+	if !ok {
+		return ""
+	}
+	return " DEFAULT " + sqlLiteral(v)
+}
+
+// propertyCheckClause renders the property's value constraints as a column
+// CHECK clause (with a leading space), or "" if the property has none that
+// SQLite can express (see schema.Property.CheckExpr).
+func propertyCheckClause(col string, p schema.Property) string {
+	expr := p.CheckExpr(col)
+	if expr == "" {
+		return ""
+	}
+	return " CHECK (" + expr + ")"
+}
+
+// sqlNumber formats a float without trailing zeros for use in DDL.
+func sqlNumber(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// sqlLiteral renders a JSON-decoded scalar as a SQL literal. Strings are
+// single-quoted with embedded quotes doubled.
+func sqlLiteral(v any) string {
+	switch x := v.(type) {
+	case string:
+		return "'" + strings.ReplaceAll(x, "'", "''") + "'"
+	case float64:
+		return sqlNumber(x)
+	case float32:
+		return sqlNumber(float64(x))
+	case int:
+		return strconv.Itoa(x)
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case bool:
+		if x {
+			return "1"
+		}
+		return "0"
+	case nil:
+		return "NULL"
+	default:
+		return "'" + strings.ReplaceAll(fmt.Sprintf("%v", x), "'", "''") + "'"
+	}
+}
@@ -0,0 +1,283 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tmbritton/ecs-db/internal/schema"
+)
+
+func computedSchema(version int, health, position map[string]schema.Property) schema.DatabaseSchema {
+	return schema.DatabaseSchema{
+		SchemaVersion: version,
+		Components: map[string]schema.Component{
+			"Health":   {Type: schema.ComponentTypeObject, Properties: health},
+			"Position": {Type: schema.ComponentTypeObject, Properties: position},
+		},
+		EntityTypes: map[string]schema.EntityType{
+			"Goblin": {RequiredComponents: []string{"Health", "Position"}, ValidationLevel: schema.ValidationStrict},
+		},
+	}
+}
+
+func healthProps() map[string]schema.Property {
+	return map[string]schema.Property{
+		"hp":    {Type: schema.PropertyTypeInteger},
+		"maxHp": {Type: schema.PropertyTypeInteger, Default: float64(10)},
+	}
+}
+
+func positionProps() map[string]schema.Property {
+	return map[string]schema.Property{
+		"x": {Type: schema.PropertyTypeNumber},
+		"y": {Type: schema.PropertyTypeNumber},
+	}
+}
+
+func withProps(props map[string]schema.Property, extra map[string]schema.Property) map[string]schema.Property {
+	for name, p := range extra {
+		props[name] = p
+	}
+	return props
+}
+
+func TestBootstrap_ComputedColumns(t *testing.T) {
+	s := computedSchema(1,
+		withProps(healthProps(), map[string]schema.Property{
+			"ratio": {Type: schema.PropertyTypeNumber, Computed: "hp * 1.0 / maxHp"},
+		}),
+		withProps(positionProps(), map[string]schema.Property{
+			"cell": {Type: schema.PropertyTypeInteger, Computed: "floor(x / 32)", Stored: true},
+		}))
+	store, err := NewSQLiteStore(t.TempDir()+"/world.sqlite", s, "")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	db := store.DB()
+
+	for _, stmt := range []string{
+		"INSERT INTO entities (id, entity_type, created_tick) VALUES (1, 'Goblin', 0)",
+		"INSERT INTO comp_health (entity_id, hp, maxhp) VALUES (1, 5, 20)",
+		"INSERT INTO comp_position (entity_id, x, y) VALUES (1, 70, 3)",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	var (
+		ratio float64
+		cell  int64
+	)
+	if err := db.QueryRow("SELECT h.ratio, p.cell FROM comp_health h JOIN comp_position p USING (entity_id)").
+		Scan(&ratio, &cell); err != nil {
+		t.Fatal(err)
+	}
+	if ratio != 0.25 || cell != 2 {
+		t.Errorf("ratio, cell = %v, %d; want 0.25, 2", ratio, cell)
+	}
+
+	cols, err := IntrospectComponentTable(db, "comp_position")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, c := range cols {
+		if c.Name == "cell" {
+			found = true
+			if c.Computed != "floor(x / 32)" || !c.Stored || !c.Nullable {
+				t.Errorf("cell column = %+v, want a nullable STORED column computed as floor(x / 32)", c)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("columns = %+v, want the generated cell column listed", cols)
+	}
+
+	domain, err := IntrospectAll(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes := schema.Diff(domain.ToDiffSchema(), &s, nil); len(changes) != 0 {
+		t.Errorf("Diff after bootstrap = %+v, want none", changes)
+	}
+}
+
+func TestTxWorldWriter_ComputedProperties(t *testing.T) {
+	s := computedSchema(1,
+		withProps(healthProps(), map[string]schema.Property{
+			"ratio": {Type: schema.PropertyTypeNumber, Computed: "hp * 1.0 / maxHp"},
+		}),
+		positionProps())
+	store, err := NewSQLiteStore(t.TempDir()+"/world.sqlite", s, "")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	db := store.DB()
+	if _, err := db.Exec("INSERT INTO entities (id, entity_type, created_tick) VALUES (1, 'Goblin', 0)"); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	w := NewTxWorldWriterWithSchema(tx, s)
+	r := NewTxWorldReaderWithSchema(tx, s)
+
+	if err := w.AttachComponent(1, "Health", map[string]any{"hp": 5, "ratio": 1.0}); err == nil ||
+		!strings.Contains(err.Error(), "read-only") {
+		t.Errorf("AttachComponent with ratio err = %v, want read-only", err)
+	}
+	if err := w.AttachComponent(1, "Health", map[string]any{"hp": 5}); err != nil {
+		t.Fatalf("AttachComponent: %v", err)
+	}
+	if err := w.SetComponentValue(1, "Health", "ratio", 1.0); err == nil || !strings.Contains(err.Error(), "read-only") {
+		t.Errorf("SetComponentValue(ratio) err = %v, want read-only", err)
+	}
+	if err := NewTxWorldWriter(tx).SetComponentValue(1, "Health", "ratio", 1.0); err == nil {
+		t.Error("SetComponentValue(ratio) without a schema succeeded, want SQLite to refuse it")
+	}
+
+	if err := w.SetComponentValue(1, "Health", "hp", 8); err != nil {
+		t.Fatalf("SetComponentValue(hp): %v", err)
+	}
+	if got, err := r.GetComponentValue(1, "Health", "ratio"); err != nil || got != 0.8 {
+		t.Errorf("ratio = %v, %v; want 0.8 after hp = 8", got, err)
+	}
+}
+
+func TestSmoke_Computed_Migrate(t *testing.T) {
+	path := t.TempDir() + "/smoke.sqlite"
+
+	s1 := computedSchema(1, healthProps(), positionProps())
+	store1, err := NewSQLiteStore(path, s1, "")
+	if err != nil {
+		t.Fatalf("v1 open: %v", err)
+	}
+	for _, stmt := range []string{
+		"INSERT INTO entities (id, entity_type, created_tick) VALUES (1, 'Goblin', 0)",
+		"INSERT INTO comp_health (entity_id, hp, maxhp) VALUES (1, 5, 20)",
+		"INSERT INTO comp_position (entity_id, x, y) VALUES (1, 70, 3)",
+	} {
+		if _, err := store1.DB().Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	_ = store1.Close()
+
+	// v2: Health gains a VIRTUAL column (ALTER TABLE), Position a STORED
+	// one and a plain z, which the rebuild creates together.
+	s2 := computedSchema(2,
+		withProps(healthProps(), map[string]schema.Property{
+			"ratio": {Type: schema.PropertyTypeNumber, Computed: "hp * 1.0 / maxHp"},
+		}),
+		withProps(positionProps(), map[string]schema.Property{
+			"cell": {Type: schema.PropertyTypeInteger, Computed: "floor(x / 32)", Stored: true},
+			"z":    {Type: schema.PropertyTypeNumber, Default: float64(4)},
+		}))
+	store2, err := NewSQLiteStore(path, s2, "")
+	if err != nil {
+		t.Fatalf("v2 open (migration): %v", err)
+	}
+	var (
+		ratio float64
+		cell  int64
+		x, z  float64
+	)
+	if err := store2.DB().QueryRow("SELECT h.ratio, p.cell, p.x, p.z FROM comp_health h JOIN comp_position p USING (entity_id)").
+		Scan(&ratio, &cell, &x, &z); err != nil {
+		t.Fatalf("reading v2: %v", err)
+	}
+	if ratio != 0.25 || cell != 2 || x != 70 || z != 4 {
+		t.Errorf("v2 ratio, cell, x, z = %v, %d, %v, %v; want 0.25, 2, 70, 4", ratio, cell, x, z)
+	}
+	assertNoDiff(t, store2, &s2)
+	_ = store2.Close()
+
+	// v3: ratio's expression changes, cell becomes VIRTUAL and the plain y
+	// becomes computed; each rebuilds the table it belongs to.
+	s3 := computedSchema(3,
+		withProps(healthProps(), map[string]schema.Property{
+			"ratio": {Type: schema.PropertyTypeNumber, Computed: "round(hp * 100.0 / maxHp)"},
+		}),
+		withProps(positionProps(), map[string]schema.Property{
+			"cell": {Type: schema.PropertyTypeInteger, Computed: "floor(x / 32)"},
+			"y":    {Type: schema.PropertyTypeNumber, Computed: "x * 2"},
+			"z":    {Type: schema.PropertyTypeNumber, Default: float64(4)},
+		}))
+	store3, err := NewSQLiteStore(path, s3, "")
+	if err != nil {
+		t.Fatalf("v3 open (migration): %v", err)
+	}
+	t.Cleanup(func() { _ = store3.Close() })
+	var y float64
+	if err := store3.DB().QueryRow("SELECT h.ratio, p.cell, p.y, p.z FROM comp_health h JOIN comp_position p USING (entity_id)").
+		Scan(&ratio, &cell, &y, &z); err != nil {
+		t.Fatalf("reading v3: %v", err)
+	}
+	if ratio != 25 || cell != 2 || y != 140 || z != 4 {
+		t.Errorf("v3 ratio, cell, y, z = %v, %d, %v, %v; want 25, 2, 140, 4", ratio, cell, y, z)
+	}
+	assertNoDiff(t, store3, &s3)
+}
+
+// assertNoDiff fails the test when the store's database differs from s.
+func assertNoDiff(t *testing.T, store *SQLiteStore, s *schema.DatabaseSchema) {
+	t.Helper()
+	domain, err := IntrospectAll(store.DB())
+	if err != nil {
+		t.Fatalf("IntrospectAll: %v", err)
+	}
+	if changes := schema.Diff(domain.ToDiffSchema(), s, nil); len(changes) != 0 {
+		t.Errorf("Diff after migration = %+v, want none", changes)
+	}
+}
+
+func TestGenerate_ComputedColumns(t *testing.T) {
+	file := computedSchema(2,
+		withProps(healthProps(), map[string]schema.Property{
+			"ratio": {Type: schema.PropertyTypeNumber, Computed: "hp * 1.0 / maxHp"},
+		}),
+		withProps(positionProps(), map[string]schema.Property{
+			"cell": {Type: schema.PropertyTypeInteger, Computed: "floor(x / 32)", Stored: true},
+			"z":    {Type: schema.PropertyTypeNumber},
+		}))
+	domain := &DomainSchema{Components: map[string]DomainComponent{
+		"health": {Type: "object", Columns: []DomainColumn{
+			{Name: "entity_id", SQLType: "INTEGER", IsPK: true},
+			{Name: "hp", SQLType: "INTEGER"},
+			{Name: "maxhp", SQLType: "INTEGER"},
+		}},
+		"position": {Type: "object", Columns: []DomainColumn{
+			{Name: "entity_id", SQLType: "INTEGER", IsPK: true},
+			{Name: "x", SQLType: "REAL"},
+			{Name: "y", SQLType: "REAL"},
+		}},
+	}}
+
+	// Without StrictDrop the STORED column's rebuild still runs: it loses
+	// no data.
+	stmts := NewGenerator(&file, domain, Config{}).Generate([]schema.Change{
+		{Kind: schema.ChangeAddedProperty, Component: "health", Property: "ratio", NewType: "REAL"},
+		{Kind: schema.ChangeAddedProperty, Component: "position", Property: "cell", NewType: "INTEGER"},
+		{Kind: schema.ChangeAddedProperty, Component: "position", Property: "z", NewType: "REAL"},
+	})
+	if len(stmts) != 5 {
+		t.Fatalf("got %d statements, want ALTER plus a 4-step rebuild: %+v", len(stmts), stmts)
+	}
+	if stmts[0].SQL != "ALTER TABLE comp_health ADD COLUMN ratio REAL GENERATED ALWAYS AS (hp * 1.0 / maxHp) VIRTUAL" {
+		t.Errorf("stmts[0] = %q", stmts[0].SQL)
+	}
+	assertContainsDDL(t, stmts[1].SQL, "cell INTEGER GENERATED ALWAYS AS (floor(x / 32)) STORED")
+	if want := "INSERT INTO comp_position_new (entity_id, x, y, z) SELECT entity_id, x, y, 0.0 FROM comp_position"; stmts[2].SQL != want {
+		t.Errorf("copy = %q, want %q", stmts[2].SQL, want)
+	}
+	for _, s := range stmts {
+		if s.Destructive {
+			t.Errorf("%s marked destructive", s.Description)
+		}
+	}
+}
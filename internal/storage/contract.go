@@ -0,0 +1,296 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tmbritton/ecs-db/internal/schema"
+	"github.com/tmbritton/ecs-db/internal/world"
+)
+
+// ContractPolicy controls what the migration runner does with existing
+// entities that violate the entity-type contracts of the schema being
+// migrated to, e.g. a newly required component or allowExtraComponents
+// turned off.
+type ContractPolicy string
+
+const (
+	// ContractRefuse rolls the migration back and returns
+	// *EntityContractError listing the violations.
+	ContractRefuse ContractPolicy = "refuse"
+	// ContractReport logs the violations and lets the migration commit.
+	ContractReport ContractPolicy = "report"
+	// ContractRepair attaches missing required components with their
+	// default values and detaches disallowed ones. Violations that cannot
+	// be repaired cause the migration to be refused.
+	ContractRepair ContractPolicy = "repair"
+)
+
+// defaultContractPolicy derives the contract policy from the migration
+// policy when none is configured: confirm refuses, auto reports.
+func defaultContractPolicy(p MigrationPolicy) ContractPolicy {
+	if p == MigrationConfirm {
+		return ContractRefuse
+	}
+	return ContractReport
+}
+
+// ViolationKind classifies an EntityContractViolation.
+type ViolationKind string
+
+const (
+	ViolationMissingRequired ViolationKind = "missing_required"
+	ViolationDisallowed      ViolationKind = "disallowed_component"
+)
+
+// EntityContractViolation describes one existing entity that does not
+// satisfy its entity type in the new schema.
+type EntityContractViolation struct {
+	EntityID   int64
+	EntityType string
+	Kind       ViolationKind
+	Component  string // canonical component name
+	Message    string
+	// Warning is true when the entity type has validationLevel "warning":
+	// the violation is reported but never refused or repaired.
+	Warning bool
+	// Repaired is true when ContractRepair fixed the violation.
+	Repaired bool
+}
+
+func (v EntityContractViolation) String() string {
+	return fmt.Sprintf("entity %d (%s): %s", v.EntityID, v.EntityType, v.Message)
+}
+
+// EntityContractError is returned by the runner when existing entities
+// violate the new entity-type contracts and the policy does not allow the
+// migration to proceed. The migration has been rolled back.
+type EntityContractError struct {
+	Violations []EntityContractViolation
+}
+
+func (e *EntityContractError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "migration refused: %d entity contract violation(s):\n", len(e.Violations))
+	for _, v := range e.Violations {
+		fmt.Fprintf(&sb, "  - %s\n", v)
+	}
+	return sb.String()
+}
+
+// checkEntityContracts scans every entity against its entity type in file,
+// running world.ValidateEntityCreation on the components the entity
+// currently has. It runs inside the migration transaction, after
+// the DDL and data migrations, so it sees the post-migration tables.
+// Entities whose type is not declared in file are skipped; removing an
+// entity type is a structural change, not a contract tightening.
+func checkEntityContracts(tx *sql.Tx, file *schema.DatabaseSchema) ([]EntityContractViolation, error) {
+	attached, err := attachedComponents(tx, file)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query("SELECT id, entity_type FROM entities ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("listing entities: %w", err)
+	}
+	type entity struct {
+		id  int64
+		typ string
+	}
+	var entities []entity
+	for rows.Next() {
+		var e entity
+		if err := rows.Scan(&e.id, &e.typ); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("scanning entity: %w", err)
+		}
+		entities = append(entities, e)
+	}
+	err = rows.Err()
+	_ = rows.Close()
+	if err != nil {
+		return nil, fmt.Errorf("listing entities: %w", err)
+	}
+
+	var violations []EntityContractViolation
+	for _, e := range entities {
+		et, ok := schema.ResolveEntityType(file, e.typ)
+		if !ok {
+			continue
+		}
+		comps := attached[e.id]
+		vr := world.ValidateEntityCreation(file, e.typ, comps)
+		et.ApplyDefaults()
+		warning := et.ValidationLevel == schema.ValidationWarning
+
+		for _, req := range vr.MissingRequired {
+			violations = append(violations, EntityContractViolation{
+				EntityID:   e.id,
+				EntityType: e.typ,
+				Kind:       ViolationMissingRequired,
+				Component:  req,
+				Message:    fmt.Sprintf("missing required component %q", req),
+				Warning:    warning,
+			})
+		}
+		for _, c := range vr.Disallowed {
+			violations = append(violations, EntityContractViolation{
+				EntityID:   e.id,
+				EntityType: e.typ,
+				Kind:       ViolationDisallowed,
+				Component:  c,
+				Message:    fmt.Sprintf("component %q is not allowed", c),
+				Warning:    warning,
+			})
+		}
+	}
+	return violations, nil
+}
+
+// attachedComponents maps each entity id to the canonical names of the
+// file components it has a row for, sorted. Relations are not attached to
+// an entity and are skipped.
+func attachedComponents(tx *sql.Tx, file *schema.DatabaseSchema) (map[int64][]string, error) {
+	names := make([]string, 0, len(file.Components))
+	for name, comp := range file.Components {
+		if comp.Type == schema.ComponentTypeRelation {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	attached := make(map[int64][]string)
+	for _, name := range names {
+		rows, err := tx.Query(fmt.Sprintf("SELECT entity_id FROM comp_%s", strings.ToLower(name)))
+		if err != nil {
+			return nil, fmt.Errorf("reading comp_%s: %w", strings.ToLower(name), err)
+		}
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				_ = rows.Close()
+				return nil, fmt.Errorf("scanning comp_%s: %w", strings.ToLower(name), err)
+			}
+			attached[id] = append(attached[id], name)
+		}
+		err = rows.Err()
+		_ = rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading comp_%s: %w", strings.ToLower(name), err)
+		}
+	}
+	return attached, nil
+}
+
+// repairEntityContracts fixes the repairable, non-warning violations in
+// place, marking them Repaired: missing components are attached with their
+// default values and disallowed ones are detached. A missing component with
+// an entity-ref column has no sensible default, and one whose default
+// values break its constraints cannot be attached; both are left
+// unrepaired.
+func repairEntityContracts(tx *sql.Tx, file *schema.DatabaseSchema, violations []EntityContractViolation) error {
+	for i := range violations {
+		v := &violations[i]
+		if v.Warning {
+			continue
+		}
+		table := "comp_" + strings.ToLower(v.Component)
+		switch v.Kind {
+		case ViolationMissingRequired:
+			comp, _ := schema.ComponentByName(file, v.Component)
+			cols, vals, ok := defaultComponentRow(comp)
+			if !ok {
+				continue
+			}
+			query := fmt.Sprintf("INSERT INTO %s (entity_id%s) VALUES (?%s)", table, cols, vals)
+			if _, err := tx.Exec(query, v.EntityID); err != nil {
+				return fmt.Errorf("attaching %s to entity %d: %w", v.Component, v.EntityID, err)
+			}
+			v.Repaired = true
+		case ViolationDisallowed:
+			if _, err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE entity_id = ?", table), v.EntityID); err != nil {
+				return fmt.Errorf("detaching %s from entity %d: %w", v.Component, v.EntityID, err)
+			}
+			v.Repaired = true
+		}
+	}
+	return nil
+}
+
+// defaultComponentRow returns the column list and SQL literal list (each
+// with a leading ", ") that fill a new row of comp with default values.
+// Scalar components rely on their column DEFAULT. ok is false when the
+// component needs an entity reference, which has no default, or when a
+// property without a default has constraints its zero value fails.
+func defaultComponentRow(comp schema.Component) (cols, vals string, ok bool) {
+	switch comp.Type {
+	case schema.ComponentTypeEntityRef:
+		return "", "", false
+	case schema.ComponentTypeObject:
+		names := make([]string, 0, len(comp.Properties))
+		for name := range comp.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var cb, vb strings.Builder
+		for _, name := range names {
+			prop := comp.Properties[name]
+			if prop.IsComputed() {
+				continue // derived by SQLite
+			}
+			if prop.Type == schema.PropertyTypeEntityRef && !prop.Nullable {
+				return "", "", false
+			}
+			fmt.Fprintf(&cb, ", %s", strings.ToLower(name))
+			if prop.Nullable && !prop.HasDefault() {
+				vb.WriteString(", NULL")
+				continue
+			}
+			if err := prop.CheckValue(zeroValueForProperty(prop)); err != nil {
+				return "", "", false
+			}
+			fmt.Fprintf(&vb, ", %s", defaultValueForProperty(prop))
+		}
+		return cb.String(), vb.String(), true
+	}
+	return "", "", true
+}
+
+// zeroValueForProperty returns the Go value of what defaultValueForProperty
+// writes for p: its declared default, or the zero value of its type.
+func zeroValueForProperty(p schema.Property) any {
+	if v, ok := p.DefaultValue(); ok {
+		return v
+	}
+	switch p.Type {
+	case schema.PropertyTypeString:
+		return ""
+	case schema.PropertyTypeInteger:
+		return int64(0)
+	case schema.PropertyTypeNumber:
+		return 0.0
+	case schema.PropertyTypeBoolean:
+		return false
+	case schema.PropertyTypeObject:
+		return "{}"
+	case schema.PropertyTypeArray:
+		return "[]"
+	}
+	return nil
+}
+
+// unresolvedViolations returns the violations that block the migration:
+// those neither repaired nor downgraded to warnings.
+func unresolvedViolations(violations []EntityContractViolation) []EntityContractViolation {
+	var out []EntityContractViolation
+	for _, v := range violations {
+		if !v.Warning && !v.Repaired {
+			out = append(out, v)
+		}
+	}
+	return out
+}
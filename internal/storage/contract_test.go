@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/tmbritton/ecs-db/internal/schema"
+)
+
+// contractSchema declares Health and Tag and a single Goblin entity type.
+func contractSchema(version int, et schema.EntityType) schema.DatabaseSchema {
+	s := historySchema(version, et)
+	s.Components["Tag"] = schema.Component{Type: schema.ComponentTypeString}
+	return s
+}
+
+// seedLooseGoblin bootstraps v1, where Goblin allows anything, and stores
+// goblin 1 with only a Tag.
+func seedLooseGoblin(t *testing.T) *sql.DB {
+	t.Helper()
+	db := openMigrationTestDB(t)
+	bootstrapMigrationDB(t, db, contractSchema(1, schema.EntityType{
+		AllowExtraComponents: true,
+		ValidationLevel:      schema.ValidationStrict,
+	}))
+	if _, err := db.Exec("INSERT INTO entities (id, entity_type) VALUES (1, 'Goblin')"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO comp_tag (entity_id, value) VALUES (1, 'boss')"); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// strictGoblin requires Health and disallows everything else.
+func strictGoblin(level schema.ValidationLevel) schema.EntityType {
+	return schema.EntityType{
+		RequiredComponents: []string{"Health"},
+		ValidationLevel:    level,
+	}
+}
+
+func TestMigrate_EntityContracts_ConfirmRefuses(t *testing.T) {
+	db := seedLooseGoblin(t)
+
+	runner := NewMigrationRunner(db, contractSchema(2, strictGoblin(schema.ValidationStrict)), MigrationConfirm, NopLogger())
+	err := runner.Run()
+
+	var ceErr *EntityContractError
+	if !errors.As(err, &ceErr) {
+		t.Fatalf("expected *EntityContractError, got %T: %v", err, err)
+	}
+	kinds := map[ViolationKind]string{}
+	for _, v := range ceErr.Violations {
+		if v.EntityID != 1 || v.EntityType != "Goblin" {
+			t.Errorf("violation = %+v, want goblin 1", v)
+		}
+		kinds[v.Kind] = v.Component
+	}
+	if kinds[ViolationMissingRequired] != "Health" || kinds[ViolationDisallowed] != "Tag" {
+		t.Errorf("violations = %+v, want missing Health and disallowed Tag", ceErr.Violations)
+	}
+	if got := readMetaValue(t, db, "schema_version"); got != "1" {
+		t.Errorf("schema_version = %q, want 1 (rolled back)", got)
+	}
+}
+
+func TestMigrate_EntityContracts_AutoReports(t *testing.T) {
+	db := seedLooseGoblin(t)
+
+	runner := NewMigrationRunner(db, contractSchema(2, strictGoblin(schema.ValidationStrict)), MigrationAuto, NopLogger())
+	if err := runner.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got := len(runner.ContractViolations()); got != 2 {
+		t.Errorf("ContractViolations = %d, want 2", got)
+	}
+	var n int
+	if err := db.QueryRow("SELECT count(*) FROM comp_tag").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("comp_tag rows = %d, want 1 (report must not modify data)", n)
+	}
+}
+
+func TestMigrate_EntityContracts_Repair(t *testing.T) {
+	db := seedLooseGoblin(t)
+
+	runner := NewMigrationRunner(db, contractSchema(2, strictGoblin(schema.ValidationStrict)), MigrationConfirm, NopLogger()).
+		WithContractPolicy(ContractRepair)
+	if err := runner.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	for _, v := range runner.ContractViolations() {
+		if !v.Repaired {
+			t.Errorf("violation not repaired: %+v", v)
+		}
+	}
+
+	var hp int
+	if err := db.QueryRow("SELECT hp FROM comp_health WHERE entity_id = 1").Scan(&hp); err != nil {
+		t.Fatalf("Health not attached: %v", err)
+	}
+	if hp != 0 {
+		t.Errorf("hp = %d, want default 0", hp)
+	}
+	var n int
+	if err := db.QueryRow("SELECT count(*) FROM comp_tag WHERE entity_id = 1").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Error("disallowed Tag should have been detached")
+	}
+}
+
+func TestMigrate_EntityContracts_RepairSkipsConstrainedDefaults(t *testing.T) {
+	// Health.hp must be at least 1 and has no default, so attaching Health
+	// with hp 0 would fail its CHECK constraint.
+	one := 1.0
+	constrained := func(version int, et schema.EntityType) schema.DatabaseSchema {
+		s := contractSchema(version, et)
+		s.Components["Health"] = schema.Component{Type: schema.ComponentTypeObject, Properties: map[string]schema.Property{
+			"hp": {Type: schema.PropertyTypeInteger, Minimum: &one},
+		}}
+		return s
+	}
+	db := openMigrationTestDB(t)
+	bootstrapMigrationDB(t, db, constrained(1, schema.EntityType{
+		AllowExtraComponents: true,
+		ValidationLevel:      schema.ValidationStrict,
+	}))
+	if _, err := db.Exec("INSERT INTO entities (id, entity_type) VALUES (1, 'Goblin')"); err != nil {
+		t.Fatal(err)
+	}
+
+	runner := NewMigrationRunner(db, constrained(2, strictGoblin(schema.ValidationStrict)), MigrationConfirm, NopLogger()).
+		WithContractPolicy(ContractRepair)
+	err := runner.Run()
+
+	var ceErr *EntityContractError
+	if !errors.As(err, &ceErr) {
+		t.Fatalf("expected *EntityContractError, got %T: %v", err, err)
+	}
+	if len(ceErr.Violations) != 1 || ceErr.Violations[0].Component != "Health" || ceErr.Violations[0].Repaired {
+		t.Errorf("violations = %+v, want the unrepaired missing Health", ceErr.Violations)
+	}
+	if got := readMetaValue(t, db, "schema_version"); got != "1" {
+		t.Errorf("schema_version = %q, want 1 (rolled back)", got)
+	}
+}
+
+func TestMigrate_EntityContracts_WarningLevelNeverRefuses(t *testing.T) {
+	db := seedLooseGoblin(t)
+
+	runner := NewMigrationRunner(db, contractSchema(2, strictGoblin(schema.ValidationWarning)), MigrationConfirm, NopLogger()).
+		WithContractPolicy(ContractRepair)
+	if err := runner.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	for _, v := range runner.ContractViolations() {
+		if !v.Warning || v.Repaired {
+			t.Errorf("violation = %+v, want an unrepaired warning", v)
+		}
+	}
+	var n int
+	if err := db.QueryRow("SELECT count(*) FROM comp_tag WHERE entity_id = 1").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Error("warning-level violations must not be repaired")
+	}
+}
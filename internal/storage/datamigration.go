@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DataMigrationFunc performs data work for a schema version bump. It runs
+// inside the migration transaction, after the generated DDL, so new tables
+// and columns already exist while dropped ones are already gone. Move data
+// out of a column in the version before the one that removes it.
+type DataMigrationFunc func(tx *sql.Tx) error
+
+// DataMigrationError is returned when a data migration hook or SQL file
+// fails. The whole migration, DDL included, is rolled back.
+type DataMigrationError struct {
+	Name        string // hook description or SQL file path
+	FromVersion int
+	ToVersion   int
+	Underlying  error
+}
+
+func (e *DataMigrationError) Error() string {
+	return fmt.Sprintf("data migration %s (v%d → v%d) failed: %v",
+		e.Name, e.FromVersion, e.ToVersion, e.Underlying)
+}
+
+// Unwrap allows errors.Is/As to reach the underlying error.
+func (e *DataMigrationError) Unwrap() error {
+	return e.Underlying
+}
+
+// dataMigration is one step of data work, either a registered hook or a
+// declarative SQL file.
+type dataMigration struct {
+	from, to int
+	name     string
+	run      DataMigrationFunc
+}
+
+var (
+	dataMigrationsMu sync.Mutex
+	dataMigrations   []dataMigration
+)
+
+// RegisterDataMigration registers fn to run when a database is migrated
+// across the version range fromVersion → toVersion, i.e. when the stored
+// schema_version is <= fromVersion and schema.json's version is >=
+// toVersion. Hooks run in order of toVersion, then fromVersion, then
+// registration order.
+//
+// It is meant to be called from init functions and panics if fn is nil or
+// fromVersion is not less than toVersion.
+func RegisterDataMigration(fromVersion, toVersion int, fn DataMigrationFunc) {
+	if fn == nil {
+		panic("storage: RegisterDataMigration fn is nil")
+	}
+	if fromVersion >= toVersion {
+		panic(fmt.Sprintf("storage: RegisterDataMigration fromVersion %d must be less than toVersion %d",
+			fromVersion, toVersion))
+	}
+	dataMigrationsMu.Lock()
+	defer dataMigrationsMu.Unlock()
+	dataMigrations = append(dataMigrations, dataMigration{
+		from: fromVersion,
+		to:   toVersion,
+		name: fmt.Sprintf("hook v%d→v%d #%d", fromVersion, toVersion, len(dataMigrations)+1),
+		run:  fn,
+	})
+}
+
+// pendingDataMigrations returns the registered hooks and the SQL files in
+// migrationsDir that apply to a migration from dbVersion to fileVersion, in
+// execution order. On equal versions registered hooks run before SQL files.
+func pendingDataMigrations(migrationsDir string, dbVersion, fileVersion int) ([]dataMigration, error) {
+	dataMigrationsMu.Lock()
+	steps := make([]dataMigration, 0, len(dataMigrations))
+	for _, m := range dataMigrations {
+		if m.from >= dbVersion && m.to <= fileVersion {
+			steps = append(steps, m)
+		}
+	}
+	dataMigrationsMu.Unlock()
+
+	files, err := sqlDataMigrations(migrationsDir, dbVersion, fileVersion)
+	if err != nil {
+		return nil, err
+	}
+	steps = append(steps, files...)
+
+	sort.SliceStable(steps, func(i, j int) bool {
+		if steps[i].to != steps[j].to {
+			return steps[i].to < steps[j].to
+		}
+		return steps[i].from < steps[j].from
+	})
+	return steps, nil
+}
+
+// sqlDataMigrations loads migrationsDir/vN/*.sql for every N with
+// dbVersion < N <= fileVersion. Each file is a step from N-1 to N; files in
+// one directory run in lexical order. An empty or missing migrationsDir
+// yields no steps.
+func sqlDataMigrations(migrationsDir string, dbVersion, fileVersion int) ([]dataMigration, error) {
+	if migrationsDir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(migrationsDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading migrations directory: %w", err)
+	}
+
+	var steps []dataMigration
+	for _, e := range entries {
+		if !e.IsDir() || !strings.HasPrefix(e.Name(), "v") {
+			continue
+		}
+		version, err := strconv.Atoi(strings.TrimPrefix(e.Name(), "v"))
+		if err != nil || version <= dbVersion || version > fileVersion {
+			continue
+		}
+		paths, err := filepath.Glob(filepath.Join(migrationsDir, e.Name(), "*.sql"))
+		if err != nil {
+			return nil, fmt.Errorf("listing %s: %w", e.Name(), err)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("reading data migration %s: %w", path, err)
+			}
+			script := string(data)
+			steps = append(steps, dataMigration{
+				from: version - 1,
+				to:   version,
+				name: path,
+				run: func(tx *sql.Tx) error {
+					_, err := tx.Exec(script)
+					return err
+				},
+			})
+		}
+	}
+	return steps, nil
+}
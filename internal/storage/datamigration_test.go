@@ -0,0 +1,209 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tmbritton/ecs-db/internal/schema"
+)
+
+// withDataMigrations swaps out the global hook registry for the duration of
+// a test.
+func withDataMigrations(t *testing.T) {
+	t.Helper()
+	dataMigrationsMu.Lock()
+	saved := dataMigrations
+	dataMigrations = nil
+	dataMigrationsMu.Unlock()
+	t.Cleanup(func() {
+		dataMigrationsMu.Lock()
+		dataMigrations = saved
+		dataMigrationsMu.Unlock()
+	})
+}
+
+func goblinSchema(version int, comps map[string]schema.Component) schema.DatabaseSchema {
+	return schema.DatabaseSchema{
+		SchemaVersion: version,
+		Components:    comps,
+		EntityTypes:   map[string]schema.EntityType{},
+	}
+}
+
+var healthComponent = schema.Component{
+	Type:       schema.ComponentTypeObject,
+	Properties: map[string]schema.Property{"hp": {Type: schema.PropertyTypeInteger}},
+}
+
+func TestRegisterDataMigration_Panics(t *testing.T) {
+	withDataMigrations(t)
+	for name, register := range map[string]func(){
+		"nil fn":         func() { RegisterDataMigration(1, 2, nil) },
+		"from equals to": func() { RegisterDataMigration(2, 2, func(*sql.Tx) error { return nil }) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected panic")
+				}
+			}()
+			register()
+		})
+	}
+}
+
+func TestPendingDataMigrations_FiltersAndOrders(t *testing.T) {
+	withDataMigrations(t)
+	noop := func(*sql.Tx) error { return nil }
+	RegisterDataMigration(2, 3, noop) // hook v2→v3 #1
+	RegisterDataMigration(1, 2, noop) // hook v1→v2 #2
+	RegisterDataMigration(3, 4, noop) // out of range
+	RegisterDataMigration(0, 1, noop) // already applied
+
+	dir := t.TempDir()
+	for _, f := range []string{"v2/b.sql", "v2/a.sql", "v3/seed.sql", "v4/late.sql"} {
+		path := filepath.Join(dir, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("SELECT 1"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	steps, err := pendingDataMigrations(dir, 1, 3)
+	if err != nil {
+		t.Fatalf("pendingDataMigrations: %v", err)
+	}
+	want := []string{
+		"hook v1→v2 #2",
+		filepath.Join(dir, "v2", "a.sql"),
+		filepath.Join(dir, "v2", "b.sql"),
+		"hook v2→v3 #1",
+		filepath.Join(dir, "v3", "seed.sql"),
+	}
+	if len(steps) != len(want) {
+		t.Fatalf("got %d steps, want %d: %+v", len(steps), len(want), steps)
+	}
+	for i, w := range want {
+		if steps[i].name != w {
+			t.Errorf("steps[%d] = %q, want %q", i, steps[i].name, w)
+		}
+	}
+}
+
+func TestPendingDataMigrations_MissingDirIsEmpty(t *testing.T) {
+	withDataMigrations(t)
+	steps, err := pendingDataMigrations(filepath.Join(t.TempDir(), "nope"), 1, 2)
+	if err != nil {
+		t.Fatalf("pendingDataMigrations: %v", err)
+	}
+	if len(steps) != 0 {
+		t.Errorf("got %d steps, want 0", len(steps))
+	}
+}
+
+func TestMigrate_DataMigrationHook_SeedsNewComponent(t *testing.T) {
+	withDataMigrations(t)
+	db := openMigrationTestDB(t)
+	bootstrapMigrationDB(t, db, goblinSchema(1, map[string]schema.Component{"Position": {Type: schema.ComponentTypeString}}))
+	if _, err := db.Exec("INSERT INTO entities (id, entity_type) VALUES (1, 'Goblin'), (2, 'Player')"); err != nil {
+		t.Fatal(err)
+	}
+
+	RegisterDataMigration(1, 2, func(tx *sql.Tx) error {
+		// comp_health already exists: the hook runs after the DDL.
+		_, err := tx.Exec(`INSERT INTO comp_health (entity_id, hp)
+			SELECT id, 10 FROM entities WHERE entity_type = 'Goblin'`)
+		return err
+	})
+
+	s2 := goblinSchema(2, map[string]schema.Component{
+		"Position": {Type: schema.ComponentTypeString},
+		"Health":   healthComponent,
+	})
+	if err := NewMigrationRunner(db, s2, MigrationAuto, NopLogger()).Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	var n int
+	if err := db.QueryRow("SELECT count(*) FROM comp_health WHERE entity_id = 1 AND hp = 10").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("seeded goblin rows = %d, want 1", n)
+	}
+	if got := readMetaValue(t, db, "schema_version"); got != "2" {
+		t.Errorf("schema_version = %q, want 2", got)
+	}
+}
+
+func TestMigrate_DataMigrationSQLFile_Runs(t *testing.T) {
+	withDataMigrations(t)
+	db := openMigrationTestDB(t)
+	bootstrapMigrationDB(t, db, goblinSchema(1, map[string]schema.Component{"Position": {Type: schema.ComponentTypeString}}))
+	if _, err := db.Exec("INSERT INTO entities (id, entity_type) VALUES (1, 'Goblin')"); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "v2"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	script := `INSERT INTO comp_health (entity_id, hp) SELECT id, 5 FROM entities;
+UPDATE comp_health SET hp = hp * 2;`
+	if err := os.WriteFile(filepath.Join(dir, "v2", "001_seed_health.sql"), []byte(script), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s2 := goblinSchema(2, map[string]schema.Component{
+		"Position": {Type: schema.ComponentTypeString},
+		"Health":   healthComponent,
+	})
+	if err := NewMigrationRunner(db, s2, MigrationAuto, NopLogger()).WithMigrationsDir(dir).Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	var hp int
+	if err := db.QueryRow("SELECT hp FROM comp_health WHERE entity_id = 1").Scan(&hp); err != nil {
+		t.Fatalf("reading comp_health: %v", err)
+	}
+	if hp != 10 {
+		t.Errorf("hp = %d, want 10 (both statements of the file must run)", hp)
+	}
+}
+
+func TestMigrate_DataMigrationFailure_RollsBackDDL(t *testing.T) {
+	withDataMigrations(t)
+	db := openMigrationTestDB(t)
+	bootstrapMigrationDB(t, db, goblinSchema(1, map[string]schema.Component{"Position": {Type: schema.ComponentTypeString}}))
+
+	boom := errors.New("boom")
+	RegisterDataMigration(1, 2, func(*sql.Tx) error { return boom })
+
+	s2 := goblinSchema(2, map[string]schema.Component{
+		"Position": {Type: schema.ComponentTypeString},
+		"Health":   healthComponent,
+	})
+	err := NewMigrationRunner(db, s2, MigrationAuto, NopLogger()).Run()
+
+	var dmErr *DataMigrationError
+	if !errors.As(err, &dmErr) {
+		t.Fatalf("expected *DataMigrationError, got %T: %v", err, err)
+	}
+	if !errors.Is(err, boom) {
+		t.Error("errors.Is should reach the hook error via Unwrap")
+	}
+	if dmErr.FromVersion != 1 || dmErr.ToVersion != 2 {
+		t.Errorf("versions = %d → %d, want 1 → 2", dmErr.FromVersion, dmErr.ToVersion)
+	}
+	if tableExists(t, db, "comp_health") {
+		t.Error("comp_health should have been rolled back")
+	}
+	if got := readMetaValue(t, db, "schema_version"); got != "1" {
+		t.Errorf("schema_version = %q, want 1 (rolled back)", got)
+	}
+}
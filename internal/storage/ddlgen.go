@@ -11,11 +11,58 @@ import (
 // Statement represents a single DDL operation or a line within a
 // multi-statement operation (e.g. table rebuild).
 type Statement struct {
-	SQL         string // The raw SQL to execute
-	Kind        string // "create_table", "alter_add_column", "rebuild_table", "drop_table"
-	Destructive bool   // true for DROP TABLE, column removal, type change
-	Component   string // affected component (lowercase)
-	Description string // human-readable summary
+	SQL         string `json:"sql"`                // The raw SQL to execute
+	Kind        string `json:"kind"`               // "create_table", "alter_add_column", "rebuild_table", "drop_table", "rename_table", "rename_column", "create_index", "drop_index"
+	Destructive bool   `json:"destructive"`        // true for DROP TABLE, column removal, type change
+	Component   string `json:"component"`          // affected component (lowercase)
+	Resource    string `json:"resource,omitempty"` // affected resource (lowercase); Component is then empty
+	Relation    string `json:"relation,omitempty"` // affected relation (lowercase); Component is then empty
+	Description string `json:"description"`        // human-readable summary
+	// Lossy is true for a rebuild copy whose CAST may change stored values
+	// (e.g. REAL → INTEGER truncates) or whose new CHECK may scrub them.
+	// LossyChecks holds one query per affected column; the runner uses
+	// them to report the rows at risk.
+	Lossy       bool         `json:"lossy,omitempty"`
+	LossyChecks []LossyCheck `json:"lossyChecks,omitempty"`
+}
+
+// subject returns the component, resource or relation the statement
+// affects.
+func (s Statement) subject() string {
+	if s.Resource != "" {
+		return "resource " + s.Resource
+	}
+	if s.Relation != "" {
+		return "relation " + s.Relation
+	}
+	return s.Component
+}
+
+// table returns the name of the table the statement affects.
+func (s Statement) table() string {
+	if s.Resource != "" {
+		return "res_" + s.Resource
+	}
+	if s.Relation != "" {
+		return "rel_" + s.Relation
+	}
+	return "comp_" + s.Component
+}
+
+// LossyCheck describes one column whose type conversion or new
+// constraint may lose data.
+type LossyCheck struct {
+	Column  string `json:"column"`  // column name in the rebuilt table
+	OldType string `json:"oldType"` // SQL type before the migration
+	NewType string `json:"newType"` // SQL type after the migration
+	// Constraint is the new CHECK condition for a constraint change, ""
+	// for a type conversion.
+	Constraint string `json:"constraint,omitempty"`
+	// Query selects (entity_id, value) for every row whose value would
+	// change — (id, value) for a resource table and (source_id, value) for
+	// a relation table. It reads the table as it is before the migration
+	// runs.
+	Query string `json:"query"`
 }
 
 // Config holds generator options.
@@ -55,8 +102,45 @@ func (g *Generator) Generate(changes []schema.Change) []Statement {
 		return []Statement{}
 	}
 
+	// A rebuild recreates the whole table, indexes included, from the file
+	// definition, so one per table covers every column and index change in
+	// the batch. Without StrictDrop the rebuild is filtered out below and
+	// index changes are applied to the existing table instead.
+	//
+	// A STORED generated column cannot be added by ALTER TABLE, so a table
+	// gaining one is rebuilt too, and that rebuild also creates the other
+	// columns added in the batch. It loses no data and is not destructive
+	// unless the table has a destructive change as well, in which case it
+	// only runs with StrictDrop.
+	rebuilds := make(map[string]bool)
+	storedAdds := make(map[string]bool)
+	for _, change := range changes {
+		if isRebuildChange(change) {
+			rebuilds[changeTable(change)] = true
+		}
+		if g.addsStoredColumn(change) {
+			storedAdds[changeTable(change)] = true
+		}
+	}
+
 	stmts := make([]Statement, 0)
+	rebuilt := make(map[string]bool)
 	for _, change := range changes {
+		table := changeTable(change)
+		absorbAdds := storedAdds[table] && (g.config.StrictDrop || !rebuilds[table])
+		if isRebuildChange(change) || (absorbAdds && change.Kind == schema.ChangeAddedProperty) {
+			if rebuilt[table] {
+				continue
+			}
+			rebuilt[table] = true
+		}
+		if isIndexChange(change) && (absorbAdds || rebuilds[table] && g.config.StrictDrop) {
+			continue
+		}
+		if absorbAdds && change.Kind == schema.ChangeAddedProperty {
+			stmts = append(stmts, g.genRebuildForStoredColumn(change, rebuilds[table])...)
+			continue
+		}
 		stmts = append(stmts, g.genChange(change)...)
 	}
 
@@ -73,19 +157,92 @@ func (g *Generator) Generate(changes []schema.Change) []Statement {
 	return stmts
 }
 
+// isRebuildChange reports whether c is implemented by a table rebuild.
+func isRebuildChange(c schema.Change) bool {
+	switch c.Kind {
+	case schema.ChangeRemovedProperty, schema.ChangedPropertyType, schema.ChangedPropertyNullability,
+		schema.ChangedPropertyReference, schema.ChangedPropertyComputed, schema.ChangedPropertyConstraint:
+		return true
+	}
+	return false
+}
+
+// addsStoredColumn reports whether c adds a computed property whose
+// generated column is STORED.
+func (g *Generator) addsStoredColumn(c schema.Change) bool {
+	if c.Kind != schema.ChangeAddedProperty {
+		return false
+	}
+	prop, ok := g.property(c)
+	return ok && prop.IsComputed() && prop.Stored
+}
+
+// property looks up the file declaration of the property a change
+// applies to, on a component, resource or relation.
+func (g *Generator) property(c schema.Change) (schema.Property, bool) {
+	var props map[string]schema.Property
+	switch {
+	case c.Resource != "":
+		res, _ := schema.ResourceByName(g.file, c.Resource)
+		props = res.Properties
+	case c.Relation != "":
+		rel, _ := schema.RelationByName(g.file, c.Relation)
+		props = rel.Properties
+	default:
+		comp, _ := schema.ComponentByName(g.file, c.Component)
+		props = comp.Properties
+	}
+	return schema.PropertyByName(props, c.Property)
+}
+
+// changeTable returns the table a change applies to: res_<name> for a
+// resource change, rel_<name> for a relation change, comp_<name>
+// otherwise.
+func changeTable(c schema.Change) string {
+	if c.Resource != "" {
+		return "res_" + c.Resource
+	}
+	if c.Relation != "" {
+		return "rel_" + c.Relation
+	}
+	return "comp_" + c.Component
+}
+
+// isIndexChange reports whether c adds or removes a declared index.
+func isIndexChange(c schema.Change) bool {
+	return c.Kind == schema.ChangeAddedIndex || c.Kind == schema.ChangeRemovedIndex
+}
+
 // genChange dispatches a single change to the appropriate generator.
 func (g *Generator) genChange(c schema.Change) []Statement {
 	switch c.Kind {
+	case schema.ChangeRenamedComponent:
+		return g.genRenameComponent(c)
+	case schema.ChangeRenamedProperty:
+		return g.genRenameProperty(c)
 	case schema.ChangeAddedComponent:
 		return g.genAddComponent(c)
 	case schema.ChangeAddedProperty:
 		return g.genAddProperty(c)
 	case schema.ChangeRemovedProperty:
 		return g.genRemoveProperty(c)
-	case schema.ChangedPropertyType:
+	case schema.ChangedPropertyType, schema.ChangedPropertyNullability, schema.ChangedPropertyReference,
+		schema.ChangedPropertyComputed, schema.ChangedPropertyConstraint:
 		return g.genChangePropertyType(c)
 	case schema.ChangeRemovedComponent:
 		return g.genRemoveComponent(c)
+	case schema.ChangeAddedIndex:
+		return g.genAddIndex(c)
+	case schema.ChangeRemovedIndex:
+		return g.genRemoveIndex(c)
+	case schema.ChangeAddedResource:
+		return g.genAddResource(c)
+	case schema.ChangeRemovedResource:
+		return g.genRemoveResource(c)
+	case schema.ChangeAddedRelation:
+		return g.genAddRelation(c)
+	case schema.ChangeRemovedRelation:
+		return g.genRemoveRelation(c)
 	// Entity type changes produce no DDL.
 	case schema.ChangeAddedEntityType,
 		schema.ChangeRemovedEntityType,
@@ -96,7 +253,36 @@ func (g *Generator) genChange(c schema.Change) []Statement {
 	}
 }
 
-// genAddComponent produces a CREATE TABLE via the existing componentTableSQL.
+// genRenameComponent produces an ALTER TABLE ... RENAME TO statement. The
+// table keeps its rows, so the rename is not destructive.
+func (g *Generator) genRenameComponent(c schema.Change) []Statement {
+	return []Statement{{
+		SQL:         fmt.Sprintf("ALTER TABLE comp_%s RENAME TO comp_%s", c.OldName, c.Component),
+		Kind:        "rename_table",
+		Destructive: false,
+		Component:   c.Component,
+		Description: fmt.Sprintf("Rename component table comp_%s to comp_%s", c.OldName, c.Component),
+	}}
+}
+
+// genRenameProperty produces an ALTER TABLE ... RENAME COLUMN statement.
+// It runs before any rebuild of the same table, which then copies the
+// column under its new name.
+func (g *Generator) genRenameProperty(c schema.Change) []Statement {
+	table := changeTable(c)
+	return []Statement{{
+		SQL:         fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s", table, c.OldName, c.Property),
+		Kind:        "rename_column",
+		Destructive: false,
+		Component:   c.Component,
+		Resource:    c.Resource,
+		Relation:    c.Relation,
+		Description: fmt.Sprintf("Rename column %q to %q in %s", c.OldName, c.Property, table),
+	}}
+}
+
+// genAddComponent produces a CREATE TABLE via the existing componentTableSQL,
+// followed by the component's declared indexes.
 func (g *Generator) genAddComponent(c schema.Change) []Statement {
 	comp, canonicalName := schema.ComponentByName(g.file, c.Component)
 	if canonicalName == "" {
@@ -116,38 +302,146 @@ func (g *Generator) genAddComponent(c schema.Change) []Statement {
 			Description: "ERROR: " + err.Error(),
 		}}
 	}
-	return []Statement{{
+	stmts := []Statement{{
 		SQL:         sql,
 		Kind:        "create_table",
 		Destructive: false,
 		Component:   c.Component,
 		Description: "Create component table comp_" + c.Component,
 	}}
+	for _, idx := range schema.ComponentIndexes(canonicalName, comp) {
+		stmts = append(stmts, Statement{
+			SQL:         createIndexSQL(c.Component, idx),
+			Kind:        "create_index",
+			Destructive: false,
+			Component:   c.Component,
+			Description: fmt.Sprintf("Create index %s on comp_%s", idx.Name, c.Component),
+		})
+	}
+	return stmts
+}
+
+// genAddIndex produces a CREATE INDEX for a declared index.
+func (g *Generator) genAddIndex(c schema.Change) []Statement {
+	comp, canonicalName := schema.ComponentByName(g.file, c.Component)
+	for _, idx := range schema.ComponentIndexes(canonicalName, comp) {
+		if idx.Name == c.Index {
+			return []Statement{{
+				SQL:         createIndexSQL(c.Component, idx),
+				Kind:        "create_index",
+				Destructive: false,
+				Component:   c.Component,
+				Description: fmt.Sprintf("Create index %s on comp_%s", idx.Name, c.Component),
+			}}
+		}
+	}
+	return []Statement{{
+		Kind:        "error",
+		Destructive: false,
+		Component:   c.Component,
+		Description: "ERROR: unknown index " + c.Index + " on comp_" + c.Component,
+	}}
+}
+
+// genRemoveIndex produces a DROP INDEX. Dropping an index loses no data.
+func (g *Generator) genRemoveIndex(c schema.Change) []Statement {
+	return []Statement{{
+		SQL:         "DROP INDEX IF EXISTS " + c.Index,
+		Kind:        "drop_index",
+		Destructive: false,
+		Component:   c.Component,
+		Description: fmt.Sprintf("Drop index %s on comp_%s", c.Index, c.Component),
+	}}
 }
 
 // genAddProperty produces an ALTER TABLE ADD COLUMN statement.
 func (g *Generator) genAddProperty(c schema.Change) []Statement {
-	comp, canonicalName := schema.ComponentByName(g.file, c.Component)
-	if canonicalName == "" || comp.Type != schema.ComponentTypeObject {
+	var props map[string]schema.Property
+	if c.Resource != "" {
+		res, canonicalName := schema.ResourceByName(g.file, c.Resource)
+		if canonicalName == "" {
+			return []Statement{{
+				Kind:        "error",
+				Destructive: false,
+				Resource:    c.Resource,
+				Description: "ERROR: unknown resource " + c.Resource,
+			}}
+		}
+		props = res.Properties
+	} else if c.Relation != "" {
+		rel, canonicalName := schema.RelationByName(g.file, c.Relation)
+		if canonicalName == "" {
+			return []Statement{{
+				Kind:        "error",
+				Destructive: false,
+				Relation:    c.Relation,
+				Description: "ERROR: unknown relation " + c.Relation,
+			}}
+		}
+		props = rel.Properties
+	} else {
+		comp, canonicalName := schema.ComponentByName(g.file, c.Component)
+		if canonicalName == "" || comp.Type != schema.ComponentTypeObject {
+			return []Statement{{
+				Kind:        "error",
+				Destructive: false,
+				Component:   c.Component,
+				Description: "ERROR: unknown property or non-object component " + c.Component,
+			}}
+		}
+		props = comp.Properties
+	}
+	table := changeTable(c)
+
+	// Look up the property definition.
+	prop, found := schema.PropertyByName(props, c.Property)
+	if !found {
 		return []Statement{{
 			Kind:        "error",
 			Destructive: false,
 			Component:   c.Component,
-			Description: "ERROR: unknown property or non-object component " + c.Component,
+			Resource:    c.Resource,
+			Relation:    c.Relation,
+			Description: "ERROR: unknown property " + c.Property + " on " + table,
 		}}
 	}
 
-	// Look up the property definition.
-	prop, found := schema.PropertyByName(comp.Properties, c.Property)
-	if !found {
+	// A VIRTUAL generated column is added as declared. A STORED one cannot
+	// be added by ALTER TABLE; Generate rebuilds the table instead, so it
+	// only gets here when a destructive change on the table makes that
+	// rebuild destructive too.
+	if prop.IsComputed() {
+		if prop.Stored {
+			return g.genRebuildForStoredColumn(c, true)
+		}
 		return []Statement{{
-			Kind:        "error",
+			SQL:         fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", table, objectColumnDef(c.Property, prop)),
+			Kind:        "alter_add_column",
 			Destructive: false,
 			Component:   c.Component,
-			Description: "ERROR: unknown property " + c.Property + " on comp_" + c.Component,
+			Resource:    c.Resource,
+			Relation:    c.Relation,
+			Description: fmt.Sprintf("Add generated column %q to %s", c.Property, table),
 		}}
 	}
 
+	// Existing rows are backfilled with the property's zero value when it
+	// declares no default; one its constraints reject cannot be written.
+	if !prop.Nullable && !prop.HasDefault() {
+		zero := zeroValueForProperty(prop)
+		if err := prop.CheckValue(zero); err != nil {
+			return []Statement{{
+				Kind:        "error",
+				Destructive: false,
+				Component:   c.Component,
+				Resource:    c.Resource,
+				Relation:    c.Relation,
+				Description: fmt.Sprintf("ERROR: cannot add %q to %s: existing rows would get %#v (%v); declare a default",
+					c.Property, table, zero, err),
+			}}
+		}
+	}
+
 	sqlType := schema.PropertySQLType(prop)
 	dflt := defaultValueForProperty(prop)
 	// entity-ref columns must be nullable when added to an existing table:
@@ -156,19 +450,114 @@ func (g *Generator) genAddProperty(c schema.Change) []Statement {
 	if prop.Type == schema.PropertyTypeEntityRef {
 		notNullClause = ""
 	}
-	extraClause := ""
+	// Nullable columns backfill existing rows with NULL unless a default is
+	// declared.
+	if prop.Nullable {
+		notNullClause = ""
+		if !prop.HasDefault() {
+			dflt = "NULL"
+		}
+	}
+	// The CHECK matches the one a bootstrap or rebuild declares, so the
+	// column has the same DDL whichever path created it.
+	extraClause := propertyCheckClause(strings.ToLower(c.Property), prop)
 	if prop.Type == schema.PropertyTypeEntityRef {
-		extraClause = " REFERENCES entities(id)"
+		extraClause += " " + schema.ReferenceClause(prop.OnDelete)
 	}
-	sql := fmt.Sprintf("ALTER TABLE comp_%s ADD COLUMN %s %s%s DEFAULT %s%s",
-		c.Component, c.Property, sqlType, notNullClause, dflt, extraClause)
+	sql := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s%s DEFAULT %s%s",
+		table, c.Property, sqlType, notNullClause, dflt, extraClause)
 
 	return []Statement{{
 		SQL:         sql,
 		Kind:        "alter_add_column",
 		Destructive: false,
 		Component:   c.Component,
-		Description: fmt.Sprintf("Add column %q to comp_%s", c.Property, c.Component),
+		Resource:    c.Resource,
+		Relation:    c.Relation,
+		Description: fmt.Sprintf("Add column %q to %s", c.Property, table),
+	}}
+}
+
+// genAddResource produces the CREATE TABLE for a resource and the INSERT
+// of its single row.
+func (g *Generator) genAddResource(c schema.Change) []Statement {
+	res, canonicalName := schema.ResourceByName(g.file, c.Resource)
+	if canonicalName == "" {
+		return []Statement{{
+			Kind:        "error",
+			Destructive: false,
+			Resource:    c.Resource,
+			Description: "ERROR: unknown resource " + c.Resource,
+		}}
+	}
+	return []Statement{
+		{
+			SQL:         resourceTableSQL(canonicalName, res),
+			Kind:        "create_table",
+			Destructive: false,
+			Resource:    c.Resource,
+			Description: "Create resource table res_" + c.Resource,
+		},
+		{
+			SQL:         resourceSeedSQL(canonicalName),
+			Kind:        "insert_row",
+			Destructive: false,
+			Resource:    c.Resource,
+			Description: "Insert the row of res_" + c.Resource,
+		},
+	}
+}
+
+// genRemoveResource produces a DROP TABLE IF EXISTS statement.
+func (g *Generator) genRemoveResource(c schema.Change) []Statement {
+	return []Statement{{
+		SQL:         "DROP TABLE IF EXISTS res_" + c.Resource,
+		Kind:        "drop_table",
+		Destructive: true,
+		Resource:    c.Resource,
+		Description: "Drop resource table res_" + c.Resource,
+	}}
+}
+
+// genAddRelation produces the CREATE TABLE for a relation and the index
+// serving reverse lookups.
+func (g *Generator) genAddRelation(c schema.Change) []Statement {
+	rel, canonicalName := schema.RelationByName(g.file, c.Relation)
+	if canonicalName == "" {
+		return []Statement{{
+			Kind:        "error",
+			Destructive: false,
+			Relation:    c.Relation,
+			Description: "ERROR: unknown relation " + c.Relation,
+		}}
+	}
+	return []Statement{
+		{
+			SQL:         relationTableSQL(canonicalName, rel),
+			Kind:        "create_table",
+			Destructive: false,
+			Relation:    c.Relation,
+			Description: "Create relation table rel_" + c.Relation,
+		},
+		{
+			SQL:         relationIndexSQL(canonicalName),
+			Kind:        "create_index",
+			Destructive: false,
+			Relation:    c.Relation,
+			Description: fmt.Sprintf("Create index idx_rel_%s_target on rel_%s", c.Relation, c.Relation),
+		},
+	}
+}
+
+// genRemoveRelation produces a DROP TABLE IF EXISTS statement, which also
+// drops the table's index.
+func (g *Generator) genRemoveRelation(c schema.Change) []Statement {
+	return []Statement{{
+		SQL:         "DROP TABLE IF EXISTS rel_" + c.Relation,
+		Kind:        "drop_table",
+		Destructive: true,
+		Relation:    c.Relation,
+		Description: "Drop relation table rel_" + c.Relation,
 	}}
 }
 
@@ -190,22 +579,50 @@ func (g *Generator) genRemoveProperty(c schema.Change) []Statement {
 			Kind:        "error",
 			Destructive: true,
 			Component:   c.Component,
-			Description: "ERROR: cannot rebuild comp_" + c.Component + " — no domain schema available",
+			Resource:    c.Resource,
+			Relation:    c.Relation,
+			Description: "ERROR: cannot rebuild " + changeTable(c) + " — no domain schema available",
 		}}
 	}
+	if c.Resource != "" {
+		return g.genRebuildResource(c.Resource, &c)
+	}
+	if c.Relation != "" {
+		return g.genRebuildRelation(c.Relation, &c)
+	}
 	return g.genRebuild(c.Component, &c)
 }
 
-// genChangePropertyType produces a table-rebuild sequence to change a column's type.
+// genRebuildForStoredColumn produces the table rebuild that adds a STORED
+// generated column, and every other column added to the table in the same
+// batch. The rebuild is marked destructive only when destructive is set.
+func (g *Generator) genRebuildForStoredColumn(c schema.Change, destructive bool) []Statement {
+	stmts := g.genChangePropertyType(c)
+	for i := range stmts {
+		stmts[i].Destructive = destructive
+	}
+	return stmts
+}
+
+// genChangePropertyType produces a table-rebuild sequence to change a
+// column's type, nullability, foreign key or computed expression.
 func (g *Generator) genChangePropertyType(c schema.Change) []Statement {
 	if g.domain == nil {
 		return []Statement{{
 			Kind:        "error",
 			Destructive: true,
 			Component:   c.Component,
-			Description: "ERROR: cannot rebuild comp_" + c.Component + " — no domain schema available",
+			Resource:    c.Resource,
+			Relation:    c.Relation,
+			Description: "ERROR: cannot rebuild " + changeTable(c) + " — no domain schema available",
 		}}
 	}
+	if c.Resource != "" {
+		return g.genRebuildResource(c.Resource, &c)
+	}
+	if c.Relation != "" {
+		return g.genRebuildRelation(c.Relation, &c)
+	}
 	return g.genRebuild(c.Component, &c)
 }
 
@@ -216,7 +633,11 @@ func (g *Generator) genChangePropertyType(c schema.Change) []Statement {
 //	INSERT INTO comp_<name>_new SELECT <cols> FROM comp_<name>;
 //	DROP TABLE comp_<name>;
 //	ALTER TABLE comp_<name>_new RENAME TO comp_<name>;
+//	CREATE INDEX ... ON comp_<name>(...);  -- one per declared index
 //	PRAGMA foreign_keys = ON;
+//
+// Dropping the old table drops its indexes, so every index declared in
+// the file is recreated on the new table.
 func (g *Generator) genRebuild(compName string, change *schema.Change) []Statement {
 	// Look up file component definition.
 	comp, canonicalName := schema.ComponentByName(g.file, compName)
@@ -229,8 +650,15 @@ func (g *Generator) genRebuild(compName string, change *schema.Change) []Stateme
 		}}
 	}
 
-	// Look up domain (DB) component to verify the table exists before rebuilding.
-	if _, ok := g.domain.Components[compName]; !ok {
+	// Look up domain (DB) component to verify the table exists before
+	// rebuilding. A renamed component is still known by its old name there.
+	domainName := compName
+	domainComp, inDomain := g.domain.Components[compName]
+	if !inDomain && comp.RenamedFrom != "" {
+		domainName = strings.ToLower(comp.RenamedFrom)
+		domainComp, inDomain = g.domain.Components[domainName]
+	}
+	if !inDomain {
 		return []Statement{{
 			Kind:        "error",
 			Destructive: true,
@@ -241,66 +669,285 @@ func (g *Generator) genRebuild(compName string, change *schema.Change) []Stateme
 
 	// Build the new column list from the file schema.
 	newCols := buildNewColumns(comp)
+	selectList, lossy := rebuildSelectList(newCols, comp.Properties,
+		"comp_"+domainName, "comp_"+compName, "entity_id", domainComp.Columns)
+	tableName := "comp_" + compName
+	stmts := rebuildStatements(tableName, newCols, nil, selectList, lossy, Statement{Component: compName})
 
+	// 5. Recreate the declared indexes lost with the old table.
+	for _, idx := range schema.ComponentIndexes(canonicalName, comp) {
+		stmts = append(stmts, Statement{
+			SQL:         createIndexSQL(compName, idx),
+			Kind:        "create_index",
+			Destructive: false,
+			Component:   compName,
+			Description: fmt.Sprintf("Recreate index %s on %s", idx.Name, tableName),
+		})
+	}
+
+	return stmts
+}
+
+// genRebuildResource generates the table-rebuild SQL sequence for a
+// resource table, as genRebuild does for a component table. Resource
+// tables have no indexes to recreate.
+func (g *Generator) genRebuildResource(resName string, change *schema.Change) []Statement {
+	res, canonicalName := schema.ResourceByName(g.file, resName)
+	if canonicalName == "" {
+		return []Statement{{
+			Kind:        "error",
+			Destructive: true,
+			Resource:    resName,
+			Description: "ERROR: unknown resource " + resName,
+		}}
+	}
+	domainRes, inDomain := g.domain.Resources[resName]
+	if !inDomain {
+		return []Statement{{
+			Kind:        "error",
+			Destructive: true,
+			Resource:    resName,
+			Description: "ERROR: res_" + resName + " not found in domain schema",
+		}}
+	}
+
+	tableName := "res_" + resName
+	newCols := resourceColumns(res)
+	selectList, lossy := rebuildSelectList(newCols, res.Properties,
+		tableName, tableName, "id", domainRes.Columns)
+	return rebuildStatements(tableName, newCols, nil, selectList, lossy, Statement{Resource: resName})
+}
+
+// genRebuildRelation generates the table-rebuild SQL sequence for a
+// relation table, as genRebuild does for a component table, keeping the
+// composite primary key and recreating the target_id index.
+func (g *Generator) genRebuildRelation(relName string, change *schema.Change) []Statement {
+	rel, canonicalName := schema.RelationByName(g.file, relName)
+	if canonicalName == "" {
+		return []Statement{{
+			Kind:        "error",
+			Destructive: true,
+			Relation:    relName,
+			Description: "ERROR: unknown relation " + relName,
+		}}
+	}
+	domainRel, inDomain := g.domain.Relations[relName]
+	if !inDomain {
+		return []Statement{{
+			Kind:        "error",
+			Destructive: true,
+			Relation:    relName,
+			Description: "ERROR: rel_" + relName + " not found in domain schema",
+		}}
+	}
+
+	tableName := "rel_" + relName
+	newCols := relationColumns(rel)
+	selectList, lossy := rebuildSelectList(newCols, rel.Properties,
+		tableName, tableName, "source_id", domainRel.Columns)
+	stmts := rebuildStatements(tableName, newCols, []string{relationKeyConstraint}, selectList, lossy,
+		Statement{Relation: relName})
+	return append(stmts, Statement{
+		SQL:         relationIndexSQL(canonicalName),
+		Kind:        "create_index",
+		Destructive: false,
+		Relation:    relName,
+		Description: fmt.Sprintf("Recreate index idx_rel_%s_target on %s", relName, tableName),
+	})
+}
+
+// rebuildStatements returns the copy-and-swap sequence that replaces
+// tableName with a table of newCols and the table constraints in
+// constraints, copying rows through selectList. Every statement takes its
+// Component, Resource or Relation from subject.
+func rebuildStatements(
+	tableName string,
+	newCols, constraints []string,
+	selectList string,
+	lossy []LossyCheck,
+	subject Statement,
+) []Statement {
 	// Build the named column list for INSERT ... SELECT. We derive column
-	// names from the new table layout (entity_id first, then the new schema's
-	// properties in sorted order). Using named columns ensures entity_id is
-	// preserved and column ordering mismatches between old and new tables
-	// cannot cause data to land in the wrong column.
+	// names from the new table layout (the key column first, then the new
+	// schema's properties in sorted order). Using named columns ensures the
+	// key is preserved and column ordering mismatches between old and new
+	// tables cannot cause data to land in the wrong column.
 	colNames := make([]string, 0, len(newCols))
-	for _, colDef := range newCols {
+	for _, colDef := range copiedColumns(newCols) {
 		colNames = append(colNames, strings.Fields(colDef)[0])
 	}
 	colList := strings.Join(colNames, ", ")
-
-	tableName := "comp_" + compName
 	tempName := tableName + "_new"
 
-	stmts := make([]Statement, 0, 4)
+	stmt := func(sql, description string) Statement {
+		s := subject
+		s.SQL = sql
+		s.Kind = "rebuild_table"
+		s.Destructive = true
+		s.Description = description
+		return s
+	}
 
-	// 1. CREATE TABLE comp_<name>_new (...)
+	// 1. CREATE TABLE <table>_new (...)
 	// PRAGMA foreign_keys toggle is handled by the caller (MigrationRunner)
 	// outside the transaction, since SQLite ignores it inside a transaction.
-	createSQL := buildCreateTable(tempName, compName, newCols)
-	stmts = append(stmts, Statement{
-		SQL:         createSQL,
-		Kind:        "rebuild_table",
-		Destructive: true,
-		Component:   compName,
-		Description: "Create temp table " + tempName,
-	})
+	create := stmt(buildCreateTable(tempName, subject.Component, append(newCols, constraints...)),
+		"Create temp table "+tempName)
 
-	// 2. INSERT INTO comp_<name>_new (cols) SELECT cols FROM comp_<name>
-	// Named columns preserve entity_id and survive column-order differences.
-	selectSQL := fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s",
-		tempName, colList, colList, tableName)
-	stmts = append(stmts, Statement{
-		SQL:         selectSQL,
-		Kind:        "rebuild_table",
-		Destructive: true,
-		Component:   compName,
-		Description: "Copy data from " + tableName,
-	})
+	// 2. INSERT INTO <table>_new (cols) SELECT cols FROM <table>
+	// Named columns preserve the key and survive column-order differences;
+	// columns whose type changes are CAST to the new type.
+	copyRows := stmt(fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s",
+		tempName, colList, selectList, tableName), "Copy data from "+tableName)
+	copyRows.Lossy = len(lossy) > 0
+	copyRows.LossyChecks = lossy
 
-	// 3. DROP TABLE comp_<name>
-	stmts = append(stmts, Statement{
-		SQL:         "DROP TABLE " + tableName,
-		Kind:        "rebuild_table",
-		Destructive: true,
-		Component:   compName,
-		Description: "Drop old table " + tableName,
-	})
+	return []Statement{
+		create,
+		copyRows,
+		// 3. DROP TABLE <table>
+		stmt("DROP TABLE "+tableName, "Drop old table "+tableName),
+		// 4. ALTER TABLE <table>_new RENAME TO <table>
+		stmt("ALTER TABLE "+tempName+" RENAME TO "+tableName, "Rename temp table to "+tableName),
+	}
+}
 
-	// 4. ALTER TABLE comp_<name>_new RENAME TO comp_<name>
-	stmts = append(stmts, Statement{
-		SQL:         "ALTER TABLE " + tempName + " RENAME TO " + tableName,
-		Kind:        "rebuild_table",
-		Destructive: true,
-		Component:   compName,
-		Description: "Rename temp table to " + tableName,
-	})
+// rebuildSelectList returns the SELECT list used to copy rows into the
+// rebuilt table, one expression per column of newCols:
+//
+//   - a column whose SQL type differs from the one in the DB is wrapped in
+//     CAST(col AS <new type>), so converted values keep their meaning
+//     instead of relying on column affinity;
+//   - a NOT NULL property whose DB column is nullable or generated is
+//     wrapped in COALESCE so existing NULLs are replaced by the property's
+//     default instead of failing the copy;
+//   - a nullable entity-ref property whose DB column lacks its foreign key,
+//     or has another delete policy, drops references to entities that no
+//     longer exist, which the new foreign key would otherwise reject;
+//   - a property with no column in the DB yet, added in the same batch as a
+//     STORED generated column, is filled as ALTER TABLE ADD COLUMN would;
+//   - a property whose CHECK changes replaces the values the new CHECK
+//     rejects with its default, or NULL when it is nullable.
+//
+// Generated columns are computed by SQLite and not copied.
+//
+// It also returns a LossyCheck for every conversion that may alter values
+// and for every constraint change that may scrub them.
+// oldTable and domainCols describe the table as it exists before the
+// migration; table is its name once renames have run, and keyCol its
+// primary key.
+func rebuildSelectList(
+	newCols []string,
+	props map[string]schema.Property,
+	oldTable, table, keyCol string,
+	domainCols []DomainColumn,
+) (string, []LossyCheck) {
+	oldCols := make(map[string]DomainColumn, len(domainCols))
+	for _, c := range domainCols {
+		oldCols[strings.ToLower(c.Name)] = c
+	}
 
-	return stmts
+	copied := copiedColumns(newCols)
+	exprs := make([]string, len(copied))
+	var lossy []LossyCheck
+	for i, colDef := range copied {
+		fields := strings.Fields(colDef)
+		col, newType := fields[0], fields[1]
+		expr := col
+
+		// A column renamed in the same batch is still under its old name
+		// in the domain schema.
+		oldName := col
+		prop, isProp := schema.PropertyByName(props, col)
+		if _, ok := oldCols[col]; !ok && isProp && prop.RenamedFrom != "" {
+			oldName = strings.ToLower(prop.RenamedFrom)
+		}
+		if _, ok := oldCols[oldName]; !ok && isProp {
+			expr = defaultValueForProperty(prop)
+			if prop.Nullable && !prop.HasDefault() {
+				expr = "NULL"
+			}
+		}
+		if old, ok := oldCols[oldName]; ok && !old.IsPK && strings.ToUpper(old.SQLType) != newType {
+			oldType := strings.ToUpper(old.SQLType)
+			expr = fmt.Sprintf("CAST(%s AS %s)", col, newType)
+			if conversionIsLossy(oldType, newType) {
+				lossy = append(lossy, LossyCheck{
+					Column:  col,
+					OldType: oldType,
+					NewType: newType,
+					Query: fmt.Sprintf(
+						"SELECT %s, %s FROM %s WHERE CAST(%s AS %s) <> %s ORDER BY %s",
+						keyCol, oldName, oldTable, oldName, newType, oldName, keyCol),
+				})
+			}
+		}
+
+		// A column whose CHECK changes keeps only the values the new
+		// constraint accepts; the others are replaced as a missing value
+		// would be, and reported. Should that value break the constraint
+		// too, the copy fails and the migration rolls back.
+		if old, ok := oldCols[oldName]; ok && isProp && !old.IsPK && !prop.IsComputed() &&
+			prop.CheckExpr(col) != "" && old.Check != prop.CheckExpr(oldName) {
+			oldValue := oldName
+			if strings.ToUpper(old.SQLType) != newType {
+				oldValue = fmt.Sprintf("CAST(%s AS %s)", oldName, newType)
+			}
+			fallback := defaultValueForProperty(prop)
+			if prop.Nullable && !prop.HasDefault() {
+				fallback = "NULL"
+			}
+			lossy = append(lossy, LossyCheck{
+				Column:     col,
+				OldType:    strings.ToUpper(old.SQLType),
+				NewType:    newType,
+				Constraint: prop.CheckExpr(col),
+				Query: fmt.Sprintf("SELECT %s, %s FROM %s WHERE NOT (%s) ORDER BY %s",
+					keyCol, oldName, oldTable, prop.CheckExpr(oldValue), keyCol),
+			})
+			expr = fmt.Sprintf("CASE WHEN NOT (%s) THEN %s ELSE %s END", prop.CheckExpr(expr), fallback, expr)
+		}
+
+		// Every column is compared with the DB, not only the one the change
+		// that triggered the rebuild names: one rebuild covers all the
+		// table's changes in the batch.
+		if old, ok := oldCols[oldName]; ok && isProp && prop.Type != schema.PropertyTypeEntityRef &&
+			schema.PropertyNotNull(prop) && (old.Nullable || old.Computed != "") {
+			expr = fmt.Sprintf("COALESCE(%s, %s)", expr, defaultValueForProperty(prop))
+		}
+		if old, ok := oldCols[oldName]; ok && isProp && prop.Type == schema.PropertyTypeEntityRef && prop.Nullable &&
+			(!old.References || old.OnDelete != prop.OnDelete) {
+			expr = fmt.Sprintf("(SELECT e.id FROM entities e WHERE e.id = %s.%s)", table, col)
+		}
+		exprs[i] = expr
+	}
+	return strings.Join(exprs, ", "), lossy
+}
+
+// copiedColumns returns the column definitions of newCols whose values a
+// rebuild copies: all but the generated columns.
+func copiedColumns(newCols []string) []string {
+	out := make([]string, 0, len(newCols))
+	for _, colDef := range newCols {
+		if !strings.Contains(colDef, " GENERATED ALWAYS AS (") {
+			out = append(out, colDef)
+		}
+	}
+	return out
+}
+
+// conversionIsLossy reports whether casting a value stored as oldType to
+// newType may change it. Any value converts to TEXT without loss, and
+// INTEGER widens to REAL; narrowing to INTEGER or parsing TEXT as a number
+// may truncate or zero a value.
+func conversionIsLossy(oldType, newType string) bool {
+	switch newType {
+	case "INTEGER":
+		return oldType == "REAL" || oldType == "TEXT"
+	case "REAL":
+		return oldType == "TEXT"
+	}
+	return false
 }
 
 // buildNewColumns generates the column definitions for a rebuild table.
@@ -317,14 +964,11 @@ func buildNewColumns(comp schema.Component) []string {
 		sort.Strings(names)
 
 		for _, propName := range names {
-			prop := comp.Properties[propName]
-			sqlType := schema.PropertySQLType(prop)
-			cols = append(cols, fmt.Sprintf("%s %s NOT NULL",
-				strings.ToLower(propName), sqlType))
+			cols = append(cols, objectColumnDef(propName, comp.Properties[propName]))
 		}
 
 	case schema.ComponentTypeEntityRef:
-		cols = append(cols, "target_entity_id INTEGER NOT NULL REFERENCES entities(id)")
+		cols = append(cols, "target_entity_id INTEGER NOT NULL "+schema.ReferenceClause(comp.OnDelete))
 	case schema.ComponentTypeArray:
 		cols = append(cols, "value TEXT NOT NULL DEFAULT '[]'")
 	case schema.ComponentTypeString:
@@ -333,6 +977,8 @@ func buildNewColumns(comp schema.Component) []string {
 		cols = append(cols, "value INTEGER NOT NULL DEFAULT 0")
 	case schema.ComponentTypeNumber:
 		cols = append(cols, "value REAL NOT NULL DEFAULT 0.0")
+	case schema.ComponentTypeTag:
+		// entity_id only.
 	}
 
 	return cols
@@ -350,9 +996,13 @@ func buildCreateTable(tableName string, compName string, cols []string) string {
 	return sql
 }
 
-// defaultValueForProperty returns the SQL DEFAULT expression for a property
-// type, used in ALTER TABLE ADD COLUMN statements.
+// defaultValueForProperty returns the SQL DEFAULT expression for a property,
+// used in ALTER TABLE ADD COLUMN statements to backfill existing rows. A
+// declared default wins; otherwise the zero value for the type is used.
 func defaultValueForProperty(p schema.Property) string {
+	if v, ok := p.DefaultValue(); ok {
+		return sqlLiteral(v)
+	}
 	switch p.Type {
 	case schema.PropertyTypeString:
 		return "''"
@@ -387,6 +1037,9 @@ func reorderStructuralChanges(stmts []Statement) []Statement {
 	// Map component name → CREATE statement index.
 	createIdx := map[string]int{}
 	for i, s := range stmts {
+		if s.Component == "" {
+			continue // resource and relation tables never change structure in place
+		}
 		switch s.Kind {
 		case "drop_table":
 			dropIdx[s.Component] = i
@@ -412,7 +1065,7 @@ func reorderStructuralChanges(stmts []Statement) []Statement {
 		if hoistedDrops[i] {
 			continue // already emitted before its CREATE
 		}
-		if s.Kind == "create_table" {
+		if s.Kind == "create_table" && s.Component != "" {
 			di, hasDrop := dropIdx[s.Component]
 			if hasDrop && di > i {
 				reordered = append(reordered, stmts[di]) // DROP first
@@ -689,6 +689,323 @@ func TestGenChangePropertyType_RebuildSequence(t *testing.T) {
 	}
 }
 
+func TestGenChangePropertyNullability_RebuildSequence(t *testing.T) {
+	file := &schema.DatabaseSchema{
+		Components: map[string]schema.Component{
+			"Target": {
+				Type: schema.ComponentTypeObject,
+				Properties: map[string]schema.Property{
+					"target_x": {Type: schema.PropertyTypeNumber, Nullable: true},
+				},
+			},
+		},
+	}
+	domain := &DomainSchema{
+		Components: map[string]DomainComponent{
+			"target": {
+				Type: "object",
+				Columns: []DomainColumn{
+					{Name: "entity_id", SQLType: "INTEGER", IsPK: true},
+					{Name: "target_x", SQLType: "REAL"},
+				},
+			},
+		},
+	}
+	g := NewGenerator(file, domain, Config{StrictDrop: true})
+
+	stmts := g.Generate([]schema.Change{{
+		Kind:        schema.ChangedPropertyNullability,
+		Component:   "target",
+		Property:    "target_x",
+		NewNullable: true,
+	}})
+
+	if len(stmts) != 4 {
+		t.Fatalf("got %d statements, want 4 (CREATE, INSERT, DROP, RENAME)", len(stmts))
+	}
+	assertContainsDDL(t, stmts[0].SQL, "target_x REAL\n")
+	assertNotContainsDDL(t, stmts[0].SQL, "target_x REAL NOT NULL")
+	assertNotContainsDDL(t, stmts[1].SQL, "COALESCE")
+}
+
+func TestGenChangePropertyNullability_TighteningCoalescesNulls(t *testing.T) {
+	file := &schema.DatabaseSchema{
+		Components: map[string]schema.Component{
+			"Target": {
+				Type: schema.ComponentTypeObject,
+				Properties: map[string]schema.Property{
+					"target_x": {Type: schema.PropertyTypeNumber, Default: 5.0},
+				},
+			},
+		},
+	}
+	domain := &DomainSchema{
+		Components: map[string]DomainComponent{
+			"target": {
+				Type: "object",
+				Columns: []DomainColumn{
+					{Name: "entity_id", SQLType: "INTEGER", IsPK: true},
+					{Name: "target_x", SQLType: "REAL", Nullable: true},
+				},
+			},
+		},
+	}
+	g := NewGenerator(file, domain, Config{StrictDrop: true})
+
+	stmts := g.Generate([]schema.Change{{
+		Kind:        schema.ChangedPropertyNullability,
+		Component:   "target",
+		Property:    "target_x",
+		OldNullable: true,
+	}})
+
+	if len(stmts) != 4 {
+		t.Fatalf("got %d statements, want 4 (CREATE, INSERT, DROP, RENAME)", len(stmts))
+	}
+	assertContainsDDL(t, stmts[0].SQL, "target_x REAL NOT NULL")
+	assertContainsDDL(t, stmts[1].SQL, "(entity_id, target_x) SELECT entity_id, COALESCE(target_x, 5) FROM comp_target")
+}
+
+func TestGenerate_OneRebuildAppliesEveryColumnChange(t *testing.T) {
+	file := &schema.DatabaseSchema{
+		Components: map[string]schema.Component{
+			"Target": {
+				Type: schema.ComponentTypeObject,
+				Properties: map[string]schema.Property{
+					"entity":   {Type: schema.PropertyTypeEntityRef, Nullable: true},
+					"target_x": {Type: schema.PropertyTypeInteger},
+					"target_y": {Type: schema.PropertyTypeNumber, Default: 5.0},
+				},
+			},
+		},
+	}
+	domain := &DomainSchema{
+		Components: map[string]DomainComponent{
+			"target": {
+				Type: "object",
+				Columns: []DomainColumn{
+					{Name: "entity_id", SQLType: "INTEGER", IsPK: true},
+					{Name: "entity", SQLType: "INTEGER", Nullable: true},
+					{Name: "target_x", SQLType: "REAL"},
+					{Name: "target_y", SQLType: "REAL", Nullable: true},
+				},
+			},
+		},
+	}
+	g := NewGenerator(file, domain, Config{StrictDrop: true})
+
+	// Only the first change reaches the rebuild; the others must still be
+	// applied to their columns.
+	stmts := g.Generate([]schema.Change{
+		{Kind: schema.ChangedPropertyType, Component: "target", Property: "target_x", OldType: "REAL", NewType: "INTEGER"},
+		{Kind: schema.ChangedPropertyNullability, Component: "target", Property: "target_y", OldNullable: true},
+		{Kind: schema.ChangedPropertyReference, Component: "target", Property: "entity"},
+	})
+
+	if len(stmts) != 4 {
+		t.Fatalf("got %d statements, want 4 (CREATE, INSERT, DROP, RENAME)", len(stmts))
+	}
+	assertContainsDDL(t, stmts[1].SQL, "SELECT entity_id, (SELECT e.id FROM entities e WHERE e.id = comp_target.entity), "+
+		"CAST(target_x AS INTEGER), COALESCE(target_y, 5) FROM comp_target")
+}
+
+func TestGenAddProperty_Nullable(t *testing.T) {
+	file := &schema.DatabaseSchema{
+		Components: map[string]schema.Component{
+			"Target": {
+				Type: schema.ComponentTypeObject,
+				Properties: map[string]schema.Property{
+					"target_x": {Type: schema.PropertyTypeNumber, Nullable: true},
+				},
+			},
+		},
+	}
+	g := NewGenerator(file, nil, Config{})
+
+	stmts := g.Generate([]schema.Change{{
+		Kind:      schema.ChangeAddedProperty,
+		Component: "target",
+		Property:  "target_x",
+	}})
+
+	if len(stmts) != 1 {
+		t.Fatalf("got %d statements, want 1", len(stmts))
+	}
+	assertContainsDDL(t, stmts[0].SQL, "ADD COLUMN target_x REAL DEFAULT NULL")
+}
+
+func TestGenAddProperty_ZeroValueBreaksConstraints(t *testing.T) {
+	one, minLen := 1.0, 1
+	file := &schema.DatabaseSchema{
+		Components: map[string]schema.Component{
+			"Stats": {
+				Type: schema.ComponentTypeObject,
+				Properties: map[string]schema.Property{
+					"level": {Type: schema.PropertyTypeInteger, Minimum: &one},
+					"rank":  {Type: schema.PropertyTypeInteger, Minimum: &one, Default: 1.0},
+					"title": {Type: schema.PropertyTypeString, Enum: []any{"grunt", "boss"}},
+					"note":  {Type: schema.PropertyTypeString, MinLength: &minLen, Nullable: true},
+				},
+			},
+		},
+	}
+	g := NewGenerator(file, nil, Config{})
+
+	for prop, wantErr := range map[string]bool{"level": true, "title": true, "rank": false, "note": false} {
+		stmts := g.Generate([]schema.Change{{Kind: schema.ChangeAddedProperty, Component: "stats", Property: prop}})
+		if len(stmts) != 1 {
+			t.Fatalf("%s: got %d statements, want 1", prop, len(stmts))
+		}
+		if got := stmts[0].Kind == "error"; got != wantErr {
+			t.Errorf("%s: statement = %+v, want error %t", prop, stmts[0], wantErr)
+		}
+	}
+}
+
+func TestGenRename_ComponentAndProperty(t *testing.T) {
+	file := &schema.DatabaseSchema{
+		Components: map[string]schema.Component{
+			"Vitality": {
+				Type:        schema.ComponentTypeObject,
+				RenamedFrom: "Health",
+				Properties: map[string]schema.Property{
+					"current": {Type: schema.PropertyTypeInteger, RenamedFrom: "hp"},
+				},
+			},
+		},
+	}
+	g := NewGenerator(file, nil, Config{})
+
+	stmts := g.Generate([]schema.Change{
+		{Kind: schema.ChangeRenamedComponent, Component: "vitality", OldName: "health"},
+		{Kind: schema.ChangeRenamedProperty, Component: "vitality", Property: "current", OldName: "hp"},
+	})
+
+	// StrictDrop is false: renames are not destructive and must survive.
+	if len(stmts) != 2 {
+		t.Fatalf("got %d statements, want 2", len(stmts))
+	}
+	if stmts[0].SQL != "ALTER TABLE comp_health RENAME TO comp_vitality" {
+		t.Errorf("stmts[0].SQL = %q", stmts[0].SQL)
+	}
+	if stmts[1].SQL != "ALTER TABLE comp_vitality RENAME COLUMN hp TO current" {
+		t.Errorf("stmts[1].SQL = %q", stmts[1].SQL)
+	}
+	if stmts[0].Kind != "rename_table" || stmts[1].Kind != "rename_column" {
+		t.Errorf("kinds = %q, %q, want rename_table, rename_column", stmts[0].Kind, stmts[1].Kind)
+	}
+}
+
+func TestGenRebuild_RenamedComponentFoundByOldName(t *testing.T) {
+	file := &schema.DatabaseSchema{
+		Components: map[string]schema.Component{
+			"Vitality": {
+				Type:        schema.ComponentTypeObject,
+				RenamedFrom: "Health",
+				Properties: map[string]schema.Property{
+					"current": {Type: schema.PropertyTypeNumber},
+				},
+			},
+		},
+	}
+	domain := &DomainSchema{
+		Components: map[string]DomainComponent{
+			"health": {
+				Type: "object",
+				Columns: []DomainColumn{
+					{Name: "entity_id", SQLType: "INTEGER", IsPK: true},
+					{Name: "current", SQLType: "INTEGER"},
+				},
+			},
+		},
+	}
+	g := NewGenerator(file, domain, Config{StrictDrop: true})
+
+	stmts := g.Generate([]schema.Change{
+		{Kind: schema.ChangeRenamedComponent, Component: "vitality", OldName: "health"},
+		{Kind: schema.ChangedPropertyType, Component: "vitality", Property: "current", OldType: "INTEGER", NewType: "REAL"},
+	})
+
+	if len(stmts) != 5 {
+		t.Fatalf("got %d statements, want 5 (RENAME + rebuild)", len(stmts))
+	}
+	for _, s := range stmts {
+		if s.Kind == "error" {
+			t.Fatalf("unexpected error statement: %s", s.Description)
+		}
+	}
+	assertContainsDDL(t, stmts[2].SQL, "FROM comp_vitality")
+}
+
+func TestGenChangePropertyType_CastsConvertedColumns(t *testing.T) {
+	file := &schema.DatabaseSchema{
+		Components: map[string]schema.Component{
+			"Stats": {
+				Type: schema.ComponentTypeObject,
+				Properties: map[string]schema.Property{
+					"level":  {Type: schema.PropertyTypeNumber},
+					"name":   {Type: schema.PropertyTypeString},
+					"weight": {Type: schema.PropertyTypeInteger},
+				},
+			},
+		},
+	}
+	domain := &DomainSchema{
+		Components: map[string]DomainComponent{
+			"stats": {
+				Type: "object",
+				Columns: []DomainColumn{
+					{Name: "entity_id", SQLType: "INTEGER", IsPK: true},
+					{Name: "level", SQLType: "INTEGER"},
+					{Name: "name", SQLType: "TEXT"},
+					{Name: "weight", SQLType: "REAL"},
+				},
+			},
+		},
+	}
+	g := NewGenerator(file, domain, Config{StrictDrop: true})
+
+	// Two type changes on one component produce a single rebuild.
+	stmts := g.Generate([]schema.Change{
+		{Kind: schema.ChangedPropertyType, Component: "stats", Property: "level", OldType: "INTEGER", NewType: "REAL"},
+		{Kind: schema.ChangedPropertyType, Component: "stats", Property: "weight", OldType: "REAL", NewType: "INTEGER"},
+	})
+	if len(stmts) != 4 {
+		t.Fatalf("got %d statements, want 4 (one rebuild)", len(stmts))
+	}
+	copyStmt := stmts[1]
+	assertContainsDDL(t, copyStmt.SQL, "SELECT entity_id, CAST(level AS REAL), name, CAST(weight AS INTEGER) FROM comp_stats")
+
+	// INTEGER → REAL widens; only REAL → INTEGER is lossy.
+	if !copyStmt.Lossy || len(copyStmt.LossyChecks) != 1 {
+		t.Fatalf("Lossy = %v, LossyChecks = %+v, want one check", copyStmt.Lossy, copyStmt.LossyChecks)
+	}
+	check := copyStmt.LossyChecks[0]
+	if check.Column != "weight" || check.OldType != "REAL" || check.NewType != "INTEGER" {
+		t.Errorf("LossyChecks[0] = %+v", check)
+	}
+	assertContainsDDL(t, check.Query, "FROM comp_stats WHERE CAST(weight AS INTEGER) <> weight")
+}
+
+func TestConversionIsLossy(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{"INTEGER", "REAL", false},
+		{"INTEGER", "TEXT", false},
+		{"REAL", "TEXT", false},
+		{"REAL", "INTEGER", true},
+		{"TEXT", "INTEGER", true},
+		{"TEXT", "REAL", true},
+	}
+	for _, tt := range tests {
+		if got := conversionIsLossy(tt.from, tt.to); got != tt.want {
+			t.Errorf("conversionIsLossy(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
 func TestGenChangePropertyType_MissingDomainReturnsError(t *testing.T) {
 	file := &schema.DatabaseSchema{
 		Components: map[string]schema.Component{
@@ -1224,6 +1541,48 @@ func TestDefaultValueForProperty_ObjectArray(t *testing.T) {
 	}
 }
 
+func TestDefaultValueForProperty_Declared(t *testing.T) {
+	tests := []struct {
+		prop schema.Property
+		want string
+	}{
+		{schema.Property{Type: schema.PropertyTypeInteger, Default: 100.0}, "100"},
+		{schema.Property{Type: schema.PropertyTypeNumber, Default: 0.5}, "0.5"},
+		{schema.Property{Type: schema.PropertyTypeString, Default: "it's"}, "'it''s'"},
+		{schema.Property{Type: schema.PropertyTypeBoolean, Default: true}, "1"},
+		{schema.Property{Type: schema.PropertyTypeArray, Default: []any{}}, "'[]'"},
+	}
+	for _, tt := range tests {
+		if got := defaultValueForProperty(tt.prop); got != tt.want {
+			t.Errorf("defaultValueForProperty(%v) = %q, want %q", tt.prop.Default, got, tt.want)
+		}
+	}
+}
+
+func TestGenAddProperty_DeclaredDefault(t *testing.T) {
+	file := &schema.DatabaseSchema{
+		Components: map[string]schema.Component{
+			"Health": {
+				Type: schema.ComponentTypeObject,
+				Properties: map[string]schema.Property{
+					"maxHp": {Type: schema.PropertyTypeInteger, Default: 100.0},
+				},
+			},
+		},
+	}
+	g := NewGenerator(file, nil, Config{})
+	stmts := g.Generate([]schema.Change{{
+		Kind:      schema.ChangeAddedProperty,
+		Component: "health",
+		Property:  "maxhp",
+		NewType:   "INTEGER",
+	}})
+	if len(stmts) != 1 {
+		t.Fatalf("got %d statements, want 1", len(stmts))
+	}
+	assertContainsDDL(t, stmts[0].SQL, "ALTER TABLE comp_health ADD COLUMN maxhp INTEGER NOT NULL DEFAULT 100")
+}
+
 func TestDefaultValueForProperty_Unknown(t *testing.T) {
 	d := defaultValueForProperty(schema.Property{Type: "bogus"})
 	if d != "NULL" {
@@ -1305,6 +1664,23 @@ func TestBuildNewColumns_ObjectPropertyNamesLowerCase(t *testing.T) {
 
 // ── genRebuild: component missing from domain map ─────────────────────
 
+func TestBuildNewColumns_PropertyConstraints(t *testing.T) {
+	maxFrame := 7.0
+	comp := schema.Component{
+		Type: schema.ComponentTypeObject,
+		Properties: map[string]schema.Property{
+			"frame":   {Type: schema.PropertyTypeInteger, Maximum: &maxFrame},
+			"imageId": {Type: schema.PropertyTypeString},
+		},
+	}
+	cols := buildNewColumns(comp)
+	if len(cols) != 3 {
+		t.Fatalf("expected 3 columns, got %d: %v", len(cols), cols)
+	}
+	assertContainsDDL(t, cols[1], "frame INTEGER NOT NULL CHECK (frame <= 7)")
+	assertNotContainsDDL(t, cols[2], "CHECK")
+}
+
 func TestGenRebuild_ComponentMissingFromDomainMap(t *testing.T) {
 	// g.domain is non-nil but the specific component is absent from
 	// its Components map — a different path than g.domain == nil.
@@ -1336,3 +1712,80 @@ func TestGenRebuild_ComponentMissingFromDomainMap(t *testing.T) {
 		t.Errorf("Description = %q, want 'not found in domain schema'", stmts[0].Description)
 	}
 }
+
+func TestGenRebuild_RecreatesDeclaredIndexes(t *testing.T) {
+	file := &schema.DatabaseSchema{
+		Components: map[string]schema.Component{
+			"Health": {
+				Type:       schema.ComponentTypeObject,
+				Properties: map[string]schema.Property{"hp": {Type: schema.PropertyTypeInteger}},
+				Indexes:    []schema.Index{{Columns: []string{"hp"}}},
+			},
+		},
+	}
+	domain := &DomainSchema{
+		Components: map[string]DomainComponent{
+			"health": {
+				Type: "object",
+				Columns: []DomainColumn{
+					{Name: "entity_id", SQLType: "INTEGER", IsPK: true},
+					{Name: "hp", SQLType: "REAL"},
+				},
+			},
+		},
+	}
+	g := NewGenerator(file, domain, Config{StrictDrop: true})
+
+	// The added_index change is covered by the rebuild's recreation.
+	stmts := g.Generate([]schema.Change{
+		{Kind: schema.ChangedPropertyType, Component: "health", Property: "hp", OldType: "REAL", NewType: "INTEGER"},
+		{Kind: schema.ChangeAddedIndex, Component: "health", Index: "idx_comp_health_hp"},
+	})
+	if len(stmts) != 5 {
+		t.Fatalf("got %d statements, want 4 rebuild + 1 index", len(stmts))
+	}
+	last := stmts[4]
+	if last.Kind != "create_index" {
+		t.Errorf("stmt 4 Kind = %q, want create_index", last.Kind)
+	}
+	if last.Destructive {
+		t.Error("stmt 4 is destructive; recreating an index loses nothing")
+	}
+	assertContainsDDL(t, last.SQL, "CREATE INDEX IF NOT EXISTS idx_comp_health_hp ON comp_health(hp)")
+}
+
+func TestGenerate_IndexChanges(t *testing.T) {
+	file := &schema.DatabaseSchema{
+		Components: map[string]schema.Component{
+			"Sprite": {
+				Type: schema.ComponentTypeObject,
+				Properties: map[string]schema.Property{
+					"imageId": {Type: schema.PropertyTypeString},
+					"layer":   {Type: schema.PropertyTypeInteger},
+				},
+				Indexes: []schema.Index{{Name: "sprite_by_image", Columns: []string{"imageId", "layer"}, Unique: true}},
+			},
+		},
+	}
+	g := NewGenerator(file, &DomainSchema{}, Config{StrictDrop: false})
+
+	stmts := g.Generate([]schema.Change{
+		{Kind: schema.ChangeRemovedIndex, Component: "sprite", Index: "old_index"},
+		{Kind: schema.ChangeAddedIndex, Component: "sprite", Index: "sprite_by_image"},
+	})
+	if len(stmts) != 2 {
+		t.Fatalf("got %d statements, want 2 (index changes are not destructive)", len(stmts))
+	}
+	if stmts[0].Kind != "drop_index" || stmts[0].SQL != "DROP INDEX IF EXISTS old_index" {
+		t.Errorf("stmt 0 = %+v", stmts[0])
+	}
+	if stmts[1].Kind != "create_index" {
+		t.Errorf("stmt 1 Kind = %q, want create_index", stmts[1].Kind)
+	}
+	assertContainsDDL(t, stmts[1].SQL, "CREATE UNIQUE INDEX IF NOT EXISTS sprite_by_image ON comp_sprite(imageid, layer)")
+
+	added := g.Generate([]schema.Change{{Kind: schema.ChangeAddedComponent, Component: "sprite"}})
+	if len(added) != 2 || added[1].Kind != "create_index" {
+		t.Errorf("added component statements = %+v, want create_table then create_index", added)
+	}
+}
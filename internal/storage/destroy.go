@@ -0,0 +1,235 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tmbritton/ecs-db/internal/schema"
+	"github.com/tmbritton/ecs-db/internal/world"
+	"modernc.org/sqlite"
+	sqliteLib "modernc.org/sqlite/lib"
+)
+
+// despawnEvent is the event of the transitions row recording that an
+// entity was destroyed. The row has no machine: machine_id is empty and
+// from_states, to_states and actions_run are empty arrays.
+const despawnEvent = "despawn"
+
+// destroyEntity deletes the entity row inside tx. The foreign keys do the
+// rest of the cleanup: comp_*, rel_* and behavior_components rows cascade,
+// and entity-ref columns pointing at the entity follow their onDelete
+// policy. event_queue and transitions carry no foreign key, so pending
+// events are deleted here and the despawn row is written here.
+//
+// With a schema, references whose policy refuses the delete are looked up
+// first so the *world.EntityReferencedError can name them; without one the
+// failed foreign key check is reported without details.
+func destroyEntity(ctx context.Context, tx *sql.Tx, s *schema.DatabaseSchema, entityID, tick int64) error {
+	var exists int
+	err := tx.QueryRowContext(ctx, "SELECT 1 FROM entities WHERE id = ?", entityID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		return &world.EntityNotFoundError{ID: entityID}
+	}
+	if err != nil {
+		return fmt.Errorf("reading entity %d: %w", entityID, err)
+	}
+
+	if s != nil {
+		refs, err := blockingReferences(ctx, tx, s, entityID)
+		if err != nil {
+			return err
+		}
+		if len(refs) > 0 {
+			return &world.EntityReferencedError{ID: entityID, References: refs}
+		}
+		// Detach the entity's own components first, so a reference from
+		// the entity to itself is gone before the entity row is: SQLite
+		// checks restrict before the cascade would remove it.
+		if err := deleteOwnComponents(ctx, tx, s, entityID); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM event_queue WHERE entity_id = ?", entityID); err != nil {
+		return fmt.Errorf("deleting pending events of entity %d: %w", entityID, err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM entities WHERE id = ?", entityID); err != nil {
+		var sqliteErr *sqlite.Error
+		// A failed deferred check reports FOREIGNKEY; restrict reports
+		// TRIGGER, the mechanism SQLite enforces it with.
+		if errors.As(err, &sqliteErr) && (sqliteErr.Code() == sqliteLib.SQLITE_CONSTRAINT_FOREIGNKEY ||
+			sqliteErr.Code() == sqliteLib.SQLITE_CONSTRAINT_TRIGGER) {
+			return &world.EntityReferencedError{ID: entityID}
+		}
+		return fmt.Errorf("deleting entity %d: %w", entityID, err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO transitions
+		   (tick, wall_ms, entity_id, machine_id, from_states, to_states, event, cond_result, actions_run)
+		 VALUES (?, ?, ?, '', '[]', '[]', ?, NULL, '[]')`,
+		tick, time.Now().UnixMilli(), entityID, despawnEvent,
+	); err != nil {
+		return fmt.Errorf("recording despawn of entity %d: %w", entityID, err)
+	}
+	return nil
+}
+
+// blockingReferences describes the entity-ref columns of other entities
+// that point at entityID with no onDelete policy or with restrict, in
+// component-name order, followed by those of resources in resource-name
+// order. A reference from the entity to itself does not block:
+// destroyEntity deletes its row first.
+func blockingReferences(ctx context.Context, tx *sql.Tx, s *schema.DatabaseSchema, entityID int64) ([]string, error) {
+	blocks := func(onDelete string) bool {
+		return onDelete == "" || onDelete == schema.OnDeleteRestrict
+	}
+	names := make([]string, 0, len(s.Components))
+	for name := range s.Components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var refs []string
+	for _, name := range names {
+		comp := s.Components[name]
+		table := "comp_" + strings.ToLower(name)
+		// col → the label reported for it, e.g. "Follower.leader".
+		cols := map[string]string{}
+		switch comp.Type {
+		case schema.ComponentTypeEntityRef:
+			if blocks(comp.OnDelete) {
+				cols["target_entity_id"] = name
+			}
+		case schema.ComponentTypeObject:
+			for propName, prop := range comp.Properties {
+				if prop.Type == schema.PropertyTypeEntityRef && blocks(prop.OnDelete) {
+					cols[strings.ToLower(propName)] = name + "." + propName
+				}
+			}
+		}
+		colNames := make([]string, 0, len(cols))
+		for col := range cols {
+			colNames = append(colNames, col)
+		}
+		sort.Strings(colNames)
+		for _, col := range colNames {
+			if err := validateIdentifier(col, "entity-ref column"); err != nil {
+				return nil, err
+			}
+			ids, err := referencingIDs(ctx, tx, table, col, entityID)
+			if err != nil {
+				return nil, err
+			}
+			for _, id := range ids {
+				refs = append(refs, fmt.Sprintf("%s of entity %d", cols[col], id))
+			}
+		}
+	}
+
+	resNames := make([]string, 0, len(s.Resources))
+	for name := range s.Resources {
+		resNames = append(resNames, name)
+	}
+	sort.Strings(resNames)
+	for _, name := range resNames {
+		res := s.Resources[name]
+		table := "res_" + strings.ToLower(name)
+		propNames := make([]string, 0, len(res.Properties))
+		for propName, prop := range res.Properties {
+			if prop.Type == schema.PropertyTypeEntityRef && blocks(prop.OnDelete) {
+				propNames = append(propNames, propName)
+			}
+		}
+		sort.Strings(propNames)
+		for _, propName := range propNames {
+			col := strings.ToLower(propName)
+			if err := validateIdentifier(col, "entity-ref column"); err != nil {
+				return nil, err
+			}
+			var n int
+			if err := tx.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s = ?", table, col),
+				entityID).Scan(&n); err != nil {
+				return nil, fmt.Errorf("finding references to entity %d in %s: %w", entityID, table, err)
+			}
+			if n > 0 {
+				refs = append(refs, fmt.Sprintf("resource %s.%s", name, propName))
+			}
+		}
+	}
+	return refs, nil
+}
+
+// deleteOwnComponents deletes the entity's rows from every comp_* table.
+func deleteOwnComponents(ctx context.Context, tx *sql.Tx, s *schema.DatabaseSchema, entityID int64) error {
+	for name, comp := range s.Components {
+		if comp.Type == schema.ComponentTypeRelation {
+			continue
+		}
+		table := "comp_" + strings.ToLower(name)
+		if err := validateIdentifier(strings.TrimPrefix(table, "comp_"), "component name"); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE entity_id = ?", table), entityID); err != nil {
+			return fmt.Errorf("deleting %s of entity %d: %w", table, entityID, err)
+		}
+	}
+	return nil
+}
+
+// referencingIDs returns the entities other than entityID whose col in
+// table holds entityID, in ascending id order.
+func referencingIDs(ctx context.Context, tx *sql.Tx, table, col string, entityID int64) ([]int64, error) {
+	rows, err := tx.QueryContext(ctx,
+		fmt.Sprintf("SELECT entity_id FROM %s WHERE %s = ? AND entity_id != ? ORDER BY entity_id", table, col),
+		entityID, entityID)
+	if err != nil {
+		return nil, fmt.Errorf("finding references to entity %d in %s: %w", entityID, table, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scanning %s: %w", table, err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("finding references to entity %d in %s: %w", entityID, table, err)
+	}
+	return ids, nil
+}
+
+// ── world.Tx ─────────────────────────────────────────────────────────
+
+func (t *sqliteTx) DestroyEntity(ctx context.Context, entityID int64, tick int64) error {
+	return destroyEntity(ctx, t.tx, &t.schema, entityID, tick)
+}
+
+// ── agent.WorldWriter ────────────────────────────────────────────────
+
+// DestroyEntity deletes the entity at the current tick. Destroying an
+// entity that no longer exists is not an error, as with DetachComponent,
+// so two actions destroying the same entity in one tick both succeed.
+func (w *txWorldWriter) DestroyEntity(entityID int64) error {
+	ctx := context.Background()
+	tick, err := readCurrentTick(ctx, w.tx.QueryRowContext)
+	if err != nil {
+		return fmt.Errorf("DestroyEntity: %w", err)
+	}
+	err = destroyEntity(ctx, w.tx, w.schema, entityID, tick)
+	var notFound *world.EntityNotFoundError
+	if errors.As(err, &notFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("DestroyEntity: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/tmbritton/ecs-db/internal/agent"
+	"github.com/tmbritton/ecs-db/internal/schema"
+	"github.com/tmbritton/ecs-db/internal/world"
+)
+
+func destroySchema() schema.DatabaseSchema {
+	return schema.DatabaseSchema{
+		SchemaVersion: 1,
+		Components: map[string]schema.Component{
+			"Health": {Type: schema.ComponentTypeInteger},
+			// cascade: the referencing component is detached.
+			"Target": {Type: schema.ComponentTypeEntityRef, OnDelete: schema.OnDeleteCascade},
+			// no policy: the destroy is refused.
+			"Owner": {Type: schema.ComponentTypeEntityRef},
+			"Memory": {Type: schema.ComponentTypeObject, Properties: map[string]schema.Property{
+				// setNull: the property is cleared.
+				"lastSeen": {Type: schema.PropertyTypeEntityRef, Nullable: true, OnDelete: schema.OnDeleteSetNull},
+				// restrict: the destroy is refused.
+				"leader": {Type: schema.PropertyTypeEntityRef, Nullable: true, OnDelete: schema.OnDeleteRestrict},
+			}},
+			"ChildOf": {Type: schema.ComponentTypeRelation},
+		},
+		Resources: map[string]schema.Resource{
+			"Quest": {Properties: map[string]schema.Property{
+				// no policy: the destroy is refused.
+				"giver": {Type: schema.PropertyTypeEntityRef, Nullable: true},
+				// setNull: the property is cleared.
+				"lastKill": {Type: schema.PropertyTypeEntityRef, Nullable: true, OnDelete: schema.OnDeleteSetNull},
+			}},
+		},
+		EntityTypes: map[string]schema.EntityType{
+			"Goblin": {OptionalComponents: []string{"Health", "Target", "Owner", "Memory"}},
+		},
+	}
+}
+
+// openDestroyStore returns a store at tick 7 holding goblins 1 to 5.
+// Goblin 1 has Health, a machine, a pending event and a ChildOf relation
+// to 5; goblins 2 and 3 and the Quest resource reference it through
+// cascade and setNull policies.
+func openDestroyStore(t *testing.T) *SQLiteStore {
+	t.Helper()
+	return openSeededStore(t, destroySchema(),
+		"INSERT OR REPLACE INTO world (key, value) VALUES ('current_tick', '7')",
+		`INSERT INTO entities (id, entity_type, created_tick) VALUES
+		 (1, 'Goblin', 0), (2, 'Goblin', 0), (3, 'Goblin', 0), (4, 'Goblin', 0), (5, 'Goblin', 0)`,
+		"INSERT INTO comp_health (entity_id, value) VALUES (1, 10)",
+		"INSERT INTO comp_target (entity_id, target_entity_id) VALUES (2, 1)",
+		"INSERT INTO comp_memory (entity_id, lastseen, leader) VALUES (3, 1, NULL), (1, 1, 1)",
+		"INSERT INTO rel_childof (source_id, target_id) VALUES (1, 5), (4, 1)",
+		"UPDATE res_quest SET lastkill = 1",
+		`INSERT INTO behavior_components (entity_id, machine_id, current_states, updated_at) VALUES (1, 'patrol', '["idle"]', 3)`,
+		`INSERT INTO event_queue (entity_id, machine_id, event_type, target_tick) VALUES
+		 (1, 'patrol', 'xstate.after(1000).patrol.idle', 9), (4, 'patrol', 'xstate.after(1000).patrol.idle', 9)`,
+	)
+}
+
+func TestStore_DestroyEntity(t *testing.T) {
+	store := openDestroyStore(t)
+	svc := world.NewEntityService(store)
+	svc.SetSchema(destroySchema())
+
+	if err := svc.DestroyEntity(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, check := range []struct {
+		query string
+		want  int
+	}{
+		{"SELECT COUNT(*) FROM entities WHERE id = 1", 0},
+		{"SELECT COUNT(*) FROM comp_health", 0},
+		{"SELECT COUNT(*) FROM comp_target", 0},                                          // cascade
+		{"SELECT COUNT(*) FROM comp_memory WHERE entity_id = 3 AND lastseen IS NULL", 1}, // setNull
+		{"SELECT COUNT(*) FROM rel_childof", 0},
+		{"SELECT COUNT(*) FROM res_quest WHERE lastkill IS NULL", 1}, // setNull
+		{"SELECT COUNT(*) FROM behavior_components", 0},
+		{"SELECT COUNT(*) FROM event_queue WHERE entity_id = 1", 0},
+		{"SELECT COUNT(*) FROM event_queue WHERE entity_id = 4", 1},
+		{`SELECT COUNT(*) FROM transitions WHERE entity_id = 1 AND tick = 7 AND event = 'despawn'
+		  AND machine_id = '' AND from_states = '[]' AND to_states = '[]' AND actions_run = '[]'`, 1},
+	} {
+		if got := countRows(t, store, check.query); got != check.want {
+			t.Errorf("%s = %d, want %d", check.query, got, check.want)
+		}
+	}
+
+	var notFound *world.EntityNotFoundError
+	if err := svc.DestroyEntity(context.Background(), 1); !errors.As(err, &notFound) {
+		t.Errorf("second destroy err = %v, want *world.EntityNotFoundError", err)
+	}
+}
+
+func TestStore_DestroyEntity_Referenced(t *testing.T) {
+	store := openDestroyStore(t)
+	for _, stmt := range []string{
+		"INSERT INTO comp_owner (entity_id, target_entity_id) VALUES (4, 1)",
+		"UPDATE comp_memory SET leader = 1 WHERE entity_id = 3",
+		"UPDATE res_quest SET giver = 1",
+	} {
+		if _, err := store.DB().Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	svc := world.NewEntityService(store)
+	svc.SetSchema(destroySchema())
+
+	err := svc.DestroyEntity(context.Background(), 1)
+	var refErr *world.EntityReferencedError
+	if !errors.As(err, &refErr) {
+		t.Fatalf("err = %v, want *world.EntityReferencedError", err)
+	}
+	want := []string{"Memory.leader of entity 3", "Owner of entity 4", "resource Quest.giver"}
+	if !reflect.DeepEqual(refErr.References, want) {
+		t.Errorf("References = %v, want %v", refErr.References, want)
+	}
+	if got := countRows(t, store, "SELECT COUNT(*) FROM entities WHERE id = 1"); got != 1 {
+		t.Error("a refused destroy deleted the entity")
+	}
+	if got := countRows(t, store, "SELECT COUNT(*) FROM transitions WHERE event = 'despawn'"); got != 0 {
+		t.Errorf("a refused destroy recorded %d despawns", got)
+	}
+
+	// Without a schema the foreign key check still refuses it.
+	tx, err := store.DB().Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	err = NewTxWorldWriter(tx).DestroyEntity(1)
+	if !errors.As(err, &refErr) || len(refErr.References) != 0 {
+		t.Errorf("schemaless err = %v, want *world.EntityReferencedError without details", err)
+	}
+}
+
+func TestTxWorldWriter_DestroyEntity(t *testing.T) {
+	store := openDestroyStore(t)
+	tx, err := store.DB().Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	w := NewTxWorldWriterWithSchema(tx, destroySchema())
+	mw := NewMachineWriter(tx)
+
+	if err := w.DestroyEntity(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.DestroyEntity(1); err != nil {
+		t.Errorf("destroying a destroyed entity: %v, want nil", err)
+	}
+	// A machine finishing its transition after destroying its own entity
+	// writes no state for it.
+	if err := mw.SetMachineState(1, "patrol", []string{"dead"}, 7); err != nil {
+		t.Fatalf("SetMachineState after destroy: %v", err)
+	}
+	if err := mw.AppendTransition(agent.TransitionRecord{Tick: 7, EntityID: 1, MachineID: "patrol",
+		FromStates: []string{"idle"}, ToStates: []string{"dead"}, Event: "DIE", ActionsRun: []string{"destroyEntity"}}); err != nil {
+		t.Fatalf("AppendTransition after destroy: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if got := countRows(t, store, "SELECT COUNT(*) FROM behavior_components WHERE entity_id = 1"); got != 0 {
+		t.Errorf("behavior_components rows for destroyed entity = %d, want 0", got)
+	}
+	if got := countRows(t, store, "SELECT COUNT(*) FROM transitions WHERE entity_id = 1"); got != 2 {
+		t.Errorf("transitions for entity 1 = %d, want the despawn and the machine's transition", got)
+	}
+}
@@ -67,6 +67,8 @@ func (t *sqliteTx) insertComponent(ctx context.Context, entityID int64, compName
 	case schema.ComponentTypeString, schema.ComponentTypeInteger,
 		schema.ComponentTypeNumber, schema.ComponentTypeBoolean:
 		return t.insertScalarComponent(ctx, tableName, entityID, comp.Type, values)
+	case schema.ComponentTypeTag:
+		return t.insertTagComponent(ctx, tableName, entityID)
 	default:
 		return fmt.Errorf("unsupported component type %q for insert", comp.Type)
 	}
@@ -85,10 +87,13 @@ func (t *sqliteTx) insertObjectComponent(
 	cols = append(cols, "entity_id")
 	args = append(args, entityID)
 
-	// Sort property names for deterministic INSERT column order.
+	// Sort property names for deterministic INSERT column order. Computed
+	// properties are derived by SQLite and cannot be inserted.
 	propNames := make([]string, 0, len(comp.Properties))
-	for name := range comp.Properties {
-		propNames = append(propNames, name)
+	for name, prop := range comp.Properties {
+		if !prop.IsComputed() {
+			propNames = append(propNames, name)
+		}
 	}
 	sort.Strings(propNames)
 
@@ -142,6 +147,15 @@ func (t *sqliteTx) insertEntityRefComponent(
 	return nil
 }
 
+// insertTagComponent marks the entity with a tag. Tags carry no values.
+func (t *sqliteTx) insertTagComponent(ctx context.Context, tableName string, entityID int64) error {
+	query := fmt.Sprintf("INSERT INTO %s (entity_id) VALUES (?)", tableName)
+	if _, err := t.tx.ExecContext(ctx, query, entityID); err != nil {
+		return fmt.Errorf("inserting into %s: %w", tableName, err)
+	}
+	return nil
+}
+
 func (t *sqliteTx) insertArrayComponent(
 	ctx context.Context,
 	tableName string,
@@ -252,8 +266,15 @@ func (s *SQLiteStore) BeginTx(ctx context.Context) (world.Tx, error) {
 // Returns 0 if no tick has been recorded yet.
 // Implements world.EntityStore.
 func (s *SQLiteStore) GetCurrentTick(ctx context.Context) (int64, error) {
+	return readCurrentTick(ctx, s.db.QueryRowContext)
+}
+
+// readCurrentTick reads the current tick from the world table through
+// queryRow, which *sql.DB and *sql.Tx both provide. Returns 0 if no tick
+// has been recorded yet.
+func readCurrentTick(ctx context.Context, queryRow func(ctx context.Context, query string, args ...any) *sql.Row) (int64, error) {
 	var tick int64
-	err := s.db.QueryRowContext(
+	err := queryRow(
 		ctx,
 		"SELECT CAST(value AS INTEGER) FROM world WHERE key='current_tick'",
 	).Scan(&tick)
@@ -269,8 +290,15 @@ func (s *SQLiteStore) GetCurrentTick(ctx context.Context) (int64, error) {
 // GetEntityType returns the entity type for the given entity ID.
 // Implements world.EntityStore.
 func (s *SQLiteStore) GetEntityType(ctx context.Context, entityID int64) (string, error) {
+	return readEntityType(ctx, s.db.QueryRowContext, entityID)
+}
+
+// readEntityType reads the entity's type through queryRow, as
+// readCurrentTick does. Returns an *world.EntityNotFoundError if the entity
+// does not exist.
+func readEntityType(ctx context.Context, queryRow func(ctx context.Context, query string, args ...any) *sql.Row, entityID int64) (string, error) {
 	var entityType string
-	err := s.db.QueryRowContext(
+	err := queryRow(
 		ctx,
 		"SELECT entity_type FROM entities WHERE id = ?",
 		entityID,
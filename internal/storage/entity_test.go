@@ -221,6 +221,47 @@ func TestSQLiteStore_CreateEntity_ScalarComponent(t *testing.T) {
 	}
 }
 
+func TestSQLiteStore_CreateEntity_TagComponent(t *testing.T) {
+	s := schema.DatabaseSchema{
+		SchemaVersion: 1,
+		Components: map[string]schema.Component{
+			"Name":   {Type: schema.ComponentTypeString},
+			"Frozen": {Type: schema.ComponentTypeTag},
+		},
+		EntityTypes: map[string]schema.EntityType{
+			"NPC": {RequiredComponents: []string{"Name", "Frozen"}},
+		},
+	}
+	store := makeStore(t, s)
+
+	svc := world.NewEntityService(store)
+	svc.SetSchema(s)
+
+	e, err := svc.CreateEntity(context.Background(), "NPC", []world.EntityComponent{
+		{Name: "Name", Values: map[string]interface{}{"value": "Merchant"}},
+		{Name: "Frozen"},
+	})
+	if err != nil {
+		t.Fatalf("CreateEntity error: %v", err)
+	}
+
+	has, err := store.HasComponent(context.Background(), e.ID, "Frozen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !has {
+		t.Error("HasComponent(Frozen) = false, want true")
+	}
+
+	_, err = svc.CreateEntity(context.Background(), "NPC", []world.EntityComponent{
+		{Name: "Name", Values: map[string]interface{}{"value": "Guard"}},
+		{Name: "Frozen", Values: map[string]interface{}{"turns": 3}},
+	})
+	if err == nil {
+		t.Fatal("CreateEntity with tag values: want error, got nil")
+	}
+}
+
 func TestSQLiteStore_CreateEntity_EntityRefComponent(t *testing.T) {
 	s := schema.DatabaseSchema{
 		SchemaVersion: 1,
@@ -742,3 +783,63 @@ func TestSQLiteStore_CreateEntity_WarningModeWithExtraComponent(t *testing.T) {
 		t.Errorf("entity ID = %d, want 1", e.ID)
 	}
 }
+
+func TestSQLiteStore_EntityRefDeletePolicies(t *testing.T) {
+	store := makeStore(t, schema.DatabaseSchema{
+		SchemaVersion: 1,
+		Components: map[string]schema.Component{
+			"Target": {
+				Type: schema.ComponentTypeObject,
+				Properties: map[string]schema.Property{
+					"entity": {Type: schema.PropertyTypeEntityRef, OnDelete: schema.OnDeleteSetNull, Nullable: true},
+				},
+			},
+			"Guard": {
+				Type: schema.ComponentTypeObject,
+				Properties: map[string]schema.Property{
+					"ward": {Type: schema.PropertyTypeEntityRef, OnDelete: schema.OnDeleteRestrict},
+				},
+			},
+			"Leader": {Type: schema.ComponentTypeEntityRef, OnDelete: schema.OnDeleteCascade},
+		},
+		EntityTypes: map[string]schema.EntityType{"Goblin": {AllowExtraComponents: true}},
+	})
+	db := store.DB()
+	mustExec := func(query string, args ...any) {
+		t.Helper()
+		if _, err := db.Exec(query, args...); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+	}
+	count := func(query string) int {
+		t.Helper()
+		var n int
+		if err := db.QueryRow(query).Scan(&n); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		return n
+	}
+	for id := 1; id <= 4; id++ {
+		mustExec("INSERT INTO entities (id, entity_type) VALUES (?, 'Goblin')", id)
+	}
+	mustExec("INSERT INTO comp_target (entity_id, entity) VALUES (1, 2)")
+	mustExec("INSERT INTO comp_leader (entity_id, target_entity_id) VALUES (1, 2)")
+	mustExec("INSERT INTO comp_guard (entity_id, ward) VALUES (3, 4)")
+
+	// A nested entity-ref property is a real foreign key.
+	if _, err := db.Exec("INSERT INTO comp_target (entity_id, entity) VALUES (3, 99)"); err == nil {
+		t.Error("dangling reference accepted")
+	}
+
+	mustExec("DELETE FROM entities WHERE id = 2")
+	if n := count("SELECT count(*) FROM comp_target WHERE entity_id = 1 AND entity IS NULL"); n != 1 {
+		t.Error("setNull did not clear Target.entity")
+	}
+	if n := count("SELECT count(*) FROM comp_leader"); n != 0 {
+		t.Error("cascade did not detach Leader")
+	}
+
+	if _, err := db.Exec("DELETE FROM entities WHERE id = 4"); err == nil {
+		t.Error("restrict allowed deleting a referenced entity")
+	}
+}
@@ -27,3 +27,25 @@ func (e *SchemaVersionMismatchError) Is(target error) bool {
 // ErrSchemaVersionMismatch is the sentinel error for schema version
 // mismatch detection.
 var ErrSchemaVersionMismatch = &SchemaVersionMismatchError{}
+
+// MigrationVerificationError is returned by NewSQLiteStoreWithConfig when
+// StoreConfig.AutoRestore is set and the database fails verification after
+// a migration committed. Restored reports whether the pre-migration backup
+// at BackupPath was put back in place.
+type MigrationVerificationError struct {
+	BackupPath string
+	Restored   bool
+	Underlying error
+}
+
+func (e *MigrationVerificationError) Error() string {
+	if e.Restored {
+		return fmt.Sprintf("post-migration verification failed, restored %s: %v", e.BackupPath, e.Underlying)
+	}
+	return fmt.Sprintf("post-migration verification failed, backup %s not restored: %v", e.BackupPath, e.Underlying)
+}
+
+// Unwrap allows errors.Is/As to reach the verification failure.
+func (e *MigrationVerificationError) Unwrap() error {
+	return e.Underlying
+}
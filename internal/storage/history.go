@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/tmbritton/ecs-db/internal/schema"
+)
+
+// schemaMigrationsTableSQL creates the migration history table. It is part
+// of bootstrap and is re-issued by the runner so databases created before
+// the table existed gain it on their next migration.
+const schemaMigrationsTableSQL = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	id              INTEGER PRIMARY KEY AUTOINCREMENT,
+	from_version    INTEGER NOT NULL,
+	to_version      INTEGER NOT NULL,
+	applied_at      TEXT NOT NULL,
+	statements      TEXT NOT NULL DEFAULT '[]',
+	data_migrations TEXT NOT NULL DEFAULT '[]',
+	backup_path     TEXT,
+	duration_ms     INTEGER NOT NULL
+)`
+
+// schemaSnapshotKey is the meta key holding the JSON of the last applied
+// schema.json. The runner diffs against it to detect entity type changes,
+// which leave no trace in the table structure.
+const schemaSnapshotKey = "schema_snapshot"
+
+// MigrationRecord is one row of the schema_migrations history table.
+type MigrationRecord struct {
+	ID             int64
+	FromVersion    int
+	ToVersion      int
+	AppliedAt      time.Time
+	Statements     []string // SQL of every DDL statement executed, in order
+	DataMigrations []string // names of the data migrations run, in order
+	BackupPath     string   // pre-migration backup, empty if none was taken
+	Duration       time.Duration
+}
+
+// MigrationHistory returns the recorded migrations, oldest first. A
+// database without a schema_migrations table has no history and yields an
+// empty slice.
+func MigrationHistory(db *sql.DB) ([]MigrationRecord, error) {
+	var n int
+	if err := db.QueryRow(
+		"SELECT count(*) FROM sqlite_master WHERE type='table' AND name='schema_migrations'",
+	).Scan(&n); err != nil {
+		return nil, fmt.Errorf("querying sqlite_master: %w", err)
+	}
+	if n == 0 {
+		return []MigrationRecord{}, nil
+	}
+
+	rows, err := db.Query(`SELECT id, from_version, to_version, applied_at, statements,
+		data_migrations, backup_path, duration_ms FROM schema_migrations ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("querying schema_migrations: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	records := []MigrationRecord{}
+	for rows.Next() {
+		var (
+			rec                    MigrationRecord
+			appliedAt, stmts, data string
+			backupPath             sql.NullString
+			durationMS             int64
+		)
+		if err := rows.Scan(&rec.ID, &rec.FromVersion, &rec.ToVersion, &appliedAt,
+			&stmts, &data, &backupPath, &durationMS); err != nil {
+			return nil, fmt.Errorf("scanning schema_migrations: %w", err)
+		}
+		if rec.AppliedAt, err = time.Parse(time.RFC3339, appliedAt); err != nil {
+			return nil, fmt.Errorf("schema_migrations row %d: applied_at: %w", rec.ID, err)
+		}
+		if err := json.Unmarshal([]byte(stmts), &rec.Statements); err != nil {
+			return nil, fmt.Errorf("schema_migrations row %d: statements: %w", rec.ID, err)
+		}
+		if err := json.Unmarshal([]byte(data), &rec.DataMigrations); err != nil {
+			return nil, fmt.Errorf("schema_migrations row %d: data_migrations: %w", rec.ID, err)
+		}
+		rec.BackupPath = backupPath.String
+		rec.Duration = time.Duration(durationMS) * time.Millisecond
+		records = append(records, rec)
+	}
+	return records, rows.Err()
+}
+
+// recordMigration appends rec to schema_migrations inside tx, creating the
+// table first if the database predates it.
+func recordMigration(tx *sql.Tx, rec MigrationRecord) error {
+	if _, err := tx.Exec(schemaMigrationsTableSQL); err != nil {
+		return fmt.Errorf("creating schema_migrations: %w", err)
+	}
+	stmts, err := json.Marshal(nonNilStrings(rec.Statements))
+	if err != nil {
+		return fmt.Errorf("encoding statements: %w", err)
+	}
+	data, err := json.Marshal(nonNilStrings(rec.DataMigrations))
+	if err != nil {
+		return fmt.Errorf("encoding data migrations: %w", err)
+	}
+	var backupPath sql.NullString
+	if rec.BackupPath != "" {
+		backupPath = sql.NullString{String: rec.BackupPath, Valid: true}
+	}
+	_, err = tx.Exec(`INSERT INTO schema_migrations
+		(from_version, to_version, applied_at, statements, data_migrations, backup_path, duration_ms)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		rec.FromVersion, rec.ToVersion, rec.AppliedAt.UTC().Format(time.RFC3339),
+		string(stmts), string(data), backupPath, rec.Duration.Milliseconds(),
+	)
+	if err != nil {
+		return fmt.Errorf("inserting schema_migrations row: %w", err)
+	}
+	return nil
+}
+
+// nonNilStrings returns s, or an empty slice when s is nil, so that it
+// encodes as [] rather than null.
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+// writeSchemaSnapshot stores s as the applied schema snapshot in meta.
+func writeSchemaSnapshot(tx *sql.Tx, s schema.DatabaseSchema) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("encoding schema snapshot: %w", err)
+	}
+	if _, err := tx.Exec(
+		"INSERT OR REPLACE INTO meta (key, value) VALUES (?, ?)",
+		schemaSnapshotKey, string(data),
+	); err != nil {
+		return fmt.Errorf("recording schema snapshot: %w", err)
+	}
+	return nil
+}
+
+// readSchemaSnapshot returns the schema stored by the last bootstrap or
+// migration. It returns nil, nil for databases that predate snapshots.
+func readSchemaSnapshot(db *sql.DB) (*schema.DatabaseSchema, error) {
+	var data string
+	err := db.QueryRow("SELECT value FROM meta WHERE key = ?", schemaSnapshotKey).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading schema snapshot: %w", err)
+	}
+	var s schema.DatabaseSchema
+	if err := json.Unmarshal([]byte(data), &s); err != nil {
+		return nil, fmt.Errorf("decoding schema snapshot: %w", err)
+	}
+	return &s, nil
+}
@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/tmbritton/ecs-db/internal/schema"
+	_ "modernc.org/sqlite"
+)
+
+func historySchema(version int, et schema.EntityType) schema.DatabaseSchema {
+	return schema.DatabaseSchema{
+		SchemaVersion: version,
+		Components: map[string]schema.Component{
+			"Health": healthComponent,
+		},
+		EntityTypes: map[string]schema.EntityType{"Goblin": et},
+	}
+}
+
+func TestBootstrap_WritesSchemaSnapshotAndHistoryTable(t *testing.T) {
+	db := openMigrationTestDB(t)
+	s := historySchema(1, schema.EntityType{
+		RequiredComponents: []string{"Health"},
+		ValidationLevel:    schema.ValidationStrict,
+	})
+	bootstrapMigrationDB(t, db, s)
+
+	if !tableExists(t, db, "schema_migrations") {
+		t.Error("schema_migrations not created by bootstrap")
+	}
+	snap, err := readSchemaSnapshot(db)
+	if err != nil {
+		t.Fatalf("readSchemaSnapshot: %v", err)
+	}
+	if snap == nil {
+		t.Fatal("readSchemaSnapshot = nil, want the bootstrapped schema")
+	}
+	if snap.SchemaVersion != 1 || len(snap.EntityTypes["Goblin"].RequiredComponents) != 1 {
+		t.Errorf("snapshot = %+v, want version 1 with Goblin requiring Health", snap)
+	}
+	history, err := MigrationHistory(db)
+	if err != nil {
+		t.Fatalf("MigrationHistory: %v", err)
+	}
+	if len(history) != 0 {
+		t.Errorf("history after bootstrap = %d rows, want 0", len(history))
+	}
+}
+
+func TestReadSchemaSnapshot_MissingReturnsNil(t *testing.T) {
+	db := openMigrationTestDB(t)
+	bootstrapMigrationDB(t, db, historySchema(1, schema.EntityType{ValidationLevel: schema.ValidationStrict}))
+	if _, err := db.Exec("DELETE FROM meta WHERE key = ?", schemaSnapshotKey); err != nil {
+		t.Fatal(err)
+	}
+	snap, err := readSchemaSnapshot(db)
+	if err != nil || snap != nil {
+		t.Errorf("readSchemaSnapshot = %v, %v, want nil, nil", snap, err)
+	}
+}
+
+func TestMigrationHistory_NoTableIsEmpty(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	history, err := MigrationHistory(db)
+	if err != nil {
+		t.Fatalf("MigrationHistory: %v", err)
+	}
+	if history == nil || len(history) != 0 {
+		t.Errorf("history = %v, want empty non-nil slice", history)
+	}
+}
+
+func TestMigrate_RecordsHistoryAndSnapshot(t *testing.T) {
+	path := t.TempDir() + "/history.sqlite"
+
+	s1 := historySchema(1, schema.EntityType{
+		RequiredComponents: []string{"Health"},
+		ValidationLevel:    schema.ValidationStrict,
+	})
+	store1, err := NewSQLiteStore(path, s1, "")
+	if err != nil {
+		t.Fatalf("v1 open: %v", err)
+	}
+	_ = store1.Close()
+
+	// v2 adds a component and relaxes the Goblin validation level, a change
+	// only visible through the snapshot.
+	s2 := historySchema(2, schema.EntityType{
+		RequiredComponents: []string{"Health"},
+		ValidationLevel:    schema.ValidationWarning,
+	})
+	s2.Components["Tag"] = schema.Component{Type: schema.ComponentTypeString}
+
+	store2, err := NewSQLiteStoreWithConfig(path, StoreConfig{Schema: s2, BackupRetention: 1})
+	if err != nil {
+		t.Fatalf("v2 open (migration): %v", err)
+	}
+	t.Cleanup(func() { _ = store2.Close() })
+	db := store2.DB()
+
+	history, err := MigrationHistory(db)
+	if err != nil {
+		t.Fatalf("MigrationHistory: %v", err)
+	}
+	if len(history) != 1 {
+		t.Fatalf("history = %d rows, want 1", len(history))
+	}
+	rec := history[0]
+	if rec.FromVersion != 1 || rec.ToVersion != 2 {
+		t.Errorf("versions = %d → %d, want 1 → 2", rec.FromVersion, rec.ToVersion)
+	}
+	if len(rec.Statements) != 1 || !strings.Contains(rec.Statements[0], "comp_tag") {
+		t.Errorf("Statements = %q, want the comp_tag CREATE TABLE", rec.Statements)
+	}
+	if rec.BackupPath == "" || !strings.Contains(rec.BackupPath, "history") {
+		t.Errorf("BackupPath = %q, want the pre-migration backup", rec.BackupPath)
+	}
+	if rec.AppliedAt.IsZero() || rec.Duration < 0 {
+		t.Errorf("AppliedAt = %v, Duration = %v", rec.AppliedAt, rec.Duration)
+	}
+
+	snap, err := readSchemaSnapshot(db)
+	if err != nil || snap == nil {
+		t.Fatalf("readSchemaSnapshot = %v, %v", snap, err)
+	}
+	if snap.SchemaVersion != 2 || snap.EntityTypes["Goblin"].ValidationLevel != schema.ValidationWarning {
+		t.Errorf("snapshot = %+v, want the v2 schema", snap)
+	}
+}
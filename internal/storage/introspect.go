@@ -13,6 +13,8 @@ import (
 type DomainSchema struct {
 	SchemaVersion   int                        // From meta table, 0 if no metadata
 	Components      map[string]DomainComponent // Key = lowercase name ("position")
+	Resources       map[string]DomainResource  // Key = lowercase name ("clock")
+	Relations       map[string]DomainRelation  // Key = lowercase name ("childof")
 	EntityTypeNames map[string]bool            // Distinct entity_type values
 }
 
@@ -20,14 +22,45 @@ type DomainSchema struct {
 type DomainComponent struct {
 	Type    string // "object", "string", "integer", etc.
 	Columns []DomainColumn
+	Indexes []DomainIndex // explicitly created indexes, sorted by name
+}
+
+// DomainResource represents a resource table's structure as found in the DB.
+type DomainResource struct {
+	Columns []DomainColumn
+}
+
+// DomainRelation represents a relation table's structure as found in the DB.
+type DomainRelation struct {
+	Columns []DomainColumn
+}
+
+// DomainIndex represents an index created on a component table.
+type DomainIndex struct {
+	Name    string
+	Columns []string // ordered by position in the index
+	Unique  bool
 }
 
 // DomainColumn represents a single column in a component table.
 type DomainColumn struct {
-	Name    string
-	SQLType string
-	Default string // Default value expression from PRAGMA, empty if none
-	IsPK    bool
+	Name     string
+	SQLType  string
+	Default  string // Default value expression from PRAGMA, empty if none
+	IsPK     bool
+	Nullable bool // true for non-PK columns without a NOT NULL constraint
+	// References is true for non-PK columns with a foreign key to
+	// entities(id); OnDelete is its delete policy as a schema onDelete
+	// keyword ("" for NO ACTION).
+	References bool
+	OnDelete   string
+	// Computed is the expression of a generated column, "" for a plain
+	// one; Stored is true when the generated column is STORED.
+	Computed string
+	Stored   bool
+	// Check is the condition of the column's CHECK constraint, "" for
+	// none.
+	Check string
 }
 
 func (c DomainColumn) DefaultVal() string {
@@ -59,6 +92,56 @@ func ListComponentTables(db *sql.DB) ([]string, error) {
 	return names, nil
 }
 
+// ListResourceTables returns the names of all resource tables (res_*) in
+// the database, sorted alphabetically.
+func ListResourceTables(db *sql.DB) ([]string, error) {
+	rows, err := db.Query(
+		"SELECT name FROM sqlite_master WHERE type='table' AND name GLOB 'res_*' ORDER BY name",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying sqlite_master for resource tables: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	names := make([]string, 0)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("scanning resource table name: %w", err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating resource tables: %w", err)
+	}
+	return names, nil
+}
+
+// ListRelationTables returns the names of all relation tables (rel_*) in
+// the database, sorted alphabetically.
+func ListRelationTables(db *sql.DB) ([]string, error) {
+	rows, err := db.Query(
+		"SELECT name FROM sqlite_master WHERE type='table' AND name GLOB 'rel_*' ORDER BY name",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying sqlite_master for relation tables: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	names := make([]string, 0)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("scanning relation table name: %w", err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating relation tables: %w", err)
+	}
+	return names, nil
+}
+
 // ReadSchemaVersion reads the stored schema_version from the meta table.
 func ReadSchemaVersion(db *sql.DB) (int, error) {
 	var stored string
@@ -76,40 +159,228 @@ func ReadSchemaVersion(db *sql.DB) (int, error) {
 }
 
 // IntrospectComponentTable returns the column definitions for a single
-// component table via PRAGMA table_info.
+// component, resource or relation table via PRAGMA table_xinfo, which
+// unlike table_info also lists generated columns. Every column of a
+// composite primary key is reported with IsPK set.
 func IntrospectComponentTable(db *sql.DB, tableName string) ([]DomainColumn, error) {
 	// Double-quote the identifier to handle names with special characters and
 	// prevent SQL injection through attacker-controlled table names.
 	quotedName := `"` + strings.ReplaceAll(tableName, `"`, `""`) + `"`
-	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", quotedName))
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_xinfo(%s)", quotedName))
 	if err != nil {
-		return nil, fmt.Errorf("PRAGMA table_info(%s): %w", tableName, err)
+		return nil, fmt.Errorf("PRAGMA table_xinfo(%s): %w", tableName, err)
 	}
 	defer func() { _ = rows.Close() }()
 
 	var columns []DomainColumn
+	var generated []int // indexes into columns of the generated columns
 	for rows.Next() {
 		var cid int
 		var name, colType string
 		var notNull IntBool
 		var dfltValue sql.NullString
-		var pk IntBool
-		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
-			return nil, fmt.Errorf("scanning PRAGMA table_info row: %w", err)
+		var pk, hidden IntBool
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk, &hidden); err != nil {
+			return nil, fmt.Errorf("scanning PRAGMA table_xinfo row: %w", err)
+		}
+		if hidden == hiddenVirtualTableColumn {
+			continue
 		}
 		columns = append(columns, DomainColumn{
-			Name:    name,
-			SQLType: strings.ToUpper(colType),
-			Default: dfltValue.String,
-			IsPK:    pk == 1,
+			Name:     name,
+			SQLType:  strings.ToUpper(colType),
+			Default:  dfltValue.String,
+			IsPK:     pk > 0,
+			Nullable: notNull == 0 && pk == 0,
+			Stored:   hidden == hiddenStoredColumn,
 		})
+		if hidden == hiddenVirtualColumn || hidden == hiddenStoredColumn {
+			generated = append(generated, len(columns)-1)
+		}
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterating PRAGMA table_info: %w", err)
+		return nil, fmt.Errorf("iterating PRAGMA table_xinfo: %w", err)
+	}
+	_ = rows.Close()
+
+	if len(columns) == 0 {
+		return nil, nil // no such table
+	}
+
+	var createSQL string
+	if err := db.QueryRow("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?",
+		tableName).Scan(&createSQL); err != nil {
+		return nil, fmt.Errorf("reading CREATE TABLE of %s: %w", tableName, err)
+	}
+	exprs := columnClauseExprs(createSQL, "GENERATED ALWAYS AS")
+	for _, i := range generated {
+		columns[i].Computed = exprs[strings.ToLower(columns[i].Name)]
+	}
+	checks := columnClauseExprs(createSQL, " CHECK")
+	for i := range columns {
+		columns[i].Check = checks[strings.ToLower(columns[i].Name)]
+	}
+
+	refs, err := introspectEntityReferences(db, tableName)
+	if err != nil {
+		return nil, err
+	}
+	for i := range columns {
+		if onDelete, ok := refs[strings.ToLower(columns[i].Name)]; ok && !columns[i].IsPK {
+			columns[i].References = true
+			columns[i].OnDelete = onDelete
+		}
 	}
 	return columns, nil
 }
 
+// Values of the hidden column of PRAGMA table_xinfo.
+const (
+	hiddenVirtualTableColumn = 1 // hidden column of a virtual table
+	hiddenVirtualColumn      = 2 // GENERATED ALWAYS AS (...) VIRTUAL
+	hiddenStoredColumn       = 3 // GENERATED ALWAYS AS (...) STORED
+)
+
+// columnClauseExprs maps each column of a CREATE TABLE statement that
+// has the given clause, by lowercase name, to the expression written
+// between the parentheses following it: the expression of GENERATED
+// ALWAYS AS or the condition of a column CHECK. SQLite keeps the text of
+// the statement, with columns added by ALTER TABLE appended, in
+// sqlite_master.
+func columnClauseExprs(createSQL, clause string) map[string]string {
+	exprs := make(map[string]string)
+	start := strings.IndexByte(createSQL, '(')
+	end := strings.LastIndexByte(createSQL, ')')
+	if start < 0 || end <= start {
+		return exprs
+	}
+	for _, def := range splitTopLevel(createSQL[start+1 : end]) {
+		fields := strings.Fields(def)
+		if len(fields) == 0 {
+			continue
+		}
+		at := strings.Index(strings.ToUpper(def), clause)
+		if at < 0 {
+			continue
+		}
+		rest := strings.TrimSpace(def[at+len(clause):])
+		if !strings.HasPrefix(rest, "(") {
+			continue
+		}
+		// The expression ends at the parenthesis closing the one after the
+		// clause, where splitTopLevel stops.
+		inner := splitTopLevel(rest[1:])
+		name := strings.ToLower(strings.Trim(fields[0], "\"`[]"))
+		exprs[name] = strings.TrimSpace(inner[0])
+	}
+	return exprs
+}
+
+// splitTopLevel splits s at the commas outside parentheses and string
+// literals. It stops at the first ')' that closes a parenthesis opened
+// before s, so the last part ends there.
+func splitTopLevel(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"' || ch == '`':
+			quote = ch
+		case ch == '(':
+			depth++
+		case ch == ')':
+			if depth == 0 {
+				return append(parts, s[start:i])
+			}
+			depth--
+		case ch == ',' && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// introspectEntityReferences maps each column of tableName that is a
+// foreign key to entities(id) to its onDelete keyword.
+func introspectEntityReferences(db *sql.DB, tableName string) (map[string]string, error) {
+	rows, err := db.Query(
+		`SELECT "from", on_delete FROM pragma_foreign_key_list(?) WHERE "table" = 'entities'`, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("PRAGMA foreign_key_list(%s): %w", tableName, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	refs := make(map[string]string)
+	for rows.Next() {
+		var from, onDelete string
+		if err := rows.Scan(&from, &onDelete); err != nil {
+			return nil, fmt.Errorf("scanning PRAGMA foreign_key_list row: %w", err)
+		}
+		refs[strings.ToLower(from)] = schema.OnDeleteFromSQL(onDelete)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating PRAGMA foreign_key_list: %w", err)
+	}
+	return refs, nil
+}
+
+// IntrospectIndexes returns the indexes created with CREATE INDEX on a
+// table, as recorded in sqlite_master. Automatic indexes backing PRIMARY
+// KEY and UNIQUE constraints have no SQL there and are skipped.
+func IntrospectIndexes(db *sql.DB, tableName string) ([]DomainIndex, error) {
+	rows, err := db.Query(`SELECT m.name, il."unique"
+		FROM sqlite_master m JOIN pragma_index_list(m.tbl_name) il ON il.name = m.name
+		WHERE m.type = 'index' AND m.tbl_name = ? AND m.sql IS NOT NULL
+		ORDER BY m.name`, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("listing indexes of %s: %w", tableName, err)
+	}
+	var indexes []DomainIndex
+	for rows.Next() {
+		var idx DomainIndex
+		var unique IntBool
+		if err := rows.Scan(&idx.Name, &unique); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("scanning index of %s: %w", tableName, err)
+		}
+		idx.Unique = unique == 1
+		indexes = append(indexes, idx)
+	}
+	err = rows.Err()
+	_ = rows.Close()
+	if err != nil {
+		return nil, fmt.Errorf("iterating indexes of %s: %w", tableName, err)
+	}
+
+	for i := range indexes {
+		cols, err := db.Query("SELECT name FROM pragma_index_info(?) ORDER BY seqno", indexes[i].Name)
+		if err != nil {
+			return nil, fmt.Errorf("reading columns of index %s: %w", indexes[i].Name, err)
+		}
+		for cols.Next() {
+			var col string
+			if err := cols.Scan(&col); err != nil {
+				_ = cols.Close()
+				return nil, fmt.Errorf("scanning column of index %s: %w", indexes[i].Name, err)
+			}
+			indexes[i].Columns = append(indexes[i].Columns, strings.ToLower(col))
+		}
+		err = cols.Err()
+		_ = cols.Close()
+		if err != nil {
+			return nil, fmt.Errorf("iterating columns of index %s: %w", indexes[i].Name, err)
+		}
+	}
+	return indexes, nil
+}
+
 // IntBool is a helper for scanning SQLite's 0/1 integers from PRAGMA.
 type IntBool int
 
@@ -149,7 +420,7 @@ func InferComponentType(cols []DomainColumn) string {
 
 	switch len(dataCols) {
 	case 0:
-		return "object" // empty object component
+		return "tag" // entity_id only
 	case 1:
 		col := dataCols[0]
 		switch {
@@ -174,6 +445,8 @@ func InferComponentType(cols []DomainColumn) string {
 func IntrospectAll(db *sql.DB) (*DomainSchema, error) {
 	result := &DomainSchema{
 		Components:      make(map[string]DomainComponent),
+		Resources:       make(map[string]DomainResource),
+		Relations:       make(map[string]DomainRelation),
 		EntityTypeNames: make(map[string]bool),
 	}
 
@@ -196,14 +469,45 @@ func IntrospectAll(db *sql.DB) (*DomainSchema, error) {
 		if err != nil {
 			return nil, fmt.Errorf("introspecting %s: %w", tableName, err)
 		}
+		indexes, err := IntrospectIndexes(db, tableName)
+		if err != nil {
+			return nil, fmt.Errorf("introspecting %s: %w", tableName, err)
+		}
 		compName := strings.TrimPrefix(tableName, "comp_")
 		result.Components[compName] = DomainComponent{
 			Type:    InferComponentType(columns),
 			Columns: columns,
+			Indexes: indexes,
+		}
+	}
+
+	// 4. Introspect each resource table.
+	resourceTables, err := ListResourceTables(db)
+	if err != nil {
+		return nil, fmt.Errorf("listing resource tables: %w", err)
+	}
+	for _, tableName := range resourceTables {
+		columns, err := IntrospectComponentTable(db, tableName)
+		if err != nil {
+			return nil, fmt.Errorf("introspecting %s: %w", tableName, err)
+		}
+		result.Resources[strings.TrimPrefix(tableName, "res_")] = DomainResource{Columns: columns}
+	}
+
+	// 5. Introspect each relation table.
+	relationTables, err := ListRelationTables(db)
+	if err != nil {
+		return nil, fmt.Errorf("listing relation tables: %w", err)
+	}
+	for _, tableName := range relationTables {
+		columns, err := IntrospectComponentTable(db, tableName)
+		if err != nil {
+			return nil, fmt.Errorf("introspecting %s: %w", tableName, err)
 		}
+		result.Relations[strings.TrimPrefix(tableName, "rel_")] = DomainRelation{Columns: columns}
 	}
 
-	// 4. Read entity type names.
+	// 6. Read entity type names.
 	rows, err := db.Query("SELECT DISTINCT entity_type FROM entities")
 	if err == nil {
 		defer func() { _ = rows.Close() }()
@@ -235,23 +539,52 @@ func (ds *DomainSchema) ToDiffSchema() *schema.DomainSchema {
 		SchemaVersion:   ds.SchemaVersion,
 		EntityTypeNames: make(map[string]bool),
 		Components:      make(map[string]schema.DomainComponent),
+		Resources:       make(map[string]schema.DomainResource),
+		Relations:       make(map[string]schema.DomainRelation),
 	}
 	for k, v := range ds.EntityTypeNames {
 		result.EntityTypeNames[k] = v
 	}
 	for k, v := range ds.Components {
-		domCols := make([]schema.DomainColumn, len(v.Columns))
-		for i, c := range v.Columns {
-			domCols[i] = schema.DomainColumn{
-				Name:    c.Name,
-				SQLType: c.SQLType,
-				IsPK:    c.IsPK,
+		domCols := toDiffColumns(v.Columns)
+		domIdx := make([]schema.DomainIndex, len(v.Indexes))
+		for i, idx := range v.Indexes {
+			domIdx[i] = schema.DomainIndex{
+				Name:    idx.Name,
+				Columns: idx.Columns,
+				Unique:  idx.Unique,
 			}
 		}
 		result.Components[k] = schema.DomainComponent{
 			Type:    v.Type,
 			Columns: domCols,
+			Indexes: domIdx,
 		}
 	}
+	for k, v := range ds.Resources {
+		result.Resources[k] = schema.DomainResource{Columns: toDiffColumns(v.Columns)}
+	}
+	for k, v := range ds.Relations {
+		result.Relations[k] = schema.DomainRelation{Columns: toDiffColumns(v.Columns)}
+	}
 	return result
 }
+
+// toDiffColumns converts introspected columns to their diff representation.
+func toDiffColumns(cols []DomainColumn) []schema.DomainColumn {
+	domCols := make([]schema.DomainColumn, len(cols))
+	for i, c := range cols {
+		domCols[i] = schema.DomainColumn{
+			Name:       c.Name,
+			SQLType:    c.SQLType,
+			IsPK:       c.IsPK,
+			Nullable:   c.Nullable,
+			References: c.References,
+			OnDelete:   c.OnDelete,
+			Computed:   c.Computed,
+			Stored:     c.Stored,
+			Check:      c.Check,
+		}
+	}
+	return domCols
+}
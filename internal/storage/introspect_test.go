@@ -2,6 +2,7 @@ package storage
 
 import (
 	"database/sql"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -225,6 +226,37 @@ func TestIntrospectComponentTable_ObjectComponent(t *testing.T) {
 	checkCol("y", "REAL", false)
 }
 
+func TestIntrospectComponentTable_RecordsNullability(t *testing.T) {
+	store, err := NewSQLiteStore(t.TempDir()+"/test.sqlite", schema.DatabaseSchema{
+		SchemaVersion: 1,
+		Components: map[string]schema.Component{
+			"Target": {
+				Type: schema.ComponentTypeObject,
+				Properties: map[string]schema.Property{
+					"target_x": {Type: schema.PropertyTypeNumber, Nullable: true},
+					"speed":    {Type: schema.PropertyTypeNumber},
+				},
+			},
+		},
+		EntityTypes: map[string]schema.EntityType{},
+	}, "")
+	if err != nil {
+		t.Fatalf("NewSQLiteStore error: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	columns, err := IntrospectComponentTable(store.db, "comp_target")
+	if err != nil {
+		t.Fatalf("IntrospectComponentTable error: %v", err)
+	}
+	want := map[string]bool{"entity_id": false, "target_x": true, "speed": false}
+	for _, c := range columns {
+		if c.Nullable != want[c.Name] {
+			t.Errorf("column %q Nullable = %v, want %v", c.Name, c.Nullable, want[c.Name])
+		}
+	}
+}
+
 func TestIntrospectComponentTable_EntityRefComponent(t *testing.T) {
 	store, err := NewSQLiteStore(t.TempDir()+"/test.sqlite", schema.DatabaseSchema{
 		SchemaVersion: 1,
@@ -396,9 +428,9 @@ func TestInferComponentType(t *testing.T) {
 		want string
 	}{
 		{
-			name: "empty_object",
+			name: "tag",
 			cols: []DomainColumn{{Name: "entity_id", SQLType: "INTEGER", IsPK: true}},
-			want: "object",
+			want: "tag",
 		},
 		{
 			name: "object_with_properties",
@@ -448,7 +480,7 @@ func TestInferComponentType(t *testing.T) {
 		{
 			name: "zero_columns",
 			cols: []DomainColumn{},
-			want: "object",
+			want: "tag",
 		},
 	}
 
@@ -726,11 +758,11 @@ func TestIntrospectAll_RoundTrip(t *testing.T) {
 			},
 		},
 		{
-			name: "empty object component",
+			name: "tag component",
 			components: map[string]schema.Component{
-				"Marker": {Type: "object", Properties: map[string]schema.Property{}},
+				"Marker": {Type: "tag"},
 			},
-			wantTypes: map[string]string{"marker": "object"},
+			wantTypes: map[string]string{"marker": "tag"},
 		},
 		{
 			name: "multi-component schema",
@@ -789,10 +821,13 @@ func TestIntrospectAll_RoundTrip(t *testing.T) {
 				for origName, compDef := range tt.components {
 					if strings.ToLower(origName) == compName {
 						wantColCount := 1 // entity_id always
-						if compDef.Type == "object" {
+						switch compDef.Type {
+						case "object":
 							wantColCount += len(compDef.Properties)
-						} else {
-							// All non-object types add exactly 1 data column.
+						case "tag":
+							// Tags have no data column.
+						default:
+							// All other types add exactly 1 data column.
 							wantColCount++
 						}
 						if len(comp.Columns) != wantColCount {
@@ -877,3 +912,40 @@ func TestToDiffSchema_Conversion(t *testing.T) {
 		t.Errorf("health.Columns len = %d, want 2", len(got.Components["health"].Columns))
 	}
 }
+
+func TestIntrospectAll_Indexes(t *testing.T) {
+	store, err := NewSQLiteStore(t.TempDir()+"/test.sqlite", schema.DatabaseSchema{
+		SchemaVersion: 1,
+		Components: map[string]schema.Component{
+			"Sprite": {
+				Type: schema.ComponentTypeObject,
+				Properties: map[string]schema.Property{
+					"imageId": {Type: schema.PropertyTypeString},
+					"layer":   {Type: schema.PropertyTypeInteger},
+				},
+				Indexes: []schema.Index{
+					{Columns: []string{"imageId"}},
+					{Name: "sprite_layer_image", Columns: []string{"layer", "imageId"}, Unique: true},
+				},
+			},
+		},
+		EntityTypes: map[string]schema.EntityType{},
+	}, "")
+	if err != nil {
+		t.Fatalf("NewSQLiteStore error: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	ds, err := IntrospectAll(store.db)
+	if err != nil {
+		t.Fatalf("IntrospectAll error: %v", err)
+	}
+	got := ds.Components["sprite"].Indexes
+	want := []DomainIndex{
+		{Name: "idx_comp_sprite_imageid", Columns: []string{"imageid"}},
+		{Name: "sprite_layer_image", Columns: []string{"layer", "imageid"}, Unique: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Indexes = %+v, want %+v", got, want)
+	}
+}
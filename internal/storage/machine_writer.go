@@ -23,13 +23,16 @@ func (w *sqliteMachineWriter) SetMachineState(entityID int64, machineID string,
 	if err != nil {
 		return fmt.Errorf("SetMachineState: marshal states: %w", err)
 	}
+	// The row is only written while the entity exists: a machine whose
+	// actions destroyed its own entity finishes its transition without
+	// recreating state for it.
 	_, err = w.tx.Exec(
 		`INSERT INTO behavior_components (entity_id, machine_id, current_states, updated_at)
-		 VALUES (?, ?, ?, ?)
+		 SELECT ?, ?, ?, ? WHERE EXISTS (SELECT 1 FROM entities WHERE id = ?)
 		 ON CONFLICT(entity_id, machine_id) DO UPDATE SET
 		   current_states = excluded.current_states,
 		   updated_at     = excluded.updated_at`,
-		entityID, machineID, string(data), tick,
+		entityID, machineID, string(data), tick, entityID,
 	)
 	if err != nil {
 		return fmt.Errorf("SetMachineState: %w", err)
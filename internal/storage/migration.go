@@ -12,8 +12,8 @@ import (
 
 // SchemaMigrationError is returned when a migration fails at a specific DDL statement.
 type SchemaMigrationError struct {
-	Change       string // component or property name affected
-	ChangeKind   string // "create_table", "alter_add_column", "rebuild_table", "drop_table"
+	Change       string // component or resource affected
+	ChangeKind   string // Statement.Kind of the failing statement, e.g. "rebuild_table"
 	SQL          string // the statement that failed
 	Underlying   error  // driver error
 	StatementIdx int    // zero-based index within the statement batch
@@ -51,18 +51,107 @@ const (
 type MigrationRequiresConfirmation struct {
 	// DestructiveStatements is a copy of the destructive statements for review.
 	DestructiveStatements []Statement
+	// LossyRows lists every existing row whose value would be changed by a
+	// lossy type conversion (see Statement.Lossy).
+	LossyRows []LossyRow
 }
 
+// maxReportedLossyRows caps the rows listed by
+// MigrationRequiresConfirmation.Error; LossyRows always holds all of them.
+const maxReportedLossyRows = 20
+
 func (e *MigrationRequiresConfirmation) Error() string {
 	var sb strings.Builder
 	fmt.Fprintf(&sb, "migration requires confirmation: %d destructive change(s):\n",
 		len(e.DestructiveStatements))
 	for _, s := range e.DestructiveStatements {
 		fmt.Fprintf(&sb, "  - %s: %s\n", s.Kind, s.Description)
+		for _, c := range s.LossyChecks {
+			if c.Constraint != "" {
+				fmt.Fprintf(&sb, "    new constraint: column %q CHECK (%s)\n", c.Column, c.Constraint)
+				continue
+			}
+			fmt.Fprintf(&sb, "    lossy conversion: column %q %s → %s\n", c.Column, c.OldType, c.NewType)
+		}
+	}
+	if len(e.LossyRows) > 0 {
+		fmt.Fprintf(&sb, "%d row(s) would be changed by lossy conversions:\n", len(e.LossyRows))
+		for i, r := range e.LossyRows {
+			if i == maxReportedLossyRows {
+				fmt.Fprintf(&sb, "  ... and %d more\n", len(e.LossyRows)-i)
+				break
+			}
+			fmt.Fprintf(&sb, "  - %s\n", r)
+		}
 	}
 	return sb.String()
 }
 
+// LossyRow identifies one stored value that a lossy type conversion would
+// change.
+type LossyRow struct {
+	Component string `json:"component"`          // lowercase component name
+	Resource  string `json:"resource,omitempty"` // lowercase resource name; Component is then empty
+	Relation  string `json:"relation,omitempty"` // lowercase relation name; Component is then empty
+	Column    string `json:"column"`
+	EntityID  int64  `json:"entityId"`
+	Value     any    `json:"value"`   // the value as currently stored
+	OldType   string `json:"oldType"` // SQL type before the migration
+	NewType   string `json:"newType"` // SQL type after the migration
+	// Constraint is the CHECK condition the value breaks; the rebuild
+	// replaces it. "" for a lossy type conversion.
+	Constraint string `json:"constraint,omitempty"`
+}
+
+func (r LossyRow) String() string {
+	change := fmt.Sprintf("%s → %s", r.OldType, r.NewType)
+	if r.Constraint != "" {
+		change = "breaks CHECK (" + r.Constraint + ")"
+	}
+	if r.Resource != "" {
+		return fmt.Sprintf("res_%s.%s: %v (%s)", r.Resource, r.Column, r.Value, change)
+	}
+	if r.Relation != "" {
+		return fmt.Sprintf("rel_%s.%s source %d: %v (%s)", r.Relation, r.Column, r.EntityID, r.Value, change)
+	}
+	return fmt.Sprintf("comp_%s.%s entity %d: %v (%s)", r.Component, r.Column, r.EntityID, r.Value, change)
+}
+
+// collectLossyRows runs the LossyChecks of every lossy statement against
+// the database as it is before migration and returns the affected rows in
+// statement order.
+func collectLossyRows(db *sql.DB, stmts []Statement) ([]LossyRow, error) {
+	var out []LossyRow
+	for _, s := range stmts {
+		if !s.Lossy {
+			continue
+		}
+		for _, c := range s.LossyChecks {
+			rows, err := db.Query(c.Query)
+			if err != nil {
+				return nil, fmt.Errorf("checking lossy conversion of %s.%s: %w", s.table(), c.Column, err)
+			}
+			for rows.Next() {
+				r := LossyRow{
+					Component: s.Component, Resource: s.Resource, Relation: s.Relation,
+					Column: c.Column, OldType: c.OldType, NewType: c.NewType, Constraint: c.Constraint,
+				}
+				if err := rows.Scan(&r.EntityID, &r.Value); err != nil {
+					rows.Close()
+					return nil, fmt.Errorf("scanning lossy row of %s.%s: %w", s.table(), c.Column, err)
+				}
+				out = append(out, r)
+			}
+			err = rows.Err()
+			rows.Close()
+			if err != nil {
+				return nil, fmt.Errorf("checking lossy conversion of %s.%s: %w", s.table(), c.Column, err)
+			}
+		}
+	}
+	return out, nil
+}
+
 // MigrationLogger receives structured events from the runner.
 // Implement it to forward to your logging library; use NopLogger() to discard.
 type MigrationLogger interface {
@@ -79,12 +168,17 @@ func (nopLogger) Warnf(_ string, _ ...interface{}) {}
 func NopLogger() MigrationLogger { return nopLogger{} }
 
 // MigrationRunner orchestrates the full migration pipeline:
-// introspect → diff → generate DDL → execute in one transaction → update meta.
+// introspect → diff → generate DDL → execute in one transaction together
+// with data migrations → update meta.
 type MigrationRunner struct {
-	db     *sql.DB
-	file   schema.DatabaseSchema
-	policy MigrationPolicy
-	logger MigrationLogger
+	db             *sql.DB
+	file           schema.DatabaseSchema
+	policy         MigrationPolicy
+	logger         MigrationLogger
+	migrationsDir  string
+	backupPath     string
+	contractPolicy ContractPolicy
+	violations     []EntityContractViolation
 }
 
 // NewMigrationRunner creates a MigrationRunner. If logger is nil, NopLogger is used.
@@ -105,29 +199,124 @@ func NewMigrationRunner(
 	}
 }
 
-// Run executes the migration pipeline. Returns nil if the database is already
-// up to date or if migration succeeds. Returns *SchemaMigrationError if a DDL
-// statement fails (with full rollback), or *MigrationRequiresConfirmation when
-// policy=confirm and destructive changes are present.
-func (r *MigrationRunner) Run() error {
-	// 1. Introspect current DB state.
+// WithMigrationsDir sets the directory holding declarative data migrations
+// (dir/vN/*.sql) and returns r. An empty dir disables SQL data migrations;
+// hooks registered with RegisterDataMigration always run.
+func (r *MigrationRunner) WithMigrationsDir(dir string) *MigrationRunner {
+	r.migrationsDir = dir
+	return r
+}
+
+// WithBackupPath records the path of the backup taken before this
+// migration in its schema_migrations row, and returns r.
+func (r *MigrationRunner) WithBackupPath(path string) *MigrationRunner {
+	r.backupPath = path
+	return r
+}
+
+// WithContractPolicy sets how existing entities that violate the new
+// entity-type contracts are handled, and returns r. When unset, the
+// policy follows the migration policy: confirm refuses, auto reports.
+func (r *MigrationRunner) WithContractPolicy(p ContractPolicy) *MigrationRunner {
+	r.contractPolicy = p
+	return r
+}
+
+// ContractViolations returns the entity contract violations found by the
+// last Run, with Repaired set on those fixed under ContractRepair.
+func (r *MigrationRunner) ContractViolations() []EntityContractViolation {
+	return r.violations
+}
+
+// preparedMigration is everything the runner computes before touching the
+// database. Run executes it; Plan describes it.
+type preparedMigration struct {
+	domain    *DomainSchema
+	changes   []schema.Change
+	stmts     []Statement
+	dataSteps []dataMigration
+	lossyRows []LossyRow
+}
+
+// prepare introspects the database, diffs it against the file schema and
+// the stored snapshot, loads the pending data migrations, generates the DDL
+// and collects lossy rows. It returns nil, nil when the database is already
+// up to date. It only reads from the database.
+func (r *MigrationRunner) prepare() (*preparedMigration, error) {
 	domain, err := IntrospectAll(r.db)
 	if err != nil {
-		return fmt.Errorf("introspecting db: %w", err)
+		return nil, fmt.Errorf("introspecting db: %w", err)
 	}
 
-	// 2. Compute structural diff.
-	changes := schema.Diff(domain.ToDiffSchema(), &r.file, nil)
+	// The snapshot of the last applied schema is the old file, so entity
+	// type spec changes are detected too.
+	snapshot, err := readSchemaSnapshot(r.db)
+	if err != nil {
+		r.logger.Warnf("ignoring schema snapshot: %v", err)
+		snapshot = nil
+	}
+	changes := schema.Diff(domain.ToDiffSchema(), &r.file, snapshot)
 
 	// Nothing to do when versions already match and structure is identical.
 	if len(changes) == 0 && domain.SchemaVersion == r.file.SchemaVersion {
-		return nil
+		return nil, nil
+	}
+
+	// Load the data migrations up front so a bad migrations directory fails
+	// before any DDL runs.
+	dataSteps, err := pendingDataMigrations(r.migrationsDir, domain.SchemaVersion, r.file.SchemaVersion)
+	if err != nil {
+		return nil, fmt.Errorf("loading data migrations: %w", err)
 	}
 
-	// 3. Generate DDL from structural changes.
 	gen := NewGenerator(&r.file, domain, Config{StrictDrop: true})
 	stmts := gen.Generate(changes)
 
+	lossyRows, err := collectLossyRows(r.db, stmts)
+	if err != nil {
+		return nil, err
+	}
+	return &preparedMigration{
+		domain:    domain,
+		changes:   changes,
+		stmts:     stmts,
+		dataSteps: dataSteps,
+		lossyRows: lossyRows,
+	}, nil
+}
+
+// Run executes the migration pipeline. Returns nil if the database is already
+// up to date or if migration succeeds. Returns *SchemaMigrationError if a DDL
+// statement fails (with full rollback), *DataMigrationError if a data
+// migration fails (with full rollback), *EntityContractError if existing
+// entities violate the new entity-type contracts and the contract policy
+// does not let the migration proceed (with full rollback), or
+// *MigrationRequiresConfirmation when policy=confirm and destructive
+// changes are present. A change that cannot be migrated, such as a
+// constrained property added without a default its existing rows could
+// take, is refused before anything runs.
+func (r *MigrationRunner) Run() error {
+	started := time.Now()
+
+	// 1–3. Introspect, diff against the file and the snapshot, load data
+	// migrations, generate DDL and find the rows lossy conversions change.
+	pm, err := r.prepare()
+	if err != nil {
+		return err
+	}
+	if pm == nil {
+		return nil // already up to date
+	}
+	domain, stmts, dataSteps := pm.domain, pm.stmts, pm.dataSteps
+
+	// A change the generator could not express refuses the whole migration
+	// before anything runs.
+	for _, s := range stmts {
+		if s.Kind == "error" {
+			return fmt.Errorf("migration refused: %s", strings.TrimPrefix(s.Description, "ERROR: "))
+		}
+	}
+
 	// 4. Check policy against destructive statements.
 	if r.policy == MigrationConfirm {
 		var destructive []Statement
@@ -137,9 +326,12 @@ func (r *MigrationRunner) Run() error {
 			}
 		}
 		if len(destructive) > 0 {
-			return &MigrationRequiresConfirmation{DestructiveStatements: destructive}
+			return &MigrationRequiresConfirmation{DestructiveStatements: destructive, LossyRows: pm.lossyRows}
 		}
 	}
+	for _, row := range pm.lossyRows {
+		r.logger.Warnf("migration: lossy conversion changes %s", row)
+	}
 
 	// 5. If any statement is a table rebuild, PRAGMA foreign_keys must be
 	// toggled on the same connection that runs the transaction. PRAGMA
@@ -187,7 +379,7 @@ func (r *MigrationRunner) Run() error {
 		if _, err := tx.Exec(stmt.SQL); err != nil {
 			_ = tx.Rollback()
 			return &SchemaMigrationError{
-				Change:       stmt.Component,
+				Change:       stmt.subject(),
 				ChangeKind:   stmt.Kind,
 				SQL:          stmt.SQL,
 				Underlying:   err,
@@ -195,10 +387,32 @@ func (r *MigrationRunner) Run() error {
 				TotalStmts:   len(stmts),
 			}
 		}
-		r.logger.Infof("migration: executed %s on %s", stmt.Kind, stmt.Component)
+		r.logger.Infof("migration: executed %s on %s", stmt.Kind, stmt.subject())
 	}
 
-	// 8. Update meta inside the same transaction.
+	// 8. Run data migrations after the DDL, inside the same transaction.
+	dataNames := make([]string, 0, len(dataSteps))
+	for _, step := range dataSteps {
+		if err := step.run(tx); err != nil {
+			_ = tx.Rollback()
+			return &DataMigrationError{
+				Name:        step.name,
+				FromVersion: step.from,
+				ToVersion:   step.to,
+				Underlying:  err,
+			}
+		}
+		r.logger.Infof("migration: ran data migration %s", step.name)
+		dataNames = append(dataNames, step.name)
+	}
+
+	// 9. Check existing entities against the new entity-type contracts.
+	if err := r.enforceEntityContracts(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	// 10. Update meta inside the same transaction.
 	versionRes, err := tx.Exec(
 		"UPDATE meta SET value = ? WHERE key = 'schema_version'",
 		fmt.Sprintf("%d", r.file.SchemaVersion),
@@ -218,8 +432,28 @@ func (r *MigrationRunner) Run() error {
 		_ = tx.Rollback()
 		return fmt.Errorf("updating meta build_time: %w", err)
 	}
+	if err := writeSchemaSnapshot(tx, r.file); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	executed := make([]string, len(stmts))
+	for i, s := range stmts {
+		executed[i] = s.SQL
+	}
+	if err := recordMigration(tx, MigrationRecord{
+		FromVersion:    domain.SchemaVersion,
+		ToVersion:      r.file.SchemaVersion,
+		AppliedAt:      started,
+		Statements:     executed,
+		DataMigrations: dataNames,
+		BackupPath:     r.backupPath,
+		Duration:       time.Since(started),
+	}); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
 
-	// 9. Commit.
+	// 11. Commit.
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("committing migration: %w", err)
 	}
@@ -229,3 +463,40 @@ func (r *MigrationRunner) Run() error {
 
 	return nil
 }
+
+// enforceEntityContracts scans entities against the new contracts and
+// applies the contract policy. It returns *EntityContractError when the
+// migration must be rolled back.
+func (r *MigrationRunner) enforceEntityContracts(tx *sql.Tx) error {
+	violations, err := checkEntityContracts(tx, &r.file)
+	if err != nil {
+		return fmt.Errorf("checking entity contracts: %w", err)
+	}
+	r.violations = violations
+	if len(violations) == 0 {
+		return nil
+	}
+
+	policy := r.contractPolicy
+	if policy == "" {
+		policy = defaultContractPolicy(r.policy)
+	}
+	if policy == ContractRepair {
+		if err := repairEntityContracts(tx, &r.file, violations); err != nil {
+			return fmt.Errorf("repairing entity contracts: %w", err)
+		}
+	}
+	if policy != ContractReport {
+		if blocking := unresolvedViolations(violations); len(blocking) > 0 {
+			return &EntityContractError{Violations: blocking}
+		}
+	}
+	for _, v := range violations {
+		if v.Repaired {
+			r.logger.Infof("migration: repaired %s", v)
+		} else {
+			r.logger.Warnf("migration: entity contract violation: %s", v)
+		}
+	}
+	return nil
+}
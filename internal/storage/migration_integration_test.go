@@ -3,6 +3,7 @@ package storage
 import (
 	"database/sql"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/tmbritton/ecs-db/internal/schema"
@@ -418,9 +419,8 @@ func TestSmoke_TypeChange_DataPreserved(t *testing.T) {
 	t.Cleanup(func() { _ = store2.Close() })
 	db2 := store2.DB()
 
-	// Both entity_ids must survive the rebuild. SQLite has loose typing: the
-	// original REAL values (10.9, 20.1) are preserved as-is in the rebuilt
-	// INTEGER affinity column, so we scan into float64.
+	// Both entity_ids must survive the rebuild. The copy CASTs the original
+	// REAL values (10.9, 20.1) to INTEGER, truncating them.
 	for i, id := range ids {
 		var gotID int64
 		var gotPts float64
@@ -432,9 +432,463 @@ func TestSmoke_TypeChange_DataPreserved(t *testing.T) {
 		if gotID != id {
 			t.Errorf("entity %d: entity_id = %d, want %d", i, gotID, id)
 		}
+		if want := []float64{10, 20}[i]; gotPts != want {
+			t.Errorf("entity %d: points = %v, want %v", i, gotPts, want)
+		}
 	}
 
 	if got := readMetaValue(t, db2, "schema_version"); got != "2" {
 		t.Errorf("schema_version = %q, want 2", got)
 	}
 }
+
+// TestSmoke_Rename_DataPreservedUnderConfirm renames a component and one of
+// its properties via renamedFrom hints. The migration must rename in place,
+// keep the data, and not require confirmation.
+func TestSmoke_Rename_DataPreservedUnderConfirm(t *testing.T) {
+	path := t.TempDir() + "/smoke.sqlite"
+
+	s1 := schema.DatabaseSchema{
+		SchemaVersion: 1,
+		Components: map[string]schema.Component{
+			"Health": {
+				Type: schema.ComponentTypeObject,
+				Properties: map[string]schema.Property{
+					"hp":  {Type: schema.PropertyTypeInteger},
+					"max": {Type: schema.PropertyTypeInteger},
+				},
+			},
+		},
+		EntityTypes: map[string]schema.EntityType{},
+	}
+	store1, err := NewSQLiteStore(path, s1, "")
+	if err != nil {
+		t.Fatalf("v1 open: %v", err)
+	}
+	db1 := store1.DB()
+	if _, err := db1.Exec("INSERT INTO entities (id, entity_type, created_tick) VALUES (1, 'Goblin', 0)"); err != nil {
+		t.Fatalf("inserting entity: %v", err)
+	}
+	if _, err := db1.Exec("INSERT INTO comp_health (entity_id, hp, max) VALUES (1, 7, 10)"); err != nil {
+		t.Fatalf("inserting comp_health: %v", err)
+	}
+	_ = store1.Close()
+
+	s2 := schema.DatabaseSchema{
+		SchemaVersion: 2,
+		Components: map[string]schema.Component{
+			"Vitality": {
+				Type:        schema.ComponentTypeObject,
+				RenamedFrom: "Health",
+				Properties: map[string]schema.Property{
+					"current": {Type: schema.PropertyTypeInteger, RenamedFrom: "hp"},
+					"max":     {Type: schema.PropertyTypeInteger},
+				},
+			},
+		},
+		EntityTypes: map[string]schema.EntityType{},
+	}
+	store2, err := NewSQLiteStoreWithConfig(path, StoreConfig{
+		Schema:          s2,
+		MigrationPolicy: MigrationConfirm,
+	})
+	if err != nil {
+		t.Fatalf("v2 open (migration): %v", err)
+	}
+	t.Cleanup(func() { _ = store2.Close() })
+	db2 := store2.DB()
+
+	if tableExists(t, db2, "comp_health") {
+		t.Error("comp_health still exists after rename")
+	}
+	var current, max int
+	if err := db2.QueryRow(
+		"SELECT current, max FROM comp_vitality WHERE entity_id = 1",
+	).Scan(&current, &max); err != nil {
+		t.Fatalf("reading comp_vitality after migration: %v", err)
+	}
+	if current != 7 || max != 10 {
+		t.Errorf("vitality = (%d, %d), want (7, 10)", current, max)
+	}
+	if got := readMetaValue(t, db2, "schema_version"); got != "2" {
+		t.Errorf("schema_version = %q, want 2", got)
+	}
+}
+
+// TestSmoke_WideningConversions_DataPreserved changes integer → number and
+// number → string. Both conversions are lossless: values must survive with
+// the new storage class.
+func TestSmoke_WideningConversions_DataPreserved(t *testing.T) {
+	path := t.TempDir() + "/smoke.sqlite"
+
+	s1 := schema.DatabaseSchema{
+		SchemaVersion: 1,
+		Components: map[string]schema.Component{
+			"Stats": {
+				Type: schema.ComponentTypeObject,
+				Properties: map[string]schema.Property{
+					"level":  {Type: schema.PropertyTypeInteger},
+					"weight": {Type: schema.PropertyTypeNumber},
+				},
+			},
+		},
+		EntityTypes: map[string]schema.EntityType{},
+	}
+	store1, err := NewSQLiteStore(path, s1, "")
+	if err != nil {
+		t.Fatalf("v1 open: %v", err)
+	}
+	db1 := store1.DB()
+	if _, err := db1.Exec("INSERT INTO entities (id, entity_type, created_tick) VALUES (1, 'Player', 0)"); err != nil {
+		t.Fatalf("inserting entity: %v", err)
+	}
+	if _, err := db1.Exec("INSERT INTO comp_stats (entity_id, level, weight) VALUES (1, 7, 2.5)"); err != nil {
+		t.Fatalf("inserting comp_stats: %v", err)
+	}
+	_ = store1.Close()
+
+	s2 := schema.DatabaseSchema{
+		SchemaVersion: 2,
+		Components: map[string]schema.Component{
+			"Stats": {
+				Type: schema.ComponentTypeObject,
+				Properties: map[string]schema.Property{
+					"level":  {Type: schema.PropertyTypeNumber},
+					"weight": {Type: schema.PropertyTypeString},
+				},
+			},
+		},
+		EntityTypes: map[string]schema.EntityType{},
+	}
+	store2, err := NewSQLiteStore(path, s2, "")
+	if err != nil {
+		t.Fatalf("v2 open (migration): %v", err)
+	}
+	t.Cleanup(func() { _ = store2.Close() })
+
+	var level float64
+	var weight, levelType, weightType string
+	if err := store2.DB().QueryRow(
+		"SELECT level, weight, typeof(level), typeof(weight) FROM comp_stats WHERE entity_id = 1",
+	).Scan(&level, &weight, &levelType, &weightType); err != nil {
+		t.Fatalf("reading comp_stats after migration: %v", err)
+	}
+	if level != 7 || levelType != "real" {
+		t.Errorf("level = %v (%s), want 7 (real)", level, levelType)
+	}
+	if weight != "2.5" || weightType != "text" {
+		t.Errorf("weight = %q (%s), want \"2.5\" (text)", weight, weightType)
+	}
+}
+
+// TestSmoke_Indexes_SurviveRebuild adds an index in v2 and rebuilds the
+// indexed table in v3; the index must exist after both migrations.
+func TestSmoke_Indexes_SurviveRebuild(t *testing.T) {
+	path := t.TempDir() + "/smoke.sqlite"
+	score := func(version int, propType string, indexes ...schema.Index) schema.DatabaseSchema {
+		return schema.DatabaseSchema{
+			SchemaVersion: version,
+			Components: map[string]schema.Component{
+				"Score": {
+					Type:       schema.ComponentTypeObject,
+					Properties: map[string]schema.Property{"points": {Type: propType}},
+					Indexes:    indexes,
+				},
+			},
+			EntityTypes: map[string]schema.EntityType{},
+		}
+	}
+	byPoints := schema.Index{Columns: []string{"points"}}
+
+	for _, s := range []schema.DatabaseSchema{
+		score(1, schema.PropertyTypeNumber),
+		score(2, schema.PropertyTypeNumber, byPoints),
+		score(3, schema.PropertyTypeInteger, byPoints),
+	} {
+		store, err := NewSQLiteStore(path, s, "")
+		if err != nil {
+			t.Fatalf("v%d open: %v", s.SchemaVersion, err)
+		}
+		indexes, err := IntrospectIndexes(store.DB(), "comp_score")
+		_ = store.Close()
+		if err != nil {
+			t.Fatalf("v%d IntrospectIndexes: %v", s.SchemaVersion, err)
+		}
+		want := len(s.Components["Score"].Indexes)
+		if len(indexes) != want {
+			t.Errorf("v%d: indexes = %+v, want %d", s.SchemaVersion, indexes, want)
+		}
+	}
+}
+
+// TestSmoke_OnDeleteChange_RebuildsForeignKey changes the delete policy of a
+// nested entity-ref property; the migration must rebuild the table with the
+// new foreign key.
+func TestSmoke_OnDeleteChange_RebuildsForeignKey(t *testing.T) {
+	path := t.TempDir() + "/smoke.sqlite"
+	target := func(version int, onDelete string) schema.DatabaseSchema {
+		return schema.DatabaseSchema{
+			SchemaVersion: version,
+			Components: map[string]schema.Component{
+				"Target": {
+					Type: schema.ComponentTypeObject,
+					Properties: map[string]schema.Property{
+						"entity": {Type: schema.PropertyTypeEntityRef, Nullable: true, OnDelete: onDelete},
+					},
+				},
+			},
+			EntityTypes: map[string]schema.EntityType{},
+		}
+	}
+
+	store1, err := NewSQLiteStore(path, target(1, ""), "")
+	if err != nil {
+		t.Fatalf("v1 open: %v", err)
+	}
+	db1 := store1.DB()
+	for _, q := range []string{
+		"INSERT INTO entities (id, entity_type) VALUES (1, 'Goblin'), (2, 'Goblin')",
+		"INSERT INTO comp_target (entity_id, entity) VALUES (1, 2)",
+	} {
+		if _, err := db1.Exec(q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+	_ = store1.Close()
+
+	store2, err := NewSQLiteStore(path, target(2, schema.OnDeleteSetNull), "")
+	if err != nil {
+		t.Fatalf("v2 open (migration): %v", err)
+	}
+	t.Cleanup(func() { _ = store2.Close() })
+	db2 := store2.DB()
+
+	cols, err := IntrospectComponentTable(db2, "comp_target")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if col := cols[1]; !col.References || col.OnDelete != schema.OnDeleteSetNull {
+		t.Errorf("entity column = %+v, want a setNull foreign key", col)
+	}
+
+	if _, err := db2.Exec("DELETE FROM entities WHERE id = 2"); err != nil {
+		t.Fatalf("deleting referenced entity: %v", err)
+	}
+	var entity sql.NullInt64
+	if err := db2.QueryRow("SELECT entity FROM comp_target WHERE entity_id = 1").Scan(&entity); err != nil {
+		t.Fatal(err)
+	}
+	if entity.Valid {
+		t.Errorf("entity = %d, want NULL after the referenced entity was deleted", entity.Int64)
+	}
+}
+
+// TestSmoke_TwoChangesOnOneComponent changes the type of one property and
+// tightens another to NOT NULL in the same version. Both are applied by the
+// one rebuild of the table, which must backfill the NULLs of the second.
+func TestSmoke_TwoChangesOnOneComponent(t *testing.T) {
+	path := t.TempDir() + "/smoke.sqlite"
+	stats := func(version int, aType string, bNullable bool) schema.DatabaseSchema {
+		return schema.DatabaseSchema{
+			SchemaVersion: version,
+			Components: map[string]schema.Component{
+				"Stats": {
+					Type: schema.ComponentTypeObject,
+					Properties: map[string]schema.Property{
+						"a": {Type: aType},
+						"b": {Type: schema.PropertyTypeInteger, Nullable: bNullable},
+					},
+				},
+			},
+			EntityTypes: map[string]schema.EntityType{},
+		}
+	}
+
+	store1, err := NewSQLiteStore(path, stats(1, schema.PropertyTypeNumber, true), "")
+	if err != nil {
+		t.Fatalf("v1 open: %v", err)
+	}
+	db1 := store1.DB()
+	for _, q := range []string{
+		"INSERT INTO entities (id, entity_type) VALUES (1, 'Goblin')",
+		"INSERT INTO comp_stats (entity_id, a, b) VALUES (1, 2.0, NULL)",
+	} {
+		if _, err := db1.Exec(q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+	_ = store1.Close()
+
+	store2, err := NewSQLiteStore(path, stats(2, schema.PropertyTypeInteger, false), "")
+	if err != nil {
+		t.Fatalf("v2 open (migration): %v", err)
+	}
+	t.Cleanup(func() { _ = store2.Close() })
+
+	var a, b int64
+	if err := store2.DB().QueryRow("SELECT a, b FROM comp_stats WHERE entity_id = 1").Scan(&a, &b); err != nil {
+		t.Fatalf("reading comp_stats: %v", err)
+	}
+	if a != 2 || b != 0 {
+		t.Errorf("a, b = %d, %d; want 2, 0", a, b)
+	}
+}
+
+// TestSmoke_AddConstrainedColumn_DeclaresCheck adds a constrained property
+// by migration. The column must get the same CHECK as a fresh bootstrap
+// gives it, so that direct SQL writes are checked too.
+func TestSmoke_AddConstrainedColumn_DeclaresCheck(t *testing.T) {
+	path := t.TempDir() + "/smoke.sqlite"
+	zero, ten := 0.0, 10.0
+	stats := func(version int, props map[string]schema.Property) schema.DatabaseSchema {
+		return schema.DatabaseSchema{
+			SchemaVersion: version,
+			Components: map[string]schema.Component{
+				"Stats": {Type: schema.ComponentTypeObject, Properties: props},
+			},
+			EntityTypes: map[string]schema.EntityType{},
+		}
+	}
+	hp := schema.Property{Type: schema.PropertyTypeInteger}
+	armor := schema.Property{Type: schema.PropertyTypeInteger, Minimum: &zero, Maximum: &ten}
+
+	store1, err := NewSQLiteStore(path, stats(1, map[string]schema.Property{"hp": hp}), "")
+	if err != nil {
+		t.Fatalf("v1 open: %v", err)
+	}
+	for _, q := range []string{
+		"INSERT INTO entities (id, entity_type) VALUES (1, 'Goblin')",
+		"INSERT INTO comp_stats (entity_id, hp) VALUES (1, 5)",
+	} {
+		if _, err := store1.DB().Exec(q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+	_ = store1.Close()
+
+	store2, err := NewSQLiteStore(path, stats(2, map[string]schema.Property{"hp": hp, "armor": armor}), "")
+	if err != nil {
+		t.Fatalf("v2 open (migration): %v", err)
+	}
+	t.Cleanup(func() { _ = store2.Close() })
+	db2 := store2.DB()
+
+	var ddl string
+	if err := db2.QueryRow("SELECT sql FROM sqlite_master WHERE name = 'comp_stats'").Scan(&ddl); err != nil {
+		t.Fatal(err)
+	}
+	if want := "CHECK (armor >= 0 AND armor <= 10)"; !strings.Contains(ddl, want) {
+		t.Errorf("comp_stats DDL = %s, want it to contain %q", ddl, want)
+	}
+	if _, err := db2.Exec("UPDATE comp_stats SET armor = 11 WHERE entity_id = 1"); err == nil {
+		t.Error("writing armor 11 succeeded, want a CHECK failure")
+	}
+}
+
+// TestSmoke_AddConstrainedColumn_RefusesInvalidBackfill adds a property
+// whose zero value breaks its minimum and that declares no default. The
+// existing row cannot be backfilled, so the migration must be refused.
+func TestSmoke_AddConstrainedColumn_RefusesInvalidBackfill(t *testing.T) {
+	path := t.TempDir() + "/smoke.sqlite"
+	one := 1.0
+	stats := func(version int, props map[string]schema.Property) schema.DatabaseSchema {
+		return schema.DatabaseSchema{
+			SchemaVersion: version,
+			Components: map[string]schema.Component{
+				"Stats": {Type: schema.ComponentTypeObject, Properties: props},
+			},
+			EntityTypes: map[string]schema.EntityType{},
+		}
+	}
+	hp := schema.Property{Type: schema.PropertyTypeInteger}
+
+	store1, err := NewSQLiteStore(path, stats(1, map[string]schema.Property{"hp": hp}), "")
+	if err != nil {
+		t.Fatalf("v1 open: %v", err)
+	}
+	for _, q := range []string{
+		"INSERT INTO entities (id, entity_type) VALUES (1, 'Goblin')",
+		"INSERT INTO comp_stats (entity_id, hp) VALUES (1, 5)",
+	} {
+		if _, err := store1.DB().Exec(q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+	_ = store1.Close()
+
+	_, err = NewSQLiteStore(path, stats(2, map[string]schema.Property{
+		"hp":    hp,
+		"level": {Type: schema.PropertyTypeInteger, Minimum: &one},
+	}), "")
+	if err == nil || !strings.Contains(err.Error(), `cannot add "level" to comp_stats`) {
+		t.Fatalf("v2 open err = %v, want the level backfill refused", err)
+	}
+
+	db := openMigrationTestDBAt(t, path)
+	if got := readMetaValue(t, db, "schema_version"); got != "1" {
+		t.Errorf("schema_version = %q, want 1", got)
+	}
+	if columnExists(t, db, "comp_stats", "level") {
+		t.Error("level was added despite the refusal")
+	}
+}
+
+// TestSmoke_ConstraintChange_ScrubsViolatingRows tightens an existing
+// property with a maximum. The plan must report the row above it, and the
+// rebuild must declare the CHECK and replace that row's value with the
+// default.
+func TestSmoke_ConstraintChange_ScrubsViolatingRows(t *testing.T) {
+	path := t.TempDir() + "/smoke.sqlite"
+	ten := 10.0
+	stats := func(version int, armor schema.Property) schema.DatabaseSchema {
+		return schema.DatabaseSchema{
+			SchemaVersion: version,
+			Components: map[string]schema.Component{
+				"Stats": {Type: schema.ComponentTypeObject, Properties: map[string]schema.Property{"armor": armor}},
+			},
+			EntityTypes: map[string]schema.EntityType{},
+		}
+	}
+	v2 := stats(2, schema.Property{Type: schema.PropertyTypeInteger, Maximum: &ten, Default: float64(1)})
+
+	store1, err := NewSQLiteStore(path, stats(1, schema.Property{Type: schema.PropertyTypeInteger}), "")
+	if err != nil {
+		t.Fatalf("v1 open: %v", err)
+	}
+	for _, q := range []string{
+		"INSERT INTO entities (id, entity_type) VALUES (1, 'Goblin'), (2, 'Goblin')",
+		"INSERT INTO comp_stats (entity_id, armor) VALUES (1, 5), (2, 15)",
+	} {
+		if _, err := store1.DB().Exec(q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+	plan, err := PlanMigration(store1.DB(), v2)
+	if err != nil {
+		t.Fatalf("PlanMigration: %v", err)
+	}
+	if len(plan.LossyRows) != 1 || plan.LossyRows[0].EntityID != 2 || plan.LossyRows[0].Constraint != "armor <= 10" {
+		t.Errorf("plan lossy rows = %+v, want entity 2 breaking armor <= 10", plan.LossyRows)
+	}
+	_ = store1.Close()
+
+	store2, err := NewSQLiteStore(path, v2, "")
+	if err != nil {
+		t.Fatalf("v2 open (migration): %v", err)
+	}
+	t.Cleanup(func() { _ = store2.Close() })
+	db2 := store2.DB()
+
+	for id, want := range map[int64]int64{1: 5, 2: 1} {
+		var got int64
+		if err := db2.QueryRow("SELECT armor FROM comp_stats WHERE entity_id = ?", id).Scan(&got); err != nil {
+			t.Fatalf("entity %d: %v", id, err)
+		}
+		if got != want {
+			t.Errorf("entity %d: armor = %d, want %d", id, got, want)
+		}
+	}
+	if _, err := db2.Exec("UPDATE comp_stats SET armor = 11 WHERE entity_id = 1"); err == nil {
+		t.Error("writing armor 11 succeeded, want a CHECK failure")
+	}
+}
@@ -139,6 +139,30 @@ func TestMigrationRequiresConfirmation_ErrorListsDestructive(t *testing.T) {
 	}
 }
 
+func TestMigrationRequiresConfirmation_ErrorListsLossyRows(t *testing.T) {
+	e := &MigrationRequiresConfirmation{
+		DestructiveStatements: []Statement{{
+			Kind:        "rebuild_table",
+			Description: "Copy data from comp_score",
+			Lossy:       true,
+			LossyChecks: []LossyCheck{{Column: "points", OldType: "REAL", NewType: "INTEGER"}},
+		}},
+		LossyRows: []LossyRow{
+			{Component: "score", Column: "points", EntityID: 3, Value: 10.5, OldType: "REAL", NewType: "INTEGER"},
+		},
+	}
+	msg := e.Error()
+	for _, want := range []string{
+		`lossy conversion: column "points" REAL → INTEGER`,
+		"1 row(s) would be changed",
+		"comp_score.points entity 3: 10.5 (REAL → INTEGER)",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message missing %q:\n%s", want, msg)
+		}
+	}
+}
+
 func TestMigrationRequiresConfirmation_IsError(t *testing.T) {
 	var e error = &MigrationRequiresConfirmation{}
 	if e.Error() == "" {
@@ -685,6 +709,53 @@ func TestMigrate_ConfirmPolicy_DestructiveBlocks(t *testing.T) {
 	}
 }
 
+func TestMigrate_ConfirmPolicy_LossyConversionReportsRows(t *testing.T) {
+	db := openMigrationTestDB(t)
+	s1 := schema.DatabaseSchema{
+		SchemaVersion: 1,
+		Components: map[string]schema.Component{
+			"Score": {Type: schema.ComponentTypeObject, Properties: map[string]schema.Property{
+				"points": {Type: schema.PropertyTypeNumber},
+			}},
+		},
+		EntityTypes: map[string]schema.EntityType{},
+	}
+	bootstrapMigrationDB(t, db, s1)
+	for id, pts := range map[int]float64{1: 10.5, 2: 20, 3: -0.25} {
+		if _, err := db.Exec("INSERT INTO entities (id, entity_type, created_tick) VALUES (?, 'Player', 0)", id); err != nil {
+			t.Fatalf("inserting entity: %v", err)
+		}
+		if _, err := db.Exec("INSERT INTO comp_score (entity_id, points) VALUES (?, ?)", id, pts); err != nil {
+			t.Fatalf("inserting comp_score: %v", err)
+		}
+	}
+
+	s2 := schema.DatabaseSchema{
+		SchemaVersion: 2,
+		Components: map[string]schema.Component{
+			"Score": {Type: schema.ComponentTypeObject, Properties: map[string]schema.Property{
+				"points": {Type: schema.PropertyTypeInteger},
+			}},
+		},
+		EntityTypes: map[string]schema.EntityType{},
+	}
+
+	err := NewMigrationRunner(db, s2, MigrationConfirm, NopLogger()).Run()
+	var conf *MigrationRequiresConfirmation
+	if !errors.As(err, &conf) {
+		t.Fatalf("expected *MigrationRequiresConfirmation, got %T: %v", err, err)
+	}
+	if len(conf.LossyRows) != 2 {
+		t.Fatalf("LossyRows = %+v, want 2 rows", conf.LossyRows)
+	}
+	if conf.LossyRows[0].EntityID != 1 || conf.LossyRows[1].EntityID != 3 {
+		t.Errorf("LossyRows entities = %d, %d, want 1, 3", conf.LossyRows[0].EntityID, conf.LossyRows[1].EntityID)
+	}
+	if conf.LossyRows[0].Column != "points" || conf.LossyRows[0].NewType != "INTEGER" {
+		t.Errorf("LossyRows[0] = %+v", conf.LossyRows[0])
+	}
+}
+
 func TestMigrate_ConfirmPolicy_NonDestructiveProceeds(t *testing.T) {
 	db := openMigrationTestDB(t)
 	s1 := schema.DatabaseSchema{
@@ -0,0 +1,293 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tmbritton/ecs-db/internal/schema"
+)
+
+// MigrationPlan describes what migrating a database to a schema file would
+// do, without doing it.
+type MigrationPlan struct {
+	FromVersion int `json:"fromVersion"`
+	ToVersion   int `json:"toVersion"`
+	// UpToDate is true when the database already matches the file; every
+	// other field is then empty.
+	UpToDate   bool            `json:"upToDate"`
+	Changes    []schema.Change `json:"changes"`
+	Statements []Statement     `json:"statements"`
+	// Destructive is true when any statement drops or rewrites data; such a
+	// migration is refused under MigrationConfirm.
+	Destructive    bool        `json:"destructive"`
+	LossyRows      []LossyRow  `json:"lossyRows"`
+	AffectedTables []TableRows `json:"affectedTables"`
+	DataMigrations []string    `json:"dataMigrations"`
+	// Backup is the path of the backup the migration would take, empty for
+	// in-memory databases. A backup is only written when
+	// StoreConfig.BackupRetention is positive.
+	Backup string `json:"backup,omitempty"`
+}
+
+// TableRows is the current row count of a table a migration touches.
+type TableRows struct {
+	Table string `json:"table"`
+	Rows  int64  `json:"rows"`
+}
+
+// PlanMigration computes the migration of db to file without applying it.
+// Registered data migration hooks are included; use
+// MigrationRunner.WithMigrationsDir(...).Plan() to include SQL files.
+func PlanMigration(db *sql.DB, file schema.DatabaseSchema) (*MigrationPlan, error) {
+	return NewMigrationRunner(db, file, MigrationAuto, nil).Plan()
+}
+
+// Plan computes what Run would do without applying it. It only reads from
+// the database. Like NewSQLiteStoreWithConfig, it treats a database whose
+// schema_version matches the file as up to date.
+func (r *MigrationRunner) Plan() (*MigrationPlan, error) {
+	dbVersion, err := ReadSchemaVersion(r.db)
+	if err != nil {
+		return nil, err
+	}
+	plan := &MigrationPlan{
+		FromVersion:    r.file.SchemaVersion,
+		ToVersion:      r.file.SchemaVersion,
+		Changes:        []schema.Change{},
+		Statements:     []Statement{},
+		LossyRows:      []LossyRow{},
+		AffectedTables: []TableRows{},
+		DataMigrations: []string{},
+	}
+
+	if dbVersion == r.file.SchemaVersion {
+		plan.UpToDate = true
+		return plan, nil
+	}
+
+	pm, err := r.prepare()
+	if err != nil {
+		return nil, err
+	}
+	if pm == nil {
+		plan.UpToDate = true
+		return plan, nil
+	}
+
+	plan.FromVersion = pm.domain.SchemaVersion
+	plan.Changes = pm.changes
+	plan.Statements = pm.stmts
+	if pm.lossyRows != nil {
+		plan.LossyRows = pm.lossyRows
+	}
+	for _, s := range pm.stmts {
+		if s.Destructive {
+			plan.Destructive = true
+		}
+	}
+	for _, step := range pm.dataSteps {
+		plan.DataMigrations = append(plan.DataMigrations, step.name)
+	}
+	if plan.AffectedTables, err = affectedTableRows(r.db, pm.domain, pm.changes); err != nil {
+		return nil, err
+	}
+
+	plan.Backup = r.backupPath
+	if plan.Backup == "" {
+		dbPath, err := mainDatabaseFile(r.db)
+		if err != nil {
+			return nil, err
+		}
+		if !isMemoryDB(dbPath) {
+			plan.Backup = backupPathFor(dbPath, plan.FromVersion)
+		}
+	}
+	return plan, nil
+}
+
+// affectedTableRows counts the rows of every existing component, resource
+// or relation table that changes touch. A renamed component is counted
+// under its old table name, the one that exists before the migration.
+func affectedTableRows(db *sql.DB, domain *DomainSchema, changes []schema.Change) ([]TableRows, error) {
+	renamed := make(map[string]string) // new component name → old
+	for _, c := range changes {
+		if c.Kind == schema.ChangeRenamedComponent {
+			renamed[c.Component] = c.OldName
+		}
+	}
+
+	seen := make(map[string]bool)
+	var tables []string
+	for _, c := range changes {
+		if c.Resource != "" {
+			table := "res_" + c.Resource
+			if _, exists := domain.Resources[c.Resource]; exists && !seen[table] {
+				seen[table] = true
+				tables = append(tables, table)
+			}
+			continue
+		}
+		if c.Relation != "" {
+			table := "rel_" + c.Relation
+			if _, exists := domain.Relations[c.Relation]; exists && !seen[table] {
+				seen[table] = true
+				tables = append(tables, table)
+			}
+			continue
+		}
+		if c.Component == "" {
+			continue // entity type changes touch no component table
+		}
+		name := c.Component
+		if old, ok := renamed[name]; ok {
+			name = old
+		}
+		table := "comp_" + name
+		if _, exists := domain.Components[name]; !exists || seen[table] {
+			continue
+		}
+		seen[table] = true
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	out := make([]TableRows, 0, len(tables))
+	for _, table := range tables {
+		var n int64
+		if err := db.QueryRow("SELECT count(*) FROM " + table).Scan(&n); err != nil {
+			return nil, fmt.Errorf("counting rows of %s: %w", table, err)
+		}
+		out = append(out, TableRows{Table: table, Rows: n})
+	}
+	return out, nil
+}
+
+// mainDatabaseFile returns the file backing db's main schema, or "" for an
+// in-memory database.
+func mainDatabaseFile(db *sql.DB) (string, error) {
+	rows, err := db.Query("PRAGMA database_list")
+	if err != nil {
+		return "", fmt.Errorf("reading database_list: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var (
+			seq        int
+			name, file string
+		)
+		if err := rows.Scan(&seq, &name, &file); err != nil {
+			return "", fmt.Errorf("scanning database_list: %w", err)
+		}
+		if name == "main" {
+			return file, nil
+		}
+	}
+	return "", rows.Err()
+}
+
+// JSON renders the plan as indented JSON.
+func (p *MigrationPlan) JSON() ([]byte, error) {
+	return json.MarshalIndent(p, "", "  ")
+}
+
+// Text renders the plan for humans.
+func (p *MigrationPlan) Text() string {
+	var sb strings.Builder
+	if p.UpToDate {
+		fmt.Fprintf(&sb, "Database is up to date (schema version %d).\n", p.ToVersion)
+		return sb.String()
+	}
+
+	fmt.Fprintf(&sb, "Migration plan: schema version %d → %d\n", p.FromVersion, p.ToVersion)
+	if p.Destructive {
+		sb.WriteString("WARNING: this migration is destructive and requires confirmation.\n")
+	}
+
+	fmt.Fprintf(&sb, "\nChanges (%d):\n", len(p.Changes))
+	for _, c := range p.Changes {
+		fmt.Fprintf(&sb, "  - %s\n", describeChange(c))
+	}
+
+	fmt.Fprintf(&sb, "\nStatements (%d):\n", len(p.Statements))
+	for i, s := range p.Statements {
+		marker := ""
+		if s.Destructive {
+			marker = " [destructive]"
+		}
+		if s.Lossy {
+			marker += " [lossy]"
+		}
+		fmt.Fprintf(&sb, "  %d. %s: %s%s\n", i+1, s.Kind, s.Description, marker)
+		for _, line := range strings.Split(s.SQL, "\n") {
+			fmt.Fprintf(&sb, "       %s\n", line)
+		}
+	}
+
+	if len(p.AffectedTables) > 0 {
+		sb.WriteString("\nAffected tables:\n")
+		for _, t := range p.AffectedTables {
+			fmt.Fprintf(&sb, "  - %s: %d row(s)\n", t.Table, t.Rows)
+		}
+	}
+
+	if len(p.LossyRows) > 0 {
+		fmt.Fprintf(&sb, "\n%d row(s) would be changed by lossy conversions:\n", len(p.LossyRows))
+		for i, r := range p.LossyRows {
+			if i == maxReportedLossyRows {
+				fmt.Fprintf(&sb, "  ... and %d more\n", len(p.LossyRows)-i)
+				break
+			}
+			fmt.Fprintf(&sb, "  - %s\n", r)
+		}
+	}
+
+	if len(p.DataMigrations) > 0 {
+		sb.WriteString("\nData migrations:\n")
+		for _, name := range p.DataMigrations {
+			fmt.Fprintf(&sb, "  - %s\n", name)
+		}
+	}
+
+	if p.Backup != "" {
+		fmt.Fprintf(&sb, "\nBackup: %s (when backups are enabled)\n", p.Backup)
+	} else {
+		sb.WriteString("\nBackup: none (in-memory database)\n")
+	}
+	return sb.String()
+}
+
+// describeChange renders a schema change as one line.
+func describeChange(c schema.Change) string {
+	owner := c.Component
+	if c.Resource != "" {
+		owner = "res_" + c.Resource
+	}
+	if c.Relation != "" {
+		owner = "rel_" + c.Relation
+	}
+	switch c.Kind {
+	case schema.ChangeRenamedComponent:
+		return fmt.Sprintf("%s: %s → %s", c.Kind, c.OldName, c.Component)
+	case schema.ChangeRenamedProperty:
+		return fmt.Sprintf("%s: %s.%s → %s.%s", c.Kind, owner, c.OldName, owner, c.Property)
+	case schema.ChangedPropertyType:
+		return fmt.Sprintf("%s: %s.%s %s → %s", c.Kind, owner, c.Property, c.OldType, c.NewType)
+	case schema.ChangedPropertyNullability:
+		return fmt.Sprintf("%s: %s.%s nullable %t → %t", c.Kind, owner, c.Property, c.OldNullable, c.NewNullable)
+	case schema.ChangeAddedIndex, schema.ChangeRemovedIndex:
+		return fmt.Sprintf("%s: %s on %s", c.Kind, c.Index, c.Component)
+	case schema.ChangeAddedEntityType, schema.ChangeRemovedEntityType, schema.ChangeChangedEntityType:
+		return fmt.Sprintf("%s: %s", c.Kind, c.ETName)
+	case schema.ChangeAddedResource, schema.ChangeRemovedResource:
+		return fmt.Sprintf("%s: %s", c.Kind, c.Resource)
+	case schema.ChangeAddedRelation, schema.ChangeRemovedRelation:
+		return fmt.Sprintf("%s: %s", c.Kind, c.Relation)
+	}
+	if c.Property != "" {
+		return fmt.Sprintf("%s: %s.%s", c.Kind, owner, c.Property)
+	}
+	return fmt.Sprintf("%s: %s", c.Kind, c.Component)
+}
@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/tmbritton/ecs-db/internal/schema"
+)
+
+// planFixture creates a v1 store on disk with one Health row and returns
+// its path.
+func planFixture(t *testing.T) string {
+	t.Helper()
+	path := t.TempDir() + "/plan.sqlite"
+	store, err := NewSQLiteStore(path, historySchema(1, schema.EntityType{ValidationLevel: schema.ValidationStrict}), "")
+	if err != nil {
+		t.Fatalf("v1 open: %v", err)
+	}
+	db := store.DB()
+	if _, err := db.Exec("INSERT INTO entities (id, entity_type) VALUES (1, 'Goblin')"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO comp_health (entity_id, hp) VALUES (1, 7)"); err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Close()
+	return path
+}
+
+func openMigrationTestDBAt(t *testing.T, path string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func TestPlanMigration_DescribesWithoutApplying(t *testing.T) {
+	path := planFixture(t)
+	db := openMigrationTestDBAt(t, path)
+
+	s2 := historySchema(2, schema.EntityType{ValidationLevel: schema.ValidationStrict})
+	s2.Components["Health"] = schema.Component{
+		Type:       schema.ComponentTypeObject,
+		Properties: map[string]schema.Property{"hp": {Type: schema.PropertyTypeNumber}},
+	}
+	s2.Components["Tag"] = schema.Component{Type: schema.ComponentTypeString}
+
+	plan, err := PlanMigration(db, s2)
+	if err != nil {
+		t.Fatalf("PlanMigration: %v", err)
+	}
+	if plan.UpToDate || plan.FromVersion != 1 || plan.ToVersion != 2 {
+		t.Errorf("plan versions = %d → %d (upToDate %v), want 1 → 2", plan.FromVersion, plan.ToVersion, plan.UpToDate)
+	}
+	if len(plan.Changes) != 2 {
+		t.Errorf("Changes = %+v, want added Tag and changed hp type", plan.Changes)
+	}
+	if !plan.Destructive {
+		t.Error("Destructive = false, want true for the hp rebuild")
+	}
+	if len(plan.AffectedTables) != 1 || plan.AffectedTables[0] != (TableRows{Table: "comp_health", Rows: 1}) {
+		t.Errorf("AffectedTables = %+v, want comp_health with 1 row", plan.AffectedTables)
+	}
+	if plan.Backup != path+".bak.v1" {
+		t.Errorf("Backup = %q, want %q", plan.Backup, path+".bak.v1")
+	}
+
+	if got := readMetaValue(t, db, "schema_version"); got != "1" {
+		t.Errorf("schema_version = %q, want 1 (plan must not migrate)", got)
+	}
+	if tableExists(t, db, "comp_tag") {
+		t.Error("comp_tag created by a plan")
+	}
+}
+
+func TestPlanMigration_UpToDate(t *testing.T) {
+	db := openMigrationTestDB(t)
+	s := historySchema(1, schema.EntityType{ValidationLevel: schema.ValidationStrict})
+	bootstrapMigrationDB(t, db, s)
+
+	plan, err := PlanMigration(db, s)
+	if err != nil {
+		t.Fatalf("PlanMigration: %v", err)
+	}
+	if !plan.UpToDate || len(plan.Statements) != 0 {
+		t.Errorf("plan = %+v, want up to date", plan)
+	}
+	if !strings.Contains(plan.Text(), "up to date") {
+		t.Errorf("Text() = %q", plan.Text())
+	}
+}
+
+func TestMigrationPlan_Render(t *testing.T) {
+	path := planFixture(t)
+	db := openMigrationTestDBAt(t, path)
+
+	s2 := historySchema(2, schema.EntityType{ValidationLevel: schema.ValidationStrict})
+	s2.Components["Tag"] = schema.Component{Type: schema.ComponentTypeString}
+	plan, err := PlanMigration(db, s2)
+	if err != nil {
+		t.Fatalf("PlanMigration: %v", err)
+	}
+
+	text := plan.Text()
+	for _, want := range []string{"schema version 1 → 2", "added_component: tag", "create_table", "Backup: "} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Text() missing %q:\n%s", want, text)
+		}
+	}
+
+	data, err := plan.JSON()
+	if err != nil {
+		t.Fatalf("JSON: %v", err)
+	}
+	var decoded struct {
+		FromVersion int `json:"fromVersion"`
+		Statements  []struct {
+			Kind string `json:"kind"`
+			SQL  string `json:"sql"`
+		} `json:"statements"`
+		LossyRows []any `json:"lossyRows"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("decoding plan JSON: %v\n%s", err, data)
+	}
+	if decoded.FromVersion != 1 || len(decoded.Statements) != 1 || decoded.Statements[0].Kind != "create_table" {
+		t.Errorf("decoded plan = %+v", decoded)
+	}
+	if decoded.LossyRows == nil {
+		t.Error("lossyRows should encode as [] rather than null")
+	}
+}
@@ -0,0 +1,305 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tmbritton/ecs-db/internal/agent"
+	"github.com/tmbritton/ecs-db/internal/schema"
+)
+
+// Query selects entities by component set; see agent.Query. It is defined
+// in the agent package so guards and actions can build queries through
+// agent.WorldReader without importing storage.
+//
+// A Query compiles to one SELECT over entities joined to the comp_* table
+// of each With component, with a NOT EXISTS per Without component:
+//
+//	SELECT e.id, e.entity_type, c0.hp, c0.maxhp, c1.x, c1.y
+//	FROM entities e
+//	JOIN comp_health c0 ON c0.entity_id = e.id
+//	JOIN comp_position c1 ON c1.entity_id = e.id
+//	WHERE NOT EXISTS (SELECT 1 FROM comp_dead WHERE entity_id = e.id)
+//	  AND c0.hp < ?
+//	ORDER BY c0.hp, e.id
+type Query = agent.Query
+
+// queryColumn is one selected component column: the alias of its comp_*
+// table, the column name, the field name rows report it under, and the
+// value standing in for NULL.
+type queryColumn struct {
+	alias string
+	col   string
+	field string
+	null  any
+}
+
+// compiledQuery is a Query rendered to SQL.
+type compiledQuery struct {
+	sql  string
+	args []any
+	cols []queryColumn
+}
+
+// queryFunc runs a query; *sql.DB and *sql.Tx both provide one.
+type queryFunc func(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+
+// compileQuery renders q to SQL. With a schema, components and fields are
+// resolved against it, names are reported as declared and NULLs become
+// typed nils (see typedNil); without one, each comp_* table's columns are
+// read with PRAGMA table_xinfo and reported by lowercase column name.
+func compileQuery(ctx context.Context, query queryFunc, s *schema.DatabaseSchema, q Query) (compiledQuery, error) {
+	var (
+		cq     compiledQuery
+		joins  []string
+		conds  []string
+		fields = map[string]queryColumn{}
+		seen   = map[string]bool{}
+	)
+	for i, name := range q.Components() {
+		canonical, cols, err := queryComponentColumns(ctx, query, s, name)
+		if err != nil {
+			return compiledQuery{}, err
+		}
+		table := "comp_" + strings.ToLower(canonical)
+		if seen[table] {
+			continue
+		}
+		seen[table] = true
+		alias := fmt.Sprintf("c%d", i)
+		joins = append(joins, fmt.Sprintf("JOIN %s %s ON %s.entity_id = e.id", table, alias, alias))
+		for _, col := range cols {
+			col.alias = alias
+			cq.cols = append(cq.cols, col)
+			fields[strings.ToLower(col.field)] = col
+		}
+	}
+	for _, name := range q.Excluded() {
+		table := "comp_" + strings.ToLower(name)
+		if err := validateIdentifier(strings.TrimPrefix(table, "comp_"), "Query Without"); err != nil {
+			return compiledQuery{}, err
+		}
+		if s != nil {
+			comp, canonical := schema.ComponentByName(s, name)
+			if canonical == "" {
+				return compiledQuery{}, fmt.Errorf("Without %q: component is not declared", name)
+			}
+			if comp.Type == schema.ComponentTypeRelation {
+				return compiledQuery{}, fmt.Errorf("Without %q: relations are not stored in comp_* tables", name)
+			}
+		}
+		if seen[table] {
+			return compiledQuery{}, fmt.Errorf("component %q is in both With and Without", name)
+		}
+		conds = append(conds, fmt.Sprintf("NOT EXISTS (SELECT 1 FROM %s WHERE entity_id = e.id)", table))
+	}
+	for _, c := range q.Conditions() {
+		col, ok := fields[strings.ToLower(c.Field)]
+		if !ok {
+			return compiledQuery{}, fmt.Errorf("Where %q: field is not on a With component", c.Field)
+		}
+		if !agent.QueryOps[c.Op] {
+			return compiledQuery{}, fmt.Errorf("Where %q: unsupported operator %q", c.Field, c.Op)
+		}
+		conds = append(conds, fmt.Sprintf("%s.%s %s ?", col.alias, col.col, c.Op))
+		cq.args = append(cq.args, c.Value)
+	}
+	var order []string
+	for _, o := range q.Order() {
+		col, ok := fields[strings.ToLower(o.Field)]
+		if !ok {
+			return compiledQuery{}, fmt.Errorf("OrderBy %q: field is not on a With component", o.Field)
+		}
+		key := col.alias + "." + col.col
+		if o.Descending {
+			key += " DESC"
+		}
+		order = append(order, key)
+	}
+	order = append(order, "e.id")
+
+	selectList := []string{"e.id", "e.entity_type"}
+	for _, col := range cq.cols {
+		selectList = append(selectList, col.alias+"."+col.col)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "SELECT %s FROM entities e", strings.Join(selectList, ", "))
+	for _, j := range joins {
+		b.WriteString(" " + j)
+	}
+	if len(conds) > 0 {
+		b.WriteString(" WHERE " + strings.Join(conds, " AND "))
+	}
+	b.WriteString(" ORDER BY " + strings.Join(order, ", "))
+	if q.MaxRows() > 0 {
+		fmt.Fprintf(&b, " LIMIT %d", q.MaxRows())
+	}
+	cq.sql = b.String()
+	return cq, nil
+}
+
+// queryComponentColumns resolves a With component to its canonical name
+// and the columns a query selects from its comp_* table. Object components
+// report "Component.property" fields; single-value components report the
+// component name; tags select nothing.
+func queryComponentColumns(
+	ctx context.Context,
+	query queryFunc,
+	s *schema.DatabaseSchema,
+	name string,
+) (string, []queryColumn, error) {
+	if err := validateIdentifier(strings.ToLower(name), "Query With"); err != nil {
+		return "", nil, err
+	}
+	if s == nil {
+		return introspectQueryColumns(ctx, query, name)
+	}
+	comp, canonical := schema.ComponentByName(s, name)
+	if canonical == "" {
+		return "", nil, fmt.Errorf("With %q: component is not declared", name)
+	}
+	switch comp.Type {
+	case schema.ComponentTypeObject:
+		props := make([]string, 0, len(comp.Properties))
+		for prop := range comp.Properties {
+			props = append(props, prop)
+		}
+		sort.Strings(props)
+		cols := make([]queryColumn, len(props))
+		for i, prop := range props {
+			cols[i] = queryColumn{col: strings.ToLower(prop), field: canonical + "." + prop, null: typedNil(comp.Properties[prop])}
+		}
+		return canonical, cols, nil
+	case schema.ComponentTypeEntityRef:
+		return canonical, []queryColumn{{col: "target_entity_id", field: canonical}}, nil
+	case schema.ComponentTypeTag:
+		return canonical, nil, nil
+	case schema.ComponentTypeRelation:
+		return "", nil, fmt.Errorf("With %q: relations are not stored in comp_* tables", name)
+	default:
+		return canonical, []queryColumn{{col: "value", field: canonical}}, nil
+	}
+}
+
+// introspectQueryColumns reads the columns of name's comp_* table for a
+// query without a schema, skipping entity_id. Fields are named in
+// lowercase, after the table and its columns.
+func introspectQueryColumns(ctx context.Context, query queryFunc, name string) (string, []queryColumn, error) {
+	table := "comp_" + strings.ToLower(name)
+	rows, err := query(ctx, fmt.Sprintf("PRAGMA table_xinfo(%s)", table))
+	if err != nil {
+		return "", nil, fmt.Errorf("With %q: reading columns of %s: %w", name, table, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var (
+		names []string
+		found bool
+	)
+	for rows.Next() {
+		found = true
+		var (
+			cid, notNull, pk, hidden int
+			colName, colType         string
+			dflt                     sql.NullString
+		)
+		if err := rows.Scan(&cid, &colName, &colType, &notNull, &dflt, &pk, &hidden); err != nil {
+			return "", nil, fmt.Errorf("With %q: scanning columns of %s: %w", name, table, err)
+		}
+		if hidden != hiddenVirtualTableColumn && colName != "entity_id" {
+			names = append(names, colName)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", nil, fmt.Errorf("With %q: reading columns of %s: %w", name, table, err)
+	}
+	if !found {
+		return "", nil, fmt.Errorf("With %q: no table %s", name, table)
+	}
+	lower := strings.ToLower(name)
+	if len(names) == 1 && (names[0] == "value" || names[0] == "target_entity_id") {
+		return lower, []queryColumn{{col: names[0], field: lower}}, nil
+	}
+	cols := make([]queryColumn, len(names))
+	for i, col := range names {
+		cols[i] = queryColumn{col: col, field: lower + "." + col}
+	}
+	return lower, cols, nil
+}
+
+// runQuery compiles q and runs it with query.
+func runQuery(ctx context.Context, query queryFunc, s *schema.DatabaseSchema, q Query) (agent.QueryRows, error) {
+	cq, err := compileQuery(ctx, query, s, q)
+	if err != nil {
+		return nil, fmt.Errorf("Query: %w", err)
+	}
+	rows, err := query(ctx, cq.sql, cq.args...)
+	if err != nil {
+		return nil, fmt.Errorf("Query: %w", err)
+	}
+	return &queryRows{rows: rows, cols: cq.cols}, nil
+}
+
+// queryRows implements agent.QueryRows over the *sql.Rows of a compiled
+// query.
+type queryRows struct {
+	rows *sql.Rows
+	cols []queryColumn
+	row  agent.QueryRow
+	err  error
+}
+
+func (r *queryRows) Next() bool {
+	if r.err != nil || !r.rows.Next() {
+		return false
+	}
+	var row agent.QueryRow
+	vals := make([]any, len(r.cols))
+	dest := make([]any, 0, 2+len(r.cols))
+	dest = append(dest, &row.EntityID, &row.EntityType)
+	for i := range vals {
+		dest = append(dest, &vals[i])
+	}
+	if err := r.rows.Scan(dest...); err != nil {
+		r.err = fmt.Errorf("Query: scanning row: %w", err)
+		return false
+	}
+	row.Values = make(map[string]any, len(r.cols))
+	for i, col := range r.cols {
+		if vals[i] == nil {
+			vals[i] = col.null
+		}
+		row.Values[col.field] = vals[i]
+	}
+	r.row = row
+	return true
+}
+
+func (r *queryRows) Row() agent.QueryRow { return r.row }
+
+func (r *queryRows) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	if err := r.rows.Err(); err != nil {
+		return fmt.Errorf("Query: %w", err)
+	}
+	return nil
+}
+
+func (r *queryRows) Close() error { return r.rows.Close() }
+
+// Query returns the entities matching q. The caller must Close the rows.
+func (s *SQLiteStore) Query(ctx context.Context, q Query) (agent.QueryRows, error) {
+	return runQuery(ctx, s.db.QueryContext, &s.schema, q)
+}
+
+// Query returns the entities matching q as seen by the transaction,
+// including its uncommitted writes. Without a schema, fields are named by
+// their lowercase columns.
+func (r *txWorldReader) Query(q agent.Query) (agent.QueryRows, error) {
+	return runQuery(context.Background(), r.tx.QueryContext, r.schema, q)
+}
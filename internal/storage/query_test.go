@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tmbritton/ecs-db/internal/agent"
+	"github.com/tmbritton/ecs-db/internal/schema"
+)
+
+func querySchema() schema.DatabaseSchema {
+	return schema.DatabaseSchema{
+		SchemaVersion: 1,
+		Components: map[string]schema.Component{
+			"Position": {Type: schema.ComponentTypeObject, Properties: map[string]schema.Property{
+				"x": {Type: schema.PropertyTypeNumber},
+				"y": {Type: schema.PropertyTypeNumber},
+			}},
+			"Health": {Type: schema.ComponentTypeObject, Properties: map[string]schema.Property{
+				"hp":    {Type: schema.PropertyTypeInteger},
+				"armor": {Type: schema.PropertyTypeInteger, Nullable: true},
+			}},
+			"Sprite": {Type: schema.ComponentTypeString},
+			"Dead":   {Type: schema.ComponentTypeTag},
+		},
+		EntityTypes: map[string]schema.EntityType{
+			"Goblin": {OptionalComponents: []string{"Position", "Health", "Sprite", "Dead"}, ValidationLevel: schema.ValidationStrict},
+		},
+	}
+}
+
+// openQueryStore returns a store holding five goblins:
+//
+//	1: Position(1,1) Health(hp 5)  Sprite "g"
+//	2: Position(2,2) Health(hp 20) Sprite "g"
+//	3: Position(3,3) Health(hp 8, armor 2) Sprite "G" Dead
+//	4: Position(4,4) Health(hp 3)
+//	5: Position(5,5) Health(hp 8)  Sprite "g"
+func openQueryStore(t *testing.T) *SQLiteStore {
+	t.Helper()
+	return openSeededStore(t, querySchema(),
+		"INSERT INTO entities (id, entity_type, created_tick) VALUES (1, 'Goblin', 0), (2, 'Goblin', 0), (3, 'Goblin', 0), (4, 'Goblin', 0), (5, 'Goblin', 0)",
+		"INSERT INTO comp_position (entity_id, x, y) VALUES (1, 1, 1), (2, 2, 2), (3, 3, 3), (4, 4, 4), (5, 5, 5)",
+		"INSERT INTO comp_health (entity_id, hp, armor) VALUES (1, 5, NULL), (2, 20, NULL), (3, 8, 2), (4, 3, NULL), (5, 8, NULL)",
+		"INSERT INTO comp_sprite (entity_id, value) VALUES (1, 'g'), (2, 'g'), (3, 'G'), (5, 'g')",
+		"INSERT INTO comp_dead (entity_id) VALUES (3)",
+	)
+}
+
+// collectRows drains rows, returning the matched rows.
+func collectRows(t *testing.T, rows agent.QueryRows, err error) []agent.QueryRow {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	defer func() { _ = rows.Close() }()
+	var got []agent.QueryRow
+	for rows.Next() {
+		got = append(got, rows.Row())
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("rows: %v", err)
+	}
+	return got
+}
+
+func rowIDs(rows []agent.QueryRow) []int64 {
+	ids := make([]int64, len(rows))
+	for i, row := range rows {
+		ids[i] = row.EntityID
+	}
+	return ids
+}
+
+func TestStoreQuery(t *testing.T) {
+	store := openQueryStore(t)
+	ctx := context.Background()
+	base := Query{}.With("Position", "Sprite").Without("Dead")
+
+	tests := []struct {
+		name string
+		q    Query
+		want []int64
+	}{
+		{"with and without", base, []int64{1, 2, 5}},
+		{"where", base.With("Health").Where("Health.hp", "<", 10), []int64{1, 5}},
+		{"where on single-value component", Query{}.With("Sprite").Where("Sprite", "=", "G"), []int64{3}},
+		{"order by desc then id", base.With("Health").OrderByDesc("health.HP"), []int64{2, 5, 1}},
+		{"order by and limit", Query{}.With("Health").OrderBy("Health.hp").Limit(2), []int64{4, 1}},
+		{"only without", Query{}.Without("Sprite"), []int64{4}},
+		{"duplicate with", Query{}.With("Sprite", "sprite").Without("Dead"), []int64{1, 2, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := store.Query(ctx, tt.q)
+			if got := rowIDs(collectRows(t, rows, err)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ids = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	rows, err := store.Query(ctx, Query{}.With("Position", "Health", "Sprite").Where("Health.hp", ">=", 8).Limit(1))
+	got := collectRows(t, rows, err)
+	if len(got) != 1 {
+		t.Fatalf("rows = %+v, want one", got)
+	}
+	row := got[0]
+	if row.EntityID != 2 || row.EntityType != "Goblin" {
+		t.Errorf("row = %d %q, want 2 Goblin", row.EntityID, row.EntityType)
+	}
+	if x, ok := row.Float("Position.x"); !ok || x != 2 {
+		t.Errorf("Position.x = %v, %v; want 2", x, ok)
+	}
+	if hp, ok := row.Int("health.hp"); !ok || hp != 20 {
+		t.Errorf("Health.hp = %v, %v; want 20", hp, ok)
+	}
+	if sprite, ok := row.String("Sprite"); !ok || sprite != "g" {
+		t.Errorf("Sprite = %q, %v; want g", sprite, ok)
+	}
+	if armor := row.Value("Health.armor"); armor != (*int64)(nil) {
+		t.Errorf("Health.armor = %#v, want (*int64)(nil) for NULL", armor)
+	}
+	if _, ok := row.Int("Health.armor"); ok {
+		t.Error("Int(NULL) ok = true, want false")
+	}
+}
+
+func TestStoreQuery_Errors(t *testing.T) {
+	store := openQueryStore(t)
+	tests := []struct {
+		name    string
+		q       Query
+		wantErr string
+	}{
+		{"undeclared with", Query{}.With("Mana"), `With "Mana": component is not declared`},
+		{"undeclared without", Query{}.With("Health").Without("Mana"), `Without "Mana": component is not declared`},
+		{"unsafe name", Query{}.With("Health; DROP TABLE entities"), "unsafe identifier"},
+		{"field not joined", Query{}.With("Health").Where("Position.x", "=", 1), `Where "Position.x": field is not on a With component`},
+		{"unknown property", Query{}.With("Health").OrderBy("Health.mp"), `OrderBy "Health.mp": field is not on a With component`},
+		{"bad operator", Query{}.With("Health").Where("Health.hp", "LIKE", 1), `unsupported operator "LIKE"`},
+		{"with and without", Query{}.With("Dead").Without("dead"), `component "dead" is in both With and Without`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := store.Query(context.Background(), tt.q)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTxWorldReader_Query(t *testing.T) {
+	store := openQueryStore(t)
+	tx, err := store.DB().Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Uncommitted writes in the same transaction are visible.
+	w := NewTxWorldWriterWithSchema(tx, querySchema())
+	if err := w.SetComponentValue(4, "Health", "hp", 1); err != nil {
+		t.Fatal(err)
+	}
+	q := Query{}.With("Health").Without("Sprite").OrderBy("Health.hp")
+
+	rows, err := NewTxWorldReaderWithSchema(tx, querySchema()).Query(q)
+	got := collectRows(t, rows, err)
+	if ids := rowIDs(got); !reflect.DeepEqual(ids, []int64{4}) {
+		t.Fatalf("ids = %v, want [4]", ids)
+	}
+	if hp, _ := got[0].Int("Health.hp"); hp != 1 {
+		t.Errorf("Health.hp = %d, want the uncommitted 1", hp)
+	}
+
+	// Without a schema, columns are read from the table and named by
+	// their lowercase column; NULL is an untyped nil.
+	rows, err = NewTxWorldReader(tx).Query(Query{}.With("Health", "Sprite").Where("health.armor", ">", 0))
+	got = collectRows(t, rows, err)
+	if len(got) != 1 || got[0].EntityID != 3 {
+		t.Fatalf("rows = %+v, want entity 3", got)
+	}
+	want := map[string]any{"health.armor": int64(2), "health.hp": int64(8), "sprite": "G"}
+	if !reflect.DeepEqual(got[0].Values, want) {
+		t.Errorf("Values = %#v, want %#v", got[0].Values, want)
+	}
+	if _, err := NewTxWorldReader(tx).Query(Query{}.With("Mana")); err == nil || !strings.Contains(err.Error(), "no table comp_mana") {
+		t.Errorf("err = %v, want missing table", err)
+	}
+}
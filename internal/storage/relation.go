@@ -0,0 +1,217 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tmbritton/ecs-db/internal/schema"
+	"github.com/tmbritton/ecs-db/internal/world"
+	"modernc.org/sqlite"
+	sqliteLib "modernc.org/sqlite/lib"
+)
+
+// Relation rows live in rel_<lowercase(name)>, keyed by (source_id,
+// target_id). The helpers below are shared by the world.Tx adapter
+// (sqliteTx), the store's read queries, and the agent world adapters.
+
+// insertRelation inserts the row linking source to target with the given
+// property values. Values are written under their lowercase names; omitted
+// properties take their column default. A duplicate pair is reported as
+// world.ErrRelationExists.
+func insertRelation(ctx context.Context, tx *sql.Tx, relName string, sourceID, targetID int64, values map[string]any) error {
+	table := "rel_" + strings.ToLower(relName)
+	if err := validateIdentifier(strings.TrimPrefix(table, "rel_"), "relation name"); err != nil {
+		return err
+	}
+	fields := make([]string, 0, len(values))
+	for field := range values {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	cols := []string{"source_id", "target_id"}
+	args := []any{sourceID, targetID}
+	for _, field := range fields {
+		col := strings.ToLower(field)
+		if err := validateIdentifier(col, "relation field"); err != nil {
+			return err
+		}
+		cols = append(cols, col)
+		args = append(args, values[field])
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (?%s)",
+		table, strings.Join(cols, ", "), strings.Repeat(", ?", len(cols)-1))
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		var sqliteErr *sqlite.Error
+		if errors.As(err, &sqliteErr) && (sqliteErr.Code() == sqliteLib.SQLITE_CONSTRAINT_PRIMARYKEY ||
+			sqliteErr.Code() == sqliteLib.SQLITE_CONSTRAINT_UNIQUE) {
+			return world.ErrRelationExists
+		}
+		return fmt.Errorf("inserting into %s: %w", table, err)
+	}
+	return nil
+}
+
+// deleteRelation deletes the row linking source to target and reports
+// whether there was one.
+func deleteRelation(ctx context.Context, tx *sql.Tx, relName string, sourceID, targetID int64) (bool, error) {
+	table := "rel_" + strings.ToLower(relName)
+	if err := validateIdentifier(strings.TrimPrefix(table, "rel_"), "relation name"); err != nil {
+		return false, err
+	}
+	res, err := tx.ExecContext(ctx,
+		fmt.Sprintf("DELETE FROM %s WHERE source_id = ? AND target_id = ?", table), sourceID, targetID)
+	if err != nil {
+		return false, fmt.Errorf("deleting from %s: %w", table, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("checking rows affected on delete from %s: %w", table, err)
+	}
+	return n > 0, nil
+}
+
+// relatedIDs runs a relation lookup that returns one id column. keyCol is
+// the column matched against id and idCol the one returned: targets of a
+// source are (source_id, target_id), sources of a target the reverse.
+func relatedIDs(
+	ctx context.Context,
+	query func(ctx context.Context, query string, args ...any) (*sql.Rows, error),
+	relName, keyCol, idCol string,
+	id int64,
+) ([]int64, error) {
+	table := "rel_" + strings.ToLower(relName)
+	if err := validateIdentifier(strings.TrimPrefix(table, "rel_"), "relation name"); err != nil {
+		return nil, err
+	}
+	rows, err := query(ctx, fmt.Sprintf("SELECT %s FROM %s WHERE %s = ? ORDER BY %s", idCol, table, keyCol, idCol), id)
+	if err != nil {
+		return nil, fmt.Errorf("querying %s: %w", table, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	ids := make([]int64, 0)
+	for rows.Next() {
+		var related int64
+		if err := rows.Scan(&related); err != nil {
+			return nil, fmt.Errorf("scanning %s: %w", table, err)
+		}
+		ids = append(ids, related)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating %s: %w", table, err)
+	}
+	return ids, nil
+}
+
+// ── world.Tx / world.EntityStore ────────────────────────────────────
+
+func (t *sqliteTx) AddRelation(
+	ctx context.Context,
+	sourceID int64,
+	relName string,
+	targetID int64,
+	values map[string]interface{},
+) error {
+	if _, canonical := schema.RelationByName(&t.schema, relName); canonical == "" {
+		return fmt.Errorf("relation %q not declared in schema", relName)
+	}
+	return insertRelation(ctx, t.tx, relName, sourceID, targetID, values)
+}
+
+func (t *sqliteTx) RemoveRelation(ctx context.Context, sourceID int64, relName string, targetID int64) error {
+	if _, canonical := schema.RelationByName(&t.schema, relName); canonical == "" {
+		return fmt.Errorf("relation %q not declared in schema", relName)
+	}
+	found, err := deleteRelation(ctx, t.tx, relName, sourceID, targetID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("entity %d has no %s relation to entity %d", sourceID, relName, targetID)
+	}
+	return nil
+}
+
+// RelatedEntities returns the targets the entity relates to through the
+// named relation, in ascending id order.
+// Implements world.EntityStore.
+func (s *SQLiteStore) RelatedEntities(ctx context.Context, entityID int64, relName string) ([]int64, error) {
+	return relatedIDs(ctx, s.db.QueryContext, relName, "source_id", "target_id", entityID)
+}
+
+// EntitiesRelatedTo returns the sources that relate to the target through
+// the named relation, in ascending id order. The lookup uses the
+// relation's target_id index.
+// Implements world.EntityStore.
+func (s *SQLiteStore) EntitiesRelatedTo(ctx context.Context, relName string, targetID int64) ([]int64, error) {
+	return relatedIDs(ctx, s.db.QueryContext, relName, "target_id", "source_id", targetID)
+}
+
+// ── agent.WorldWriter / agent.WorldReader ───────────────────────────
+
+// AddRelation links source to target through the named relation. With a
+// schema, the relation must be declared, the target must satisfy its
+// refType, omitted properties are filled from their defaults and values are
+// checked against property constraints.
+func (w *txWorldWriter) AddRelation(sourceID int64, relName string, targetID int64, values map[string]any) error {
+	if w.schema != nil {
+		rel, canonical := schema.RelationByName(w.schema, relName)
+		if canonical == "" {
+			return fmt.Errorf("AddRelation %q: relation is not declared", relName)
+		}
+		if rel.RefType != "" {
+			targetType, err := readEntityType(context.Background(), w.tx.QueryRowContext, targetID)
+			if err != nil {
+				return fmt.Errorf("AddRelation %q: %w", relName, err)
+			}
+			if !schema.IsA(w.schema, targetType, rel.RefType) {
+				return fmt.Errorf("AddRelation %q: %w", relName, &world.RelationError{
+					Action:   "add",
+					Relation: canonical,
+					SourceID: sourceID,
+					TargetID: targetID,
+					Errors: []string{fmt.Sprintf("target entity %d has type %q, want %q",
+						targetID, targetType, rel.RefType)},
+				})
+			}
+		}
+		values = rel.WithDefaults(values)
+		if err := w.checkValues(canonical, values); err != nil {
+			return fmt.Errorf("AddRelation %q: %w", relName, err)
+		}
+	}
+	if err := insertRelation(context.Background(), w.tx, relName, sourceID, targetID, values); err != nil {
+		return fmt.Errorf("AddRelation %q: %w", relName, err)
+	}
+	return nil
+}
+
+// RemoveRelation unlinks source from target. Removing a link that does not
+// exist is not an error, as with DetachComponent.
+func (w *txWorldWriter) RemoveRelation(sourceID int64, relName string, targetID int64) error {
+	if _, err := deleteRelation(context.Background(), w.tx, relName, sourceID, targetID); err != nil {
+		return fmt.Errorf("RemoveRelation %q: %w", relName, err)
+	}
+	return nil
+}
+
+func (r *txWorldReader) RelatedEntities(entityID int64, relName string) ([]int64, error) {
+	ids, err := relatedIDs(context.Background(), r.tx.QueryContext, relName, "source_id", "target_id", entityID)
+	if err != nil {
+		return nil, fmt.Errorf("RelatedEntities %q: %w", relName, err)
+	}
+	return ids, nil
+}
+
+func (r *txWorldReader) EntitiesRelatedTo(relName string, targetID int64) ([]int64, error) {
+	ids, err := relatedIDs(context.Background(), r.tx.QueryContext, relName, "target_id", "source_id", targetID)
+	if err != nil {
+		return nil, fmt.Errorf("EntitiesRelatedTo %q: %w", relName, err)
+	}
+	return ids, nil
+}
@@ -0,0 +1,298 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tmbritton/ecs-db/internal/schema"
+	"github.com/tmbritton/ecs-db/internal/world"
+)
+
+func relationSchema(version int, relations map[string]schema.Component) schema.DatabaseSchema {
+	s := schema.DatabaseSchema{
+		SchemaVersion: version,
+		Components: map[string]schema.Component{
+			"Health": {Type: schema.ComponentTypeInteger},
+		},
+		EntityTypes: map[string]schema.EntityType{
+			"Node": {OptionalComponents: []string{"Health"}, ValidationLevel: schema.ValidationStrict},
+			"Rock": {ValidationLevel: schema.ValidationStrict},
+		},
+	}
+	for name, rel := range relations {
+		s.Components[name] = rel
+	}
+	return s
+}
+
+func childOfRelation() schema.Component {
+	minSlot := 0.0
+	return schema.Component{Type: schema.ComponentTypeRelation, RefType: "Node", Properties: map[string]schema.Property{
+		"slot": {Type: schema.PropertyTypeInteger, Default: float64(0), Minimum: &minSlot},
+	}}
+}
+
+func TestBootstrap_CreatesRelationTable(t *testing.T) {
+	store, err := NewSQLiteStore(t.TempDir()+"/world.sqlite",
+		relationSchema(1, map[string]schema.Component{"ChildOf": childOfRelation()}), "")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	db := store.DB()
+
+	cols, err := IntrospectComponentTable(db, "rel_childof")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cols) != 3 || cols[0].Name != "source_id" || !cols[0].IsPK || cols[1].Name != "target_id" || !cols[1].IsPK {
+		t.Errorf("rel_childof columns = %+v, want source_id and target_id as the key, then slot", cols)
+	}
+	if tableExists(t, db, "comp_childof") {
+		t.Error("a relation must not get a comp_ table")
+	}
+	var idx int
+	if err := db.QueryRow(
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name = 'idx_rel_childof_target'").Scan(&idx); err != nil || idx != 1 {
+		t.Errorf("target index count = %d, %v; want 1", idx, err)
+	}
+
+	// Deleting either end deletes the row.
+	for _, stmt := range []string{
+		"INSERT INTO entities (id, entity_type, created_tick) VALUES (1, 'Node', 0), (2, 'Node', 0), (3, 'Node', 0)",
+		"INSERT INTO rel_childof (source_id, target_id) VALUES (2, 1), (3, 2)",
+		"DELETE FROM entities WHERE id = 1",
+		"DELETE FROM entities WHERE id = 3",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM rel_childof").Scan(&n); err != nil || n != 0 {
+		t.Errorf("rel_childof has %d rows after deleting both ends, want 0 (%v)", n, err)
+	}
+}
+
+func TestRelationAdapter(t *testing.T) {
+	s := relationSchema(1, map[string]schema.Component{"ChildOf": childOfRelation()})
+	store, err := NewSQLiteStore(t.TempDir()+"/world.sqlite", s, "")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	if _, err := store.DB().Exec(
+		"INSERT INTO entities (id, entity_type, created_tick) VALUES (1, 'Node', 0), (2, 'Node', 0), (3, 'Node', 0), (4, 'Rock', 0)"); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := store.DB().Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	w := NewTxWorldWriterWithSchema(tx, s)
+	r := NewTxWorldReaderWithSchema(tx, s)
+
+	for _, child := range []int64{3, 2} {
+		if err := w.AddRelation(child, "ChildOf", 1, nil); err != nil {
+			t.Fatalf("AddRelation(%d): %v", child, err)
+		}
+	}
+	if err := w.AddRelation(3, "childof", 2, map[string]any{"slot": 4}); err != nil {
+		t.Fatalf("AddRelation(3 → 2): %v", err)
+	}
+
+	if got, err := r.EntitiesRelatedTo("ChildOf", 1); err != nil || !reflect.DeepEqual(got, []int64{2, 3}) {
+		t.Errorf("EntitiesRelatedTo(ChildOf, 1) = %v, %v; want [2 3]", got, err)
+	}
+	if got, err := r.RelatedEntities(3, "ChildOf"); err != nil || !reflect.DeepEqual(got, []int64{1, 2}) {
+		t.Errorf("RelatedEntities(3, ChildOf) = %v, %v; want [1 2]", got, err)
+	}
+	if got, err := r.EntitiesRelatedTo("ChildOf", 3); err != nil || len(got) != 0 {
+		t.Errorf("EntitiesRelatedTo(ChildOf, 3) = %v, %v; want none", got, err)
+	}
+	var slot int64
+	if err := tx.QueryRow("SELECT slot FROM rel_childof WHERE source_id = 3 AND target_id = 2").Scan(&slot); err != nil || slot != 4 {
+		t.Errorf("slot = %d, %v; want 4", slot, err)
+	}
+
+	if err := w.AddRelation(2, "ChildOf", 1, nil); !errors.Is(err, world.ErrRelationExists) {
+		t.Errorf("duplicate AddRelation err = %v, want ErrRelationExists", err)
+	}
+	if err := w.AddRelation(2, "Health", 1, nil); err == nil || !strings.Contains(err.Error(), "relation is not declared") {
+		t.Errorf("AddRelation on a component err = %v, want relation is not declared", err)
+	}
+	if err := w.AddRelation(2, "ChildOf", 3, map[string]any{"slot": -1}); err == nil ||
+		!strings.Contains(err.Error(), "less than minimum 0") {
+		t.Errorf("AddRelation with a negative slot err = %v, want a constraint error", err)
+	}
+	var relErr *world.RelationError
+	if err := w.AddRelation(2, "ChildOf", 4, nil); !errors.As(err, &relErr) ||
+		!strings.Contains(err.Error(), `has type "Rock", want "Node"`) {
+		t.Errorf("AddRelation to a Rock err = %v, want a RelationError for the refType", err)
+	}
+	if err := w.AddRelation(2, "ChildOf", 99, nil); !errors.As(err, new(*world.EntityNotFoundError)) {
+		t.Errorf("AddRelation to a missing entity err = %v, want EntityNotFoundError", err)
+	}
+
+	if err := w.RemoveRelation(3, "ChildOf", 1); err != nil {
+		t.Fatalf("RemoveRelation: %v", err)
+	}
+	if err := w.RemoveRelation(3, "ChildOf", 1); err != nil {
+		t.Errorf("removing a missing relation err = %v, want nil", err)
+	}
+	if got, _ := r.EntitiesRelatedTo("ChildOf", 1); !reflect.DeepEqual(got, []int64{2}) {
+		t.Errorf("EntitiesRelatedTo(ChildOf, 1) after remove = %v, want [2]", got)
+	}
+}
+
+func TestEntityService_Relations(t *testing.T) {
+	s := relationSchema(1, map[string]schema.Component{"ChildOf": childOfRelation()})
+	store, err := NewSQLiteStore(t.TempDir()+"/world.sqlite", s, "")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	svc := world.NewEntityService(store)
+	svc.SetSchema(s)
+	ctx := context.Background()
+
+	parent, err := svc.CreateEntity(ctx, "Node", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	child, err := svc.CreateEntity(ctx, "Node", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rock, err := svc.CreateEntity(ctx, "Rock", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := svc.AddRelation(ctx, child.ID, "ChildOf", parent.ID, nil); err != nil {
+		t.Fatalf("AddRelation: %v", err)
+	}
+	if err := svc.AddRelation(ctx, child.ID, "ChildOf", parent.ID, nil); !world.IsRelationExists(err) {
+		t.Errorf("duplicate AddRelation err = %v, want ErrRelationExists", err)
+	}
+	var relErr *world.RelationError
+	if err := svc.AddRelation(ctx, child.ID, "ChildOf", rock.ID, nil); !errors.As(err, &relErr) ||
+		!strings.Contains(err.Error(), `has type "Rock", want "Node"`) {
+		t.Errorf("AddRelation to a Rock err = %v, want a refType RelationError", err)
+	}
+	if _, err := svc.CreateEntity(ctx, "Node", []world.EntityComponent{{Name: "ChildOf"}}); err == nil ||
+		!strings.Contains(err.Error(), "is a relation") {
+		t.Errorf("CreateEntity with a relation err = %v, want it refused", err)
+	}
+
+	if got, err := svc.EntitiesRelatedTo(ctx, "ChildOf", parent.ID); err != nil || !reflect.DeepEqual(got, []int64{child.ID}) {
+		t.Errorf("EntitiesRelatedTo = %v, %v; want [%d]", got, err, child.ID)
+	}
+	if err := svc.RemoveRelation(ctx, child.ID, "ChildOf", parent.ID); err != nil {
+		t.Fatalf("RemoveRelation: %v", err)
+	}
+	if err := svc.RemoveRelation(ctx, child.ID, "ChildOf", parent.ID); err == nil {
+		t.Error("removing a missing relation through the service succeeded, want an error")
+	}
+	if got, err := svc.RelatedEntities(ctx, child.ID, "ChildOf"); err != nil || len(got) != 0 {
+		t.Errorf("RelatedEntities after remove = %v, %v; want none", got, err)
+	}
+}
+
+func TestSmoke_Relations_Migrate(t *testing.T) {
+	path := t.TempDir() + "/smoke.sqlite"
+
+	s1 := relationSchema(1, map[string]schema.Component{
+		"ChildOf": childOfRelation(),
+		"Follows": {Type: schema.ComponentTypeRelation},
+	})
+	store1, err := NewSQLiteStore(path, s1, "")
+	if err != nil {
+		t.Fatalf("v1 open: %v", err)
+	}
+	for _, stmt := range []string{
+		"INSERT INTO entities (id, entity_type, created_tick) VALUES (1, 'Node', 0), (2, 'Node', 0), (3, 'Rock', 0)",
+		"INSERT INTO rel_childof (source_id, target_id, slot) VALUES (2, 1, 7)",
+	} {
+		if _, err := store1.DB().Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	_ = store1.Close()
+
+	// v2: ChildOf changes slot to REAL (a rebuild) and gains a property,
+	// Follows is removed and Likes is added.
+	childOf := childOfRelation()
+	childOf.Properties["slot"] = schema.Property{Type: schema.PropertyTypeNumber, Default: float64(0)}
+	childOf.Properties["rank"] = schema.Property{Type: schema.PropertyTypeInteger, Default: float64(1)}
+	s2 := relationSchema(2, map[string]schema.Component{
+		"ChildOf": childOf,
+		"Likes":   {Type: schema.ComponentTypeRelation},
+	})
+	store2, err := NewSQLiteStore(path, s2, "")
+	if err != nil {
+		t.Fatalf("v2 open (migration): %v", err)
+	}
+	t.Cleanup(func() { _ = store2.Close() })
+	db2 := store2.DB()
+
+	var (
+		slot float64
+		rank int64
+	)
+	if err := db2.QueryRow("SELECT slot, rank FROM rel_childof WHERE source_id = 2 AND target_id = 1").
+		Scan(&slot, &rank); err != nil {
+		t.Fatalf("reading rel_childof: %v", err)
+	}
+	if slot != 7 || rank != 1 {
+		t.Errorf("rel_childof = (%v, %d), want (7, 1)", slot, rank)
+	}
+	if _, err := db2.Exec("INSERT INTO rel_childof (source_id, target_id) VALUES (2, 1)"); err == nil {
+		t.Error("duplicate pair accepted after rebuild, want the composite key kept")
+	}
+	var idx int
+	if err := db2.QueryRow(
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name = 'idx_rel_childof_target'").Scan(&idx); err != nil || idx != 1 {
+		t.Errorf("target index count after rebuild = %d, %v; want 1", idx, err)
+	}
+	if !tableExists(t, db2, "rel_likes") || tableExists(t, db2, "rel_follows") {
+		t.Error("want rel_likes created and rel_follows dropped")
+	}
+
+	domain, err := IntrospectAll(db2)
+	if err != nil {
+		t.Fatalf("IntrospectAll: %v", err)
+	}
+	if changes := schema.Diff(domain.ToDiffSchema(), &s2, nil); len(changes) != 0 {
+		t.Errorf("Diff after migration = %+v, want none", changes)
+	}
+}
+
+func TestGenerate_RelationStatements(t *testing.T) {
+	file := relationSchema(2, map[string]schema.Component{"ChildOf": childOfRelation()})
+	stmts := NewGenerator(&file, nil, Config{StrictDrop: true}).Generate([]schema.Change{
+		{Kind: schema.ChangeAddedRelation, Relation: "childof"},
+		{Kind: schema.ChangeRemovedRelation, Relation: "follows"},
+	})
+	if len(stmts) != 3 {
+		t.Fatalf("got %d statements, want 3: %+v", len(stmts), stmts)
+	}
+	assertContainsDDL(t, stmts[0].SQL, "CREATE TABLE rel_childof")
+	assertContainsDDL(t, stmts[0].SQL, "source_id INTEGER NOT NULL REFERENCES entities(id) ON DELETE CASCADE")
+	assertContainsDDL(t, stmts[0].SQL, "target_id INTEGER NOT NULL REFERENCES entities(id) ON DELETE CASCADE")
+	assertContainsDDL(t, stmts[0].SQL, "PRIMARY KEY (source_id, target_id)")
+	assertContainsDDL(t, stmts[1].SQL, "CREATE INDEX IF NOT EXISTS idx_rel_childof_target ON rel_childof(target_id)")
+	if stmts[2].SQL != "DROP TABLE IF EXISTS rel_follows" || !stmts[2].Destructive {
+		t.Errorf("stmts[2] = %+v, want a destructive DROP of rel_follows", stmts[2])
+	}
+	for _, s := range stmts {
+		if s.Component != "" || s.Relation == "" {
+			t.Errorf("statement %q: Component = %q, Relation = %q", s.SQL, s.Component, s.Relation)
+		}
+	}
+}
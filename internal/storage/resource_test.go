@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tmbritton/ecs-db/internal/schema"
+)
+
+func resourceSchema(version int, resources map[string]schema.Resource) schema.DatabaseSchema {
+	return schema.DatabaseSchema{
+		SchemaVersion: version,
+		Components: map[string]schema.Component{
+			"Health": {Type: schema.ComponentTypeInteger},
+		},
+		EntityTypes: map[string]schema.EntityType{},
+		Resources:   resources,
+	}
+}
+
+func clockResource() schema.Resource {
+	return schema.Resource{Properties: map[string]schema.Property{
+		"tick":    {Type: schema.PropertyTypeInteger, Default: float64(0)},
+		"isNight": {Type: schema.PropertyTypeBoolean, Default: false},
+		"label":   {Type: schema.PropertyTypeString, Nullable: true},
+	}}
+}
+
+func TestBootstrap_CreatesResourceRow(t *testing.T) {
+	store, err := NewSQLiteStore(t.TempDir()+"/world.sqlite",
+		resourceSchema(1, map[string]schema.Resource{"Clock": clockResource()}), "")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	var (
+		id, tick, isNight int64
+		label             any
+	)
+	if err := store.DB().QueryRow("SELECT id, tick, isnight, label FROM res_clock").
+		Scan(&id, &tick, &isNight, &label); err != nil {
+		t.Fatalf("reading res_clock: %v", err)
+	}
+	if id != 1 || tick != 0 || isNight != 0 || label != nil {
+		t.Errorf("row = (%d, %d, %d, %v), want (1, 0, 0, <nil>)", id, tick, isNight, label)
+	}
+	if _, err := store.DB().Exec("INSERT INTO res_clock (id) VALUES (2)"); err == nil {
+		t.Error("inserting a second row succeeded, want the CHECK to reject it")
+	}
+}
+
+func TestResourceAdapter_GetSet(t *testing.T) {
+	s := resourceSchema(1, map[string]schema.Resource{"Clock": clockResource()})
+	minHour, maxHour := 0.0, 23.0
+	s.Resources["Clock"].Properties["hour"] = schema.Property{
+		Type: schema.PropertyTypeInteger, Default: float64(0), Minimum: &minHour, Maximum: &maxHour,
+	}
+	store, err := NewSQLiteStore(t.TempDir()+"/world.sqlite", s, "")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+
+	tx, err := store.DB().Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	r := NewTxWorldReaderWithSchema(tx, s)
+	w := NewTxWorldWriterWithSchema(tx, s)
+
+	if err := w.SetResource("Clock", "isNight", true); err != nil {
+		t.Fatalf("SetResource: %v", err)
+	}
+	if v, err := r.GetResource("Clock", "isNight"); err != nil || v != int64(1) {
+		t.Errorf("GetResource(isNight) = %v, %v; want 1", v, err)
+	}
+	if v, err := r.GetResource("clock", "label"); err != nil || v != (*string)(nil) {
+		t.Errorf("GetResource(label) = %#v, %v; want (*string)(nil)", v, err)
+	}
+
+	for _, tt := range []struct {
+		name, field string
+		value       any
+		wantErr     string
+	}{
+		{"Weather", "rain", 1, `resource is not declared`},
+		{"Clock", "season", "spring", `property is not declared`},
+		{"Clock", "hour", 24, "greater than maximum 23"},
+		{"Clock; DROP TABLE entities", "tick", 1, "unsafe identifier"},
+	} {
+		err := w.SetResource(tt.name, tt.field, tt.value)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("SetResource(%q, %q) err = %v, want containing %q", tt.name, tt.field, err, tt.wantErr)
+		}
+	}
+
+	if _, err := NewTxWorldReader(tx).GetResource("Weather", "rain"); err == nil {
+		t.Error("GetResource on a missing table succeeded, want an error")
+	}
+}
+
+func TestSmoke_Resources_Migrate(t *testing.T) {
+	path := t.TempDir() + "/smoke.sqlite"
+
+	s1 := resourceSchema(1, map[string]schema.Resource{
+		"Clock": clockResource(),
+		"Weather": {Properties: map[string]schema.Property{
+			"rain": {Type: schema.PropertyTypeNumber, Default: 0.5},
+		}},
+	})
+	store1, err := NewSQLiteStore(path, s1, "")
+	if err != nil {
+		t.Fatalf("v1 open: %v", err)
+	}
+	if _, err := store1.DB().Exec("UPDATE res_clock SET tick = 42, label = 'dusk'"); err != nil {
+		t.Fatalf("seeding res_clock: %v", err)
+	}
+	_ = store1.Close()
+
+	// v2: Clock gains a property and changes tick to REAL (a rebuild),
+	// Weather is removed and Economy is added.
+	clock := clockResource()
+	clock.Properties["tick"] = schema.Property{Type: schema.PropertyTypeNumber, Default: float64(0)}
+	clock.Properties["day"] = schema.Property{Type: schema.PropertyTypeInteger, Default: float64(1)}
+	s2 := resourceSchema(2, map[string]schema.Resource{
+		"Clock": clock,
+		"Economy": {Properties: map[string]schema.Property{
+			"gold": {Type: schema.PropertyTypeInteger, Default: float64(100)},
+		}},
+	})
+	store2, err := NewSQLiteStore(path, s2, "")
+	if err != nil {
+		t.Fatalf("v2 open (migration): %v", err)
+	}
+	t.Cleanup(func() { _ = store2.Close() })
+	db2 := store2.DB()
+
+	var (
+		tick  float64
+		day   int64
+		label string
+	)
+	if err := db2.QueryRow("SELECT tick, day, label FROM res_clock WHERE id = 1").Scan(&tick, &day, &label); err != nil {
+		t.Fatalf("reading res_clock: %v", err)
+	}
+	if tick != 42 || day != 1 || label != "dusk" {
+		t.Errorf("res_clock = (%v, %d, %q), want (42, 1, dusk)", tick, day, label)
+	}
+	var gold int64
+	if err := db2.QueryRow("SELECT gold FROM res_economy WHERE id = 1").Scan(&gold); err != nil || gold != 100 {
+		t.Errorf("res_economy gold = %d, %v; want 100", gold, err)
+	}
+	if tableExists(t, db2, "res_weather") {
+		t.Error("res_weather not dropped")
+	}
+
+	domain, err := IntrospectAll(db2)
+	if err != nil {
+		t.Fatalf("IntrospectAll: %v", err)
+	}
+	if changes := schema.Diff(domain.ToDiffSchema(), &s2, nil); len(changes) != 0 {
+		t.Errorf("Diff after migration = %+v, want none", changes)
+	}
+}
+
+func TestGenerate_ResourceStatements(t *testing.T) {
+	file := resourceSchema(2, map[string]schema.Resource{"Clock": clockResource()})
+	stmts := NewGenerator(&file, nil, Config{StrictDrop: true}).Generate([]schema.Change{
+		{Kind: schema.ChangeAddedResource, Resource: "clock"},
+		{Kind: schema.ChangeRemovedResource, Resource: "weather"},
+	})
+	if len(stmts) != 3 {
+		t.Fatalf("got %d statements, want 3: %+v", len(stmts), stmts)
+	}
+	assertContainsDDL(t, stmts[0].SQL, "CREATE TABLE res_clock")
+	assertContainsDDL(t, stmts[0].SQL, "id INTEGER PRIMARY KEY CHECK (id = 1)")
+	assertContainsDDL(t, stmts[0].SQL, "isnight INTEGER NOT NULL DEFAULT 0")
+	assertContainsDDL(t, stmts[1].SQL, "INSERT OR IGNORE INTO res_clock (id) VALUES (1)")
+	if stmts[2].SQL != "DROP TABLE IF EXISTS res_weather" || !stmts[2].Destructive {
+		t.Errorf("stmts[2] = %+v, want a destructive DROP of res_weather", stmts[2])
+	}
+	for _, s := range stmts {
+		if s.Component != "" || s.Resource == "" {
+			t.Errorf("statement %q: Component = %q, Resource = %q", s.SQL, s.Component, s.Resource)
+		}
+	}
+}
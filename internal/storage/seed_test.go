@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/tmbritton/ecs-db/internal/schema"
+)
+
+// openSeededStore opens a store for s in a temporary directory, closed when
+// the test ends, and runs stmts against it in order.
+func openSeededStore(t *testing.T, s schema.DatabaseSchema, stmts ...string) *SQLiteStore {
+	t.Helper()
+	store, err := NewSQLiteStore(t.TempDir()+"/world.sqlite", s, "")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	for _, stmt := range stmts {
+		if _, err := store.DB().Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	return store
+}
+
+// countRows returns the count selected by query, a SELECT COUNT(*).
+func countRows(t *testing.T, store *SQLiteStore, query string, args ...any) int {
+	t.Helper()
+	var n int
+	if err := store.DB().QueryRow(query, args...).Scan(&n); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	return n
+}
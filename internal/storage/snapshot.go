@@ -0,0 +1,233 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tmbritton/ecs-db/internal/schema"
+	"github.com/tmbritton/ecs-db/internal/world"
+)
+
+// GetEntity returns the entity with the values of every attached component
+// and the states of its behavior machines. Components are read one comp_*
+// table at a time, in component-name order.
+// Implements world.EntityStore.
+func (s *SQLiteStore) GetEntity(ctx context.Context, entityID int64) (*world.EntitySnapshot, error) {
+	snap := &world.EntitySnapshot{Components: map[string]map[string]interface{}{}}
+	err := s.db.QueryRowContext(ctx,
+		"SELECT id, entity_type, created_tick FROM entities WHERE id = ?", entityID,
+	).Scan(&snap.ID, &snap.EntityType, &snap.CreatedTick)
+	if err == sql.ErrNoRows {
+		return nil, &world.EntityNotFoundError{ID: entityID}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading entity %d: %w", entityID, err)
+	}
+
+	names := make([]string, 0, len(s.schema.Components))
+	for name, comp := range s.schema.Components {
+		if comp.Type != schema.ComponentTypeRelation {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		values, ok, err := s.readComponent(ctx, entityID, name, s.schema.Components[name])
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			snap.Components[name] = values
+		}
+	}
+
+	machines, err := s.machineStates(ctx, entityID)
+	if err != nil {
+		return nil, err
+	}
+	snap.Machines = machines
+	return snap, nil
+}
+
+// snapshotColumn is one column GetEntity reads from a comp_* table: the
+// key its value is reported under and the property that decodes it.
+type snapshotColumn struct {
+	col  string
+	key  string
+	prop schema.Property
+}
+
+// snapshotColumns returns the columns of comp's table in the shape
+// EntitySnapshot.Components reports them.
+func snapshotColumns(comp schema.Component) []snapshotColumn {
+	switch comp.Type {
+	case schema.ComponentTypeObject:
+		props := make([]string, 0, len(comp.Properties))
+		for name := range comp.Properties {
+			props = append(props, name)
+		}
+		sort.Strings(props)
+		cols := make([]snapshotColumn, len(props))
+		for i, name := range props {
+			cols[i] = snapshotColumn{col: strings.ToLower(name), key: name, prop: comp.Properties[name]}
+		}
+		return cols
+	case schema.ComponentTypeEntityRef:
+		return []snapshotColumn{{col: "target_entity_id", key: "target_entity_id",
+			prop: schema.Property{Type: schema.PropertyTypeEntityRef}}}
+	case schema.ComponentTypeTag:
+		return nil
+	default:
+		// string, integer, number and boolean share their property type's
+		// name; array components hold a JSON array like array properties.
+		return []snapshotColumn{{col: "value", key: "value", prop: schema.Property{Type: comp.Type}}}
+	}
+}
+
+// readComponent reads the entity's row of the named component and reports
+// whether it has one.
+func (s *SQLiteStore) readComponent(
+	ctx context.Context,
+	entityID int64,
+	name string,
+	comp schema.Component,
+) (map[string]interface{}, bool, error) {
+	table := "comp_" + strings.ToLower(name)
+	if err := validateIdentifier(strings.TrimPrefix(table, "comp_"), "GetEntity component"); err != nil {
+		return nil, false, err
+	}
+	cols := snapshotColumns(comp)
+	selectList := []string{"entity_id"}
+	for _, c := range cols {
+		selectList = append(selectList, c.col)
+	}
+	var id int64
+	vals := make([]interface{}, len(cols))
+	dest := []interface{}{&id}
+	for i := range vals {
+		dest = append(dest, &vals[i])
+	}
+	err := s.db.QueryRowContext(ctx,
+		fmt.Sprintf("SELECT %s FROM %s WHERE entity_id = ?", strings.Join(selectList, ", "), table), entityID,
+	).Scan(dest...)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("reading %s for entity %d: %w", table, entityID, err)
+	}
+	values := make(map[string]interface{}, len(cols))
+	for i, c := range cols {
+		values[c.key] = decodeColumnValue(c.prop, vals[i])
+	}
+	return values, true, nil
+}
+
+// decodeColumnValue converts a value read from a column of prop's type to
+// the value CreateEntity would have been given: booleans become bool and
+// JSON text in object and array columns is unmarshalled. Text that is not
+// valid JSON is returned as the string it is.
+func decodeColumnValue(prop schema.Property, v interface{}) interface{} {
+	switch prop.Type {
+	case schema.PropertyTypeBoolean:
+		if n, ok := v.(int64); ok {
+			return n != 0
+		}
+	case schema.PropertyTypeObject, schema.PropertyTypeArray:
+		if text, ok := v.(string); ok {
+			var decoded interface{}
+			if err := json.Unmarshal([]byte(text), &decoded); err == nil {
+				return decoded
+			}
+		}
+	}
+	return v
+}
+
+// machineStates returns the entity's rows in behavior_components, ordered
+// by machine id.
+func (s *SQLiteStore) machineStates(ctx context.Context, entityID int64) ([]world.MachineState, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT machine_id, current_states, updated_at FROM behavior_components
+		 WHERE entity_id = ? ORDER BY machine_id`, entityID)
+	if err != nil {
+		return nil, fmt.Errorf("reading machine states for entity %d: %w", entityID, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	machines := make([]world.MachineState, 0)
+	for rows.Next() {
+		var (
+			m      world.MachineState
+			states string
+		)
+		if err := rows.Scan(&m.MachineID, &states, &m.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scanning machine state for entity %d: %w", entityID, err)
+		}
+		if err := json.Unmarshal([]byte(states), &m.States); err != nil {
+			return nil, fmt.Errorf("decoding states of machine %q on entity %d: %w", m.MachineID, entityID, err)
+		}
+		machines = append(machines, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading machine states for entity %d: %w", entityID, err)
+	}
+	return machines, nil
+}
+
+// ListEntities returns up to filter.Limit entities matching filter whose
+// ids are greater than cursor, in ascending id order. An entity type
+// matches its subtypes too.
+// Implements world.EntityStore.
+func (s *SQLiteStore) ListEntities(ctx context.Context, filter world.EntityFilter, cursor int64) (world.EntityPage, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = world.DefaultEntityPageSize
+	}
+	conds := []string{"id > ?"}
+	args := []interface{}{cursor}
+	if filter.EntityType != "" {
+		types := schema.Subtypes(&s.schema, filter.EntityType)
+		conds = append(conds, "entity_type IN (?"+strings.Repeat(", ?", len(types)-1)+")")
+		for _, t := range types {
+			args = append(args, t)
+		}
+	}
+	for _, name := range filter.Components {
+		table := "comp_" + strings.ToLower(name)
+		if err := validateIdentifier(strings.TrimPrefix(table, "comp_"), "ListEntities component"); err != nil {
+			return world.EntityPage{}, err
+		}
+		conds = append(conds, fmt.Sprintf("EXISTS (SELECT 1 FROM %s WHERE entity_id = entities.id)", table))
+	}
+	args = append(args, limit+1)
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(
+		"SELECT id, entity_type, created_tick FROM entities WHERE %s ORDER BY id LIMIT ?",
+		strings.Join(conds, " AND ")), args...)
+	if err != nil {
+		return world.EntityPage{}, fmt.Errorf("listing entities: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	page := world.EntityPage{Entities: make([]world.Entity, 0)}
+	for rows.Next() {
+		var e world.Entity
+		if err := rows.Scan(&e.ID, &e.EntityType, &e.CreatedTick); err != nil {
+			return world.EntityPage{}, fmt.Errorf("scanning entity: %w", err)
+		}
+		page.Entities = append(page.Entities, e)
+	}
+	if err := rows.Err(); err != nil {
+		return world.EntityPage{}, fmt.Errorf("listing entities: %w", err)
+	}
+	if len(page.Entities) > limit {
+		page.Entities = page.Entities[:limit]
+		page.NextCursor = page.Entities[limit-1].ID
+	}
+	return page, nil
+}
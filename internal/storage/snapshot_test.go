@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/tmbritton/ecs-db/internal/schema"
+	"github.com/tmbritton/ecs-db/internal/world"
+)
+
+func snapshotSchema() schema.DatabaseSchema {
+	return schema.DatabaseSchema{
+		SchemaVersion: 1,
+		Components: map[string]schema.Component{
+			"Stats": {Type: schema.ComponentTypeObject, Properties: map[string]schema.Property{
+				"hp":      {Type: schema.PropertyTypeInteger},
+				"speed":   {Type: schema.PropertyTypeNumber},
+				"hostile": {Type: schema.PropertyTypeBoolean},
+				"home":    {Type: schema.PropertyTypeObject, Nullable: true},
+				"loot":    {Type: schema.PropertyTypeArray, Items: &schema.Property{Type: schema.PropertyTypeString}},
+			}},
+			"Name":      {Type: schema.ComponentTypeString},
+			"Asleep":    {Type: schema.ComponentTypeBoolean},
+			"Inventory": {Type: schema.ComponentTypeArray, Items: &schema.Property{Type: schema.PropertyTypeInteger}},
+			"Target":    {Type: schema.ComponentTypeEntityRef},
+			"Boss":      {Type: schema.ComponentTypeTag},
+			"ChildOf":   {Type: schema.ComponentTypeRelation},
+		},
+		EntityTypes: map[string]schema.EntityType{
+			"Monster": {OptionalComponents: []string{"Stats", "Name", "Asleep", "Inventory", "Target", "Boss"}},
+			"Goblin":  {Extends: "Monster"},
+			"Rock":    {},
+		},
+	}
+}
+
+func TestStore_GetEntity(t *testing.T) {
+	store := openSeededStore(t, snapshotSchema(),
+		"INSERT INTO entities (id, entity_type, created_tick) VALUES (1, 'Goblin', 12), (2, 'Rock', 0)",
+		`INSERT INTO comp_stats (entity_id, hp, speed, hostile, home, loot) VALUES (1, 7, 1.5, 1, NULL, '["gold","tooth"]')`,
+		"INSERT INTO comp_name (entity_id, value) VALUES (1, 'Grak')",
+		"INSERT INTO comp_asleep (entity_id, value) VALUES (1, 0)",
+		"INSERT INTO comp_inventory (entity_id, value) VALUES (1, '[3,4]')",
+		"INSERT INTO comp_target (entity_id, target_entity_id) VALUES (1, 2)",
+		"INSERT INTO comp_boss (entity_id) VALUES (1)",
+		`INSERT INTO behavior_components (entity_id, machine_id, current_states, updated_at)
+		 VALUES (1, 'patrol', '["walking"]', 10), (1, 'combat', '["idle","alert"]', 11)`,
+	)
+
+	snap, err := store.GetEntity(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.ID != 1 || snap.EntityType != "Goblin" || snap.CreatedTick != 12 {
+		t.Errorf("entity = %+v, want 1 Goblin created at 12", snap.Entity)
+	}
+	want := map[string]map[string]interface{}{
+		"Stats": {"hp": int64(7), "speed": 1.5, "hostile": true, "home": nil,
+			"loot": []interface{}{"gold", "tooth"}},
+		"Name":      {"value": "Grak"},
+		"Asleep":    {"value": false},
+		"Inventory": {"value": []interface{}{float64(3), float64(4)}},
+		"Target":    {"target_entity_id": int64(2)},
+		"Boss":      {},
+	}
+	if !reflect.DeepEqual(snap.Components, want) {
+		t.Errorf("Components =\n%#v\nwant\n%#v", snap.Components, want)
+	}
+	wantMachines := []world.MachineState{
+		{MachineID: "combat", States: []string{"idle", "alert"}, UpdatedAt: 11},
+		{MachineID: "patrol", States: []string{"walking"}, UpdatedAt: 10},
+	}
+	if !reflect.DeepEqual(snap.Machines, wantMachines) {
+		t.Errorf("Machines = %+v, want %+v", snap.Machines, wantMachines)
+	}
+
+	rock, err := store.GetEntity(context.Background(), 2)
+	if err != nil || len(rock.Components) != 0 || len(rock.Machines) != 0 {
+		t.Errorf("GetEntity(rock) = %+v, %v; want no components or machines", rock, err)
+	}
+
+	var notFound *world.EntityNotFoundError
+	if _, err := store.GetEntity(context.Background(), 99); !errors.As(err, &notFound) {
+		t.Errorf("err = %v, want *world.EntityNotFoundError", err)
+	}
+}
+
+func TestStore_ListEntities(t *testing.T) {
+	store := openSeededStore(t, snapshotSchema(),
+		`INSERT INTO entities (id, entity_type, created_tick) VALUES
+		 (1, 'Goblin', 0), (2, 'Rock', 0), (3, 'Monster', 0), (4, 'Goblin', 0), (5, 'Goblin', 0), (6, 'Rock', 0)`,
+		"INSERT INTO comp_boss (entity_id) VALUES (3), (5)",
+	)
+
+	// pages collects every page of filter, returning the ids of each.
+	pages := func(filter world.EntityFilter) [][]int64 {
+		var out [][]int64
+		for cursor := int64(0); ; {
+			page, err := store.ListEntities(context.Background(), filter, cursor)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := make([]int64, 0, len(page.Entities))
+			for _, e := range page.Entities {
+				ids = append(ids, e.ID)
+			}
+			out = append(out, ids)
+			if cursor = page.NextCursor; cursor == 0 {
+				return out
+			}
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter world.EntityFilter
+		want   [][]int64
+	}{
+		{"all", world.EntityFilter{}, [][]int64{{1, 2, 3, 4, 5, 6}}},
+		{"paged", world.EntityFilter{Limit: 2}, [][]int64{{1, 2}, {3, 4}, {5, 6}}},
+		{"type and subtypes", world.EntityFilter{EntityType: "Monster", Limit: 3}, [][]int64{{1, 3, 4}, {5}}},
+		{"exact page", world.EntityFilter{EntityType: "Rock", Limit: 2}, [][]int64{{2, 6}}},
+		{"component", world.EntityFilter{EntityType: "Goblin", Components: []string{"Boss"}}, [][]int64{{5}}},
+		{"none", world.EntityFilter{EntityType: "Dragon"}, [][]int64{{}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pages(tt.filter); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pages = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
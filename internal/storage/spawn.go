@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/tmbritton/ecs-db/internal/agent"
+	"github.com/tmbritton/ecs-db/internal/schema"
+	"github.com/tmbritton/ecs-db/internal/world"
+)
+
+// Behaviors is what a world writer needs to start the behavior machine of
+// the entities it spawns.
+type Behaviors struct {
+	// Machines supplies the definitions named by entity type behaviors,
+	// usually the *agent.Loader the machines were loaded with.
+	Machines agent.MachineSource
+	// Registry holds the actions the machines' entry actions run.
+	Registry *agent.Registry
+	// TickDurationMs converts after delays to ticks; <= 0 uses the agent
+	// default.
+	TickDurationMs int64
+}
+
+// NewTxWorldWriterWithBehaviors wraps tx to produce an agent.WorldWriter
+// that validates against s, as NewTxWorldWriterWithSchema does, and starts
+// the behavior machine of each entity it spawns from b.
+func NewTxWorldWriterWithBehaviors(tx *sql.Tx, s schema.DatabaseSchema, b Behaviors) agent.WorldWriter {
+	return &txWorldWriter{tx: tx, schema: &s, behaviors: &b}
+}
+
+// SpawnEntity creates an entity of entityType stamped with the current
+// tick and attaches components to it, in name order.
+//
+// With a schema, the entity is checked as EntityService.CreateEntity checks
+// it (see world.PrepareEntityCreation) before anything is written, and a
+// failed check is returned as a *world.ValidationError; warnings do not
+// stop the spawn. With behaviors as well, the machine named by the entity
+// type's behavior is started in the same transaction: context components
+// are seeded, entry actions run and the initial state is persisted. A type
+// naming a machine the behaviors do not have is an error.
+//
+// Without a schema, entityType and the components are written as given.
+func (w *txWorldWriter) SpawnEntity(entityType string, components map[string]map[string]any) (int64, error) {
+	ctx := context.Background()
+
+	comps := world.EntityComponents(components)
+	if w.schema != nil {
+		filled, _, err := world.PrepareEntityCreation(ctx, w.schema, entityType, comps, w.entityType)
+		if err != nil {
+			return 0, fmt.Errorf("SpawnEntity: %w", err)
+		}
+		comps = filled
+	}
+
+	tick, err := readCurrentTick(ctx, w.tx.QueryRowContext)
+	if err != nil {
+		return 0, fmt.Errorf("SpawnEntity: %w", err)
+	}
+	res, err := w.tx.ExecContext(ctx,
+		"INSERT INTO entities (entity_type, created_tick) VALUES (?, ?)", entityType, tick,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("SpawnEntity: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("SpawnEntity: LastInsertId: %w", err)
+	}
+
+	for _, c := range comps {
+		values := c.Values
+		if values == nil {
+			values = map[string]any{}
+		}
+		if err := w.AttachComponent(id, c.Name, values); err != nil {
+			return 0, fmt.Errorf("SpawnEntity: %w", err)
+		}
+	}
+
+	if err := w.startBehavior(id, entityType, tick); err != nil {
+		return 0, fmt.Errorf("SpawnEntity: %w", err)
+	}
+	return id, nil
+}
+
+// startBehavior starts the behavior machine of the entity's type, if the
+// writer has behaviors and the type (or an ancestor) names one.
+func (w *txWorldWriter) startBehavior(entityID int64, entityType string, tick int64) error {
+	if w.behaviors == nil || w.schema == nil {
+		return nil
+	}
+	et, ok := schema.ResolveEntityType(w.schema, entityType)
+	if !ok || et.Behavior == "" {
+		return nil
+	}
+	if w.behaviors.Machines == nil {
+		return fmt.Errorf("behavior %q of entity type %q: no machines loaded", et.Behavior, entityType)
+	}
+	def, ok := w.behaviors.Machines.Get(et.Behavior)
+	if !ok {
+		return fmt.Errorf("behavior %q of entity type %q: machine is not loaded", et.Behavior, entityType)
+	}
+	a := agent.NewAgent(def, entityID, "", w.behaviors.TickDurationMs)
+	reader := NewTxWorldReaderWithSchema(w.tx, *w.schema)
+	if err := agent.StartAgent(a, w.behaviors.Registry, tick, w, reader, NewMachineWriter(w.tx)); err != nil {
+		return fmt.Errorf("starting behavior %q: %w", et.Behavior, err)
+	}
+	return nil
+}
+
+// SpawnPrefab spawns an entity from the prefab reference prefab ("Goblin"
+// or "Goblin/elite") with overrides merged over its values, drawing its
+// ranges afresh; see schema.InstantiatePrefab. The entity is then created
+// as SpawnEntity creates it. Prefabs are declared in the schema, so a
+// writer without one refuses.
+func (w *txWorldWriter) SpawnPrefab(prefab string, overrides map[string]map[string]any) (int64, error) {
+	if w.schema == nil {
+		return 0, fmt.Errorf("SpawnPrefab %q: no schema to read prefabs from", prefab)
+	}
+	entityType, components, err := schema.InstantiatePrefab(w.schema, prefab, overrides, nil)
+	if err != nil {
+		return 0, fmt.Errorf("SpawnPrefab: %w", err)
+	}
+	id, err := w.SpawnEntity(entityType, components)
+	if err != nil {
+		return 0, fmt.Errorf("SpawnPrefab %q: %w", prefab, err)
+	}
+	return id, nil
+}
@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tmbritton/ecs-db/internal/agent"
+	"github.com/tmbritton/ecs-db/internal/schema"
+	"github.com/tmbritton/ecs-db/internal/world"
+)
+
+func spawnSchema() schema.DatabaseSchema {
+	ten := 10.0
+	return schema.DatabaseSchema{
+		SchemaVersion: 1,
+		Components: map[string]schema.Component{
+			"Health": {Type: schema.ComponentTypeObject, Properties: map[string]schema.Property{
+				"hp":    {Type: schema.PropertyTypeInteger, Maximum: &ten},
+				"maxHp": {Type: schema.PropertyTypeInteger, Default: float64(10)},
+			}},
+			"Leader": {Type: schema.ComponentTypeEntityRef, RefType: "Goblin"},
+			"Patrol": {Type: schema.ComponentTypeObject, Properties: map[string]schema.Property{
+				"laps": {Type: schema.PropertyTypeInteger, Default: float64(0)},
+			}},
+		},
+		EntityTypes: map[string]schema.EntityType{
+			"Goblin": {Behavior: "patrol", RequiredComponents: []string{"Health"},
+				OptionalComponents: []string{"Leader", "Patrol"}},
+			"Rock": {},
+		},
+	}
+}
+
+// machineMap is an agent.MachineSource over a fixed set of definitions.
+type machineMap map[string]*agent.MachineDefinition
+
+func (m machineMap) Get(machineID string) (*agent.MachineDefinition, bool) {
+	def, ok := m[machineID]
+	return def, ok
+}
+
+// recordAction records the entity each run was for.
+type recordAction struct{ ran []int64 }
+
+func (a *recordAction) Run(ctx agent.ActionContext) error {
+	a.ran = append(a.ran, ctx.EntityID)
+	return nil
+}
+
+func patrolMachine(t *testing.T) *agent.MachineDefinition {
+	t.Helper()
+	def, err := agent.ParseMachine([]byte(`{
+	  "id": "patrol",
+	  "initial": "walking",
+	  "context": {"laps": 3},
+	  "states": {"walking": {"entry": ["greet"], "after": {"1000": "walking"}}}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	def.ContextManifest = map[string]string{"laps": "Patrol"}
+	return def
+}
+
+// openSpawnStore returns a store at tick 5 holding Goblin 1 and Rock 2.
+func openSpawnStore(t *testing.T) *SQLiteStore {
+	t.Helper()
+	return openSeededStore(t, spawnSchema(),
+		"INSERT OR REPLACE INTO world (key, value) VALUES ('current_tick', '5')",
+		"INSERT INTO entities (id, entity_type, created_tick) VALUES (1, 'Goblin', 0), (2, 'Rock', 0)",
+		"INSERT INTO comp_health (entity_id, hp, maxhp) VALUES (1, 10, 10)",
+	)
+}
+
+func TestTxWorldWriter_SpawnEntity_Behavior(t *testing.T) {
+	store := openSpawnStore(t)
+	greet := &recordAction{}
+	registry := agent.NewRegistry()
+	registry.RegisterAction(agent.ActionMeta{Name: "greet"}, greet)
+
+	tx, err := store.DB().Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	w := NewTxWorldWriterWithBehaviors(tx, spawnSchema(), Behaviors{
+		Machines: machineMap{"patrol": patrolMachine(t)}, Registry: registry, TickDurationMs: 100,
+	})
+
+	id, err := w.SpawnEntity("Goblin", map[string]map[string]any{
+		"Health": {"hp": int64(4)},
+		"Leader": {"target_entity_id": int64(1)},
+	})
+	if err != nil {
+		t.Fatalf("SpawnEntity: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	snap, err := store.GetEntity(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.CreatedTick != 5 {
+		t.Errorf("CreatedTick = %d, want the current tick 5", snap.CreatedTick)
+	}
+	want := map[string]map[string]interface{}{
+		"Health": {"hp": int64(4), "maxHp": int64(10)}, // maxHp from its default
+		"Leader": {"target_entity_id": int64(1)},
+		"Patrol": {"laps": int64(3)}, // seeded from the machine context
+	}
+	if !reflect.DeepEqual(snap.Components, want) {
+		t.Errorf("Components = %v, want %v", snap.Components, want)
+	}
+	wantMachines := []world.MachineState{{MachineID: "patrol", States: []string{"patrol.walking"}, UpdatedAt: 5}}
+	if !reflect.DeepEqual(snap.Machines, wantMachines) {
+		t.Errorf("Machines = %+v, want %+v", snap.Machines, wantMachines)
+	}
+	if !reflect.DeepEqual(greet.ran, []int64{id}) {
+		t.Errorf("entry action ran for %v, want [%d]", greet.ran, id)
+	}
+	if got := countRows(t, store, "SELECT COUNT(*) FROM event_queue WHERE entity_id = ? AND target_tick = 15", id); got != 1 {
+		t.Errorf("scheduled after events = %d, want 1 at tick 15", got)
+	}
+}
+
+func TestTxWorldWriter_SpawnEntity_Invalid(t *testing.T) {
+	tests := []struct {
+		name       string
+		entityType string
+		components map[string]map[string]any
+		behaviors  Behaviors
+		wantErr    string
+	}{
+		{"unknown type", "Orc", nil, Behaviors{}, `unknown entity type "Orc"`},
+		{"missing required component", "Goblin", nil, Behaviors{}, `"Health"`},
+		{"value out of range", "Goblin", map[string]map[string]any{"Health": {"hp": int64(11)}}, Behaviors{}, "hp"},
+		{"wrong ref type", "Goblin", map[string]map[string]any{
+			"Health": {"hp": int64(1)},
+			"Leader": {"target_entity_id": int64(2)},
+		}, Behaviors{}, `of type "Rock", want "Goblin"`},
+		{"machine not loaded", "Goblin", map[string]map[string]any{"Health": {"hp": int64(1)}},
+			Behaviors{Machines: machineMap{}, Registry: agent.NewRegistry()}, `behavior "patrol"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := openSpawnStore(t)
+			tx, err := store.DB().Begin()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = tx.Rollback() }()
+			w := NewTxWorldWriterWithBehaviors(tx, spawnSchema(), tt.behaviors)
+
+			_, err = w.SpawnEntity(tt.entityType, tt.components)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+			}
+			var vErr *world.ValidationError
+			if tt.behaviors.Machines == nil && !errors.As(err, &vErr) {
+				t.Errorf("err = %v, want a *world.ValidationError", err)
+			}
+		})
+	}
+}
@@ -35,6 +35,17 @@ type StoreConfig struct {
 	// BackupRetention is the number of versioned backups to keep before migration.
 	// 0 (the default) disables backup. A positive value enables backup and retention.
 	BackupRetention int
+	// MigrationsDir holds declarative data migrations as MigrationsDir/vN/*.sql,
+	// run when migrating to version N. Empty disables SQL data migrations.
+	MigrationsDir string
+	// ContractPolicy controls how existing entities that violate tightened
+	// entity-type contracts are handled. Empty follows MigrationPolicy:
+	// confirm refuses, auto reports.
+	ContractPolicy ContractPolicy
+	// AutoRestore verifies the database after a migration (integrity_check,
+	// foreign_key_check, schema_version) and, if verification fails, puts
+	// the pre-migration backup back in place. It needs BackupRetention > 0.
+	AutoRestore bool
 }
 
 // NewSQLiteStore opens or creates a SQLite database at dbPath using the
@@ -77,7 +88,7 @@ func NewSQLiteStoreWithConfig(dbPath string, cfg StoreConfig) (*SQLiteStore, err
 	}
 
 	// Open database connection
-	db, err := sql.Open("sqlite", dbPath)
+	db, err := sql.Open("sqlite", sqliteDSN(dbPath))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -109,9 +120,24 @@ func NewSQLiteStoreWithConfig(dbPath string, cfg StoreConfig) (*SQLiteStore, err
 	}
 
 	if existing {
-		// Existing database — check version and migrate if needed.
+		// Existing database — upgrade the engine-owned tables, then check
+		// the schema.json version and migrate if needed.
+		if err := upgradeSystemTables(db); err != nil {
+			_ = db.Close()
+			return nil, fmt.Errorf("upgrading system tables: %w", err)
+		}
 		if err := checkAndMigrate(db, dbPath, cfg); err != nil {
 			_ = db.Close()
+			var verr *MigrationVerificationError
+			if errors.As(err, &verr) {
+				// The connection is closed, so the backup can be swapped in.
+				if rerr := restoreFile(dbPath, verr.BackupPath); rerr != nil {
+					cfg.Logger.Warnf("auto-restore from %s failed: %v", verr.BackupPath, rerr)
+				} else {
+					verr.Restored = true
+					cfg.Logger.Warnf("migration verification failed; restored %s", verr.BackupPath)
+				}
+			}
 			return nil, err
 		}
 	} else {
@@ -179,6 +205,18 @@ func checkSchemaVersion(db *sql.DB, currentVersion int) error {
 	return nil
 }
 
+// sqliteDSN returns the data source name for dbPath. foreign_keys is
+// per-connection in SQLite and database/sql pools connections, so it is
+// requested in the DSN to hold on every connection, not only the first;
+// entity-ref delete policies depend on it.
+func sqliteDSN(dbPath string) string {
+	sep := "?"
+	if strings.Contains(dbPath, "?") {
+		sep = "&"
+	}
+	return dbPath + sep + "_pragma=foreign_keys(1)"
+}
+
 // isMemoryDB reports whether dbPath refers to an in-memory SQLite database,
 // for which file-based backup is not applicable.
 func isMemoryDB(path string) bool {
@@ -201,19 +239,64 @@ func checkAndMigrate(db *sql.DB, dbPath string, cfg StoreConfig) error {
 	}
 
 	// Back up before migration so the user has a restore point.
+	var backupPath string
 	if cfg.BackupRetention > 0 && !isMemoryDB(dbPath) {
-		backupPath, backupErr := backupDatabase(db, dbPath, mismatch.DBVersion)
+		path, backupErr := backupDatabase(db, dbPath, mismatch.DBVersion)
 		if backupErr != nil {
 			cfg.Logger.Warnf("backup failed (migration will proceed): %v", backupErr)
 		} else {
-			cfg.Logger.Infof("backup created: %s", backupPath)
+			cfg.Logger.Infof("backup created: %s", path)
 			pruneBackups(dbPath, cfg.BackupRetention, cfg.Logger)
+			backupPath = path
 		}
 	}
 
 	// Run the migration pipeline.
-	runner := NewMigrationRunner(db, cfg.Schema, cfg.MigrationPolicy, cfg.Logger)
-	return runner.Run()
+	runner := NewMigrationRunner(db, cfg.Schema, cfg.MigrationPolicy, cfg.Logger).
+		WithMigrationsDir(cfg.MigrationsDir).
+		WithBackupPath(backupPath).
+		WithContractPolicy(cfg.ContractPolicy)
+	if err := runner.Run(); err != nil {
+		return err
+	}
+
+	if cfg.AutoRestore && backupPath != "" {
+		if err := verifyMigratedDatabase(db, dbPath, cfg.Schema.SchemaVersion); err != nil {
+			return &MigrationVerificationError{BackupPath: backupPath, Underlying: err}
+		}
+	}
+	return nil
+}
+
+// verifyMigratedDatabase checks a freshly migrated database: integrity,
+// foreign keys and the recorded schema_version.
+func verifyMigratedDatabase(db *sql.DB, dbPath string, wantVersion int) error {
+	version, err := verifyDatabase(db, dbPath)
+	if err != nil {
+		return err
+	}
+	if version != wantVersion {
+		return &BackupVerificationError{Path: dbPath,
+			Reason: fmt.Sprintf("schema_version is %d, want %d", version, wantVersion)}
+	}
+	rows, err := db.Query("PRAGMA foreign_key_check")
+	if err != nil {
+		return &BackupVerificationError{Path: dbPath, Reason: fmt.Sprintf("foreign_key_check: %v", err)}
+	}
+	defer func() { _ = rows.Close() }()
+	if rows.Next() {
+		var (
+			table, parent string
+			rowid         sql.NullInt64
+			fkid          int
+		)
+		if err := rows.Scan(&table, &rowid, &parent, &fkid); err != nil {
+			return &BackupVerificationError{Path: dbPath, Reason: fmt.Sprintf("foreign_key_check: %v", err)}
+		}
+		return &BackupVerificationError{Path: dbPath,
+			Reason: fmt.Sprintf("foreign_key_check: %s row %d references missing %s", table, rowid.Int64, parent)}
+	}
+	return rows.Err()
 }
 
 // bootstrapDatabase creates all tables and writes initial meta rows
@@ -253,14 +336,6 @@ func bootstrapDatabase(db *sql.DB, s schema.DatabaseSchema, schemaHash string) e
 
 	CREATE INDEX idx_entity_type ON entities(entity_type);
 
-	CREATE TABLE event_queue (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		tick INTEGER NOT NULL,
-		target_entity INTEGER,
-		kind TEXT NOT NULL,
-		payload TEXT NOT NULL DEFAULT '{}'
-	);
-
 	CREATE TABLE input_events (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		received_at_ms INTEGER NOT NULL,
@@ -269,30 +344,26 @@ func bootstrapDatabase(db *sql.DB, s schema.DatabaseSchema, schemaHash string) e
 		consumed INTEGER NOT NULL DEFAULT 0
 	);
 
-	CREATE TABLE transitions (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		tick INTEGER NOT NULL,
-		wall_ms INTEGER NOT NULL,
-		entity_id INTEGER NOT NULL,
-		machine_id TEXT NOT NULL,
-		from_state TEXT NOT NULL,
-		to_state TEXT NOT NULL,
-		event TEXT NOT NULL,
-		guard_result TEXT,
-		actions_run TEXT
-	);
-
 	-- Indexes on query-hot columns
-	CREATE INDEX idx_event_queue_tick ON event_queue(tick);
 	CREATE INDEX idx_input_events_consumed ON input_events(consumed);
-	CREATE INDEX idx_transitions_entity_id ON transitions(entity_id);
 	`
 	if _, err := tx.Exec(fixed); err != nil {
 		return fmt.Errorf("creating fixed tables: %w", err)
 	}
+	if _, err := tx.Exec(schemaMigrationsTableSQL); err != nil {
+		return fmt.Errorf("creating schema_migrations: %w", err)
+	}
+	// Interpreter tables come from the system migrations, their single
+	// definition, which also records system_schema_version.
+	if err := migrateSystemTables(tx); err != nil {
+		return fmt.Errorf("creating interpreter tables: %w", err)
+	}
 
 	// Generate component tables.
 	for name, comp := range s.Components {
+		if comp.Type == schema.ComponentTypeRelation {
+			continue // created below
+		}
 		stmt, err := componentTableSQL(name, comp)
 		if err != nil {
 			return fmt.Errorf("building table for component %q: %w", name, err)
@@ -300,6 +371,29 @@ func bootstrapDatabase(db *sql.DB, s schema.DatabaseSchema, schemaHash string) e
 		if _, err := tx.Exec(stmt); err != nil {
 			return fmt.Errorf("creating table for component %q: %w", name, err)
 		}
+		for _, stmt := range componentIndexSQL(name, comp) {
+			if _, err := tx.Exec(stmt); err != nil {
+				return fmt.Errorf("creating index for component %q: %w", name, err)
+			}
+		}
+	}
+
+	// Generate resource tables, each with its single row.
+	for name, res := range s.Resources {
+		for _, stmt := range []string{resourceTableSQL(name, res), resourceSeedSQL(name)} {
+			if _, err := tx.Exec(stmt); err != nil {
+				return fmt.Errorf("creating table for resource %q: %w", name, err)
+			}
+		}
+	}
+
+	// Generate relation tables with their reverse-lookup index.
+	for name, comp := range schema.Relations(&s) {
+		for _, stmt := range []string{relationTableSQL(name, comp), relationIndexSQL(name)} {
+			if _, err := tx.Exec(stmt); err != nil {
+				return fmt.Errorf("creating table for relation %q: %w", name, err)
+			}
+		}
 	}
 
 	// Write meta rows.
@@ -326,6 +420,10 @@ func bootstrapDatabase(db *sql.DB, s schema.DatabaseSchema, schemaHash string) e
 		}
 	}
 
+	if err := writeSchemaSnapshot(tx, s); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("committing bootstrap transaction: %w", err)
 	}
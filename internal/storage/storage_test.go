@@ -43,6 +43,67 @@ func TestComponentTableSQL_ObjectWithMixedProperties(t *testing.T) {
 	assertContains(t, sql, "frame INTEGER NOT NULL")
 }
 
+func TestComponentTableSQL_PropertyConstraints(t *testing.T) {
+	minHP, maxHP := 0.0, 500.0
+	minLen := 1
+	comp := schema.Component{
+		Type: schema.ComponentTypeObject,
+		Properties: map[string]schema.Property{
+			"hp":      {Type: schema.PropertyTypeInteger, Minimum: &minHP, Maximum: &maxHP},
+			"imageId": {Type: schema.PropertyTypeString, MinLength: &minLen, Pattern: "^[a-z]+$"},
+			"layer":   {Type: schema.PropertyTypeString, Enum: []any{"ground", "it's air"}},
+		},
+	}
+	sql, err := componentTableSQL("Mixed", comp)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertContains(t, sql, "hp INTEGER NOT NULL CHECK (hp >= 0 AND hp <= 500)")
+	assertContains(t, sql, "imageid TEXT NOT NULL CHECK (length(imageid) >= 1)")
+	assertContains(t, sql, "layer TEXT NOT NULL CHECK (layer IN ('ground', 'it''s air'))")
+}
+
+func TestNewSQLiteStore_CheckConstraintRejectsInvalidRow(t *testing.T) {
+	minHP := 0.0
+	store := makeStore(t, schema.DatabaseSchema{
+		SchemaVersion: 1,
+		Components: map[string]schema.Component{
+			"Health": {
+				Type: schema.ComponentTypeObject,
+				Properties: map[string]schema.Property{
+					"hp": {Type: schema.PropertyTypeInteger, Minimum: &minHP},
+				},
+			},
+		},
+	})
+	if _, err := store.db.Exec("INSERT INTO entities (entity_type) VALUES ('Goblin')"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.db.Exec("INSERT INTO comp_health (entity_id, hp) VALUES (1, -40)"); err == nil {
+		t.Fatal("expected CHECK constraint failure for hp = -40")
+	}
+	if _, err := store.db.Exec("INSERT INTO comp_health (entity_id, hp) VALUES (1, 40)"); err != nil {
+		t.Fatalf("valid insert failed: %v", err)
+	}
+}
+
+func TestComponentTableSQL_PropertyDefault(t *testing.T) {
+	minHP := 0.0
+	comp := schema.Component{
+		Type: schema.ComponentTypeObject,
+		Properties: map[string]schema.Property{
+			"maxHp":   {Type: schema.PropertyTypeInteger, Default: 100.0, Minimum: &minHP},
+			"imageId": {Type: schema.PropertyTypeString, Default: "goblin"},
+		},
+	}
+	sql, err := componentTableSQL("Mixed", comp)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertContains(t, sql, "maxhp INTEGER NOT NULL DEFAULT 100 CHECK (maxhp >= 0)")
+	assertContains(t, sql, "imageid TEXT NOT NULL DEFAULT 'goblin'")
+}
+
 func TestComponentTableSQL_EntityRef(t *testing.T) {
 	comp := schema.Component{Type: schema.ComponentTypeEntityRef}
 	sql, err := componentTableSQL("Wielder", comp)
@@ -142,18 +203,19 @@ func TestPropertySQLType(t *testing.T) {
 // We only verify column *names* exist, not types (PRAGMA table_info doesn't
 // reliably return the full DDL). Exact types are checked by the schema test.
 var fixedTableColumns = map[string][]string{
-	"meta":         {"key", "value"},
-	"world":        {"key", "value"},
-	"entities":     {"id", "entity_type", "created_tick"},
-	"event_queue":  {"id", "tick", "target_entity", "kind", "payload"},
-	"input_events": {"id", "received_at_ms", "kind", "payload", "consumed"},
-	"transitions":  {"id", "tick", "wall_ms", "entity_id", "machine_id", "from_state", "to_state", "event", "guard_result", "actions_run"},
+	"meta":                {"key", "value"},
+	"world":               {"key", "value"},
+	"entities":            {"id", "entity_type", "created_tick"},
+	"event_queue":         {"id", "entity_id", "machine_id", "event_type", "payload", "target_tick"},
+	"input_events":        {"id", "received_at_ms", "kind", "payload", "consumed"},
+	"transitions":         {"id", "tick", "wall_ms", "entity_id", "machine_id", "from_states", "to_states", "event", "cond_result", "actions_run"},
+	"behavior_components": {"entity_id", "machine_id", "current_states", "updated_at"},
 }
 
 // expectedIndexes lists indexes that createTables must create.
 var expectedIndexes = []string{
 	"idx_entity_type",
-	"idx_event_queue_tick",
+	"idx_event_queue_target_tick",
 	"idx_input_events_consumed",
 	"idx_transitions_entity_id",
 }
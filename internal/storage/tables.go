@@ -3,48 +3,230 @@ package storage
 import (
 	"database/sql"
 	"fmt"
+	"strconv"
 )
 
-// EnsureInterpreterTables creates the three interpreter-managed tables if they
-// do not already exist. CREATE TABLE IF NOT EXISTS makes each call idempotent,
-// so it is safe to call on both fresh and existing databases.
+// SystemSchemaVersion is the version of the engine-owned interpreter tables
+// (behavior_components, transitions, event_queue) this build expects. It is
+// recorded in meta under system_schema_version, independently of the
+// schema.json version, and advanced by systemMigrations.
+const SystemSchemaVersion = 1
+
+const systemSchemaVersionKey = "system_schema_version"
+
+// systemMigration upgrades the interpreter tables from version-1 to
+// version. Each step runs inside the transaction that records its version.
+type systemMigration struct {
+	version     int
+	description string
+	apply       func(tx *sql.Tx) error
+}
+
+// systemMigrations is the ordered, append-only upgrade path of the
+// interpreter tables. Never edit a released step; add a new one and bump
+// SystemSchemaVersion.
+var systemMigrations = []systemMigration{
+	{
+		version:     1,
+		description: "unify interpreter tables",
+		apply:       unifyInterpreterTables,
+	},
+}
+
+// interpreterTablesSQL is the authoritative definition of the interpreter
+// tables at version 1.
+var interpreterTablesSQL = []string{
+	`CREATE TABLE IF NOT EXISTS behavior_components (
+		entity_id      INTEGER NOT NULL REFERENCES entities(id) ON DELETE CASCADE,
+		machine_id     TEXT NOT NULL,
+		current_states TEXT NOT NULL,
+		updated_at     INTEGER NOT NULL,
+		PRIMARY KEY (entity_id, machine_id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS transitions (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		tick        INTEGER NOT NULL,
+		wall_ms     INTEGER NOT NULL,
+		entity_id   INTEGER NOT NULL,
+		machine_id  TEXT NOT NULL,
+		from_states TEXT NOT NULL,
+		to_states   TEXT NOT NULL,
+		event       TEXT NOT NULL,
+		cond_result INTEGER,
+		actions_run TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS event_queue (
+		id          INTEGER PRIMARY KEY AUTOINCREMENT,
+		entity_id   INTEGER NOT NULL,
+		machine_id  TEXT NOT NULL,
+		event_type  TEXT NOT NULL,
+		payload     TEXT,
+		target_tick INTEGER NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_transitions_entity_id ON transitions(entity_id)`,
+	`CREATE INDEX IF NOT EXISTS idx_event_queue_target_tick ON event_queue(target_tick)`,
+}
+
+// unifyInterpreterTables creates the version 1 interpreter tables. Databases
+// bootstrapped before system tables were versioned have event_queue(tick,
+// target_entity, kind, payload) and transitions(from_state, to_state,
+// guard_result); those are rebuilt into the interpreter's shape, keeping
+// every row that can be expressed in it.
+func unifyInterpreterTables(tx *sql.Tx) error {
+	legacyQueue, err := hasColumn(tx, "event_queue", "target_entity")
+	if err != nil {
+		return err
+	}
+	legacyTransitions, err := hasColumn(tx, "transitions", "from_state")
+	if err != nil {
+		return err
+	}
+	if legacyQueue {
+		if _, err := tx.Exec(`DROP INDEX IF EXISTS idx_event_queue_tick`); err != nil {
+			return fmt.Errorf("dropping legacy event_queue index: %w", err)
+		}
+		if _, err := tx.Exec(`ALTER TABLE event_queue RENAME TO event_queue_legacy`); err != nil {
+			return fmt.Errorf("renaming legacy event_queue: %w", err)
+		}
+	}
+	if legacyTransitions {
+		if _, err := tx.Exec(`DROP INDEX IF EXISTS idx_transitions_entity_id`); err != nil {
+			return fmt.Errorf("dropping legacy transitions index: %w", err)
+		}
+		if _, err := tx.Exec(`ALTER TABLE transitions RENAME TO transitions_legacy`); err != nil {
+			return fmt.Errorf("renaming legacy transitions: %w", err)
+		}
+	}
+
+	for _, stmt := range interpreterTablesSQL {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("creating interpreter tables: %w", err)
+		}
+	}
+
+	if legacyQueue {
+		// Legacy events without a target entity cannot be delivered by the
+		// interpreter and are dropped. The legacy shape had no machine id.
+		if _, err := tx.Exec(`INSERT INTO event_queue (id, entity_id, machine_id, event_type, payload, target_tick)
+			SELECT id, target_entity, '', kind, payload, tick FROM event_queue_legacy
+			WHERE target_entity IS NOT NULL`); err != nil {
+			return fmt.Errorf("copying legacy event_queue: %w", err)
+		}
+		if _, err := tx.Exec(`DROP TABLE event_queue_legacy`); err != nil {
+			return fmt.Errorf("dropping legacy event_queue: %w", err)
+		}
+	}
+	if legacyTransitions {
+		if _, err := tx.Exec(`INSERT INTO transitions
+			(id, tick, wall_ms, entity_id, machine_id, from_states, to_states, event, cond_result, actions_run)
+			SELECT id, tick, wall_ms, entity_id, machine_id,
+				json_array(from_state), json_array(to_state), event,
+				CASE lower(guard_result) WHEN 'true' THEN 1 WHEN '1' THEN 1
+					WHEN 'false' THEN 0 WHEN '0' THEN 0 END,
+				COALESCE(actions_run, '[]')
+			FROM transitions_legacy`); err != nil {
+			return fmt.Errorf("copying legacy transitions: %w", err)
+		}
+		if _, err := tx.Exec(`DROP TABLE transitions_legacy`); err != nil {
+			return fmt.Errorf("dropping legacy transitions: %w", err)
+		}
+	}
+	return nil
+}
+
+// hasColumn reports whether table exists and has column col.
+func hasColumn(tx *sql.Tx, table, col string) (bool, error) {
+	var n int
+	if err := tx.QueryRow(
+		"SELECT count(*) FROM pragma_table_info(?) WHERE name = ?", table, col,
+	).Scan(&n); err != nil {
+		return false, fmt.Errorf("inspecting %s: %w", table, err)
+	}
+	return n > 0, nil
+}
+
+// readSystemSchemaVersion returns the recorded system_schema_version, or 0
+// for databases that predate it.
+func readSystemSchemaVersion(tx *sql.Tx) (int, error) {
+	var stored string
+	err := tx.QueryRow("SELECT value FROM meta WHERE key = ?", systemSchemaVersionKey).Scan(&stored)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("reading system_schema_version: %w", err)
+	}
+	v, err := strconv.Atoi(stored)
+	if err != nil {
+		return 0, fmt.Errorf("corrupted system_schema_version in meta: %q", stored)
+	}
+	return v, nil
+}
+
+// migrateSystemTables applies every system migration newer than the
+// recorded system_schema_version inside tx and records the new version. A
+// database newer than this build is refused rather than downgraded.
+func migrateSystemTables(tx *sql.Tx) error {
+	current, err := readSystemSchemaVersion(tx)
+	if err != nil {
+		return err
+	}
+	if current > SystemSchemaVersion {
+		return fmt.Errorf("system_schema_version %d is newer than this build supports (%d)",
+			current, SystemSchemaVersion)
+	}
+	for _, m := range systemMigrations {
+		if m.version <= current {
+			continue
+		}
+		if err := m.apply(tx); err != nil {
+			return fmt.Errorf("system migration v%d (%s): %w", m.version, m.description, err)
+		}
+	}
+	if current == SystemSchemaVersion {
+		return nil
+	}
+	if _, err := tx.Exec(
+		"INSERT OR REPLACE INTO meta (key, value) VALUES (?, ?)",
+		systemSchemaVersionKey, strconv.Itoa(SystemSchemaVersion),
+	); err != nil {
+		return fmt.Errorf("recording system_schema_version: %w", err)
+	}
+	return nil
+}
+
+// EnsureInterpreterTables brings the interpreter-managed tables up to
+// SystemSchemaVersion in one transaction. It is idempotent: on an up to
+// date database it only reads meta. NewSQLiteStore already runs the same
+// migrations; this is for callers that open the database themselves.
 //
-// Call this after NewSQLiteStore has bootstrapped or migrated the schema-managed
-// tables — behavior_components references entities(id) which must exist first.
+// The meta table is created if missing. behavior_components references
+// entities(id), which must exist first.
 func EnsureInterpreterTables(db *sql.DB) error {
-	stmts := []string{
-		`CREATE TABLE IF NOT EXISTS behavior_components (
-			entity_id      INTEGER NOT NULL REFERENCES entities(id) ON DELETE CASCADE,
-			machine_id     TEXT NOT NULL,
-			current_states TEXT NOT NULL,
-			updated_at     INTEGER NOT NULL,
-			PRIMARY KEY (entity_id, machine_id)
-		)`,
-		`CREATE TABLE IF NOT EXISTS transitions (
-			id          INTEGER PRIMARY KEY AUTOINCREMENT,
-			tick        INTEGER NOT NULL,
-			wall_ms     INTEGER NOT NULL,
-			entity_id   INTEGER NOT NULL,
-			machine_id  TEXT NOT NULL,
-			from_states TEXT NOT NULL,
-			to_states   TEXT NOT NULL,
-			event       TEXT NOT NULL,
-			cond_result INTEGER,
-			actions_run TEXT NOT NULL
-		)`,
-		`CREATE TABLE IF NOT EXISTS event_queue (
-			id          INTEGER PRIMARY KEY AUTOINCREMENT,
-			entity_id   INTEGER NOT NULL,
-			machine_id  TEXT NOT NULL,
-			event_type  TEXT NOT NULL,
-			payload     TEXT,
-			target_tick INTEGER NOT NULL
-		)`,
-	}
-	for _, stmt := range stmts {
-		if _, err := db.Exec(stmt); err != nil {
-			return fmt.Errorf("EnsureInterpreterTables: %w", err)
-		}
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS meta (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("EnsureInterpreterTables: creating meta: %w", err)
+	}
+	if err := upgradeSystemTables(db); err != nil {
+		return fmt.Errorf("EnsureInterpreterTables: %w", err)
+	}
+	return nil
+}
+
+// upgradeSystemTables runs migrateSystemTables in its own transaction.
+func upgradeSystemTables(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("beginning system migration: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	if err := migrateSystemTables(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing system migration: %w", err)
 	}
 	return nil
 }
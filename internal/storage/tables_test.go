@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"testing"
 
+	"github.com/tmbritton/ecs-db/internal/agent"
+	"github.com/tmbritton/ecs-db/internal/schema"
 	_ "modernc.org/sqlite"
 )
 
@@ -140,3 +142,127 @@ func TestEnsureInterpreterTables_BehaviorComponents_CompositeKey(t *testing.T) {
 		t.Fatal("expected UNIQUE constraint violation for duplicate (entity_id, machine_id), got nil")
 	}
 }
+
+// legacyBootstrapSQL is the shape bootstrapDatabase gave the interpreter
+// tables before they were versioned.
+const legacyBootstrapSQL = `
+	CREATE TABLE meta (key TEXT PRIMARY KEY, value TEXT NOT NULL);
+	CREATE TABLE entities (id INTEGER PRIMARY KEY AUTOINCREMENT, entity_type TEXT NOT NULL, created_tick INTEGER NOT NULL DEFAULT 0);
+	CREATE TABLE event_queue (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		tick INTEGER NOT NULL,
+		target_entity INTEGER,
+		kind TEXT NOT NULL,
+		payload TEXT NOT NULL DEFAULT '{}'
+	);
+	CREATE TABLE transitions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		tick INTEGER NOT NULL,
+		wall_ms INTEGER NOT NULL,
+		entity_id INTEGER NOT NULL,
+		machine_id TEXT NOT NULL,
+		from_state TEXT NOT NULL,
+		to_state TEXT NOT NULL,
+		event TEXT NOT NULL,
+		guard_result TEXT,
+		actions_run TEXT
+	);
+	CREATE INDEX idx_event_queue_tick ON event_queue(tick);
+	CREATE INDEX idx_transitions_entity_id ON transitions(entity_id);
+`
+
+func TestEnsureInterpreterTables_UpgradesLegacyBootstrapTables(t *testing.T) {
+	db := openMemoryDB(t)
+	if _, err := db.Exec(legacyBootstrapSQL); err != nil {
+		t.Fatalf("legacy setup: %v", err)
+	}
+	if _, err := db.Exec(`
+		INSERT INTO event_queue (tick, target_entity, kind, payload) VALUES (5, 1, 'HIT', '{"dmg":3}'), (6, NULL, 'BROADCAST', '{}');
+		INSERT INTO transitions (tick, wall_ms, entity_id, machine_id, from_state, to_state, event, guard_result, actions_run)
+			VALUES (1, 10, 1, 'goblin', 'idle', 'chase', 'SEE', 'true', NULL);
+	`); err != nil {
+		t.Fatalf("legacy rows: %v", err)
+	}
+
+	if err := EnsureInterpreterTables(db); err != nil {
+		t.Fatalf("EnsureInterpreterTables: %v", err)
+	}
+
+	var entityID, targetTick int64
+	var eventType, payload string
+	if err := db.QueryRow("SELECT entity_id, event_type, payload, target_tick FROM event_queue").
+		Scan(&entityID, &eventType, &payload, &targetTick); err != nil {
+		t.Fatalf("reading upgraded event_queue: %v", err)
+	}
+	if entityID != 1 || eventType != "HIT" || payload != `{"dmg":3}` || targetTick != 5 {
+		t.Errorf("event = (%d, %q, %q, %d), want (1, HIT, {\"dmg\":3}, 5)", entityID, eventType, payload, targetTick)
+	}
+	var n int
+	if err := db.QueryRow("SELECT count(*) FROM event_queue").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("event_queue rows = %d, want 1 (untargeted event dropped)", n)
+	}
+
+	var from, to, actions string
+	var cond sql.NullInt64
+	if err := db.QueryRow("SELECT from_states, to_states, cond_result, actions_run FROM transitions").
+		Scan(&from, &to, &cond, &actions); err != nil {
+		t.Fatalf("reading upgraded transitions: %v", err)
+	}
+	if from != `["idle"]` || to != `["chase"]` || !cond.Valid || cond.Int64 != 1 || actions != "[]" {
+		t.Errorf("transition = (%s, %s, %v, %s)", from, to, cond, actions)
+	}
+
+	if got := readMetaValue(t, db, "system_schema_version"); got != "1" {
+		t.Errorf("system_schema_version = %q, want 1", got)
+	}
+	if tableExists(t, db, "event_queue_legacy") || tableExists(t, db, "transitions_legacy") {
+		t.Error("legacy tables left behind")
+	}
+}
+
+func TestEnsureInterpreterTables_RefusesNewerSystemVersion(t *testing.T) {
+	db := openMemoryDB(t)
+	if err := EnsureInterpreterTables(db); err != nil {
+		t.Fatalf("EnsureInterpreterTables: %v", err)
+	}
+	if _, err := db.Exec("UPDATE meta SET value = '99' WHERE key = 'system_schema_version'"); err != nil {
+		t.Fatal(err)
+	}
+	if err := EnsureInterpreterTables(db); err == nil {
+		t.Fatal("expected an error for a system_schema_version newer than the build")
+	}
+}
+
+func TestBootstrap_InterpreterTablesAcceptMachineWriter(t *testing.T) {
+	store, err := NewSQLiteStore(t.TempDir()+"/sys.sqlite", historySchema(1, schema.EntityType{}), "")
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	db := store.DB()
+
+	if got := readMetaValue(t, db, "system_schema_version"); got != "1" {
+		t.Errorf("system_schema_version = %q, want 1", got)
+	}
+	if _, err := db.Exec("INSERT INTO entities (id, entity_type) VALUES (1, 'Goblin')"); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	mw := NewMachineWriter(tx)
+	if err := mw.ScheduleAfterEvent(1, "goblin", "xstate.after(500).idle", 10); err != nil {
+		t.Errorf("ScheduleAfterEvent on a bootstrapped database: %v", err)
+	}
+	if err := mw.AppendTransition(agent.TransitionRecord{
+		Tick: 1, EntityID: 1, MachineID: "goblin",
+		FromStates: []string{"idle"}, ToStates: []string{"chase"}, Event: "SEE",
+	}); err != nil {
+		t.Errorf("AppendTransition on a bootstrapped database: %v", err)
+	}
+}
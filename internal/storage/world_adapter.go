@@ -1,12 +1,16 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
 
 	"github.com/tmbritton/ecs-db/internal/agent"
+	"github.com/tmbritton/ecs-db/internal/schema"
+	"github.com/tmbritton/ecs-db/internal/world"
 )
 
 var safeIdentifier = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
@@ -20,24 +24,54 @@ func validateIdentifier(s, context string) error {
 
 // txWorldWriter implements agent.WorldWriter using a live *sql.Tx.
 // Table names are "comp_" + lowercase(compName); column names are lowercase(field).
-// No schema reference is required — callers supply valid component and field names.
-type txWorldWriter struct{ tx *sql.Tx }
+// The schema is optional: without one, callers supply valid component and
+// field names and values are written as given; with one, omitted fields are
+// filled from declared defaults and values are checked against property
+// constraints and refTypes before they reach SQL.
+type txWorldWriter struct {
+	tx        *sql.Tx
+	schema    *schema.DatabaseSchema
+	behaviors *Behaviors
+}
 
 // NewTxWorldWriter wraps tx to produce an agent.WorldWriter.
 func NewTxWorldWriter(tx *sql.Tx) agent.WorldWriter { return &txWorldWriter{tx: tx} }
 
-func (w *txWorldWriter) SpawnEntity(entityType string) (int64, error) {
-	res, err := w.tx.Exec(
-		"INSERT INTO entities (entity_type, created_tick) VALUES (?, 0)", entityType,
-	)
-	if err != nil {
-		return 0, fmt.Errorf("SpawnEntity: %w", err)
+// NewTxWorldWriterWithSchema wraps tx to produce an agent.WorldWriter that
+// applies the property defaults declared in s and rejects values violating
+// its property constraints or referencing entities of the wrong type.
+func NewTxWorldWriterWithSchema(tx *sql.Tx, s schema.DatabaseSchema) agent.WorldWriter {
+	return &txWorldWriter{tx: tx, schema: &s}
+}
+
+// checkValues returns the constraint and refType violations for values as
+// a single error wrapping each *world.FieldError, or nil when no schema is
+// set. Referenced entities are looked up through the transaction, so one
+// created earlier in it counts.
+func (w *txWorldWriter) checkValues(compName string, values map[string]any) error {
+	if w.schema == nil {
+		return nil
 	}
-	id, err := res.LastInsertId()
+	fieldErrs := world.ValidateComponentValues(w.schema, compName, values)
+	refErrs, err := world.CheckRefTypes(context.Background(), w.schema, w.entityType, compName, values)
 	if err != nil {
-		return 0, fmt.Errorf("SpawnEntity: LastInsertId: %w", err)
+		return err
 	}
-	return id, nil
+	fieldErrs = append(fieldErrs, refErrs...)
+	if len(fieldErrs) == 0 {
+		return nil
+	}
+	errs := make([]error, len(fieldErrs))
+	for i, fe := range fieldErrs {
+		errs[i] = fe
+	}
+	return errors.Join(errs...)
+}
+
+// entityType reads the type of an entity through the transaction; it is
+// the world.EntityTypeLookup of the writer.
+func (w *txWorldWriter) entityType(ctx context.Context, entityID int64) (string, error) {
+	return readEntityType(ctx, w.tx.QueryRowContext, entityID)
 }
 
 func (w *txWorldWriter) AttachComponent(entityID int64, compName string, values map[string]any) error {
@@ -45,6 +79,14 @@ func (w *txWorldWriter) AttachComponent(entityID int64, compName string, values
 	if err := validateIdentifier(strings.TrimPrefix(table, "comp_"), "AttachComponent compName"); err != nil {
 		return err
 	}
+	if w.schema != nil {
+		if comp, canonical := schema.ComponentByName(w.schema, compName); canonical != "" {
+			values = comp.WithDefaults(values)
+		}
+	}
+	if err := w.checkValues(compName, values); err != nil {
+		return fmt.Errorf("AttachComponent %q: %w", compName, err)
+	}
 	cols := []string{"entity_id"}
 	args := []any{entityID}
 	for col, val := range values {
@@ -81,6 +123,10 @@ func (w *txWorldWriter) DetachComponent(entityID int64, compName string) error {
 	return nil
 }
 
+// SetComponentValue updates one field of the entity's component row. With
+// a schema, the value must satisfy the property's constraints and writes
+// to computed properties are refused; without one, SQLite refuses writes
+// to their generated columns itself.
 func (w *txWorldWriter) SetComponentValue(entityID int64, compName, field string, value any) error {
 	table := "comp_" + strings.ToLower(compName)
 	col := strings.ToLower(field)
@@ -90,6 +136,9 @@ func (w *txWorldWriter) SetComponentValue(entityID int64, compName, field string
 	if err := validateIdentifier(col, "SetComponentValue field"); err != nil {
 		return err
 	}
+	if err := w.checkValues(compName, map[string]any{field: value}); err != nil {
+		return fmt.Errorf("SetComponentValue %q.%q: %w", compName, field, err)
+	}
 	_, err := w.tx.Exec(
 		fmt.Sprintf("UPDATE %s SET %s = ? WHERE entity_id = ?", table, col),
 		value, entityID,
@@ -100,13 +149,62 @@ func (w *txWorldWriter) SetComponentValue(entityID int64, compName, field string
 	return nil
 }
 
+// SetResource updates one property of the resource's single row, in
+// res_<lowercase(name)>. With a schema, the resource and property must be
+// declared and the value must satisfy the property's constraints.
+func (w *txWorldWriter) SetResource(name, field string, value any) error {
+	table := "res_" + strings.ToLower(name)
+	col := strings.ToLower(field)
+	if err := validateIdentifier(strings.TrimPrefix(table, "res_"), "SetResource name"); err != nil {
+		return err
+	}
+	if err := validateIdentifier(col, "SetResource field"); err != nil {
+		return err
+	}
+	if w.schema != nil {
+		res, canonical := schema.ResourceByName(w.schema, name)
+		if canonical == "" {
+			return fmt.Errorf("SetResource %q: resource is not declared", name)
+		}
+		prop, ok := schema.PropertyByName(res.Properties, field)
+		if !ok {
+			return fmt.Errorf("SetResource %q.%q: property is not declared", name, field)
+		}
+		if err := prop.CheckValue(value); err != nil {
+			return fmt.Errorf("SetResource %q.%q: %w", name, field, err)
+		}
+	}
+	result, err := w.tx.Exec(fmt.Sprintf("UPDATE %s SET %s = ? WHERE id = 1", table, col), value)
+	if err != nil {
+		return fmt.Errorf("SetResource %q.%q: %w", name, field, err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("SetResource %q.%q: %s has no row", name, field, table)
+	}
+	return nil
+}
+
 // txWorldReader implements agent.WorldReader using a live *sql.Tx.
 // Reads within the same transaction see uncommitted writes from the same tx.
-type txWorldReader struct{ tx *sql.Tx }
+// The schema is optional: with one, a NULL stored in a nullable property is
+// returned as a typed nil pointer so callers can tell it apart from a
+// component that is not attached (an untyped nil), and entity type lookups
+// follow extends.
+type txWorldReader struct {
+	tx     *sql.Tx
+	schema *schema.DatabaseSchema
+}
 
 // NewTxWorldReader wraps tx to produce an agent.WorldReader.
 func NewTxWorldReader(tx *sql.Tx) agent.WorldReader { return &txWorldReader{tx: tx} }
 
+// NewTxWorldReaderWithSchema wraps tx to produce an agent.WorldReader that
+// reports NULL property values as typed nil pointers (see nullValue) and
+// matches entity subtypes in FindEntityByType and IsA.
+func NewTxWorldReaderWithSchema(tx *sql.Tx, s schema.DatabaseSchema) agent.WorldReader {
+	return &txWorldReader{tx: tx, schema: &s}
+}
+
 func (r *txWorldReader) GetComponentValue(entityID int64, compName, field string) (any, error) {
 	table := "comp_" + strings.ToLower(compName)
 	col := strings.ToLower(field)
@@ -126,6 +224,70 @@ func (r *txWorldReader) GetComponentValue(entityID int64, compName, field string
 	if err != nil {
 		return nil, fmt.Errorf("GetComponentValue %q.%q: %w", compName, field, err)
 	}
+	if val == nil {
+		return r.nullValue(compName, field), nil
+	}
+	return val, nil
+}
+
+// nullValue returns the typed nil standing in for a NULL column value:
+// (*int64)(nil) for integer, boolean and entity-ref properties,
+// (*float64)(nil) for number properties and (*string)(nil) for string,
+// object and array properties. Without a schema, or for a field the schema
+// does not know, it returns an untyped nil.
+func (r *txWorldReader) nullValue(compName, field string) any {
+	if r.schema == nil {
+		return nil
+	}
+	comp, canonical := schema.ComponentByName(r.schema, compName)
+	if canonical == "" || comp.Type != schema.ComponentTypeObject {
+		return nil
+	}
+	prop, ok := schema.PropertyByName(comp.Properties, field)
+	if !ok {
+		return nil
+	}
+	return typedNil(prop)
+}
+
+// typedNil returns the typed nil pointer nullValue describes for prop.
+func typedNil(prop schema.Property) any {
+	switch prop.Type {
+	case schema.PropertyTypeInteger, schema.PropertyTypeBoolean, schema.PropertyTypeEntityRef:
+		return (*int64)(nil)
+	case schema.PropertyTypeNumber:
+		return (*float64)(nil)
+	default:
+		return (*string)(nil)
+	}
+}
+
+// GetResource returns one property of the resource's single row. With a
+// schema, a NULL is returned as a typed nil pointer, as GetComponentValue
+// does for nullable properties.
+func (r *txWorldReader) GetResource(name, field string) (any, error) {
+	table := "res_" + strings.ToLower(name)
+	col := strings.ToLower(field)
+	if err := validateIdentifier(strings.TrimPrefix(table, "res_"), "GetResource name"); err != nil {
+		return nil, err
+	}
+	if err := validateIdentifier(col, "GetResource field"); err != nil {
+		return nil, err
+	}
+	var val any
+	err := r.tx.QueryRow(fmt.Sprintf("SELECT %s FROM %s WHERE id = 1", col, table)).Scan(&val)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GetResource %q.%q: %w", name, field, err)
+	}
+	if val == nil && r.schema != nil {
+		res, canonical := schema.ResourceByName(r.schema, name)
+		if prop, ok := schema.PropertyByName(res.Properties, field); canonical != "" && ok {
+			return typedNil(prop), nil
+		}
+	}
 	return val, nil
 }
 
@@ -147,10 +309,21 @@ func (r *txWorldReader) HasComponent(entityID int64, compName string) (bool, err
 	return true, nil
 }
 
+// FindEntityByType returns the lowest-id entity of entityType. With a
+// schema, entities of any subtype of entityType match as well.
 func (r *txWorldReader) FindEntityByType(entityType string) (int64, error) {
+	types := []string{entityType}
+	if r.schema != nil {
+		types = schema.Subtypes(r.schema, entityType)
+	}
+	args := make([]any, len(types))
+	for i, t := range types {
+		args[i] = t
+	}
 	var id int64
 	err := r.tx.QueryRow(
-		"SELECT id FROM entities WHERE entity_type = ? LIMIT 1", entityType,
+		"SELECT id FROM entities WHERE entity_type IN (?"+strings.Repeat(", ?", len(types)-1)+") ORDER BY id LIMIT 1",
+		args...,
 	).Scan(&id)
 	if err == sql.ErrNoRows {
 		return 0, fmt.Errorf("no entity of type %q", entityType)
@@ -161,6 +334,23 @@ func (r *txWorldReader) FindEntityByType(entityType string) (int64, error) {
 	return id, nil
 }
 
+// IsA reports whether the entity's type is ancestor or, with a schema, one
+// of its subtypes. A missing entity is not an error; it is not anything.
+func (r *txWorldReader) IsA(entityID int64, ancestor string) (bool, error) {
+	var entityType string
+	err := r.tx.QueryRow("SELECT entity_type FROM entities WHERE id = ?", entityID).Scan(&entityType)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("IsA %q: %w", ancestor, err)
+	}
+	if r.schema == nil {
+		return entityType == ancestor, nil
+	}
+	return schema.IsA(r.schema, entityType, ancestor), nil
+}
+
 // Compile-time interface checks.
 var (
 	_ agent.WorldWriter = (*txWorldWriter)(nil)
@@ -2,9 +2,12 @@ package storage_test
 
 import (
 	"database/sql"
+	"errors"
 	"testing"
 
+	"github.com/tmbritton/ecs-db/internal/schema"
 	"github.com/tmbritton/ecs-db/internal/storage"
+	"github.com/tmbritton/ecs-db/internal/world"
 	_ "modernc.org/sqlite"
 )
 
@@ -17,6 +20,8 @@ func setupAdapterDB(t *testing.T) *sql.DB {
 	t.Cleanup(func() { db.Close() })
 	stmts := []string{
 		`CREATE TABLE entities (id INTEGER PRIMARY KEY AUTOINCREMENT, entity_type TEXT NOT NULL, created_tick INTEGER NOT NULL DEFAULT 0)`,
+		`CREATE TABLE world (key TEXT PRIMARY KEY, value TEXT NOT NULL)`,
+		`INSERT INTO world (key, value) VALUES ('current_tick', '3')`,
 		`CREATE TABLE comp_position (entity_id INTEGER PRIMARY KEY, x REAL NOT NULL DEFAULT 0, y REAL NOT NULL DEFAULT 0)`,
 		`CREATE TABLE comp_health   (entity_id INTEGER PRIMARY KEY, hp REAL NOT NULL DEFAULT 100)`,
 	}
@@ -45,7 +50,7 @@ func TestTxWorldWriter_SpawnEntity(t *testing.T) {
 	tx := beginAdapterTx(t, db)
 	w := storage.NewTxWorldWriter(tx)
 
-	id, err := w.SpawnEntity("Goblin")
+	id, err := w.SpawnEntity("Goblin", map[string]map[string]any{"Position": {"x": 3.0}})
 	if err != nil {
 		t.Fatalf("SpawnEntity: %v", err)
 	}
@@ -54,10 +59,19 @@ func TestTxWorldWriter_SpawnEntity(t *testing.T) {
 	}
 
 	var entityType string
-	_ = db.QueryRow("SELECT entity_type FROM entities WHERE id = ?", id).Scan(&entityType)
+	var createdTick int64
+	_ = db.QueryRow("SELECT entity_type, created_tick FROM entities WHERE id = ?", id).Scan(&entityType, &createdTick)
 	if entityType != "Goblin" {
 		t.Errorf("entity_type = %q, want Goblin", entityType)
 	}
+	if createdTick != 3 {
+		t.Errorf("created_tick = %d, want the current tick 3", createdTick)
+	}
+	var x, y float64
+	_ = db.QueryRow("SELECT x, y FROM comp_position WHERE entity_id = ?", id).Scan(&x, &y)
+	if x != 3.0 || y != 0 {
+		t.Errorf("position = (%v, %v), want (3, 0)", x, y)
+	}
 }
 
 func TestTxWorldWriter_AttachComponent(t *testing.T) {
@@ -116,6 +130,139 @@ func TestTxWorldWriter_SetComponentValue(t *testing.T) {
 	}
 }
 
+func constrainedHealthSchema() schema.DatabaseSchema {
+	minHP := 0.0
+	return schema.DatabaseSchema{
+		SchemaVersion: 1,
+		Components: map[string]schema.Component{
+			"Health": {
+				Type: schema.ComponentTypeObject,
+				Properties: map[string]schema.Property{
+					"hp": {Type: schema.PropertyTypeNumber, Minimum: &minHP},
+				},
+			},
+		},
+	}
+}
+
+func TestTxWorldWriter_WithSchema_SetComponentValueRejectsViolation(t *testing.T) {
+	db := setupAdapterDB(t)
+	tx := beginAdapterTx(t, db)
+	w := storage.NewTxWorldWriterWithSchema(tx, constrainedHealthSchema())
+
+	_, _ = tx.Exec("INSERT INTO entities (entity_type, created_tick) VALUES ('Goblin', 0)")
+	_, _ = tx.Exec("INSERT INTO comp_health (entity_id, hp) VALUES (1, 10)")
+
+	err := w.SetComponentValue(1, "Health", "hp", -40.0)
+	var fe *world.FieldError
+	if !errors.As(err, &fe) {
+		t.Fatalf("SetComponentValue error = %v, want *world.FieldError", err)
+	}
+	if fe.Component != "Health" || fe.Field != "hp" {
+		t.Errorf("FieldError = %+v, want Health.hp", fe)
+	}
+
+	if err := w.SetComponentValue(1, "Health", "hp", 5.0); err != nil {
+		t.Fatalf("SetComponentValue valid value: %v", err)
+	}
+	var hp float64
+	_ = tx.QueryRow("SELECT hp FROM comp_health WHERE entity_id = 1").Scan(&hp)
+	if hp != 5.0 {
+		t.Errorf("hp = %v, want 5", hp)
+	}
+}
+
+func TestTxWorldWriter_WithSchema_AttachComponentRejectsViolation(t *testing.T) {
+	db := setupAdapterDB(t)
+	tx := beginAdapterTx(t, db)
+	w := storage.NewTxWorldWriterWithSchema(tx, constrainedHealthSchema())
+
+	_, _ = tx.Exec("INSERT INTO entities (entity_type, created_tick) VALUES ('Goblin', 0)")
+	err := w.AttachComponent(1, "Health", map[string]any{"hp": -1.0})
+	var fe *world.FieldError
+	if !errors.As(err, &fe) {
+		t.Fatalf("AttachComponent error = %v, want *world.FieldError", err)
+	}
+
+	var n int
+	_ = tx.QueryRow("SELECT count(*) FROM comp_health").Scan(&n)
+	if n != 0 {
+		t.Errorf("comp_health rows = %d, want 0 after rejected attach", n)
+	}
+}
+
+func TestTxWorldWriter_WithSchema_AttachComponentFillsDefaults(t *testing.T) {
+	db := setupAdapterDB(t)
+	tx := beginAdapterTx(t, db)
+	w := storage.NewTxWorldWriterWithSchema(tx, schema.DatabaseSchema{
+		SchemaVersion: 1,
+		Components: map[string]schema.Component{
+			"Position": {
+				Type: schema.ComponentTypeObject,
+				Properties: map[string]schema.Property{
+					"x": {Type: schema.PropertyTypeNumber},
+					"y": {Type: schema.PropertyTypeNumber, Default: 7.0},
+				},
+			},
+		},
+	})
+
+	_, _ = tx.Exec("INSERT INTO entities (entity_type, created_tick) VALUES ('Goblin', 0)")
+	if err := w.AttachComponent(1, "Position", map[string]any{"x": 3.0}); err != nil {
+		t.Fatalf("AttachComponent: %v", err)
+	}
+	var x, y float64
+	_ = tx.QueryRow("SELECT x, y FROM comp_position WHERE entity_id = 1").Scan(&x, &y)
+	if x != 3.0 || y != 7.0 {
+		t.Errorf("position = (%v, %v), want (3, 7)", x, y)
+	}
+}
+
+func TestTxWorldWriter_WithSchema_RejectsWrongRefType(t *testing.T) {
+	db := setupAdapterDB(t)
+	tx := beginAdapterTx(t, db)
+	w := storage.NewTxWorldWriterWithSchema(tx, schema.DatabaseSchema{
+		SchemaVersion: 1,
+		Components: map[string]schema.Component{
+			"Target": {
+				Type: schema.ComponentTypeObject,
+				Properties: map[string]schema.Property{
+					"who": {Type: schema.PropertyTypeEntityRef, RefType: "Goblin"},
+				},
+			},
+		},
+		EntityTypes: map[string]schema.EntityType{"Goblin": {}, "Tree": {}},
+	})
+
+	for _, q := range []string{
+		"CREATE TABLE comp_target (entity_id INTEGER PRIMARY KEY, who INTEGER)",
+		"INSERT INTO entities (entity_type, created_tick) VALUES ('Goblin', 0), ('Tree', 0), ('Goblin', 0)",
+	} {
+		if _, err := tx.Exec(q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+
+	err := w.AttachComponent(3, "Target", map[string]any{"who": int64(2)})
+	var fe *world.FieldError
+	if !errors.As(err, &fe) || fe.Field != "who" {
+		t.Fatalf("AttachComponent to a Tree error = %v, want *world.FieldError on who", err)
+	}
+	if err := w.AttachComponent(3, "Target", map[string]any{"who": int64(1)}); err != nil {
+		t.Fatalf("AttachComponent to a Goblin: %v", err)
+	}
+	err = w.SetComponentValue(3, "Target", "who", int64(2))
+	if !errors.As(err, &fe) || fe.Field != "who" {
+		t.Fatalf("SetComponentValue to a Tree error = %v, want *world.FieldError on who", err)
+	}
+
+	var who int64
+	_ = tx.QueryRow("SELECT who FROM comp_target WHERE entity_id = 3").Scan(&who)
+	if who != 1 {
+		t.Errorf("who = %d, want 1 after the rejected write", who)
+	}
+}
+
 // ── txWorldReader ─────────────────────────────────────────────────────────────
 
 func TestTxWorldReader_GetComponentValue(t *testing.T) {
@@ -158,6 +305,58 @@ func TestTxWorldReader_GetComponentValue_Missing(t *testing.T) {
 	}
 }
 
+func TestTxWorldReader_WithSchema_NullReturnsTypedNil(t *testing.T) {
+	db := setupAdapterDB(t)
+	if _, err := db.Exec(`CREATE TABLE comp_target (entity_id INTEGER PRIMARY KEY, target_x REAL, leader INTEGER, label TEXT)`); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	_, _ = db.Exec("INSERT INTO comp_target (entity_id) VALUES (1)")
+
+	s := schema.DatabaseSchema{
+		SchemaVersion: 1,
+		Components: map[string]schema.Component{
+			"Target": {
+				Type: schema.ComponentTypeObject,
+				Properties: map[string]schema.Property{
+					"target_x": {Type: schema.PropertyTypeNumber, Nullable: true},
+					"leader":   {Type: schema.PropertyTypeEntityRef, Nullable: true},
+					"label":    {Type: schema.PropertyTypeString, Nullable: true},
+				},
+			},
+		},
+	}
+	tx := beginAdapterTx(t, db)
+	r := storage.NewTxWorldReaderWithSchema(tx, s)
+
+	tests := []struct {
+		field string
+		check func(any) bool
+		want  string
+	}{
+		{"target_x", func(v any) bool { p, ok := v.(*float64); return ok && p == nil }, "(*float64)(nil)"},
+		{"leader", func(v any) bool { p, ok := v.(*int64); return ok && p == nil }, "(*int64)(nil)"},
+		{"label", func(v any) bool { p, ok := v.(*string); return ok && p == nil }, "(*string)(nil)"},
+	}
+	for _, tt := range tests {
+		val, err := r.GetComponentValue(1, "Target", tt.field)
+		if err != nil {
+			t.Fatalf("GetComponentValue(%q): %v", tt.field, err)
+		}
+		if !tt.check(val) {
+			t.Errorf("GetComponentValue(%q) = %#v, want %s", tt.field, val, tt.want)
+		}
+	}
+
+	// A component that is not attached is still reported as an untyped nil.
+	val, err := r.GetComponentValue(2, "Target", "target_x")
+	if err != nil {
+		t.Fatalf("GetComponentValue on missing row: %v", err)
+	}
+	if val != nil {
+		t.Errorf("expected untyped nil for missing entity, got %#v", val)
+	}
+}
+
 func TestTxWorldReader_HasComponent(t *testing.T) {
 	db := setupAdapterDB(t)
 	_, _ = db.Exec("INSERT INTO comp_health (entity_id, hp) VALUES (1, 100)")
@@ -196,6 +395,49 @@ func TestTxWorldReader_FindEntityByType(t *testing.T) {
 	}
 }
 
+func TestTxWorldReader_Subtypes(t *testing.T) {
+	db := setupAdapterDB(t)
+	db.Exec("INSERT INTO entities (entity_type, created_tick) VALUES ('Player', 0)")
+	res, _ := db.Exec("INSERT INTO entities (entity_type, created_tick) VALUES ('GoblinArcher', 0)")
+	archerID, _ := res.LastInsertId()
+
+	s := schema.DatabaseSchema{
+		EntityTypes: map[string]schema.EntityType{
+			"Goblin":       {},
+			"GoblinArcher": {Extends: "Goblin"},
+		},
+	}
+	tx := beginAdapterTx(t, db)
+	r := storage.NewTxWorldReaderWithSchema(tx, s)
+
+	id, err := r.FindEntityByType("Goblin")
+	if err != nil {
+		t.Fatalf("FindEntityByType(Goblin): %v", err)
+	}
+	if id != archerID {
+		t.Errorf("id = %d, want GoblinArcher %d", id, archerID)
+	}
+
+	for ancestor, want := range map[string]bool{"Goblin": true, "GoblinArcher": true, "Player": false} {
+		got, err := r.IsA(archerID, ancestor)
+		if err != nil {
+			t.Fatalf("IsA(%q): %v", ancestor, err)
+		}
+		if got != want {
+			t.Errorf("IsA(%q) = %v, want %v", ancestor, got, want)
+		}
+	}
+
+	// Without a schema only the exact type matches.
+	plain := storage.NewTxWorldReader(tx)
+	if _, err := plain.FindEntityByType("Goblin"); err == nil {
+		t.Error("FindEntityByType(Goblin) without schema: want error, got nil")
+	}
+	if got, _ := plain.IsA(archerID, "GoblinArcher"); !got {
+		t.Error("IsA(GoblinArcher) without schema = false, want true")
+	}
+}
+
 func TestTxWorldReader_FindEntityByType_Missing(t *testing.T) {
 	db := setupAdapterDB(t)
 	tx := beginAdapterTx(t, db)
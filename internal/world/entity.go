@@ -1,5 +1,7 @@
 package world
 
+import "sort"
+
 // Entity represents a single game entity — a unique identity with a
 // declared type and a creation tick. In pure ECS, entities are opaque
 // IDs carrying no data of their own; here we track the entity type so
@@ -17,3 +19,65 @@ type EntityComponent struct {
 	Name   string
 	Values map[string]interface{}
 }
+
+// EntityComponents converts component name → values, the shape prefabs and
+// action params use, to EntityComponents in name order.
+func EntityComponents(components map[string]map[string]interface{}) []EntityComponent {
+	names := make([]string, 0, len(components))
+	for name := range components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := make([]EntityComponent, len(names))
+	for i, name := range names {
+		out[i] = EntityComponent{Name: name, Values: components[name]}
+	}
+	return out
+}
+
+// EntitySnapshot is an entity with everything attached to it, as read by
+// GetEntity for the debugger and other inspection tools.
+type EntitySnapshot struct {
+	Entity
+	// Components maps each attached component to its values, in the shape
+	// CreateEntity accepts: properties by declared name for object
+	// components, "value" for single-value and array components,
+	// "target_entity_id" for entity-refs and no values for tags. JSON
+	// columns are decoded and booleans are reported as bool.
+	Components map[string]map[string]interface{}
+	// Machines lists the entity's active state machines by machine id.
+	Machines []MachineState
+}
+
+// MachineState is the persisted state of one behavior machine on an
+// entity, from behavior_components.
+type MachineState struct {
+	MachineID string
+	States    []string
+	// UpdatedAt is the tick of the machine's last transition.
+	UpdatedAt int64
+}
+
+// EntityFilter selects the entities ListEntities returns. The zero value
+// matches every entity.
+type EntityFilter struct {
+	// EntityType restricts the list to one type and its subtypes.
+	EntityType string
+	// Components restricts the list to entities that have every named
+	// component.
+	Components []string
+	// Limit caps the page size; zero or less means DefaultEntityPageSize.
+	Limit int
+}
+
+// DefaultEntityPageSize is the page size ListEntities uses when the
+// filter sets no limit.
+const DefaultEntityPageSize = 100
+
+// EntityPage is one page of ListEntities results, in ascending id order.
+type EntityPage struct {
+	Entities []Entity
+	// NextCursor is passed to ListEntities to fetch the following page.
+	// It is 0 on the last page.
+	NextCursor int64
+}
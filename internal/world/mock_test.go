@@ -7,8 +7,14 @@ type mockTx struct {
 	insertEntityResults []insertEntityResult
 	insertEntityIdx     int
 	insertCompErr       error
+	inserted            []EntityComponent // components passed to InsertComponent
 	attachCompErr       error
 	detachCompErr       error
+	addRelationErr      error
+	removeRelationErr   error
+	destroyErr          error
+	destroyed           []int64 // ids passed to DestroyEntity
+	destroyTick         int64
 	commitErr           error
 	rollbackErr         error
 	committed           bool
@@ -30,6 +36,7 @@ func (m *mockTx) InsertEntity(ctx context.Context, entityType string, createdTic
 }
 
 func (m *mockTx) InsertComponent(ctx context.Context, entityID int64, compName string, values map[string]interface{}) error {
+	m.inserted = append(m.inserted, EntityComponent{Name: compName, Values: values})
 	return m.insertCompErr
 }
 
@@ -41,6 +48,20 @@ func (m *mockTx) DetachComponent(ctx context.Context, entityID int64, compName s
 	return m.detachCompErr
 }
 
+func (m *mockTx) AddRelation(ctx context.Context, sourceID int64, relName string, targetID int64, values map[string]interface{}) error {
+	return m.addRelationErr
+}
+
+func (m *mockTx) RemoveRelation(ctx context.Context, sourceID int64, relName string, targetID int64) error {
+	return m.removeRelationErr
+}
+
+func (m *mockTx) DestroyEntity(ctx context.Context, entityID int64, tick int64) error {
+	m.destroyed = append(m.destroyed, entityID)
+	m.destroyTick = tick
+	return m.destroyErr
+}
+
 func (m *mockTx) Commit() error {
 	m.committed = true
 	return m.commitErr
@@ -59,8 +80,15 @@ type mockStore struct {
 	tx              *mockTx
 	entityType      string
 	entityTypeErr   error
+	entityTypes     map[int64]string // per-id types; nil uses entityType for every id
 	hasComponent    bool
 	hasComponentErr error
+	related         []int64
+	relatedErr      error
+	snapshot        *EntitySnapshot
+	snapshotErr     error
+	page            EntityPage
+	listFilter      EntityFilter // the filter passed to the last ListEntities
 }
 
 func (m *mockStore) BeginTx(ctx context.Context) (Tx, error) {
@@ -75,9 +103,32 @@ func (m *mockStore) GetCurrentTick(ctx context.Context) (int64, error) {
 }
 
 func (m *mockStore) GetEntityType(ctx context.Context, entityID int64) (string, error) {
+	if m.entityTypes != nil {
+		if et, ok := m.entityTypes[entityID]; ok {
+			return et, nil
+		}
+		return "", &EntityNotFoundError{ID: entityID}
+	}
 	return m.entityType, m.entityTypeErr
 }
 
 func (m *mockStore) HasComponent(ctx context.Context, entityID int64, compName string) (bool, error) {
 	return m.hasComponent, m.hasComponentErr
 }
+
+func (m *mockStore) RelatedEntities(ctx context.Context, entityID int64, relName string) ([]int64, error) {
+	return m.related, m.relatedErr
+}
+
+func (m *mockStore) EntitiesRelatedTo(ctx context.Context, relName string, targetID int64) ([]int64, error) {
+	return m.related, m.relatedErr
+}
+
+func (m *mockStore) GetEntity(ctx context.Context, entityID int64) (*EntitySnapshot, error) {
+	return m.snapshot, m.snapshotErr
+}
+
+func (m *mockStore) ListEntities(ctx context.Context, filter EntityFilter, cursor int64) (EntityPage, error) {
+	m.listFilter = filter
+	return m.page, nil
+}
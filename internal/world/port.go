@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 )
 
 // Tx is the domain-side interface for a database transaction. The storage
@@ -20,6 +21,18 @@ type Tx interface {
 	AttachComponent(ctx context.Context, entityID int64, compName string, values map[string]interface{}) error
 	// DetachComponent deletes the component row for the given entity.
 	DetachComponent(ctx context.Context, entityID int64, compName string) error
+	// AddRelation inserts a row into the rel_* table of the named relation
+	// linking source to target, with the given property values.
+	// Returns errors.Is(err, ErrRelationExists) if the pair is already related.
+	AddRelation(ctx context.Context, sourceID int64, relName string, targetID int64, values map[string]interface{}) error
+	// RemoveRelation deletes the row linking source to target.
+	RemoveRelation(ctx context.Context, sourceID int64, relName string, targetID int64) error
+	// DestroyEntity deletes the entity. Its comp_*, rel_* and
+	// behavior_components rows are deleted with it, entity-refs pointing at
+	// it follow their onDelete policy, its pending event_queue rows are
+	// deleted and a despawn row is appended to transitions at tick.
+	// Returns an *EntityReferencedError if a reference refuses the delete.
+	DestroyEntity(ctx context.Context, entityID int64, tick int64) error
 	// Commit commits the transaction.
 	Commit() error
 	// Rollback rolls back the transaction.
@@ -29,6 +42,10 @@ type Tx interface {
 // ErrAlreadyAttached is returned when an attach would duplicate a component.
 var ErrAlreadyAttached = errors.New("component already attached")
 
+// ErrRelationExists is returned when adding a relation would duplicate a
+// (source, target) pair.
+var ErrRelationExists = errors.New("relation already exists")
+
 // EntityStore is the port the entity service uses for persistence.
 // The SQLite adapter implements this interface.
 type EntityStore interface {
@@ -42,6 +59,20 @@ type EntityStore interface {
 	GetEntityType(ctx context.Context, entityID int64) (string, error)
 	// HasComponent returns true if the entity has the named component attached.
 	HasComponent(ctx context.Context, entityID int64, compName string) (bool, error)
+	// RelatedEntities returns the targets the entity relates to through the
+	// named relation, in ascending id order.
+	RelatedEntities(ctx context.Context, entityID int64, relName string) ([]int64, error)
+	// EntitiesRelatedTo returns the sources that relate to the target
+	// through the named relation, in ascending id order: every entity that
+	// is ChildOf X.
+	EntitiesRelatedTo(ctx context.Context, relName string, targetID int64) ([]int64, error)
+	// GetEntity returns the entity with its component values and machine
+	// states. Returns an *EntityNotFoundError if the entity does not exist.
+	GetEntity(ctx context.Context, entityID int64) (*EntitySnapshot, error)
+	// ListEntities returns the page of entities matching filter whose ids
+	// follow cursor, the NextCursor of the previous page or 0 for the
+	// first.
+	ListEntities(ctx context.Context, filter EntityFilter, cursor int64) (EntityPage, error)
 }
 
 // IsAlreadyAttached reports whether an error is the ErrAlreadyAttached sentinel.
@@ -57,3 +88,21 @@ type EntityNotFoundError struct {
 func (e *EntityNotFoundError) Error() string {
 	return fmt.Sprintf("entity %d not found", e.ID)
 }
+
+// EntityReferencedError is returned when an entity cannot be destroyed
+// because entity-refs without an onDelete policy, or with "restrict",
+// still point at it.
+type EntityReferencedError struct {
+	ID int64
+	// References describes each blocking reference, such as
+	// "Follower.leader of entity 4". It may be empty when the store can
+	// only tell that the foreign key check failed.
+	References []string
+}
+
+func (e *EntityReferencedError) Error() string {
+	if len(e.References) == 0 {
+		return fmt.Sprintf("entity %d is still referenced", e.ID)
+	}
+	return fmt.Sprintf("entity %d is still referenced by %s", e.ID, strings.Join(e.References, ", "))
+}
@@ -0,0 +1,28 @@
+package world
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tmbritton/ecs-db/internal/schema"
+)
+
+// Spawn creates an entity from a prefab declared in the schema. prefab is
+// the prefab name, or "name/variant" for one of its variants; overrides
+// are merged over the prefab's component values field by field, and
+// ranges are drawn afresh for every call (see schema.InstantiatePrefab).
+// The entity is then validated and created as by CreateEntity.
+func (s *EntityService) Spawn(
+	ctx context.Context,
+	prefab string,
+	overrides map[string]map[string]interface{},
+) (*Entity, error) {
+	if s.schema == nil {
+		return nil, fmt.Errorf("spawning prefab %q: no schema set", prefab)
+	}
+	entityType, components, err := schema.InstantiatePrefab(s.schema, prefab, overrides, nil)
+	if err != nil {
+		return nil, fmt.Errorf("spawning: %w", err)
+	}
+	return s.CreateEntity(ctx, entityType, EntityComponents(components))
+}
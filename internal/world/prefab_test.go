@@ -0,0 +1,79 @@
+package world
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tmbritton/ecs-db/internal/schema"
+)
+
+func prefabServiceSchema() schema.DatabaseSchema {
+	s := baseSchema()
+	s.Prefabs = map[string]schema.Prefab{
+		"Grunt": {
+			EntityType: "Goblin",
+			Components: map[string]map[string]interface{}{
+				"Position": {"x": 0.0, "y": 0.0},
+				"Health":   {"hp": 8.0},
+			},
+			Variants: map[string]schema.PrefabVariant{
+				"elite": {Components: map[string]map[string]interface{}{"Health": {"hp": 20.0}}},
+			},
+		},
+	}
+	return s
+}
+
+func TestEntityService_Spawn(t *testing.T) {
+	tx := &mockTx{insertEntityResults: []insertEntityResult{{id: 3}}}
+	svc := NewEntityService(&mockStore{currentTick: 2, tx: tx})
+	svc.SetSchema(prefabServiceSchema())
+
+	e, err := svc.Spawn(context.Background(), "Grunt/elite", map[string]map[string]interface{}{
+		"Position": {"x": 4.0},
+	})
+	if err != nil {
+		t.Fatalf("Spawn: %v", err)
+	}
+	if e.ID != 3 || e.EntityType != "Goblin" || e.CreatedTick != 2 {
+		t.Errorf("entity = %+v, want Goblin 3 created at tick 2", e)
+	}
+	want := []EntityComponent{
+		{Name: "Health", Values: map[string]interface{}{"hp": 20.0}},
+		{Name: "Position", Values: map[string]interface{}{"x": 4.0, "y": 0.0}},
+	}
+	if !reflect.DeepEqual(tx.inserted, want) {
+		t.Errorf("inserted %v, want %v", tx.inserted, want)
+	}
+}
+
+func TestEntityService_Spawn_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		prefab    string
+		overrides map[string]map[string]interface{}
+		wantErr   string
+	}{
+		{"undeclared prefab", "Orc", nil, `prefab "Orc" is not declared`},
+		{"undeclared variant", "Grunt/giant", nil, `prefab "Grunt" has no variant "giant"`},
+		{"override fails validation", "Grunt", map[string]map[string]interface{}{"Mana": {}},
+			`component "Mana" is not declared`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := &mockTx{}
+			svc := NewEntityService(&mockStore{tx: tx})
+			svc.SetSchema(prefabServiceSchema())
+
+			_, err := svc.Spawn(context.Background(), tt.prefab, tt.overrides)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+			}
+			if len(tx.inserted) != 0 || tx.committed {
+				t.Error("a failed spawn wrote to the store")
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package world
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tmbritton/ecs-db/internal/schema"
+)
+
+// AddRelation links source to target through the named relation component,
+// with the given property values. Both entities must exist, the target
+// must satisfy the relation's refType, and the values are validated like
+// an object component's after defaults are filled in. Runs in its own
+// transaction.
+func (s *EntityService) AddRelation(
+	ctx context.Context,
+	sourceID int64,
+	relName string,
+	targetID int64,
+	values map[string]interface{},
+) error {
+	rel, canonical, err := s.relation("add", sourceID, relName, targetID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.store.GetEntityType(ctx, sourceID); err != nil {
+		return fmt.Errorf("adding relation %s from entity %d: %w", canonical, sourceID, err)
+	}
+	targetType, err := s.store.GetEntityType(ctx, targetID)
+	if err != nil {
+		return fmt.Errorf("adding relation %s to entity %d: %w", canonical, targetID, err)
+	}
+	if rel.RefType != "" && !schema.IsA(s.schema, targetType, rel.RefType) {
+		return &RelationError{
+			Action:   "add",
+			Relation: canonical,
+			SourceID: sourceID,
+			TargetID: targetID,
+			Errors:   []string{fmt.Sprintf("target entity %d has type %q, want %q", targetID, targetType, rel.RefType)},
+		}
+	}
+
+	values = applyDefaults(s.schema, canonical, values)
+	fieldErrs := ValidateComponentValues(s.schema, canonical, values)
+	refErrs, err := CheckRefTypes(ctx, s.schema, s.store.GetEntityType, canonical, values)
+	if err != nil {
+		return fmt.Errorf("adding relation %s: %w", canonical, err)
+	}
+	fieldErrs = append(fieldErrs, refErrs...)
+	if len(fieldErrs) > 0 {
+		return &RelationError{
+			Action:   "add",
+			Relation: canonical,
+			SourceID: sourceID,
+			TargetID: targetID,
+			Errors:   fieldErrorMessages(fieldErrs),
+			Fields:   fieldErrs,
+		}
+	}
+
+	tx, err := s.store.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("adding relation %s: %w", canonical, err)
+	}
+	if err := tx.AddRelation(ctx, sourceID, canonical, targetID, values); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("adding relation %s from entity %d to %d: %w", canonical, sourceID, targetID, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("adding relation %s: %w", canonical, err)
+	}
+	return nil
+}
+
+// RemoveRelation deletes the link from source to target through the named
+// relation. Runs in its own transaction.
+func (s *EntityService) RemoveRelation(ctx context.Context, sourceID int64, relName string, targetID int64) error {
+	_, canonical, err := s.relation("remove", sourceID, relName, targetID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.store.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("removing relation %s: %w", canonical, err)
+	}
+	if err := tx.RemoveRelation(ctx, sourceID, canonical, targetID); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("removing relation %s from entity %d to %d: %w", canonical, sourceID, targetID, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("removing relation %s: %w", canonical, err)
+	}
+	return nil
+}
+
+// RelatedEntities returns the targets the entity relates to through the
+// named relation, in ascending id order.
+func (s *EntityService) RelatedEntities(ctx context.Context, entityID int64, relName string) ([]int64, error) {
+	_, canonical, err := s.relation("query", entityID, relName, 0)
+	if err != nil {
+		return nil, err
+	}
+	return s.store.RelatedEntities(ctx, entityID, canonical)
+}
+
+// EntitiesRelatedTo returns the sources that relate to the target through
+// the named relation, in ascending id order. For a ChildOf relation these
+// are the children of targetID.
+func (s *EntityService) EntitiesRelatedTo(ctx context.Context, relName string, targetID int64) ([]int64, error) {
+	_, canonical, err := s.relation("query", 0, relName, targetID)
+	if err != nil {
+		return nil, err
+	}
+	return s.store.EntitiesRelatedTo(ctx, canonical, targetID)
+}
+
+// relation resolves relName to a declared relation component and its
+// canonical name, or returns a RelationError for action.
+func (s *EntityService) relation(action string, sourceID int64, relName string, targetID int64) (schema.Component, string, error) {
+	fail := func(msg string) (schema.Component, string, error) {
+		return schema.Component{}, "", &RelationError{
+			Action:   action,
+			Relation: relName,
+			SourceID: sourceID,
+			TargetID: targetID,
+			Errors:   []string{msg},
+		}
+	}
+	if s.schema == nil {
+		return fail("no schema loaded")
+	}
+	comp, canonical := schema.ComponentByName(s.schema, relName)
+	if canonical == "" {
+		return fail(fmt.Sprintf("component %q is not declared in schema", relName))
+	}
+	if comp.Type != schema.ComponentTypeRelation {
+		return fail(fmt.Sprintf("component %q has type %q, not %q", canonical, comp.Type, schema.ComponentTypeRelation))
+	}
+	return comp, canonical, nil
+}
+
+// IsRelationExists reports whether an error is the ErrRelationExists
+// sentinel.
+func IsRelationExists(err error) bool {
+	return errors.Is(err, ErrRelationExists)
+}
+
+// RelationError is returned when adding, removing or querying a relation
+// fails validation.
+type RelationError struct {
+	Action   string // "add", "remove" or "query"
+	Relation string
+	SourceID int64
+	TargetID int64
+	Errors   []string
+	Fields   []*FieldError // constraint violations, also present in Errors
+}
+
+func (e *RelationError) Error() string {
+	if len(e.Errors) == 0 {
+		return fmt.Sprintf("relation %s validation failed for %s: no details", e.Action, e.Relation)
+	}
+	return fmt.Sprintf("relation %s validation failed for %s (%d → %d): %s",
+		e.Action, e.Relation, e.SourceID, e.TargetID, e.Errors[0])
+}
@@ -0,0 +1,83 @@
+package world
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tmbritton/ecs-db/internal/schema"
+)
+
+func relationServiceSchema() schema.DatabaseSchema {
+	s := baseSchema()
+	minWeight := 0.0
+	s.Components["ChildOf"] = schema.Component{Type: schema.ComponentTypeRelation, RefType: "Goblin"}
+	s.Components["Likes"] = schema.Component{Type: schema.ComponentTypeRelation, Properties: map[string]schema.Property{
+		"weight": {Type: schema.PropertyTypeNumber, Default: float64(1), Minimum: &minWeight},
+	}}
+	s.EntityTypes["Rock"] = schema.EntityType{ValidationLevel: schema.ValidationStrict}
+	return s
+}
+
+func TestEntityService_AddRelation(t *testing.T) {
+	ctx := context.Background()
+	types := map[int64]string{1: "Goblin", 2: "Goblin", 3: "Rock"}
+
+	tests := []struct {
+		name    string
+		rel     string
+		target  int64
+		values  map[string]interface{}
+		txErr   error
+		wantErr string
+	}{
+		{"ok", "ChildOf", 2, nil, nil, ""},
+		{"case-insensitive name", "childof", 2, nil, nil, ""},
+		{"not a relation", "Position", 2, nil, nil, `component "Position" has type "object", not "relation"`},
+		{"undeclared", "OwnedBy", 2, nil, nil, `component "OwnedBy" is not declared`},
+		{"refType", "ChildOf", 3, nil, nil, `target entity 3 has type "Rock", want "Goblin"`},
+		{"missing target", "ChildOf", 9, nil, nil, "entity 9 not found"},
+		{"constraint", "Likes", 3, map[string]interface{}{"weight": -1}, nil, "less than minimum 0"},
+		{"duplicate", "ChildOf", 2, nil, ErrRelationExists, "relation already exists"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := &mockTx{addRelationErr: tt.txErr}
+			svc := NewEntityService(&mockStore{tx: tx, entityTypes: types})
+			svc.SetSchema(relationServiceSchema())
+
+			err := svc.AddRelation(ctx, 1, tt.rel, tt.target, tt.values)
+			if tt.wantErr == "" {
+				if err != nil || !tx.committed {
+					t.Fatalf("err = %v, committed = %v; want a committed add", err, tx.committed)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+			}
+			if tx.committed {
+				t.Error("failed AddRelation committed")
+			}
+		})
+	}
+}
+
+func TestEntityService_RelationQueries(t *testing.T) {
+	ctx := context.Background()
+	store := &mockStore{tx: &mockTx{}, related: []int64{4, 5}}
+	svc := NewEntityService(store)
+	svc.SetSchema(relationServiceSchema())
+
+	if got, err := svc.EntitiesRelatedTo(ctx, "ChildOf", 1); err != nil || len(got) != 2 {
+		t.Errorf("EntitiesRelatedTo = %v, %v; want [4 5]", got, err)
+	}
+	var relErr *RelationError
+	if _, err := svc.RelatedEntities(ctx, 1, "Health"); !errors.As(err, &relErr) {
+		t.Errorf("RelatedEntities on a component err = %v, want a RelationError", err)
+	}
+	if err := svc.RemoveRelation(ctx, 1, "ChildOf", 2); err != nil || !store.tx.committed {
+		t.Errorf("RemoveRelation err = %v, committed = %v", err, store.tx.committed)
+	}
+}
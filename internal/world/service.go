@@ -43,27 +43,12 @@ func (s *EntityService) CreateEntity(
 	entityTypeName string,
 	components []EntityComponent,
 ) (*Entity, error) {
-	// Extract component names for validation.
-	names := make([]string, len(components))
-	for i, c := range components {
-		names[i] = c.Name
-	}
-
-	// Validate against schema.
-	vr := ValidateEntityCreation(s.schema, entityTypeName, names)
-	if !vr.Valid() {
-		s.warnings = vr.Warnings
-		return nil, &ValidationError{
-			Type:     entityTypeName,
-			Errors:   vr.Errors,
-			Warnings: vr.Warnings,
-		}
+	filled, warnings, err := PrepareEntityCreation(ctx, s.schema, entityTypeName, components, s.store.GetEntityType)
+	s.warnings = warnings
+	if err != nil {
+		return nil, err
 	}
 
-	// Collect warnings (may be non-empty in warning mode).
-	s.warnings = make([]string, len(vr.Warnings))
-	copy(s.warnings, vr.Warnings)
-
 	// Begin transaction.
 	tx, err := s.store.BeginTx(ctx)
 	if err != nil {
@@ -85,7 +70,7 @@ func (s *EntityService) CreateEntity(
 	}
 
 	// Insert each component row.
-	for _, comp := range components {
+	for _, comp := range filled {
 		if err := tx.InsertComponent(ctx, entityID, comp.Name, comp.Values); err != nil {
 			_ = tx.Rollback()
 			return nil, err
@@ -104,11 +89,90 @@ func (s *EntityService) CreateEntity(
 	}, nil
 }
 
+// PrepareEntityCreation runs CreateEntity's checks on an entity of the
+// given type with the provided components, without writing anything:
+// the entity type contract (ValidateEntityCreation), declared defaults,
+// property constraints and entity-ref refTypes, resolved through
+// entityType. It returns the components with their defaults filled in and
+// the validation warnings. A failed check is returned as a
+// *ValidationError; any other error comes from entityType.
+//
+// Writers that create entities outside EntityService, such as the agent
+// world writer, call it to validate the same way CreateEntity does.
+func PrepareEntityCreation(
+	ctx context.Context,
+	s *schema.DatabaseSchema,
+	entityTypeName string,
+	components []EntityComponent,
+	entityType EntityTypeLookup,
+) ([]EntityComponent, []string, error) {
+	// Extract component names for validation.
+	names := make([]string, len(components))
+	for i, c := range components {
+		names[i] = c.Name
+	}
+
+	// Validate against schema.
+	vr := ValidateEntityCreation(s, entityTypeName, names)
+	if !vr.Valid() {
+		return nil, vr.Warnings, &ValidationError{
+			Type:     entityTypeName,
+			Errors:   vr.Errors,
+			Warnings: vr.Warnings,
+		}
+	}
+
+	// Fill omitted fields from declared defaults, then validate component
+	// values against property constraints and entity references against
+	// their refType.
+	filled := make([]EntityComponent, len(components))
+	var fieldErrs []*FieldError
+	for i, c := range components {
+		filled[i] = EntityComponent{Name: c.Name, Values: applyDefaults(s, c.Name, c.Values)}
+		fieldErrs = append(fieldErrs, ValidateComponentValues(s, c.Name, filled[i].Values)...)
+		refErrs, err := CheckRefTypes(ctx, s, entityType, c.Name, filled[i].Values)
+		if err != nil {
+			return nil, vr.Warnings, err
+		}
+		fieldErrs = append(fieldErrs, refErrs...)
+	}
+	if len(fieldErrs) > 0 {
+		return nil, vr.Warnings, &ValidationError{
+			Type:     entityTypeName,
+			Errors:   fieldErrorMessages(fieldErrs),
+			Warnings: vr.Warnings,
+			Fields:   fieldErrs,
+		}
+	}
+
+	// Collect warnings (may be non-empty in warning mode).
+	warnings := make([]string, len(vr.Warnings))
+	copy(warnings, vr.Warnings)
+	return filled, warnings, nil
+}
+
+// applyDefaults returns values with every omitted property that declares a
+// default filled in. Unknown components are returned unchanged so that
+// validation can report them.
+func applyDefaults(s *schema.DatabaseSchema, compName string, values map[string]interface{}) map[string]interface{} {
+	if s == nil {
+		return values
+	}
+	comp, canonical := schema.ComponentByName(s, compName)
+	if canonical == "" {
+		return values
+	}
+	return comp.WithDefaults(values)
+}
+
 // ValidationError is returned when entity creation fails validation.
+// Fields is populated when the failure is caused by component values that
+// violate property constraints; each entry is also present in Errors.
 type ValidationError struct {
 	Type     string
 	Errors   []string
 	Warnings []string
+	Fields   []*FieldError
 }
 
 func (e *ValidationError) Error() string {
@@ -152,6 +216,28 @@ func (s *EntityService) AttachComponent(
 		}
 	}
 
+	// Fill omitted fields from declared defaults and validate the values
+	// against property constraints and entity references against their
+	// refType.
+	values = applyDefaults(s.schema, compName, values)
+	fieldErrs := ValidateComponentValues(s.schema, compName, values)
+	refErrs, err := CheckRefTypes(ctx, s.schema, s.store.GetEntityType, compName, values)
+	if err != nil {
+		return fmt.Errorf("attaching component to entity %d: %w", entityID, err)
+	}
+	fieldErrs = append(fieldErrs, refErrs...)
+	if len(fieldErrs) > 0 {
+		s.warnings = vr.Warnings
+		return &ComponentMutationError{
+			Action:   "attach",
+			EntityID: entityID,
+			Type:     entityTypeName,
+			Errors:   fieldErrorMessages(fieldErrs),
+			Warnings: vr.Warnings,
+			Fields:   fieldErrs,
+		}
+	}
+
 	s.warnings = make([]string, len(vr.Warnings))
 	copy(s.warnings, vr.Warnings)
 
@@ -219,6 +305,34 @@ func (s *EntityService) DetachComponent(
 	return nil
 }
 
+// DestroyEntity deletes an existing entity and everything attached to it
+// in its own transaction, recording a despawn in transitions at the current
+// tick. Entity-refs pointing at it follow their onDelete policy: cascade
+// detaches the referencing component, setNull clears the property, and
+// restrict or no policy refuses the destroy with an *EntityReferencedError.
+func (s *EntityService) DestroyEntity(ctx context.Context, entityID int64) error {
+	if _, err := s.store.GetEntityType(ctx, entityID); err != nil {
+		return fmt.Errorf("destroying entity %d: %w", entityID, err)
+	}
+	tick, err := s.store.GetCurrentTick(ctx)
+	if err != nil {
+		return fmt.Errorf("destroying entity %d: %w", entityID, err)
+	}
+
+	tx, err := s.store.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("destroying entity %d: %w", entityID, err)
+	}
+	if err := tx.DestroyEntity(ctx, entityID, tick); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("destroying entity %d: %w", entityID, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("destroying entity %d: %w", entityID, err)
+	}
+	return nil
+}
+
 // ComponentMutationError is returned when attach/detach fails validation.
 type ComponentMutationError struct {
 	Action   string // "attach" or "detach"
@@ -226,6 +340,7 @@ type ComponentMutationError struct {
 	Type     string
 	Errors   []string
 	Warnings []string
+	Fields   []*FieldError // constraint violations, also present in Errors
 }
 
 func (e *ComponentMutationError) Error() string {
@@ -647,3 +647,33 @@ func TestEntityService_DetachComponent_BeginTxError(t *testing.T) {
 		t.Errorf("expected 'db locked' in error: %v", err)
 	}
 }
+
+func TestEntityService_DestroyEntity(t *testing.T) {
+	ctx := context.Background()
+
+	tx := &mockTx{}
+	svc := NewEntityService(&mockStore{tx: tx, currentTick: 12, entityTypes: map[int64]string{4: "Goblin"}})
+	if err := svc.DestroyEntity(ctx, 4); err != nil {
+		t.Fatal(err)
+	}
+	if !tx.committed || len(tx.destroyed) != 1 || tx.destroyed[0] != 4 || tx.destroyTick != 12 {
+		t.Errorf("tx = %+v, want entity 4 destroyed at tick 12 and committed", tx)
+	}
+
+	tx = &mockTx{}
+	svc = NewEntityService(&mockStore{tx: tx, entityTypes: map[int64]string{}})
+	var notFound *EntityNotFoundError
+	if err := svc.DestroyEntity(ctx, 4); !errors.As(err, &notFound) || len(tx.destroyed) != 0 {
+		t.Errorf("err = %v, destroyed %v; want *EntityNotFoundError before any write", err, tx.destroyed)
+	}
+
+	tx = &mockTx{destroyErr: &EntityReferencedError{ID: 4, References: []string{"Owner of entity 5"}}}
+	svc = NewEntityService(&mockStore{tx: tx, entityTypes: map[int64]string{4: "Goblin"}})
+	err := svc.DestroyEntity(ctx, 4)
+	if err == nil || !strings.Contains(err.Error(), "entity 4 is still referenced by Owner of entity 5") {
+		t.Errorf("err = %v, want the reference named", err)
+	}
+	if tx.committed || !tx.rolledBack {
+		t.Error("a refused destroy must roll back")
+	}
+}
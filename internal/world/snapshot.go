@@ -0,0 +1,52 @@
+package world
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tmbritton/ecs-db/internal/schema"
+)
+
+// GetEntity returns the entity with its component values and the states
+// of its behavior machines. Returns an *EntityNotFoundError if the entity
+// does not exist.
+func (s *EntityService) GetEntity(ctx context.Context, entityID int64) (*EntitySnapshot, error) {
+	return s.store.GetEntity(ctx, entityID)
+}
+
+// ListEntities returns one page of the entities matching filter, in
+// ascending id order. Pass cursor 0 for the first page and the returned
+// NextCursor for each following page until it is 0:
+//
+//	for cursor := int64(0); ; {
+//		page, err := svc.ListEntities(ctx, filter, cursor)
+//		...
+//		if cursor = page.NextCursor; cursor == 0 {
+//			break
+//		}
+//	}
+//
+// With a schema, the filter's entity type and components must be declared;
+// component names are matched case-insensitively.
+func (s *EntityService) ListEntities(ctx context.Context, filter EntityFilter, cursor int64) (EntityPage, error) {
+	if s.schema != nil {
+		if filter.EntityType != "" {
+			if _, ok := s.schema.EntityTypes[filter.EntityType]; !ok {
+				return EntityPage{}, fmt.Errorf("ListEntities: entity type %q is not declared in schema", filter.EntityType)
+			}
+		}
+		components := make([]string, len(filter.Components))
+		for i, name := range filter.Components {
+			comp, canonical := schema.ComponentByName(s.schema, name)
+			if canonical == "" {
+				return EntityPage{}, fmt.Errorf("ListEntities: component %q is not declared in schema", name)
+			}
+			if comp.Type == schema.ComponentTypeRelation {
+				return EntityPage{}, fmt.Errorf("ListEntities: %q is a relation, not an entity component", canonical)
+			}
+			components[i] = canonical
+		}
+		filter.Components = components
+	}
+	return s.store.ListEntities(ctx, filter, cursor)
+}
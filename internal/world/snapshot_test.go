@@ -0,0 +1,56 @@
+package world
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEntityService_ListEntities(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  EntityFilter
+		want    []string // components passed to the store
+		wantErr string
+	}{
+		{"empty filter", EntityFilter{}, []string{}, ""},
+		{"canonical component names", EntityFilter{EntityType: "Goblin", Components: []string{"health", "POSITION"}},
+			[]string{"Health", "Position"}, ""},
+		{"undeclared type", EntityFilter{EntityType: "Orc"}, nil, `entity type "Orc" is not declared`},
+		{"undeclared component", EntityFilter{Components: []string{"Mana"}}, nil, `component "Mana" is not declared`},
+		{"relation", EntityFilter{Components: []string{"childof"}}, nil, `"ChildOf" is a relation`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &mockStore{page: EntityPage{Entities: []Entity{{ID: 4}}, NextCursor: 4}}
+			svc := NewEntityService(store)
+			svc.SetSchema(relationServiceSchema())
+
+			page, err := svc.ListEntities(context.Background(), tt.filter, 0)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if page.NextCursor != 4 || len(page.Entities) != 1 {
+				t.Errorf("page = %+v, want the store's page", page)
+			}
+			if !reflect.DeepEqual(store.listFilter.Components, tt.want) {
+				t.Errorf("store got components %v, want %v", store.listFilter.Components, tt.want)
+			}
+		})
+	}
+}
+
+func TestEntityService_GetEntity(t *testing.T) {
+	snap := &EntitySnapshot{Entity: Entity{ID: 7, EntityType: "Goblin"}}
+	svc := NewEntityService(&mockStore{snapshot: snap})
+	if got, err := svc.GetEntity(context.Background(), 7); err != nil || got != snap {
+		t.Errorf("GetEntity = %+v, %v; want the store's snapshot", got, err)
+	}
+}
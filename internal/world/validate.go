@@ -13,6 +13,11 @@ import (
 type ValidationResult struct {
 	Errors   []string
 	Warnings []string
+	// MissingRequired and Disallowed name the components behind the
+	// contract violations of checks (3) and (4), whichever of Errors or
+	// Warnings they were reported in.
+	MissingRequired []string
+	Disallowed      []string
 }
 
 // Valid reports whether the result has no hard errors.
@@ -26,7 +31,8 @@ func (r ValidationResult) Valid() bool {
 // Checks (in order):
 //  1. Entity type must exist in schema.
 //  2. All provided components must be declared in schema.Components
-//     (always a hard error — no table exists for undeclared components).
+//     (always a hard error — no table exists for undeclared components)
+//     and must not be relations, which link two entities.
 //  3. All required components must be present.
 //  4. No disallowed extra components when allowExtraComponents is false.
 //
@@ -40,7 +46,7 @@ func ValidateEntityCreation(
 	var vr ValidationResult
 
 	// 1. Entity type must exist.
-	et, ok := s.EntityTypes[entityTypeName]
+	et, ok := schema.ResolveEntityType(s, entityTypeName)
 	if !ok {
 		vr.Errors = append(vr.Errors,
 			fmt.Sprintf("unknown entity type %q", entityTypeName))
@@ -57,9 +63,14 @@ func ValidateEntityCreation(
 	// 2. All provided components must be declared.
 	// Sort for deterministic error ordering.
 	sorted := make([]string, 0, len(providedComponents))
+	var relations []string
 	for _, c := range providedComponents {
-		if _, ok := s.Components[c]; !ok {
+		comp, ok := s.Components[c]
+		switch {
+		case !ok:
 			sorted = append(sorted, c)
+		case comp.Type == schema.ComponentTypeRelation:
+			relations = append(relations, c)
 		}
 	}
 	sort.Strings(sorted)
@@ -67,8 +78,14 @@ func ValidateEntityCreation(
 		vr.Errors = append(vr.Errors,
 			fmt.Sprintf("component %q is not declared in schema", c))
 	}
-	// If any components are undeclared, return early — we can't proceed.
-	if len(sorted) > 0 {
+	sort.Strings(relations)
+	for _, c := range relations {
+		vr.Errors = append(vr.Errors,
+			fmt.Sprintf("component %q is a relation; add it between two entities with AddRelation", c))
+	}
+	// If any components are undeclared or relations, return early — we
+	// can't proceed.
+	if len(sorted) > 0 || len(relations) > 0 {
 		return vr
 	}
 
@@ -76,6 +93,7 @@ func ValidateEntityCreation(
 	warningMode := et.ValidationLevel == schema.ValidationWarning
 	for _, req := range et.RequiredComponents {
 		if !providedSet[req] {
+			vr.MissingRequired = append(vr.MissingRequired, req)
 			msg := fmt.Sprintf("missing required component %q for entity type %q", req, entityTypeName)
 			if warningMode {
 				vr.Warnings = append(vr.Warnings, msg)
@@ -89,6 +107,7 @@ func ValidateEntityCreation(
 	if !et.AllowExtraComponents {
 		for _, provided := range providedComponents {
 			if !et.IsComponentAllowed(provided) {
+				vr.Disallowed = append(vr.Disallowed, provided)
 				msg := fmt.Sprintf("component %q is not allowed for entity type %q", provided, entityTypeName)
 				if warningMode {
 					vr.Warnings = append(vr.Warnings, msg)
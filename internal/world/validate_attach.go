@@ -11,7 +11,8 @@ import (
 //
 // Checks (in order):
 //  1. Entity type must exist in schema.
-//  2. Component must be declared in schema.Components (always hard error).
+//  2. Component must be declared in schema.Components and not be a
+//     relation (always hard error).
 //  3. Component must be allowed on the entity type.
 //  4. Component must not already be attached (always hard error, no upsert).
 //
@@ -26,7 +27,7 @@ func ValidateAttachComponent(
 	var vr ValidationResult
 
 	// 1. Entity type must exist.
-	et, ok := s.EntityTypes[entityTypeName]
+	et, ok := schema.ResolveEntityType(s, entityTypeName)
 	if !ok {
 		vr.Errors = append(vr.Errors,
 			fmt.Sprintf("unknown entity type %q", entityTypeName))
@@ -36,11 +37,17 @@ func ValidateAttachComponent(
 	et.ApplyDefaults()
 
 	// 2. Component must be declared.
-	if _, ok := s.Components[componentName]; !ok {
+	comp, ok := s.Components[componentName]
+	if !ok {
 		vr.Errors = append(vr.Errors,
 			fmt.Sprintf("component %q is not declared in schema", componentName))
 		return vr
 	}
+	if comp.Type == schema.ComponentTypeRelation {
+		vr.Errors = append(vr.Errors,
+			fmt.Sprintf("component %q is a relation; add it between two entities with AddRelation", componentName))
+		return vr
+	}
 
 	// 3. Component must be allowed on the entity type.
 	if !et.IsComponentAllowed(componentName) && !et.AllowExtraComponents {
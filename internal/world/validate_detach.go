@@ -22,7 +22,7 @@ func ValidateDetachComponent(
 	var vr ValidationResult
 
 	// 1. Entity type must exist.
-	et, ok := s.EntityTypes[entityTypeName]
+	et, ok := schema.ResolveEntityType(s, entityTypeName)
 	if !ok {
 		vr.Errors = append(vr.Errors,
 			fmt.Sprintf("unknown entity type %q", entityTypeName))
@@ -0,0 +1,114 @@
+package world
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/tmbritton/ecs-db/internal/schema"
+)
+
+// EntityTypeLookup returns the type of an existing entity, or an
+// *EntityNotFoundError when there is none. EntityStore.GetEntityType is one.
+type EntityTypeLookup func(ctx context.Context, entityID int64) (string, error)
+
+// CheckRefTypes verifies that every entity-ref value of the named
+// component whose declaration has a refType points at an existing entity
+// of that type or one of its subtypes. Violations are returned as field
+// errors, one per offending field, sorted by field name; the error return
+// is reserved for store failures. nil values and values that are not
+// entity ids are skipped; the foreign key and the value checks report
+// those.
+func CheckRefTypes(
+	ctx context.Context,
+	s *schema.DatabaseSchema,
+	entityType EntityTypeLookup,
+	componentName string,
+	values map[string]interface{},
+) ([]*FieldError, error) {
+	if s == nil {
+		return nil, nil
+	}
+	comp, canonical := schema.ComponentByName(s, componentName)
+	if canonical == "" {
+		return nil, nil
+	}
+
+	// refTypes maps each value key to the entity type it must reference.
+	refTypes := make(map[string]string)
+	switch comp.Type {
+	case schema.ComponentTypeEntityRef:
+		if comp.RefType != "" {
+			for _, key := range []string{"target_entity_id", "target"} {
+				if _, ok := values[key]; ok {
+					refTypes[key] = comp.RefType
+				}
+			}
+		}
+	case schema.ComponentTypeObject, schema.ComponentTypeRelation:
+		for field := range values {
+			prop, ok := schema.PropertyByName(comp.Properties, field)
+			if ok && prop.Type == schema.PropertyTypeEntityRef && prop.RefType != "" {
+				refTypes[field] = prop.RefType
+			}
+		}
+	}
+
+	fields := make([]string, 0, len(refTypes))
+	for field := range refTypes {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var errs []*FieldError
+	for _, field := range fields {
+		id, ok := entityID(values[field])
+		if !ok {
+			continue
+		}
+		want := refTypes[field]
+		got, err := entityType(ctx, id)
+		var notFound *EntityNotFoundError
+		switch {
+		case errors.As(err, &notFound):
+			errs = append(errs, &FieldError{
+				Component: canonical,
+				Field:     field,
+				Message:   fmt.Sprintf("references entity %d, which does not exist (want a %s)", id, want),
+			})
+		case err != nil:
+			return nil, err
+		case !schema.IsA(s, got, want):
+			errs = append(errs, &FieldError{
+				Component: canonical,
+				Field:     field,
+				Message:   fmt.Sprintf("references entity %d of type %q, want %q", id, got, want),
+			})
+		}
+	}
+	return errs, nil
+}
+
+// entityID converts a decoded entity-ref value to an entity id. Whole
+// floats are accepted because JSON numbers decode as float64.
+func entityID(v interface{}) (int64, bool) {
+	switch x := v.(type) {
+	case int64:
+		return x, true
+	case int:
+		return int64(x), true
+	case int32:
+		return int64(x), true
+	case float64:
+		if x != float64(int64(x)) {
+			return 0, false
+		}
+		return int64(x), true
+	case json.Number:
+		n, err := x.Int64()
+		return n, err == nil
+	}
+	return 0, false
+}
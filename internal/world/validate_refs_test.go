@@ -0,0 +1,81 @@
+package world
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tmbritton/ecs-db/internal/schema"
+)
+
+// refSchema declares a Goblin with an optional Target whose entity
+// property must reference a Player, and a Leader entity-ref component
+// restricted to Goblins.
+func refSchema() schema.DatabaseSchema {
+	return schema.DatabaseSchema{
+		SchemaVersion: 1,
+		Components: map[string]schema.Component{
+			"Target": {
+				Type: schema.ComponentTypeObject,
+				Properties: map[string]schema.Property{
+					"entity": {Type: schema.PropertyTypeEntityRef, RefType: "Player", Nullable: true},
+				},
+			},
+			"Leader": {Type: schema.ComponentTypeEntityRef, RefType: "Goblin"},
+		},
+		EntityTypes: map[string]schema.EntityType{
+			"Goblin": {OptionalComponents: []string{"Target", "Leader"}, ValidationLevel: schema.ValidationStrict},
+			"Player": {ValidationLevel: schema.ValidationStrict},
+		},
+	}
+}
+
+func TestEntityService_CreateEntity_RefType(t *testing.T) {
+	tests := []struct {
+		name    string
+		comps   []EntityComponent
+		wantErr string
+	}{
+		{"matching type", []EntityComponent{{Name: "Target", Values: map[string]interface{}{"entity": int64(1)}}}, ""},
+		{"null reference", []EntityComponent{{Name: "Target", Values: map[string]interface{}{"entity": nil}}}, ""},
+		{"wrong type", []EntityComponent{{Name: "Target", Values: map[string]interface{}{"entity": 2.0}}}, `type "Goblin", want "Player"`},
+		{"missing entity", []EntityComponent{{Name: "Target", Values: map[string]interface{}{"entity": 9}}}, "does not exist"},
+		{"component ref", []EntityComponent{{Name: "Leader", Values: map[string]interface{}{"target": int64(1)}}}, `type "Player", want "Goblin"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := &mockTx{insertEntityResults: []insertEntityResult{{id: 3}}}
+			store := &mockStore{tx: tx, entityTypes: map[int64]string{1: "Player", 2: "Goblin"}}
+			svc := NewEntityService(store)
+			svc.SetSchema(refSchema())
+
+			_, err := svc.CreateEntity(context.Background(), "Goblin", tt.comps)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("CreateEntity: %v", err)
+				}
+				return
+			}
+			var ve *ValidationError
+			if !errors.As(err, &ve) || len(ve.Fields) != 1 || !strings.Contains(ve.Fields[0].Message, tt.wantErr) {
+				t.Fatalf("err = %v, want a field error containing %q", err, tt.wantErr)
+			}
+			if tx.committed {
+				t.Error("transaction committed despite a refType violation")
+			}
+		})
+	}
+}
+
+func TestEntityService_AttachComponent_RefType(t *testing.T) {
+	store := &mockStore{tx: &mockTx{}, entityTypes: map[int64]string{1: "Goblin", 2: "Goblin"}}
+	svc := NewEntityService(store)
+	svc.SetSchema(refSchema())
+
+	err := svc.AttachComponent(context.Background(), 1, "Target", map[string]interface{}{"entity": int64(2)})
+	var me *ComponentMutationError
+	if !errors.As(err, &me) || len(me.Fields) != 1 || me.Fields[0].Field != "entity" {
+		t.Fatalf("err = %v, want a field error on entity", err)
+	}
+}
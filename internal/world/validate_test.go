@@ -1,6 +1,7 @@
 package world
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 
@@ -62,6 +63,31 @@ func TestValidateEntityCreation(t *testing.T) {
 			wantErrors:   0,
 			wantWarnings: 0,
 		},
+		{
+			name: "subtype missing inherited required component",
+			schema: schema.DatabaseSchema{
+				SchemaVersion: 1,
+				Components: map[string]schema.Component{
+					"Position": {Type: schema.ComponentTypeObject},
+					"Health":   {Type: schema.ComponentTypeObject},
+					"Bow":      {Type: schema.ComponentTypeTag},
+				},
+				EntityTypes: map[string]schema.EntityType{
+					"Goblin": {
+						RequiredComponents: []string{"Position", "Health"},
+						ValidationLevel:    schema.ValidationStrict,
+					},
+					"GoblinArcher": {
+						Extends:            "Goblin",
+						RequiredComponents: []string{"Bow"},
+					},
+				},
+			},
+			entityType:      "GoblinArcher",
+			provided:        []string{"Position", "Bow"},
+			wantErrors:      1,
+			wantErrorSubstr: "Health",
+		},
 		{
 			name: "missing required component strict mode",
 			schema: schema.DatabaseSchema{
@@ -309,3 +335,24 @@ func TestValidateEntityCreation(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateEntityCreation_NamesViolatingComponents(t *testing.T) {
+	for _, level := range []schema.ValidationLevel{schema.ValidationStrict, schema.ValidationWarning} {
+		s := schema.DatabaseSchema{
+			SchemaVersion: 1,
+			Components: map[string]schema.Component{
+				"Position": {Type: schema.ComponentTypeObject},
+				"Health":   {Type: schema.ComponentTypeObject},
+				"Sprite":   {Type: schema.ComponentTypeObject},
+			},
+			EntityTypes: map[string]schema.EntityType{
+				"Goblin": {RequiredComponents: []string{"Position", "Health"}, ValidationLevel: level},
+			},
+		}
+		vr := ValidateEntityCreation(&s, "Goblin", []string{"Position", "Sprite"})
+		if !reflect.DeepEqual(vr.MissingRequired, []string{"Health"}) || !reflect.DeepEqual(vr.Disallowed, []string{"Sprite"}) {
+			t.Errorf("%s: MissingRequired = %v, Disallowed = %v; want [Health] and [Sprite]",
+				level, vr.MissingRequired, vr.Disallowed)
+		}
+	}
+}
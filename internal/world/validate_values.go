@@ -0,0 +1,98 @@
+package world
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/tmbritton/ecs-db/internal/schema"
+)
+
+// FieldError describes a single component property value that violates the
+// constraints declared for it in schema.json (minimum, maximum, enum,
+// pattern, minLength, maxLength).
+type FieldError struct {
+	Component string
+	Field     string
+	Message   string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("component %q field %q: %s", e.Component, e.Field, e.Message)
+}
+
+// ValidateComponentValues checks each value against the constraints of the
+// matching property of the named object or relation component. Property names are
+// matched case-insensitively, mirroring the lowercase column names in
+// comp_* tables. Keys with no matching property and nil values are skipped;
+// unknown components and other non-object components produce no errors,
+// except tags, which reject every value since they have no fields. A value
+// for a computed property is always an error: SQLite derives it. Object and
+// array values are checked down to their nested properties and items.
+//
+// Errors are returned one per offending field, sorted by field name.
+func ValidateComponentValues(
+	s *schema.DatabaseSchema,
+	componentName string,
+	values map[string]interface{},
+) []*FieldError {
+	if s == nil {
+		return nil
+	}
+	comp, canonical := schema.ComponentByName(s, componentName)
+	if canonical == "" || (comp.Type != schema.ComponentTypeObject && comp.Type != schema.ComponentTypeTag &&
+		comp.Type != schema.ComponentTypeRelation) {
+		return nil
+	}
+
+	fields := make([]string, 0, len(values))
+	for field := range values {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	if comp.Type == schema.ComponentTypeTag {
+		errs := make([]*FieldError, len(fields))
+		for i, field := range fields {
+			errs[i] = &FieldError{
+				Component: canonical,
+				Field:     field,
+				Message:   "tag components have no fields",
+			}
+		}
+		return errs
+	}
+
+	var errs []*FieldError
+	for _, field := range fields {
+		prop, ok := schema.PropertyByName(comp.Properties, field)
+		if !ok {
+			continue
+		}
+		if prop.IsComputed() {
+			errs = append(errs, &FieldError{
+				Component: canonical,
+				Field:     field,
+				Message:   "computed property is read-only",
+			})
+			continue
+		}
+		if err := prop.CheckValue(values[field]); err != nil {
+			errs = append(errs, &FieldError{
+				Component: canonical,
+				Field:     field,
+				Message:   err.Error(),
+			})
+		}
+	}
+	return errs
+}
+
+// fieldErrorMessages flattens field errors into the string form carried by
+// ValidationError and ComponentMutationError.
+func fieldErrorMessages(errs []*FieldError) []string {
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Error()
+	}
+	return msgs
+}
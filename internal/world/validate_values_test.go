@@ -0,0 +1,144 @@
+package world
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tmbritton/ecs-db/internal/schema"
+)
+
+// constrainedSchema declares Health.hp >= 0, the computed Health.alive, a
+// non-empty Sprite.imageId and Sprite.offset.x >= 0 nested in an object.
+func constrainedSchema() schema.DatabaseSchema {
+	minHP := 0.0
+	minLen := 1
+	return schema.DatabaseSchema{
+		SchemaVersion: 1,
+		Components: map[string]schema.Component{
+			"Health": {
+				Type: schema.ComponentTypeObject,
+				Properties: map[string]schema.Property{
+					"hp":    {Type: schema.PropertyTypeInteger, Minimum: &minHP},
+					"alive": {Type: schema.PropertyTypeBoolean, Computed: "hp > 0"},
+				},
+			},
+			"Sprite": {
+				Type: schema.ComponentTypeObject,
+				Properties: map[string]schema.Property{
+					"imageId": {Type: schema.PropertyTypeString, MinLength: &minLen},
+					"frame":   {Type: schema.PropertyTypeInteger},
+					"offset": {Type: schema.PropertyTypeObject, Properties: map[string]schema.Property{
+						"x": {Type: schema.PropertyTypeInteger, Minimum: &minHP},
+					}},
+				},
+			},
+		},
+		EntityTypes: map[string]schema.EntityType{
+			"Goblin": {
+				RequiredComponents: []string{"Health", "Sprite"},
+				ValidationLevel:    schema.ValidationStrict,
+			},
+		},
+	}
+}
+
+func TestValidateComponentValues(t *testing.T) {
+	s := constrainedSchema()
+	tests := []struct {
+		name       string
+		component  string
+		values     map[string]interface{}
+		wantFields []string
+	}{
+		{"valid", "Health", map[string]interface{}{"hp": 10}, nil},
+		{"below minimum", "Health", map[string]interface{}{"hp": -40}, []string{"hp"}},
+		{"case-insensitive field", "Sprite", map[string]interface{}{"imageid": ""}, []string{"imageid"}},
+		{"multiple fields sorted", "Sprite", map[string]interface{}{"imageId": "", "frame": "x"}, []string{"imageId"}},
+		{"nil value skipped", "Health", map[string]interface{}{"hp": nil}, nil},
+		{"unknown field skipped", "Health", map[string]interface{}{"mana": -1}, nil},
+		{"unknown component", "Mana", map[string]interface{}{"mp": -1}, nil},
+		{"computed is read-only", "Health", map[string]interface{}{"hp": 1, "alive": true}, []string{"alive"}},
+		{"nested property", "Sprite", map[string]interface{}{"offset": map[string]interface{}{"x": -1}}, []string{"offset"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := ValidateComponentValues(&s, tt.component, tt.values)
+			if len(errs) != len(tt.wantFields) {
+				t.Fatalf("got %d field errors %v, want fields %v", len(errs), errs, tt.wantFields)
+			}
+			for i, fe := range errs {
+				if fe.Field != tt.wantFields[i] {
+					t.Errorf("errs[%d].Field = %q, want %q", i, fe.Field, tt.wantFields[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateComponentValues_NilSchema(t *testing.T) {
+	if errs := ValidateComponentValues(nil, "Health", map[string]interface{}{"hp": -1}); errs != nil {
+		t.Errorf("expected no errors without a schema, got %v", errs)
+	}
+}
+
+func TestFieldError_Error(t *testing.T) {
+	fe := &FieldError{Component: "Health", Field: "hp", Message: "value -40 is less than minimum 0"}
+	want := `component "Health" field "hp": value -40 is less than minimum 0`
+	if fe.Error() != want {
+		t.Errorf("Error() = %q, want %q", fe.Error(), want)
+	}
+}
+
+func TestEntityService_CreateEntity_ConstraintViolation_NoDBCall(t *testing.T) {
+	tx := &mockTx{insertEntityResults: []insertEntityResult{{id: 1}}}
+	store := &mockStore{tx: tx}
+	svc := NewEntityService(store)
+	svc.SetSchema(constrainedSchema())
+
+	_, err := svc.CreateEntity(context.Background(), "Goblin", []EntityComponent{
+		{Name: "Health", Values: map[string]interface{}{"hp": -40}},
+		{Name: "Sprite", Values: map[string]interface{}{"imageId": "", "frame": 0}},
+	})
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("expected *ValidationError, got %T: %v", err, err)
+	}
+	if len(ve.Fields) != 2 {
+		t.Fatalf("Fields = %v, want 2 entries", ve.Fields)
+	}
+	if ve.Fields[0].Component != "Health" || ve.Fields[1].Component != "Sprite" {
+		t.Errorf("Fields = %+v, want Health then Sprite", ve.Fields)
+	}
+	if !strings.Contains(err.Error(), "less than minimum") {
+		t.Errorf("error should describe the violation: %v", err)
+	}
+	if tx.committed || tx.insertEntityIdx != 0 {
+		t.Error("no transaction work should happen when values are invalid")
+	}
+}
+
+func TestEntityService_AttachComponent_ConstraintViolation(t *testing.T) {
+	tx := &mockTx{}
+	store := &mockStore{tx: tx, entityType: "Goblin"}
+	svc := NewEntityService(store)
+	s := constrainedSchema()
+	et := s.EntityTypes["Goblin"]
+	et.RequiredComponents = []string{"Sprite"}
+	et.OptionalComponents = []string{"Health"}
+	s.EntityTypes["Goblin"] = et
+	svc.SetSchema(s)
+
+	err := svc.AttachComponent(context.Background(), 1, "Health", map[string]interface{}{"hp": -1})
+	var cme *ComponentMutationError
+	if !errors.As(err, &cme) {
+		t.Fatalf("expected *ComponentMutationError, got %T: %v", err, err)
+	}
+	if len(cme.Fields) != 1 || cme.Fields[0].Field != "hp" {
+		t.Errorf("Fields = %+v, want one hp violation", cme.Fields)
+	}
+	if tx.committed {
+		t.Error("transaction should not be committed")
+	}
+}